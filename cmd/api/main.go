@@ -3,6 +3,15 @@
 // @description     Todo API with auth, search, overdue.
 // @BasePath        /api/v1
 
+// @securityDefinitions.apikey  CookieAuth
+// @in                          cookie
+// @name                        session_id
+
+// @securityDefinitions.apikey  BearerAuth
+// @in                          header
+// @name                        Authorization
+// @description                 Personal API token: "Bearer tdo_..."
+
 package main
 
 import (
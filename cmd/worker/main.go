@@ -0,0 +1,39 @@
+// Command worker runs background jobs (reminders, trash retention, webhooks) next to the API. Several
+// replicas may run at once: jobs claim their rows with SELECT ... FOR UPDATE SKIP LOCKED, and
+// reminders and webhook deliveries are leased for the time they are being sent.
+package main
+
+import (
+	"context"
+	"log"
+	"os/signal"
+	"syscall"
+
+	"Worker/internal/app"
+	"Worker/internal/config"
+)
+
+func main() {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("config: %v", err)
+	}
+	w, err := app.NewWorker(cfg, nil)
+	if err != nil {
+		log.Fatalf("worker init: %v", err)
+	}
+	defer w.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	log.Printf("worker started, polling reminders every %s", cfg.Worker.ReminderInterval)
+	if cfg.Worker.TrashRetention > 0 {
+		log.Printf("purging todos deleted more than %s ago every %s", cfg.Worker.TrashRetention, cfg.Worker.TrashPurgeInterval)
+	}
+	log.Printf("pruning sync tombstones older than %s every %s", cfg.Sync.TokenTTL, cfg.Worker.TrashPurgeInterval)
+	log.Printf("sending webhooks every %s (up to %d attempts), scanning for overdue todos every %s",
+		cfg.Webhook.PollInterval, cfg.Webhook.MaxAttempts, cfg.Webhook.OverdueInterval)
+	w.Run(ctx)
+	log.Printf("worker stopped")
+}
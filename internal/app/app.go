@@ -6,6 +6,8 @@ import (
 	"time"
 
 	"Worker/internal/config"
+	"Worker/internal/mail"
+	"Worker/internal/secretbox"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -44,7 +46,23 @@ func New(cfg config.Config) (*App, error) {
 		return nil, err
 	}
 
-	a.router = newRouter(cfg, a.db, a.redis)
+	mailer, err := newMailer(cfg.Mail)
+	if err != nil {
+		a.redis.Close()
+		a.db.Close()
+		return nil, err
+	}
+
+	var mfaBox *secretbox.Box
+	if len(cfg.MFA.EncryptionKey) > 0 {
+		if mfaBox, err = secretbox.New(cfg.MFA.EncryptionKey); err != nil {
+			a.redis.Close()
+			a.db.Close()
+			return nil, fmt.Errorf("MFA_ENCRYPTION_KEY: %w", err)
+		}
+	}
+
+	a.router = newRouter(cfg, a.db, a.redis, mailer, mfaBox)
 	return a, nil
 }
 
@@ -105,6 +123,17 @@ func newRedis(cfg config.RedisConfig) (*redis.Client, error) {
 	return rdb, nil
 }
 
+func newMailer(cfg config.MailConfig) (mail.Mailer, error) {
+	switch cfg.Driver {
+	case "log":
+		return mail.NewLogMailer(), nil
+	case "file":
+		return mail.NewFileMailer(cfg.Dir, cfg.From)
+	default:
+		return nil, fmt.Errorf("unknown MAIL_DRIVER %q", cfg.Driver)
+	}
+}
+
 func runMigrations(dsn string, migrationsDir string) error {
 
 	db, err := goose.OpenDBWithDriver("pgx", dsn)
@@ -119,17 +148,17 @@ func runMigrations(dsn string, migrationsDir string) error {
 	return nil
 }
 
-func newRouter(cfg config.Config, db *pgxpool.Pool, rdb *redis.Client) *gin.Engine {
+func newRouter(cfg config.Config, db *pgxpool.Pool, rdb *redis.Client, mailer mail.Mailer, mfaBox *secretbox.Box) *gin.Engine {
 	r := gin.Default()
 
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:  []string{"*", "http://localhost:3000"},
 		AllowMethods:  []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS", "HEAD"},
-		AllowHeaders:  []string{"Origin", "Content-Type", "Accept", "Authorization", "Cookie"},
-		ExposeHeaders: []string{"Content-Length", "Content-Type"},
+		AllowHeaders:  []string{"Origin", "Content-Type", "Accept", "Authorization", "Cookie", "If-Match", "If-None-Match", "Idempotency-Key"},
+		ExposeHeaders: []string{"Content-Length", "Content-Type", "ETag", "Idempotent-Replayed"},
 		MaxAge:        12 * time.Hour,
 	}))
 
-	Setup(r, cfg, db, rdb)
+	Setup(r, cfg, db, rdb, mailer, mfaBox)
 	return r
 }
@@ -1,13 +1,17 @@
 package app
 
 import (
-	"time"
-
 	"Worker/internal/auth"
 	"Worker/internal/cache"
 	"Worker/internal/config"
+	dom "Worker/internal/domain"
 	"Worker/internal/handlers"
+	"Worker/internal/idempotency"
+	"Worker/internal/mail"
+	"Worker/internal/ratelimit"
+	"Worker/internal/realtime"
 	"Worker/internal/repo"
+	"Worker/internal/secretbox"
 	"Worker/internal/service"
 
 	"github.com/gin-gonic/gin"
@@ -19,7 +23,7 @@ import (
 )
 
 // Setup registers all routes on the given engine.
-func Setup(r *gin.Engine, cfg config.Config, db *pgxpool.Pool, rdb *redis.Client) {
+func Setup(r *gin.Engine, cfg config.Config, db *pgxpool.Pool, rdb *redis.Client, mailer mail.Mailer, mfaBox *secretbox.Box) {
 	r.GET("/", rootHandler(cfg))
 	r.GET("/health", healthHandler(cfg))
 	r.GET("/version", versionHandler(cfg))
@@ -34,19 +38,122 @@ func Setup(r *gin.Engine, cfg config.Config, db *pgxpool.Pool, rdb *redis.Client
 
 	api := r.Group("/api/v1")
 
-	sessionStore := auth.NewStore(rdb, 24*time.Hour)
+	// With limiting off, the zero limits below make every middleware a no-op.
+	limits := cfg.RateLimit
+	var loginGuard *ratelimit.LoginGuard
+	limiter := ratelimit.NewLimiter(rdb)
+	if limits.Enabled {
+		lockout := ratelimit.NewLockout(rdb, ratelimit.LockoutPolicy{
+			Threshold: limits.LockoutThreshold,
+			Base:      limits.LockoutBase,
+			Max:       limits.LockoutMax,
+		})
+		loginGuard = ratelimit.NewLoginGuard(limiter, lockout, limits.LoginUser)
+	} else {
+		limits = config.RateLimitConfig{}
+	}
+
+	sessionStore := auth.NewStore(rdb, cfg.Session.TTL, cfg.Session.Sliding)
 	userRepo := repo.NewPGUserRepo(db)
-	userSvc := service.NewUserService(userRepo)
-	authHandler := handlers.NewAuthHandler(sessionStore, userSvc)
-	registerAuthRoutes(api, authHandler)
+	resetRepo := repo.NewPGPasswordResetRepo(db)
+	userSvc := service.NewUserService(userRepo, resetRepo, mailer, service.PasswordResetOptions{
+		TTL: cfg.PasswordReset.TTL,
+		URL: cfg.PasswordReset.URL,
+	})
+	mfaSvc := service.NewMFAService(repo.NewPGMFARepo(db), mfaBox, cfg.MFA.Issuer)
+	pendingStore := auth.NewPendingStore(rdb, cfg.MFA.PendingTTL)
+	authHandler := handlers.NewAuthHandler(sessionStore, pendingStore, userSvc, mfaSvc, loginGuard)
+	registerAuthRoutes(api, authHandler, authLimits{
+		login:         ratelimit.Middleware(limiter, "login", limits.LoginIP, ratelimit.ByIP),
+		register:      ratelimit.Middleware(limiter, "register", limits.RegisterIP, ratelimit.ByIP),
+		passwordReset: ratelimit.Middleware(limiter, "password_reset", limits.PasswordReset, ratelimit.ByIP),
+	})
+
+	tokenRepo := repo.NewPGAPITokenRepo(db)
+	tokenSvc := service.NewTokenService(tokenRepo)
+	protected := api.Group("",
+		auth.RequireSession(sessionStore, tokenSvc, userSvc),
+		ratelimit.Middleware(limiter, "api", limits.API, ratelimit.ByUser),
+	)
+	// Token and session management need an interactive login, not a token.
+	sessionOnly := protected.Group("", auth.SessionOnly())
+	tokenHandler := handlers.NewTokenHandler(tokenSvc)
+	registerTokenRoutes(sessionOnly, tokenHandler)
+	sessionHandler := handlers.NewSessionHandler(sessionStore)
+	registerSessionRoutes(sessionOnly, sessionHandler)
+	sessionOnly.POST("/auth/password", authHandler.ChangePassword)
+	mfaHandler := handlers.NewMFAHandler(mfaSvc, userSvc)
+	registerMFARoutes(sessionOnly, mfaHandler)
+
+	todoEventRepo := repo.NewPGTodoEventRepo(db)
+	adminSvc := service.NewAdminService(userRepo, repo.NewPGAdminRepo(db), todoEventRepo, userSvc)
+	adminHandler := handlers.NewAdminHandler(adminSvc, sessionStore, cfg.Session.ImpersonationTTL)
+	registerAdminRoutes(sessionOnly.Group("/admin", auth.RequireRole(dom.RoleAdmin)), adminHandler)
 
-	protected := api.Group("", auth.RequireSession(sessionStore))
 	todoRepo := repo.NewPGTodoRepo(db)
+	projectRepo := repo.NewPGProjectRepo(db)
 	todoCache := cache.NewTodoCache(rdb, cfg.Redis.DefaultTTL)
-	todoSvc := service.NewTodoService(todoRepo, todoCache)
+
+	workspaceRepo := repo.NewPGWorkspaceRepo(db)
+	workspaceSvc := service.NewWorkspaceService(workspaceRepo, userRepo, mailer, todoCache, service.WorkspaceOptions{
+		InviteTTL: cfg.Workspace.InviteTTL,
+		InviteURL: cfg.Workspace.InviteURL,
+	})
+	workspaceHandler := handlers.NewWorkspaceHandler(workspaceSvc)
+
+	// Idempotency-Key covers the data routes; token, session, MFA and admin
+	// routes stay out so that no credentials end up stored in Redis. On workspace
+	// routes it runs after RequireWorkspace, so keys are kept per workspace.
+	idem := idempotency.Middleware(idempotency.NewStore(rdb, cfg.Idempotency.TTL, cfg.Idempotency.LockTTL))
+	idempotent := protected.Group("", idem)
+	idempotent.GET("/workspaces", workspaceHandler.List)
+	idempotent.POST("/workspaces", workspaceHandler.Create)
+	idempotent.POST("/workspaces/invitations/accept", workspaceHandler.AcceptInvitation)
+
+	// Workspace data is reachable both under /workspaces/:workspace_id and at the
+	// top level, where X-Workspace-ID (or the personal workspace) picks the workspace.
+	inWorkspace := []gin.HandlerFunc{auth.RequireWorkspace(workspaceSvc), auth.RequireWorkspaceWriter(), idem}
+	scoped := []*gin.RouterGroup{
+		protected.Group("", inWorkspace...),
+		protected.Group("/workspaces/:workspace_id", inWorkspace...),
+	}
+	registerWorkspaceRoutes(protected.Group("/workspaces/:workspace_id", auth.RequireWorkspace(workspaceSvc), idem), workspaceHandler)
+
+	broker := realtime.NewBroker(rdb, cfg.Events.Backlog, cfg.Events.Retention)
+	eventsHandler := handlers.NewEventsHandler(broker, cfg.Events.Heartbeat, cfg.Events.MaxDuration)
+	protected.GET("/events", eventsHandler.Stream)
+	protected.GET("/events/ws", eventsHandler.WebSocket)
+
+	webhookHandler := handlers.NewWebhookHandler(service.NewWebhookService(repo.NewPGWebhookRepo(db), cfg.Webhook.AllowPrivate))
+	// Creating a webhook returns its signing secret, so, like tokens, webhooks
+	// stay out of Idempotency-Key storage.
+	registerWebhookRoutes(protected, webhookHandler)
+
+	todoSvc := service.NewTodoService(todoRepo, projectRepo, todoEventRepo, todoCache, broker)
 	todoHandler := handlers.NewTodoHandler(todoSvc)
-	registerTodoRoutes(protected, todoHandler)
+	projectSvc := service.NewProjectService(projectRepo, todoCache)
+	projectHandler := handlers.NewProjectHandler(projectSvc)
+	shareSvc := service.NewShareService(repo.NewPGShareRepo(db), todoRepo, projectRepo, userRepo, workspaceRepo, todoCache)
+	shareHandler := handlers.NewShareHandler(shareSvc)
+	tagRepo := repo.NewPGTagRepo(db)
+	tagSvc := service.NewTagService(tagRepo, todoCache)
+	tagHandler := handlers.NewTagHandler(tagSvc)
+	syncHandler := handlers.NewSyncHandler(service.NewSyncService(todoSvc, todoRepo, cfg.Sync.TokenTTL))
+	for _, g := range scoped {
+		registerTodoRoutes(g, todoHandler)
+		registerProjectRoutes(g, projectHandler, todoHandler)
+		registerShareRoutes(g, shareHandler, todoHandler)
+		registerTagRoutes(g, tagHandler)
+		registerSyncRoutes(g, syncHandler)
+	}
 
+	// The feed secret is a credential, so managing it needs a session and stays
+	// out of Idempotency-Key storage; the feed itself is public.
+	calendarHandler := handlers.NewCalendarHandler(
+		service.NewCalendarService(repo.NewPGCalendarFeedRepo(db), todoSvc, workspaceRepo, userRepo), cfg.Calendar.BaseURL)
+	registerCalendarRoutes(sessionOnly.Group("", auth.RequireWorkspace(workspaceSvc)), calendarHandler)
+	registerCalendarRoutes(sessionOnly.Group("/workspaces/:workspace_id", auth.RequireWorkspace(workspaceSvc)), calendarHandler)
+	api.GET("/calendar/:token", ratelimit.Middleware(limiter, "calendar", limits.API, ratelimit.ByIP), calendarHandler.Feed)
 }
 
 func rootHandler(cfg config.Config) gin.HandlerFunc {
@@ -57,8 +164,8 @@ func rootHandler(cfg config.Config) gin.HandlerFunc {
 			"env":     cfg.App.Env,
 			"docs":    "/swagger/index.html",
 			"spec":    "/swagger-doc.json",
-			"health": "/health",
-			"api":    "/api/v1",
+			"health":  "/health",
+			"api":     "/api/v1",
 		})
 	}
 }
@@ -88,17 +195,135 @@ func swaggerDocHandler() gin.HandlerFunc {
 
 func registerTodoRoutes(api *gin.RouterGroup, h *handlers.TodoHandler) {
 	api.POST("/todos", h.Create)
+	api.POST("/todos/batch", h.Batch)
 	api.GET("/todos", h.List)
 	api.GET("/todos/search", h.Search)
 	api.GET("/todos/overdue", h.Overdue)
+	api.GET("/todos/trash", h.Trash)
+	api.GET("/todos/export", h.Export)
+	api.POST("/todos/import", h.Import)
 	api.GET("/todos/:id", h.GetByID)
 	api.PATCH("/todos/:id", h.Update)
 	api.DELETE("/todos/:id", h.Delete)
+	api.POST("/todos/:id/restore", h.Restore)
 	api.POST("/todos/:id/complete", h.Complete)
+	api.POST("/todos/:id/move", h.Move)
+	api.GET("/todos/:id/subtasks", h.Subtasks)
+	api.POST("/todos/:id/subtasks", h.CreateSubtask)
+	api.POST("/todos/:id/subtasks/reorder", h.ReorderSubtasks)
+	api.GET("/todos/:id/history", h.History)
+	api.GET("/activity", h.Activity)
+}
+
+func registerProjectRoutes(api *gin.RouterGroup, h *handlers.ProjectHandler, todos *handlers.TodoHandler) {
+	api.GET("/projects", h.List)
+	api.POST("/projects", h.Create)
+	api.GET("/projects/inbox", h.Inbox)
+	api.GET("/projects/:id", h.GetByID)
+	api.PATCH("/projects/:id", h.Update)
+	api.DELETE("/projects/:id", h.Delete)
+	api.GET("/projects/:id/todos", todos.ListByProject)
+}
+
+func registerShareRoutes(api *gin.RouterGroup, h *handlers.ShareHandler, todos *handlers.TodoHandler) {
+	api.GET("/todos/shared", todos.Shared)
+	api.GET("/todos/:id/shares", h.ListTodo)
+	api.POST("/todos/:id/shares", h.ShareTodo)
+	api.DELETE("/todos/:id/shares/:user_id", h.UnshareTodo)
+	api.GET("/projects/:id/shares", h.ListProject)
+	api.POST("/projects/:id/shares", h.ShareProject)
+	api.DELETE("/projects/:id/shares/:user_id", h.UnshareProject)
+}
+
+func registerTagRoutes(api *gin.RouterGroup, h *handlers.TagHandler) {
+	api.GET("/tags", h.List)
+	api.POST("/tags", h.Create)
+	api.GET("/tags/:id", h.GetByID)
+	api.PATCH("/tags/:id", h.Rename)
+	api.DELETE("/tags/:id", h.Delete)
+}
+
+func registerSyncRoutes(api *gin.RouterGroup, h *handlers.SyncHandler) {
+	api.GET("/sync", h.Changes)
+	api.POST("/sync", h.Push)
+}
+
+func registerCalendarRoutes(api *gin.RouterGroup, h *handlers.CalendarHandler) {
+	api.GET("/calendar/feed", h.Get)
+	api.POST("/calendar/feed", h.Create)
+	api.DELETE("/calendar/feed", h.Delete)
+}
+
+// registerWorkspaceRoutes registers the routes of a single workspace; api is
+// the /workspaces/:workspace_id group behind auth.RequireWorkspace.
+func registerWorkspaceRoutes(api *gin.RouterGroup, h *handlers.WorkspaceHandler) {
+	admin := auth.RequireWorkspaceRole(dom.WorkspaceRoleAdmin)
+	owner := auth.RequireWorkspaceRole(dom.WorkspaceRoleOwner)
+	api.GET("", h.Get)
+	api.PATCH("", owner, h.Rename)
+	api.DELETE("", owner, h.Delete)
+	api.GET("/members", h.Members)
+	api.PUT("/members/:user_id", admin, h.SetMemberRole)
+	api.DELETE("/members/:user_id", h.RemoveMember)
+	api.GET("/invitations", admin, h.Invitations)
+	api.POST("/invitations", admin, h.Invite)
+	api.DELETE("/invitations/:id", admin, h.RevokeInvitation)
+}
+
+func registerWebhookRoutes(api *gin.RouterGroup, h *handlers.WebhookHandler) {
+	api.GET("/webhooks", h.List)
+	api.POST("/webhooks", h.Create)
+	api.GET("/webhooks/:id", h.GetByID)
+	api.PATCH("/webhooks/:id", h.Update)
+	api.DELETE("/webhooks/:id", h.Delete)
+	api.GET("/webhooks/:id/deliveries", h.Deliveries)
+	api.GET("/webhooks/:id/deliveries/:delivery_id", h.Delivery)
+	api.POST("/webhooks/:id/deliveries/:delivery_id/redeliver", h.Redeliver)
+}
+
+func registerTokenRoutes(api *gin.RouterGroup, h *handlers.TokenHandler) {
+	api.GET("/auth/tokens", h.List)
+	api.POST("/auth/tokens", h.Create)
+	api.DELETE("/auth/tokens/:id", h.Revoke)
+}
+
+func registerSessionRoutes(api *gin.RouterGroup, h *handlers.SessionHandler) {
+	api.GET("/auth/sessions", h.List)
+	api.DELETE("/auth/sessions", h.RevokeAll)
+	api.DELETE("/auth/sessions/:id", h.Revoke)
+}
+
+func registerMFARoutes(api *gin.RouterGroup, h *handlers.MFAHandler) {
+	api.GET("/auth/mfa", h.Status)
+	api.POST("/auth/mfa/enroll", h.Enroll)
+	api.POST("/auth/mfa/confirm", h.Confirm)
+	api.POST("/auth/mfa/disable", h.Disable)
+	api.POST("/auth/mfa/recovery-codes", h.RecoveryCodes)
+}
+
+// authLimits are the per-IP rate limits of the public auth endpoints.
+type authLimits struct {
+	login, register, passwordReset gin.HandlerFunc
+}
+
+func registerAdminRoutes(api *gin.RouterGroup, h *handlers.AdminHandler) {
+	api.GET("/users", h.ListUsers)
+	api.GET("/users/:id", h.GetUser)
+	api.POST("/users/:id/disable", h.Disable)
+	api.POST("/users/:id/enable", h.Enable)
+	api.PUT("/users/:id/role", h.SetRole)
+	api.POST("/users/:id/password-reset", h.ForcePasswordReset)
+	api.POST("/users/:id/impersonate", h.Impersonate)
+	api.GET("/stats", h.Stats)
+	api.GET("/audit", h.Audit)
+	api.GET("/todo-events", h.TodoEvents)
 }
 
-func registerAuthRoutes(api *gin.RouterGroup, h *handlers.AuthHandler) {
-	api.POST("/auth/login", h.Login)
-	api.POST("/auth/register", h.Register)
+func registerAuthRoutes(api *gin.RouterGroup, h *handlers.AuthHandler, l authLimits) {
+	api.POST("/auth/login", l.login, h.Login)
+	api.POST("/auth/login/mfa", l.login, h.LoginMFA)
+	api.POST("/auth/register", l.register, h.Register)
 	api.POST("/auth/logout", h.Logout)
+	api.POST("/auth/password/forgot", l.passwordReset, h.ForgotPassword)
+	api.POST("/auth/password/reset", l.passwordReset, h.ResetPassword)
 }
@@ -0,0 +1,85 @@
+package app
+
+import (
+	"context"
+	"sync"
+
+	"Worker/internal/config"
+	"Worker/internal/notify"
+	"Worker/internal/repo"
+	"Worker/internal/webhook"
+	"Worker/internal/worker"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Worker runs the background jobs: reminder delivery, trash retention, pruning
+// of sync tombstones, webhook delivery and overdue detection for webhooks. It
+// shares the database with the API but does not run migrations; the API applies
+// them on start.
+type Worker struct {
+	cfg       config.Config
+	db        *pgxpool.Pool
+	reminders *worker.ReminderScheduler
+	trash     *worker.TrashPurger // nil when TRASH_RETENTION is 0
+	tombs     *worker.TombstonePurger
+	webhooks  *worker.WebhookDispatcher
+	overdue   *worker.OverdueScanner
+}
+
+// NewWorker connects to Postgres and wires the jobs. Reminders go to n; a nil n
+// logs them.
+func NewWorker(cfg config.Config, n notify.Notifier) (*Worker, error) {
+	db, err := newPostgres(cfg.PG.DSN)
+	if err != nil {
+		return nil, err
+	}
+	if n == nil {
+		n = notify.NewLogNotifier(nil)
+	}
+	w := &Worker{
+		cfg: cfg,
+		db:  db,
+		reminders: worker.NewReminderScheduler(repo.NewPGReminderRepo(db), n,
+			cfg.Worker.ReminderInterval, cfg.Worker.ReminderBatch),
+	}
+	w.tombs = worker.NewTombstonePurger(repo.NewPGTodoRepo(db), cfg.Sync.TokenTTL, cfg.Worker.TrashPurgeInterval)
+	webhookRepo := repo.NewPGWebhookRepo(db)
+	w.webhooks = worker.NewWebhookDispatcher(webhookRepo,
+		webhook.NewSender(cfg.Webhook.Timeout, cfg.Webhook.AllowPrivate),
+		worker.WebhookDispatcherOptions{
+			Interval:     cfg.Webhook.PollInterval,
+			Batch:        cfg.Webhook.Batch,
+			MaxAttempts:  cfg.Webhook.MaxAttempts,
+			RetryBase:    cfg.Webhook.RetryBase,
+			DisableAfter: cfg.Webhook.DisableAfter,
+		})
+	w.overdue = worker.NewOverdueScanner(webhookRepo, cfg.Webhook.OverdueInterval, cfg.Webhook.Batch)
+	if cfg.Worker.TrashRetention > 0 {
+		w.trash = worker.NewTrashPurger(repo.NewPGTodoRepo(db), cfg.Worker.TrashRetention, cfg.Worker.TrashPurgeInterval)
+	}
+	return w, nil
+}
+
+// Run blocks until ctx is cancelled and every job has stopped.
+func (w *Worker) Run(ctx context.Context) {
+	jobs := []func(context.Context){w.reminders.Run, w.tombs.Run, w.webhooks.Run, w.overdue.Run}
+	if w.trash != nil {
+		jobs = append(jobs, w.trash.Run)
+	}
+	var wg sync.WaitGroup
+	for _, job := range jobs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			job(ctx)
+		}()
+	}
+	wg.Wait()
+}
+
+func (w *Worker) Close() {
+	if w.db != nil {
+		w.db.Close()
+	}
+}
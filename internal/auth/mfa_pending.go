@@ -0,0 +1,100 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	mfaPendingKeyPrefix = "mfa:pending:"
+	// MaxMFAAttempts is how many codes may be tried with one pending token.
+	MaxMFAAttempts = 5
+)
+
+// ErrMFAPendingInvalid is returned for unknown, expired or exhausted pending tokens.
+var ErrMFAPendingInvalid = errors.New("login expired, sign in again")
+
+// PendingStore keeps "password OK, second factor missing" logins in Redis as
+// mfa:pending:<token> hashes with the user ID and an attempt counter.
+type PendingStore struct {
+	rdb *redis.Client
+	ttl time.Duration
+}
+
+// NewPendingStore returns a new PendingStore; tokens live for ttl.
+func NewPendingStore(rdb *redis.Client, ttl time.Duration) *PendingStore {
+	if ttl <= 0 {
+		ttl = 5 * time.Minute
+	}
+	return &PendingStore{rdb: rdb, ttl: ttl}
+}
+
+// TTL is how long a pending login may wait for its second factor.
+func (p *PendingStore) TTL() time.Duration { return p.ttl }
+
+// Create returns a new pending token for the user.
+func (p *PendingStore) Create(ctx context.Context, userID int64) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	key := mfaPendingKeyPrefix + token
+	_, err := p.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, key, "user_id", strconv.FormatInt(userID, 10), "attempts", 0)
+		pipe.Expire(ctx, key, p.ttl)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// attemptScript counts an attempt on an existing pending token and returns
+// {user_id, attempts}, or nil if the token does not exist.
+var attemptScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then return false end
+local n = redis.call('HINCRBY', KEYS[1], 'attempts', 1)
+return {redis.call('HGET', KEYS[1], 'user_id'), n}
+`)
+
+// Attempt counts one verification attempt and returns the token's user. The
+// token is dropped once MaxMFAAttempts is reached.
+func (p *PendingStore) Attempt(ctx context.Context, token string) (int64, error) {
+	if token == "" {
+		return 0, ErrMFAPendingInvalid
+	}
+	key := mfaPendingKeyPrefix + token
+	res, err := attemptScript.Run(ctx, p.rdb, []string{key}).Slice()
+	if errors.Is(err, redis.Nil) {
+		return 0, ErrMFAPendingInvalid
+	}
+	if err != nil {
+		return 0, err
+	}
+	if len(res) != 2 {
+		return 0, ErrMFAPendingInvalid
+	}
+	if n, _ := res[1].(int64); n > MaxMFAAttempts {
+		_ = p.rdb.Del(ctx, key).Err()
+		return 0, ErrMFAPendingInvalid
+	}
+	raw, _ := res[0].(string)
+	userID, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return 0, ErrMFAPendingInvalid
+	}
+	return userID, nil
+}
+
+// Delete removes a pending token after the login completes.
+func (p *PendingStore) Delete(ctx context.Context, token string) error {
+	return p.rdb.Del(ctx, mfaPendingKeyPrefix+token).Err()
+}
@@ -1,14 +1,42 @@
 package auth
 
 import (
+	"context"
+	"errors"
 	"net/http"
+	"slices"
+	"strings"
+
+	dom "Worker/internal/domain"
 
 	"github.com/gin-gonic/gin"
 )
 
 const sessionCookieName = "session_id"
 
-const contextKeyUserID = "user_id"
+const (
+	contextKeyUserID       = "user_id"
+	contextKeyUserRole     = "user_role"
+	contextKeyTokenID      = "token_id"
+	contextKeySessionID    = "session_id"
+	contextKeyImpersonator = "impersonator_id"
+)
+
+// ErrAccountDisabled is returned by an AccountChecker for disabled accounts.
+var ErrAccountDisabled = errors.New("account disabled")
+
+// TokenAuthenticator resolves a bearer token to its record; any error means the
+// token is not accepted. service.TokenService implements it.
+type TokenAuthenticator interface {
+	Authenticate(ctx context.Context, token string) (dom.APIToken, error)
+}
+
+// AccountChecker loads the account behind a request. It returns
+// ErrAccountDisabled for disabled accounts; any other error rejects the request
+// as unauthenticated. service.UserService implements it.
+type AccountChecker interface {
+	ActiveAccount(ctx context.Context, userID int64) (dom.User, error)
+}
 
 // UserIDFromContext returns the current user ID set by RequireSession. 0 if not set.
 func UserIDFromContext(c *gin.Context) int64 {
@@ -23,21 +51,146 @@ func UserIDFromContext(c *gin.Context) int64 {
 	return id
 }
 
-// RequireSession returns a middleware that checks for a valid session cookie
-// and sets the current user ID in context. If missing or invalid, responds with 401.
-func RequireSession(sessions *Store) gin.HandlerFunc {
+// TokenIDFromContext returns the API token the request was authenticated with,
+// or 0 for a session cookie.
+func TokenIDFromContext(c *gin.Context) int64 {
+	id, _ := c.Get(contextKeyTokenID)
+	v, _ := id.(int64)
+	return v
+}
+
+// RoleFromContext returns the current user's role set by RequireSession.
+func RoleFromContext(c *gin.Context) string {
+	return c.GetString(contextKeyUserRole)
+}
+
+// ImpersonatorIDFromContext returns the admin acting as the current user, or 0.
+func ImpersonatorIDFromContext(c *gin.Context) int64 {
+	id, _ := c.Get(contextKeyImpersonator)
+	v, _ := id.(int64)
+	return v
+}
+
+// SessionIDFromContext returns the session the request was authenticated with,
+// or "" for an API token.
+func SessionIDFromContext(c *gin.Context) string {
+	return c.GetString(contextKeySessionID)
+}
+
+// RequireSession returns a middleware that accepts either "Authorization: Bearer <token>"
+// (a personal API token) or the session cookie, and sets the current user ID in context.
+// A token without the write scope may only use GET and HEAD. If missing or invalid,
+// responds with 401; a disabled account gets 403. Each request updates the
+// session's last-seen data; with a sliding store it also renews the session
+// and its cookie, except for impersonation sessions.
+func RequireSession(sessions *Store, tokens TokenAuthenticator, accounts AccountChecker) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if token, ok := bearerToken(c); ok {
+			if tokens == nil {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "authorization required"})
+				return
+			}
+			t, err := tokens.Authenticate(c.Request.Context(), token)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired token"})
+				return
+			}
+			scope := dom.ScopeWrite
+			if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+				scope = dom.ScopeRead
+			}
+			if !t.HasScope(scope) {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "token lacks the " + scope + " scope"})
+				return
+			}
+			if !setAccount(c, accounts, t.UserID) {
+				return
+			}
+			c.Set(contextKeyTokenID, t.ID)
+			c.Next()
+			return
+		}
 		sessionID, err := c.Cookie(sessionCookieName)
 		if err != nil || sessionID == "" {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "authorization required"})
 			return
 		}
-		userID, ok := sessions.GetUserID(c.Request.Context(), sessionID)
+		userID, impersonatorID, ok := sessions.Lookup(c.Request.Context(), sessionID)
 		if !ok {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "authorization required"})
 			return
 		}
-		c.Set(contextKeyUserID, userID)
+		if !setAccount(c, accounts, userID) {
+			return
+		}
+		renew := impersonatorID == 0
+		_ = sessions.Touch(c.Request.Context(), sessionID, userID, c.Request.UserAgent(), c.ClientIP(), renew)
+		if renew && sessions.Sliding() {
+			c.SetCookie(sessionCookieName, sessionID, int(sessions.TTL().Seconds()), "/", "", false, true)
+		}
+		c.Set(contextKeySessionID, sessionID)
+		if impersonatorID != 0 {
+			c.Set(contextKeyImpersonator, impersonatorID)
+		}
+		c.Next()
+	}
+}
+
+// setAccount checks that the user's account is active and puts its ID and role
+// in context. On failure it aborts the request and returns false.
+func setAccount(c *gin.Context, accounts AccountChecker, userID int64) bool {
+	u, err := accounts.ActiveAccount(c.Request.Context(), userID)
+	if err != nil {
+		if errors.Is(err, ErrAccountDisabled) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "account disabled"})
+			return false
+		}
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "authorization required"})
+		return false
+	}
+	c.Set(contextKeyUserID, u.ID)
+	c.Set(contextKeyUserRole, u.Role)
+	return true
+}
+
+// RequireRole rejects users whose role is not one of roles with 403. Use it
+// after RequireSession.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !slices.Contains(roles, RoleFromContext(c)) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "insufficient role"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// SessionOnly rejects requests authenticated with an API token. Use it after
+// RequireSession on routes that must need an interactive login, such as token
+// management: otherwise a leaked read-only token could mint a write token.
+// Impersonation sessions are rejected too, so support staff cannot change a
+// user's credentials or mint tokens that outlive the impersonation.
+func SessionOnly() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if TokenIDFromContext(c) != 0 {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "this endpoint requires a session login"})
+			return
+		}
+		if ImpersonatorIDFromContext(c) != 0 {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "not allowed while impersonating"})
+			return
+		}
 		c.Next()
 	}
 }
+
+// bearerToken returns the token from "Authorization: Bearer <token>".
+func bearerToken(c *gin.Context) (string, bool) {
+	h := c.GetHeader("Authorization")
+	scheme, token, ok := strings.Cut(h, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
@@ -3,8 +3,10 @@ package auth
 import (
 	"context"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
+	"sort"
 	"strconv"
 	"time"
 
@@ -12,33 +14,87 @@ import (
 )
 
 const (
-	sessionKeyPrefix = "session:"
-	sessionTTL       = 24 * time.Hour
+	sessionKeyPrefix     = "session:"
+	sessionMetaKeyPrefix = "session:meta:"
+	userSessionsPrefix   = "user:sessions:"
+	sessionTTL           = 24 * time.Hour
 )
 
-// Store manages sessions in Redis. Value is user_id (int64 as string).
+// Session describes one login as shown to its user.
+type Session struct {
+	// Handle identifies the session in the API. It is derived from the session ID,
+	// which is a credential and never leaves the cookie.
+	Handle     string
+	UserID     int64
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+	ExpiresAt  time.Time
+	UserAgent  string
+	IP         string
+	// ImpersonatorID is the admin acting as the user, 0 for a normal login.
+	ImpersonatorID int64
+}
+
+// Store manages sessions in Redis:
+//   - session:<id>         user_id (int64 as string), expires after the TTL;
+//   - session:meta:<id>    hash with created_at, last_seen_at, user_agent, ip
+//     and, for support sessions, impersonator_id;
+//   - user:sessions:<uid>  set of the user's session IDs, pruned lazily.
 type Store struct {
-	rdb *redis.Client
-	ttl time.Duration
+	rdb     *redis.Client
+	ttl     time.Duration
+	sliding bool
 }
 
-// NewStore returns a new session store.
-func NewStore(rdb *redis.Client, ttl time.Duration) *Store {
+// NewStore returns a new session store. With sliding, every request renews the
+// session for another ttl, so only idle sessions expire.
+func NewStore(rdb *redis.Client, ttl time.Duration, sliding bool) *Store {
 	if ttl <= 0 {
 		ttl = sessionTTL
 	}
-	return &Store{rdb: rdb, ttl: ttl}
+	return &Store{rdb: rdb, ttl: ttl, sliding: sliding}
 }
 
+// TTL is the lifetime of a session (since login, or since the last request with sliding).
+func (s *Store) TTL() time.Duration { return s.ttl }
+
+// Sliding reports whether requests renew sessions.
+func (s *Store) Sliding() bool { return s.sliding }
+
 // Create stores a new session for the given user and returns session ID.
-func (s *Store) Create(ctx context.Context, userID int64) (string, error) {
+// userAgent and ip are kept for the session list.
+func (s *Store) Create(ctx context.Context, userID int64, userAgent, ip string) (string, error) {
+	return s.create(ctx, userID, 0, userAgent, ip, s.ttl)
+}
+
+// CreateImpersonation stores a session in which adminID acts as userID. It
+// lasts ttl and is never renewed by requests.
+func (s *Store) CreateImpersonation(ctx context.Context, userID, adminID int64, userAgent, ip string, ttl time.Duration) (string, error) {
+	return s.create(ctx, userID, adminID, userAgent, ip, ttl)
+}
+
+func (s *Store) create(ctx context.Context, userID, impersonatorID int64, userAgent, ip string, ttl time.Duration) (string, error) {
 	id, err := newSessionID()
 	if err != nil {
 		return "", err
 	}
-	key := sessionKeyPrefix + id
+	now := strconv.FormatInt(time.Now().Unix(), 10)
 	val := strconv.FormatInt(userID, 10)
-	if err := s.rdb.Set(ctx, key, val, s.ttl).Err(); err != nil {
+	fields := []any{"user_id", val, "created_at", now, "last_seen_at", now, "user_agent", userAgent, "ip", ip}
+	if impersonatorID != 0 {
+		fields = append(fields, "impersonator_id", strconv.FormatInt(impersonatorID, 10))
+	}
+	_, err = s.rdb.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		p.Set(ctx, sessionKeyPrefix+id, val, ttl)
+		p.HSet(ctx, sessionMetaKeyPrefix+id, fields...)
+		p.Expire(ctx, sessionMetaKeyPrefix+id, ttl)
+		p.SAdd(ctx, userSessionsPrefix+val, id)
+		// The set must live as long as the longest session in it.
+		p.ExpireGT(ctx, userSessionsPrefix+val, ttl)
+		p.ExpireNX(ctx, userSessionsPrefix+val, ttl)
+		return nil
+	})
+	if err != nil {
 		return "", err
 	}
 	return id, nil
@@ -60,9 +116,149 @@ func (s *Store) GetUserID(ctx context.Context, sessionID string) (int64, bool) {
 	return userID, true
 }
 
+// Lookup returns the session's user and, for an impersonation session, the
+// admin behind it. ok is false if the session does not exist.
+func (s *Store) Lookup(ctx context.Context, sessionID string) (userID, impersonatorID int64, ok bool) {
+	if sessionID == "" {
+		return 0, 0, false
+	}
+	var uid *redis.StringCmd
+	var imp *redis.StringCmd
+	_, _ = s.rdb.Pipelined(ctx, func(p redis.Pipeliner) error {
+		uid = p.Get(ctx, sessionKeyPrefix+sessionID)
+		imp = p.HGet(ctx, sessionMetaKeyPrefix+sessionID, "impersonator_id")
+		return nil
+	})
+	userID, err := strconv.ParseInt(uid.Val(), 10, 64)
+	if uid.Err() != nil || err != nil {
+		return 0, 0, false
+	}
+	impersonatorID, _ = strconv.ParseInt(imp.Val(), 10, 64)
+	return userID, impersonatorID, true
+}
+
+// Touch records a request on the session and, if renew is set and the store
+// is sliding, extends it by the TTL.
+func (s *Store) Touch(ctx context.Context, sessionID string, userID int64, userAgent, ip string, renew bool) error {
+	meta := sessionMetaKeyPrefix + sessionID
+	_, err := s.rdb.Pipelined(ctx, func(p redis.Pipeliner) error {
+		p.HSet(ctx, meta, "last_seen_at", strconv.FormatInt(time.Now().Unix(), 10), "user_agent", userAgent, "ip", ip)
+		if renew && s.sliding {
+			p.Expire(ctx, sessionKeyPrefix+sessionID, s.ttl)
+			p.Expire(ctx, meta, s.ttl)
+			p.ExpireGT(ctx, userSessionsPrefix+strconv.FormatInt(userID, 10), s.ttl)
+		} else {
+			// Sessions created before metadata existed have no hash yet; do not let
+			// the one HSET just created outlive them.
+			p.ExpireNX(ctx, meta, s.ttl)
+		}
+		return nil
+	})
+	return err
+}
+
+// List returns the user's live sessions, most recently used first.
+func (s *Store) List(ctx context.Context, userID int64) ([]Session, error) {
+	setKey := userSessionsPrefix + strconv.FormatInt(userID, 10)
+	ids, err := s.rdb.SMembers(ctx, setKey).Result()
+	if err != nil {
+		return nil, err
+	}
+	metas := make([]*redis.MapStringStringCmd, len(ids))
+	ttls := make([]*redis.DurationCmd, len(ids))
+	_, err = s.rdb.Pipelined(ctx, func(p redis.Pipeliner) error {
+		for i, id := range ids {
+			metas[i] = p.HGetAll(ctx, sessionMetaKeyPrefix+id)
+			ttls[i] = p.PTTL(ctx, sessionKeyPrefix+id)
+		}
+		return nil
+	})
+	if err != nil && err != redis.Nil {
+		return nil, err
+	}
+	now := time.Now()
+	var out []Session
+	var stale []any
+	for i, id := range ids {
+		ttl := ttls[i].Val()
+		if ttl <= 0 {
+			stale = append(stale, id)
+			continue
+		}
+		m := metas[i].Val()
+		out = append(out, Session{
+			Handle:     SessionHandle(id),
+			UserID:     userID,
+			CreatedAt:  unixField(m["created_at"]),
+			LastSeenAt: unixField(m["last_seen_at"]),
+			ExpiresAt:  now.Add(ttl).UTC(),
+			UserAgent:  m["user_agent"],
+			IP:         m["ip"],
+		})
+		out[len(out)-1].ImpersonatorID, _ = strconv.ParseInt(m["impersonator_id"], 10, 64)
+	}
+	if len(stale) > 0 {
+		_ = s.rdb.SRem(ctx, setKey, stale...).Err()
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].LastSeenAt.After(out[j].LastSeenAt) })
+	return out, nil
+}
+
 // Delete removes a session by ID.
 func (s *Store) Delete(ctx context.Context, id string) error {
-	return s.rdb.Del(ctx, sessionKeyPrefix+id).Err()
+	val, err := s.rdb.Get(ctx, sessionKeyPrefix+id).Result()
+	if err != nil && err != redis.Nil {
+		return err
+	}
+	_, err = s.rdb.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		p.Del(ctx, sessionKeyPrefix+id, sessionMetaKeyPrefix+id)
+		if val != "" {
+			p.SRem(ctx, userSessionsPrefix+val, id)
+		}
+		return nil
+	})
+	return err
+}
+
+// DeleteByHandle removes one of the user's sessions by its handle. It returns
+// false if the user has no such session.
+func (s *Store) DeleteByHandle(ctx context.Context, userID int64, handle string) (bool, error) {
+	ids, err := s.rdb.SMembers(ctx, userSessionsPrefix+strconv.FormatInt(userID, 10)).Result()
+	if err != nil {
+		return false, err
+	}
+	for _, id := range ids {
+		if SessionHandle(id) == handle {
+			return true, s.Delete(ctx, id)
+		}
+	}
+	return false, nil
+}
+
+// DeleteAll logs the user out everywhere except the session exceptID ("" = none)
+// and returns how many sessions were removed.
+func (s *Store) DeleteAll(ctx context.Context, userID int64, exceptID string) (int, error) {
+	setKey := userSessionsPrefix + strconv.FormatInt(userID, 10)
+	ids, err := s.rdb.SMembers(ctx, setKey).Result()
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	_, err = s.rdb.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		for _, id := range ids {
+			if id == exceptID {
+				continue
+			}
+			p.Del(ctx, sessionKeyPrefix+id, sessionMetaKeyPrefix+id)
+			p.SRem(ctx, setKey, id)
+			n++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return n, nil
 }
 
 // Exists returns true if the session exists.
@@ -74,6 +270,20 @@ func (s *Store) Exists(ctx context.Context, id string) (bool, error) {
 	return n > 0, nil
 }
 
+// SessionHandle returns the public handle of a session ID.
+func SessionHandle(id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return hex.EncodeToString(sum[:8])
+}
+
+func unixField(v string) time.Time {
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.Unix(n, 0).UTC()
+}
+
 func newSessionID() (string, error) {
 	b := make([]byte, 16)
 	if _, err := rand.Read(b); err != nil {
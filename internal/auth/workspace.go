@@ -0,0 +1,103 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	dom "Worker/internal/domain"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WorkspaceHeader selects the active workspace on routes outside /workspaces/:workspace_id.
+const WorkspaceHeader = "X-Workspace-ID"
+
+const (
+	contextKeyWorkspaceID   = "workspace_id"
+	contextKeyWorkspaceRole = "workspace_role"
+)
+
+// ErrNotWorkspaceMember is returned by a WorkspaceResolver when the user does not
+// belong to the requested workspace.
+var ErrNotWorkspaceMember = errors.New("not a member of this workspace")
+
+// WorkspaceResolver returns the workspace a request of userID works in and the
+// user's role there. workspaceID 0 asks for the user's personal workspace.
+// service.WorkspaceService implements it.
+type WorkspaceResolver interface {
+	ResolveWorkspace(ctx context.Context, userID, workspaceID int64) (dom.Workspace, error)
+}
+
+// WorkspaceIDFromContext returns the active workspace set by RequireWorkspace. 0 if not set.
+func WorkspaceIDFromContext(c *gin.Context) int64 {
+	id, _ := c.Get(contextKeyWorkspaceID)
+	v, _ := id.(int64)
+	return v
+}
+
+// WorkspaceRoleFromContext returns the user's role in the active workspace.
+func WorkspaceRoleFromContext(c *gin.Context) string {
+	return c.GetString(contextKeyWorkspaceRole)
+}
+
+// RequireWorkspace returns a middleware that sets the active workspace: the
+// :workspace_id path parameter if the route has one, else the X-Workspace-ID
+// header, else the user's personal workspace. An invalid ID is 400; a workspace
+// the user does not belong to is 404, so its existence is not revealed.
+// Use it after RequireSession.
+func RequireWorkspace(workspaces WorkspaceResolver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		raw, ok := c.Params.Get("workspace_id")
+		if !ok {
+			raw = strings.TrimSpace(c.GetHeader(WorkspaceHeader))
+		}
+		var id int64
+		if raw != "" {
+			var err error
+			if id, err = strconv.ParseInt(raw, 10, 64); err != nil || id <= 0 {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "invalid workspace id"})
+				return
+			}
+		}
+		w, err := workspaces.ResolveWorkspace(c.Request.Context(), UserIDFromContext(c), id)
+		if err != nil {
+			if errors.Is(err, ErrNotWorkspaceMember) {
+				c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "workspace not found"})
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "failed to resolve workspace"})
+			return
+		}
+		c.Set(contextKeyWorkspaceID, w.ID)
+		c.Set(contextKeyWorkspaceRole, w.Role)
+		c.Next()
+	}
+}
+
+// RequireWorkspaceRole rejects users whose role in the active workspace is below
+// min with 403. Use it after RequireWorkspace.
+func RequireWorkspaceRole(min string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !dom.WorkspaceRoleAtLeast(WorkspaceRoleFromContext(c), min) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "insufficient workspace role"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireWorkspaceWriter lets every member read (GET and HEAD) but needs at
+// least the member role for anything else, so viewers cannot change data.
+func RequireWorkspaceWriter() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead &&
+			!dom.WorkspaceRoleAtLeast(WorkspaceRoleFromContext(c), dom.WorkspaceRoleMember) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "insufficient workspace role"})
+			return
+		}
+		c.Next()
+	}
+}
@@ -12,13 +12,20 @@ import (
 	"github.com/redis/go-redis/v9"
 )
 
+// Keys are todo:<workspaceID>:<kind>:<userID>[:<rest>], so one workspace can never
+// read another's entries, even for the same user.
 const (
-	keyListPrefix    = "todo:list:"
-	keyOverduePrefix = "todo:overdue:"
-	keySearchPrefix  = "todo:search:"
+	keyPrefix   = "todo:"
+	kindList    = "list"
+	kindOverdue = "overdue"
+	kindSearch  = "search"
 )
 
-// TodoCache caches todo list, search, and overdue results in Redis.
+// AllWorkspaces makes InvalidateAll drop the user's keys in every workspace.
+const AllWorkspaces int64 = 0
+
+// TodoCache caches todo list pages, search hits, and overdue results in Redis,
+// separately per workspace and user.
 type TodoCache struct {
 	rdb *redis.Client
 	ttl time.Duration
@@ -29,9 +36,10 @@ func NewTodoCache(rdb *redis.Client, ttl time.Duration) *TodoCache {
 	return &TodoCache{rdb: rdb, ttl: ttl}
 }
 
-// GetList returns cached list for user or nil if miss.
-func (c *TodoCache) GetList(ctx context.Context, userID int64) ([]dom.Todo, error) {
-	key := keyListPrefix + userKey(userID)
+// GetList returns the cached list page for user, or nil if miss.
+// pageKey identifies the page (filters, sort and cursor) within the user's list.
+func (c *TodoCache) GetList(ctx context.Context, workspaceID, userID int64, pageKey string) (*dom.TodoPage, error) {
+	key := userKey(kindList, workspaceID, userID) + ":" + pageKey
 	b, err := c.rdb.Get(ctx, key).Bytes()
 	if err == redis.Nil {
 		return nil, nil
@@ -39,25 +47,25 @@ func (c *TodoCache) GetList(ctx context.Context, userID int64) ([]dom.Todo, erro
 	if err != nil {
 		return nil, err
 	}
-	var list []dom.Todo
-	if err := json.Unmarshal(b, &list); err != nil {
+	var page dom.TodoPage
+	if err := json.Unmarshal(b, &page); err != nil {
 		return nil, err
 	}
-	return list, nil
+	return &page, nil
 }
 
-// SetList stores the list in cache for user.
-func (c *TodoCache) SetList(ctx context.Context, userID int64, list []dom.Todo) error {
-	b, err := json.Marshal(list)
+// SetList stores one list page in cache for user.
+func (c *TodoCache) SetList(ctx context.Context, workspaceID, userID int64, pageKey string, page dom.TodoPage) error {
+	b, err := json.Marshal(page)
 	if err != nil {
 		return err
 	}
-	return c.rdb.Set(ctx, keyListPrefix+userKey(userID), b, c.ttl).Err()
+	return c.rdb.Set(ctx, userKey(kindList, workspaceID, userID)+":"+pageKey, b, c.ttl).Err()
 }
 
 // GetSearch returns cached search result for user and query q, or nil if miss.
-func (c *TodoCache) GetSearch(ctx context.Context, userID int64, q string) ([]dom.Todo, error) {
-	key := keySearchPrefix + userKey(userID) + ":" + normalizeQuery(q)
+func (c *TodoCache) GetSearch(ctx context.Context, workspaceID, userID int64, q string) ([]dom.TodoSearchHit, error) {
+	key := userKey(kindSearch, workspaceID, userID) + ":" + normalizeQuery(q)
 	b, err := c.rdb.Get(ctx, key).Bytes()
 	if err == redis.Nil {
 		return nil, nil
@@ -65,7 +73,7 @@ func (c *TodoCache) GetSearch(ctx context.Context, userID int64, q string) ([]do
 	if err != nil {
 		return nil, err
 	}
-	var list []dom.Todo
+	var list []dom.TodoSearchHit
 	if err := json.Unmarshal(b, &list); err != nil {
 		return nil, err
 	}
@@ -73,18 +81,18 @@ func (c *TodoCache) GetSearch(ctx context.Context, userID int64, q string) ([]do
 }
 
 // SetSearch stores the search result in cache for user.
-func (c *TodoCache) SetSearch(ctx context.Context, userID int64, q string, list []dom.Todo) error {
+func (c *TodoCache) SetSearch(ctx context.Context, workspaceID, userID int64, q string, list []dom.TodoSearchHit) error {
 	b, err := json.Marshal(list)
 	if err != nil {
 		return err
 	}
-	key := keySearchPrefix + userKey(userID) + ":" + normalizeQuery(q)
+	key := userKey(kindSearch, workspaceID, userID) + ":" + normalizeQuery(q)
 	return c.rdb.Set(ctx, key, b, c.ttl).Err()
 }
 
 // GetOverdue returns cached overdue list for user or nil if miss.
-func (c *TodoCache) GetOverdue(ctx context.Context, userID int64) ([]dom.Todo, error) {
-	key := keyOverduePrefix + userKey(userID)
+func (c *TodoCache) GetOverdue(ctx context.Context, workspaceID, userID int64) ([]dom.Todo, error) {
+	key := userKey(kindOverdue, workspaceID, userID)
 	b, err := c.rdb.Get(ctx, key).Bytes()
 	if err == redis.Nil {
 		return nil, nil
@@ -100,31 +108,63 @@ func (c *TodoCache) GetOverdue(ctx context.Context, userID int64) ([]dom.Todo, e
 }
 
 // SetOverdue stores the overdue list in cache for user.
-func (c *TodoCache) SetOverdue(ctx context.Context, userID int64, list []dom.Todo) error {
+func (c *TodoCache) SetOverdue(ctx context.Context, workspaceID, userID int64, list []dom.Todo) error {
 	b, err := json.Marshal(list)
 	if err != nil {
 		return err
 	}
-	return c.rdb.Set(ctx, keyOverduePrefix+userKey(userID), b, c.ttl).Err()
+	return c.rdb.Set(ctx, userKey(kindOverdue, workspaceID, userID), b, c.ttl).Err()
 }
 
-// InvalidateAll removes list, overdue, and search keys for the user (cache invalidation on write).
-func (c *TodoCache) InvalidateAll(ctx context.Context, userID int64) error {
-	uk := userKey(userID)
-	if err := c.rdb.Del(ctx, keyListPrefix+uk, keyOverduePrefix+uk).Err(); err != nil {
+// InvalidateAll removes list pages, overdue, and search keys for the user in one
+// workspace, or in all of them with AllWorkspaces (cache invalidation on write).
+func (c *TodoCache) InvalidateAll(ctx context.Context, workspaceID, userID int64) error {
+	patterns := []string{userKey(kindList, workspaceID, userID) + ":*", userKey(kindSearch, workspaceID, userID) + ":*"}
+	if workspaceID == AllWorkspaces {
+		patterns = append(patterns, userKey(kindOverdue, workspaceID, userID))
+	} else if err := c.rdb.Del(ctx, userKey(kindOverdue, workspaceID, userID)).Err(); err != nil {
 		return err
 	}
-	iter := c.rdb.Scan(ctx, 0, keySearchPrefix+uk+"*", 100).Iterator()
-	for iter.Next(ctx) {
-		if err := c.rdb.Del(ctx, iter.Val()).Err(); err != nil {
+	for _, pattern := range patterns {
+		iter := c.rdb.Scan(ctx, 0, pattern, 100).Iterator()
+		for iter.Next(ctx) {
+			if err := c.rdb.Del(ctx, iter.Val()).Err(); err != nil {
+				return err
+			}
+		}
+		if err := iter.Err(); err != nil {
 			return err
 		}
 	}
-	return iter.Err()
+	return nil
 }
 
-func userKey(userID int64) string {
-	return strconv.FormatInt(userID, 10)
+// InvalidateUsers runs InvalidateAll in one workspace for every distinct user, e.g.
+// all collaborators of a shared todo. It keeps going past a failing user and
+// returns the first error.
+func (c *TodoCache) InvalidateUsers(ctx context.Context, workspaceID int64, userIDs ...int64) error {
+	var first error
+	seen := make(map[int64]bool, len(userIDs))
+	for _, id := range userIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		if err := c.InvalidateAll(ctx, workspaceID, id); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// userKey returns todo:<workspaceID>:<kind>:<userID>; AllWorkspaces becomes a
+// SCAN wildcard.
+func userKey(kind string, workspaceID, userID int64) string {
+	ws := "*"
+	if workspaceID != AllWorkspaces {
+		ws = strconv.FormatInt(workspaceID, 10)
+	}
+	return keyPrefix + ws + ":" + kind + ":" + strconv.FormatInt(userID, 10)
 }
 
 func normalizeQuery(q string) string {
@@ -5,16 +5,30 @@ import (
 	"strings"
 	"time"
 
+	"Worker/internal/ratelimit"
+	"Worker/internal/secretbox"
 	"Worker/internal/utils"
 
 	"github.com/ilyakaznacheev/cleanenv"
 )
 
 type Config struct {
-	App   AppConfig
-	HTTP  HTTPConfig
-	PG    PGConfig
-	Redis RedisConfig
+	App           AppConfig
+	HTTP          HTTPConfig
+	PG            PGConfig
+	Redis         RedisConfig
+	Session       SessionConfig
+	PasswordReset PasswordResetConfig
+	Mail          MailConfig
+	MFA           MFAConfig
+	RateLimit     RateLimitConfig
+	Workspace     WorkspaceConfig
+	Idempotency   IdempotencyConfig
+	Events        EventsConfig
+	Webhook       WebhookConfig
+	Sync          SyncConfig
+	Calendar      CalendarConfig
+	Worker        WorkerConfig
 }
 
 type AppConfig struct {
@@ -52,6 +66,161 @@ type RedisConfig struct {
 	DefaultTTL    time.Duration `env:"-"`
 }
 
+// SessionConfig configures login sessions.
+type SessionConfig struct {
+	// Время жизни сессии: "24h", "30m" или число секунд.
+	TTLRaw string        `env:"SESSION_TTL" env-default:"24h"`
+	TTL    time.Duration `env:"-"`
+	// Продлевать сессию на TTL при каждом запросе (разлогинивает только неактивных).
+	Sliding bool `env:"SESSION_SLIDING" env-default:"false"`
+	// Время жизни сессии, в которой админ действует от имени пользователя.
+	ImpersonationTTLRaw string        `env:"SESSION_IMPERSONATION_TTL" env-default:"1h"`
+	ImpersonationTTL    time.Duration `env:"-"`
+}
+
+// PasswordResetConfig configures the password reset flow.
+type PasswordResetConfig struct {
+	// Время жизни токена сброса: "1h", "30m" или число секунд.
+	TTLRaw string        `env:"PASSWORD_RESET_TTL" env-default:"1h"`
+	TTL    time.Duration `env:"-"`
+	// Страница фронтенда для ссылки в письме; токен добавляется как ?token=.
+	// Пусто — в письме только сам токен.
+	URL string `env:"PASSWORD_RESET_URL" env-default:""`
+}
+
+// MailConfig selects how outgoing mail is delivered.
+type MailConfig struct {
+	// "log" — писать письма в лог, "file" — сохранять .eml в MAIL_DIR.
+	Driver string `env:"MAIL_DRIVER" env-default:"log"`
+	Dir    string `env:"MAIL_DIR" env-default:"./mail"`
+	From   string `env:"MAIL_FROM" env-default:"no-reply@localhost"`
+}
+
+// MFAConfig configures TOTP two-factor authentication.
+type MFAConfig struct {
+	// Ключ шифрования TOTP-секретов: 32 байта в hex или base64.
+	// Пусто — подключить 2FA нельзя.
+	EncryptionKeyRaw string `env:"MFA_ENCRYPTION_KEY" env-default:""`
+	EncryptionKey    []byte `env:"-"`
+	// Название сервиса в приложении-аутентификаторе.
+	Issuer string `env:"MFA_ISSUER" env-default:"Todo API"`
+	// Сколько ждать второй фактор после пароля: "5m" или число секунд.
+	PendingTTLRaw string        `env:"MFA_PENDING_TTL" env-default:"5m"`
+	PendingTTL    time.Duration `env:"-"`
+}
+
+// RateLimitConfig configures request limits and login lockout.
+// Limits are "N/period", e.g. "10/1m"; "0" turns a limit off.
+type RateLimitConfig struct {
+	Enabled bool `env:"RATE_LIMIT_ENABLED" env-default:"true"`
+
+	LoginIPRaw       string          `env:"RATE_LIMIT_LOGIN_IP" env-default:"20/1m"`
+	LoginUserRaw     string          `env:"RATE_LIMIT_LOGIN_USER" env-default:"10/1m"`
+	RegisterIPRaw    string          `env:"RATE_LIMIT_REGISTER_IP" env-default:"5/1h"`
+	PasswordResetRaw string          `env:"RATE_LIMIT_PASSWORD_RESET_IP" env-default:"5/1h"`
+	APIRaw           string          `env:"RATE_LIMIT_API" env-default:"600/1m"`
+	LoginIP          ratelimit.Limit `env:"-"`
+	LoginUser        ratelimit.Limit `env:"-"`
+	RegisterIP       ratelimit.Limit `env:"-"`
+	PasswordReset    ratelimit.Limit `env:"-"`
+	API              ratelimit.Limit `env:"-"` // per user (or IP) on authenticated routes
+
+	// Блокировка имени пользователя после LOGIN_LOCKOUT_THRESHOLD неудачных входов
+	// подряд: сначала на LOGIN_LOCKOUT_BASE, далее вдвое дольше, но не больше LOGIN_LOCKOUT_MAX.
+	LockoutThreshold int           `env:"LOGIN_LOCKOUT_THRESHOLD" env-default:"5"`
+	LockoutBaseRaw   string        `env:"LOGIN_LOCKOUT_BASE" env-default:"1m"`
+	LockoutMaxRaw    string        `env:"LOGIN_LOCKOUT_MAX" env-default:"1h"`
+	LockoutBase      time.Duration `env:"-"`
+	LockoutMax       time.Duration `env:"-"`
+}
+
+// WorkspaceConfig configures workspace invitations.
+type WorkspaceConfig struct {
+	// Время жизни приглашения: "168h", "24h" или число секунд.
+	InviteTTLRaw string        `env:"WORKSPACE_INVITE_TTL" env-default:"168h"`
+	InviteTTL    time.Duration `env:"-"`
+	// Страница фронтенда для ссылки в письме; токен добавляется как ?token=.
+	// Пусто — в письме только сам токен.
+	InviteURL string `env:"WORKSPACE_INVITE_URL" env-default:""`
+}
+
+// IdempotencyConfig configures Idempotency-Key handling on POST requests.
+type IdempotencyConfig struct {
+	// Сколько хранить ответ по ключу для повторов: "24h" или число секунд.
+	TTLRaw string        `env:"IDEMPOTENCY_TTL" env-default:"24h"`
+	TTL    time.Duration `env:"-"`
+	// Сколько ключ считается занятым выполняющимся запросом (на случай падения процесса).
+	LockTTLRaw string        `env:"IDEMPOTENCY_LOCK_TTL" env-default:"1m"`
+	LockTTL    time.Duration `env:"-"`
+}
+
+// EventsConfig configures the real-time todo event streams.
+type EventsConfig struct {
+	// Сколько последних событий на пользователя хранить для продолжения по Last-Event-ID.
+	Backlog int `env:"EVENTS_BACKLOG" env-default:"1000"`
+	// Сколько хранить очередь событий пользователя после последнего события.
+	RetentionRaw string        `env:"EVENTS_RETENTION" env-default:"24h"`
+	Retention    time.Duration `env:"-"`
+	// Как часто слать heartbeat в открытый поток (прокси закрывают «молчащие» соединения).
+	HeartbeatRaw string        `env:"EVENTS_HEARTBEAT" env-default:"25s"`
+	Heartbeat    time.Duration `env:"-"`
+	// Максимальная длительность одного потока; потом клиент переподключается.
+	MaxDurationRaw string        `env:"EVENTS_MAX_DURATION" env-default:"1h"`
+	MaxDuration    time.Duration `env:"-"`
+}
+
+// WebhookConfig configures outgoing webhooks; the deliveries are sent by cmd/worker.
+type WebhookConfig struct {
+	// Разрешить адреса в частных сетях и localhost (только для разработки).
+	AllowPrivate bool `env:"WEBHOOK_ALLOW_PRIVATE" env-default:"false"`
+	// Как часто проверять очередь доставок.
+	PollIntervalRaw string        `env:"WEBHOOK_POLL_INTERVAL" env-default:"5s"`
+	PollInterval    time.Duration `env:"-"`
+	// Сколько доставок забирать за один проход.
+	Batch int `env:"WEBHOOK_BATCH_SIZE" env-default:"50"`
+	// Таймаут одного запроса к получателю.
+	TimeoutRaw string        `env:"WEBHOOK_TIMEOUT" env-default:"10s"`
+	Timeout    time.Duration `env:"-"`
+	// Сколько попыток на доставку; задержка перед n-й повторной — WEBHOOK_RETRY_BASE·2^(n-1).
+	MaxAttempts  int           `env:"WEBHOOK_MAX_ATTEMPTS" env-default:"10"`
+	RetryBaseRaw string        `env:"WEBHOOK_RETRY_BASE" env-default:"30s"`
+	RetryBase    time.Duration `env:"-"`
+	// После скольких неудачных попыток подряд вебхук отключается.
+	DisableAfter int `env:"WEBHOOK_DISABLE_AFTER" env-default:"20"`
+	// Как часто искать просроченные задачи для события todo.overdue.
+	OverdueIntervalRaw string        `env:"WEBHOOK_OVERDUE_INTERVAL" env-default:"1m"`
+	OverdueInterval    time.Duration `env:"-"`
+}
+
+// SyncConfig configures delta sync (GET /sync).
+type SyncConfig struct {
+	// Сколько действует токен синхронизации; столько же worker хранит записи об удалённых навсегда задачах.
+	TokenTTLRaw string        `env:"SYNC_TOKEN_TTL" env-default:"720h"`
+	TokenTTL    time.Duration `env:"-"`
+}
+
+// CalendarConfig configures iCalendar feeds.
+type CalendarConfig struct {
+	// Публичный адрес API для ссылок на ленты, например https://api.example.com.
+	// Пусто — адрес берётся из запроса (Host и X-Forwarded-Proto).
+	BaseURL string `env:"CALENDAR_BASE_URL" env-default:""`
+}
+
+// WorkerConfig configures cmd/worker.
+type WorkerConfig struct {
+	// Как часто проверять напоминания: "30s", "1m" или число секунд.
+	ReminderIntervalRaw string        `env:"REMINDER_POLL_INTERVAL" env-default:"30s"`
+	ReminderInterval    time.Duration `env:"-"`
+	// Сколько напоминаний забирать одной транзакцией.
+	ReminderBatch int `env:"REMINDER_BATCH_SIZE" env-default:"100"`
+	// Сколько задача лежит в корзине до окончательного удаления; 0 — хранить вечно.
+	TrashRetentionRaw string        `env:"TRASH_RETENTION" env-default:"720h"`
+	TrashRetention    time.Duration `env:"-"`
+	// Как часто чистить корзину.
+	TrashPurgeIntervalRaw string        `env:"TRASH_PURGE_INTERVAL" env-default:"1h"`
+	TrashPurgeInterval    time.Duration `env:"-"`
+}
+
 func Load() (Config, error) {
 	var cfg Config
 	if err := cleanenv.ReadEnv(&cfg); err != nil {
@@ -97,5 +266,162 @@ func Load() (Config, error) {
 		return Config{}, fmt.Errorf("REDIS_DEFAULT_TTL: %w", err)
 	}
 
+	// Parse session TTL
+	if cfg.Session.TTL, err = utils.ParseDurationEnv(cfg.Session.TTLRaw); err != nil {
+		return Config{}, fmt.Errorf("SESSION_TTL: %w", err)
+	}
+	if cfg.Session.TTL <= 0 {
+		return Config{}, fmt.Errorf("SESSION_TTL must be positive")
+	}
+	if cfg.Session.ImpersonationTTL, err = utils.ParseDurationEnv(cfg.Session.ImpersonationTTLRaw); err != nil {
+		return Config{}, fmt.Errorf("SESSION_IMPERSONATION_TTL: %w", err)
+	}
+	if cfg.Session.ImpersonationTTL <= 0 {
+		return Config{}, fmt.Errorf("SESSION_IMPERSONATION_TTL must be positive")
+	}
+
+	// Parse password reset TTL
+	if cfg.PasswordReset.TTL, err = utils.ParseDurationEnv(cfg.PasswordReset.TTLRaw); err != nil {
+		return Config{}, fmt.Errorf("PASSWORD_RESET_TTL: %w", err)
+	}
+	if cfg.PasswordReset.TTL <= 0 {
+		return Config{}, fmt.Errorf("PASSWORD_RESET_TTL must be positive")
+	}
+	if cfg.Mail.Driver != "log" && cfg.Mail.Driver != "file" {
+		return Config{}, fmt.Errorf("MAIL_DRIVER must be log or file")
+	}
+
+	// Parse MFA settings
+	if cfg.MFA.EncryptionKeyRaw != "" {
+		if cfg.MFA.EncryptionKey, err = secretbox.ParseKey(cfg.MFA.EncryptionKeyRaw); err != nil {
+			return Config{}, fmt.Errorf("MFA_ENCRYPTION_KEY: %w", err)
+		}
+	}
+	if cfg.MFA.PendingTTL, err = utils.ParseDurationEnv(cfg.MFA.PendingTTLRaw); err != nil {
+		return Config{}, fmt.Errorf("MFA_PENDING_TTL: %w", err)
+	}
+	if cfg.MFA.PendingTTL <= 0 {
+		return Config{}, fmt.Errorf("MFA_PENDING_TTL must be positive")
+	}
+
+	// Parse rate limits
+	for _, l := range []struct {
+		name string
+		raw  string
+		dst  *ratelimit.Limit
+	}{
+		{"RATE_LIMIT_LOGIN_IP", cfg.RateLimit.LoginIPRaw, &cfg.RateLimit.LoginIP},
+		{"RATE_LIMIT_LOGIN_USER", cfg.RateLimit.LoginUserRaw, &cfg.RateLimit.LoginUser},
+		{"RATE_LIMIT_REGISTER_IP", cfg.RateLimit.RegisterIPRaw, &cfg.RateLimit.RegisterIP},
+		{"RATE_LIMIT_PASSWORD_RESET_IP", cfg.RateLimit.PasswordResetRaw, &cfg.RateLimit.PasswordReset},
+		{"RATE_LIMIT_API", cfg.RateLimit.APIRaw, &cfg.RateLimit.API},
+	} {
+		if *l.dst, err = ratelimit.ParseLimit(l.raw); err != nil {
+			return Config{}, fmt.Errorf("%s: %w", l.name, err)
+		}
+	}
+	if cfg.RateLimit.LockoutBase, err = utils.ParseDurationEnv(cfg.RateLimit.LockoutBaseRaw); err != nil {
+		return Config{}, fmt.Errorf("LOGIN_LOCKOUT_BASE: %w", err)
+	}
+	if cfg.RateLimit.LockoutMax, err = utils.ParseDurationEnv(cfg.RateLimit.LockoutMaxRaw); err != nil {
+		return Config{}, fmt.Errorf("LOGIN_LOCKOUT_MAX: %w", err)
+	}
+	if cfg.RateLimit.LockoutThreshold > 0 && (cfg.RateLimit.LockoutBase <= 0 || cfg.RateLimit.LockoutMax < cfg.RateLimit.LockoutBase) {
+		return Config{}, fmt.Errorf("LOGIN_LOCKOUT_BASE must be positive and not above LOGIN_LOCKOUT_MAX")
+	}
+
+	// Parse workspace settings
+	if cfg.Workspace.InviteTTL, err = utils.ParseDurationEnv(cfg.Workspace.InviteTTLRaw); err != nil {
+		return Config{}, fmt.Errorf("WORKSPACE_INVITE_TTL: %w", err)
+	}
+	if cfg.Workspace.InviteTTL <= 0 {
+		return Config{}, fmt.Errorf("WORKSPACE_INVITE_TTL must be positive")
+	}
+
+	// Parse idempotency settings
+	if cfg.Idempotency.TTL, err = utils.ParseDurationEnv(cfg.Idempotency.TTLRaw); err != nil {
+		return Config{}, fmt.Errorf("IDEMPOTENCY_TTL: %w", err)
+	}
+	if cfg.Idempotency.LockTTL, err = utils.ParseDurationEnv(cfg.Idempotency.LockTTLRaw); err != nil {
+		return Config{}, fmt.Errorf("IDEMPOTENCY_LOCK_TTL: %w", err)
+	}
+	if cfg.Idempotency.TTL <= 0 || cfg.Idempotency.LockTTL <= 0 {
+		return Config{}, fmt.Errorf("IDEMPOTENCY_TTL and IDEMPOTENCY_LOCK_TTL must be positive")
+	}
+
+	// Parse event stream settings
+	if cfg.Events.Backlog <= 0 {
+		return Config{}, fmt.Errorf("EVENTS_BACKLOG must be positive")
+	}
+	for _, d := range []struct {
+		name string
+		raw  string
+		dst  *time.Duration
+	}{
+		{"EVENTS_RETENTION", cfg.Events.RetentionRaw, &cfg.Events.Retention},
+		{"EVENTS_HEARTBEAT", cfg.Events.HeartbeatRaw, &cfg.Events.Heartbeat},
+		{"EVENTS_MAX_DURATION", cfg.Events.MaxDurationRaw, &cfg.Events.MaxDuration},
+	} {
+		if *d.dst, err = utils.ParseDurationEnv(d.raw); err != nil {
+			return Config{}, fmt.Errorf("%s: %w", d.name, err)
+		}
+		if *d.dst <= 0 {
+			return Config{}, fmt.Errorf("%s must be positive", d.name)
+		}
+	}
+
+	// Parse webhook settings
+	if cfg.Webhook.Batch <= 0 || cfg.Webhook.MaxAttempts <= 0 || cfg.Webhook.DisableAfter <= 0 {
+		return Config{}, fmt.Errorf("WEBHOOK_BATCH_SIZE, WEBHOOK_MAX_ATTEMPTS and WEBHOOK_DISABLE_AFTER must be positive")
+	}
+	for _, d := range []struct {
+		name string
+		raw  string
+		dst  *time.Duration
+	}{
+		{"WEBHOOK_POLL_INTERVAL", cfg.Webhook.PollIntervalRaw, &cfg.Webhook.PollInterval},
+		{"WEBHOOK_TIMEOUT", cfg.Webhook.TimeoutRaw, &cfg.Webhook.Timeout},
+		{"WEBHOOK_RETRY_BASE", cfg.Webhook.RetryBaseRaw, &cfg.Webhook.RetryBase},
+		{"WEBHOOK_OVERDUE_INTERVAL", cfg.Webhook.OverdueIntervalRaw, &cfg.Webhook.OverdueInterval},
+	} {
+		if *d.dst, err = utils.ParseDurationEnv(d.raw); err != nil {
+			return Config{}, fmt.Errorf("%s: %w", d.name, err)
+		}
+		if *d.dst <= 0 {
+			return Config{}, fmt.Errorf("%s must be positive", d.name)
+		}
+	}
+
+	// Parse sync settings
+	if cfg.Sync.TokenTTL, err = utils.ParseDurationEnv(cfg.Sync.TokenTTLRaw); err != nil {
+		return Config{}, fmt.Errorf("SYNC_TOKEN_TTL: %w", err)
+	}
+	if cfg.Sync.TokenTTL <= 0 {
+		return Config{}, fmt.Errorf("SYNC_TOKEN_TTL must be positive")
+	}
+
+	// Parse worker settings
+	if cfg.Worker.ReminderInterval, err = utils.ParseDurationEnv(cfg.Worker.ReminderIntervalRaw); err != nil {
+		return Config{}, fmt.Errorf("REMINDER_POLL_INTERVAL: %w", err)
+	}
+	if cfg.Worker.ReminderInterval <= 0 {
+		return Config{}, fmt.Errorf("REMINDER_POLL_INTERVAL must be positive")
+	}
+	if cfg.Worker.ReminderBatch <= 0 {
+		return Config{}, fmt.Errorf("REMINDER_BATCH_SIZE must be positive")
+	}
+	if cfg.Worker.TrashRetention, err = utils.ParseDurationEnv(cfg.Worker.TrashRetentionRaw); err != nil {
+		return Config{}, fmt.Errorf("TRASH_RETENTION: %w", err)
+	}
+	if cfg.Worker.TrashRetention < 0 {
+		return Config{}, fmt.Errorf("TRASH_RETENTION must not be negative")
+	}
+	if cfg.Worker.TrashPurgeInterval, err = utils.ParseDurationEnv(cfg.Worker.TrashPurgeIntervalRaw); err != nil {
+		return Config{}, fmt.Errorf("TRASH_PURGE_INTERVAL: %w", err)
+	}
+	if cfg.Worker.TrashPurgeInterval <= 0 {
+		return Config{}, fmt.Errorf("TRASH_PURGE_INTERVAL must be positive")
+	}
+
 	return cfg, nil
 }
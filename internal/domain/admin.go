@@ -0,0 +1,48 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Admin audit actions.
+const (
+	AuditUserDisabled      = "user.disabled"
+	AuditUserEnabled       = "user.enabled"
+	AuditUserRoleChanged   = "user.role_changed"
+	AuditUserPasswordReset = "user.password_reset"
+	AuditUserImpersonated  = "user.impersonated"
+)
+
+// AdminAuditEntry records one administrative action.
+type AdminAuditEntry struct {
+	ID           int64
+	AdminID      *int64 // nil once the admin account is deleted
+	Action       string
+	TargetUserID *int64
+	Details      json.RawMessage
+	IP           string
+	CreatedAt    time.Time
+}
+
+// SystemStats is an overview for administrators.
+type SystemStats struct {
+	Users          int64
+	Admins         int64
+	DisabledUsers  int64
+	Todos          int64
+	OpenTodos      int64
+	OverdueTodos   int64
+	Projects       int64
+	Tags           int64
+	APITokens      int64
+	PendingReminds int64
+}
+
+// AdminAuditQuery filters the admin audit log, newest first.
+type AdminAuditQuery struct {
+	AdminID      int64
+	TargetUserID int64
+	BeforeID     int64 // keyset cursor: return entries with a smaller ID
+	Limit        int
+}
@@ -0,0 +1,34 @@
+package domain
+
+import (
+	"slices"
+	"time"
+)
+
+// Token scopes. ScopeWrite implies ScopeRead.
+const (
+	ScopeRead  = "read"
+	ScopeWrite = "write"
+)
+
+// APIToken is a personal access token. The secret itself is never stored.
+type APIToken struct {
+	ID         int64
+	UserID     int64
+	Name       string
+	Prefix     string // first characters of the secret, for display
+	Scopes     []string
+	ExpiresAt  *time.Time // nil = never expires
+	LastUsedAt *time.Time
+	CreatedAt  time.Time
+}
+
+// Expired reports whether the token is past its expiry at now.
+func (t APIToken) Expired(now time.Time) bool {
+	return t.ExpiresAt != nil && !now.Before(*t.ExpiresAt)
+}
+
+// HasScope reports whether the token grants scope.
+func (t APIToken) HasScope(scope string) bool {
+	return slices.Contains(t.Scopes, scope) || (scope == ScopeRead && slices.Contains(t.Scopes, ScopeWrite))
+}
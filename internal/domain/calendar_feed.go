@@ -0,0 +1,14 @@
+package domain
+
+import "time"
+
+// CalendarFeed is a user's iCalendar feed of one workspace. The secret in its
+// URL is never stored.
+type CalendarFeed struct {
+	ID          int64
+	UserID      int64
+	WorkspaceID int64
+	Prefix      string // first characters of the secret, for display
+	LastUsedAt  *time.Time
+	CreatedAt   time.Time
+}
@@ -0,0 +1,22 @@
+package domain
+
+import "time"
+
+// UserMFA is a user's TOTP enrollment. The secret is stored encrypted.
+type UserMFA struct {
+	UserID            int64
+	SecretEnc         []byte
+	EnabledAt         *time.Time // nil while enrollment is not confirmed
+	LastUsedStep      int64      // last accepted TOTP time step, for replay protection
+	RecoveryCodesLeft int
+	CreatedAt         time.Time
+}
+
+// RecoveryCode is an unused recovery code; only its hash is stored.
+type RecoveryCode struct {
+	ID   int64
+	Hash string
+}
+
+// Enabled reports whether enrollment was confirmed.
+func (m UserMFA) Enabled() bool { return m.EnabledAt != nil }
@@ -0,0 +1,26 @@
+package domain
+
+import "time"
+
+// Project is a named list of todos owned by one user and possibly shared with others.
+// The inbox project collects todos of deleted projects and cannot be deleted itself.
+type Project struct {
+	ID          int64
+	UserID      int64
+	WorkspaceID int64
+	Name        string
+	Color       string
+	Archived    bool
+	IsInbox     bool
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+
+	// Permission of the user who read the project; set only by ProjectRepo.Access.
+	Permission Permission
+}
+
+// What happens to the todos of a deleted project.
+const (
+	ProjectDeleteCascade = "cascade" // todos are soft-deleted with the project
+	ProjectDeleteToInbox = "inbox"   // todos move to the user's inbox project
+)
@@ -0,0 +1,21 @@
+package domain
+
+import "time"
+
+// MaxReminders and MaxReminderOffset bound the reminders of one todo.
+const (
+	MaxReminders      = 10
+	MaxReminderOffset = 30 * 24 * time.Hour
+)
+
+// Reminder is one due reminder as handed to a notifier.
+type Reminder struct {
+	ID        int64
+	TodoID    int64
+	UserID    int64
+	Title     string
+	DueAt     time.Time
+	BeforeDue time.Duration // how long before DueAt the reminder fires
+	RemindAt  time.Time
+	Attempts  int // failed deliveries so far
+}
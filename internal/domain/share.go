@@ -0,0 +1,45 @@
+package domain
+
+import "time"
+
+// Permission is what a user may do with a todo or project. Each level includes
+// the ones below it.
+type Permission int
+
+const (
+	PermissionNone   Permission = iota
+	PermissionViewer            // read the todo and its subtasks
+	PermissionEditor            // change, complete and reorder; add subtasks
+	PermissionOwner             // also delete, move between projects and manage shares
+)
+
+var permissionNames = map[Permission]string{
+	PermissionViewer: "viewer",
+	PermissionEditor: "editor",
+	PermissionOwner:  "owner",
+}
+
+// String returns the API and database name of p, or "" for PermissionNone.
+func (p Permission) String() string {
+	return permissionNames[p]
+}
+
+// ParsePermission is the inverse of Permission.String.
+func ParsePermission(s string) (Permission, bool) {
+	for p, name := range permissionNames {
+		if name == s {
+			return p, true
+		}
+	}
+	return PermissionNone, false
+}
+
+// Share grants a user access to a todo (with all its subtasks) or to every todo
+// of a project. The owner of the todo or project has no share: their access is implied.
+type Share struct {
+	UserID     int64
+	Username   string
+	Permission Permission
+	GrantedBy  *int64 // nil once the granting user is deleted
+	CreatedAt  time.Time
+}
@@ -0,0 +1,19 @@
+package domain
+
+import "time"
+
+// Tag is a user-scoped label that can be attached to todos.
+type Tag struct {
+	ID        int64
+	UserID    int64
+	Name      string
+	TodoCount int // live (not deleted) todos carrying the tag; filled by listings only
+	CreatedAt time.Time
+}
+
+// TagFilter narrows a list or search to todos carrying the given tag names.
+// MatchAll requires every tag (AND); otherwise any one of them is enough (OR).
+type TagFilter struct {
+	Names    []string
+	MatchAll bool
+}
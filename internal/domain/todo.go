@@ -2,17 +2,46 @@ package domain
 
 import "time"
 
+// MaxTodoDepth is how deep subtasks may nest: a top-level todo has depth 0,
+// so a todo at depth MaxTodoDepth cannot get children.
+const MaxTodoDepth = 3
+
 // Domain entity: бизнес-объект (истина).
 // Не зависит от Gin, Postgres, Redis.
 type Todo struct {
 	ID          int64
 	UserID      int64
+	WorkspaceID int64
 	Title       string
 	Description string
 	IsDone      bool
 	DueAt       *time.Time
+	Tags        []string        // tag names; on update nil means "leave unchanged"
+	Reminders   []time.Duration // how long before DueAt to remind; on update nil means "leave unchanged"
+	ProjectID   *int64
+	Position    int64 // manual order among siblings in the project; lower comes first
+	ParentID    *int64
+
+	// Recurrence is an RRULE (RFC 5545) or "". Completing a recurring todo creates
+	// the next occurrence; the rule runs on the wall clock of RecurrenceTZ
+	// ("" = UTC) from RecurrenceStart, the due date of the first todo of the series.
+	Recurrence      string
+	RecurrenceTZ    string
+	RecurrenceStart *time.Time
+
+	// Direct subtasks that are not deleted; computed on read.
+	ChildrenDone  int
+	ChildrenTotal int
+
+	// Permission of the user who read the todo; set only by reads that resolve
+	// sharing (a single todo, the shared-with-me list), zero otherwise.
+	Permission Permission
 
 	CreatedAt time.Time
 	UpdatedAt time.Time
 	DeletedAt *time.Time
+
+	// Version is bumped by every write of the row; it backs the ETag. On update
+	// a non-zero Version is the one the caller expects to overwrite.
+	Version int64
 }
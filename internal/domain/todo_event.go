@@ -0,0 +1,47 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Todo event actions.
+const (
+	TodoEventCreated   = "created"
+	TodoEventUpdated   = "updated"
+	TodoEventCompleted = "completed"
+	TodoEventMoved     = "moved"
+	TodoEventReordered = "reordered"
+	TodoEventDeleted   = "deleted"
+	TodoEventRestored  = "restored"
+	TodoEventPurged    = "purged"
+)
+
+// TodoEvent records one mutation of a todo. Events are append-only and written
+// in the same transaction as the change.
+type TodoEvent struct {
+	ID          int64
+	TodoID      int64
+	WorkspaceID int64
+	OwnerID     *int64 // owner of the todo at the time; nil once the account is deleted
+	ActorID     *int64 // who made the change; nil for system changes or deleted accounts
+	Action      string
+	// Changes maps each changed field to {"from": old, "to": new}.
+	Changes   json.RawMessage
+	CreatedAt time.Time
+}
+
+// TodoEventQuery filters todo events, newest first. Zero fields do not filter.
+type TodoEventQuery struct {
+	TodoID      int64
+	WorkspaceID int64
+	ActorID     int64
+	OwnerID     int64
+	// VisibleTo keeps events the user made or that concern todos the user owns
+	// or can see through a share (the activity feed).
+	VisibleTo int64
+	From      *time.Time // created_at >= From
+	To        *time.Time // created_at < To
+	BeforeID  int64      // keyset cursor: return events with a smaller ID
+	Limit     int
+}
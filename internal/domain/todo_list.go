@@ -0,0 +1,62 @@
+package domain
+
+import "time"
+
+// Sort keys accepted by todo lists. Anything else is rejected before it reaches SQL.
+const (
+	SortCreatedAt = "created_at"
+	SortUpdatedAt = "updated_at"
+	SortDueAt     = "due_at"
+	SortTitle     = "title"
+	SortPosition  = "position"
+
+	// SortDeletedAt orders the trash; it is not accepted by the other lists.
+	SortDeletedAt = "deleted_at"
+)
+
+// IsValidTodoSort reports whether key is one of the whitelisted sort keys.
+func IsValidTodoSort(key string) bool {
+	switch key {
+	case SortCreatedAt, SortUpdatedAt, SortDueAt, SortTitle, SortPosition:
+		return true
+	}
+	return false
+}
+
+// TodoCursor points at the last item of a page: the sort key it was built for,
+// the value of that key (as text) and the row ID as a tie-breaker.
+type TodoCursor struct {
+	Sort  string `json:"s"`
+	Desc  bool   `json:"d,omitempty"`
+	Value string `json:"v"`
+	ID    int64  `json:"id"`
+}
+
+// TodoListQuery describes one page of a todo list: filters, order and position.
+type TodoListQuery struct {
+	WorkspaceID int64 // set by the service from the active workspace
+
+	Limit int
+	After *TodoCursor
+
+	IsDone       *bool
+	DueBefore    *time.Time
+	DueAfter     *time.Time
+	CreatedAfter *time.Time
+	Tags         TagFilter
+	ProjectID    *int64 // only todos of this project
+	TopLevelOnly bool   // skip subtasks
+
+	// Trashed lists deleted todos instead of live ones: those deleted on their
+	// own or together with their subtree, not subtasks deleted with a parent.
+	Trashed bool
+
+	Sort string
+	Desc bool
+}
+
+// TodoPage is one page of todos. Next is nil on the last page.
+type TodoPage struct {
+	Items []Todo      `json:"items"`
+	Next  *TodoCursor `json:"next,omitempty"`
+}
@@ -0,0 +1,17 @@
+package domain
+
+// Markers around matched terms in search highlights. Control characters cannot
+// appear in user text, so the presentation layer can escape the text first and
+// then swap the markers for real tags.
+const (
+	HighlightStart = "\x02"
+	HighlightStop  = "\x03"
+)
+
+// TodoSearchHit is one full-text search result with its relevance and highlighted snippets.
+type TodoSearchHit struct {
+	Todo                 Todo    `json:"todo"`
+	Rank                 float32 `json:"rank"`
+	TitleHighlight       string  `json:"title_highlight"`
+	DescriptionHighlight string  `json:"description_highlight"`
+}
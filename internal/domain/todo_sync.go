@@ -0,0 +1,39 @@
+package domain
+
+import "time"
+
+// SyncToken is where a sync client stands in the change feed of one workspace:
+// it has every change up to Seq, except changes of transactions that were still
+// running when the token was issued, which all have IDs from XMin on.
+type SyncToken struct {
+	WorkspaceID int64 `json:"w"`
+	Seq         int64 `json:"s"`
+	XMin        int64 `json:"x"`
+	IssuedAt    int64 `json:"t"` // Unix seconds
+}
+
+// TodoChangesQuery asks for the changes of the user's todos in a workspace after
+// a token. AfterSeq 0 is a full sync: live todos only, no tombstones.
+type TodoChangesQuery struct {
+	WorkspaceID int64
+	AfterSeq    int64
+	XMin        int64
+	Limit       int
+}
+
+// TodoTombstone reports a todo that is gone: in the trash, or purged for good.
+type TodoTombstone struct {
+	ID        int64
+	DeletedAt time.Time // purge time for purged todos
+	Purged    bool
+}
+
+// TodoChanges is one page of the change feed. Seq and XMin make up the next
+// token; More means the client should ask again right away.
+type TodoChanges struct {
+	Todos      []Todo
+	Tombstones []TodoTombstone
+	Seq        int64
+	XMin       int64
+	More       bool
+}
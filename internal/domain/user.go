@@ -2,10 +2,34 @@ package domain
 
 import "time"
 
+// User roles.
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
 // User is the domain entity for a user account.
 type User struct {
 	ID           int64
 	Username     string
+	Email        string // optional; needed for password reset
 	PasswordHash string
+	Role         string
+	DisabledAt   *time.Time // nil = active
 	CreatedAt    time.Time
 }
+
+// Disabled reports whether an admin has disabled the account.
+func (u User) Disabled() bool { return u.DisabledAt != nil }
+
+// IsAdmin reports whether the user has the admin role.
+func (u User) IsAdmin() bool { return u.Role == RoleAdmin }
+
+// UserQuery filters the admin user list. Results are ordered by ID.
+type UserQuery struct {
+	Search   string // substring of username or email, case-insensitive
+	Role     string
+	Disabled *bool
+	AfterID  int64 // keyset cursor: return users with a larger ID
+	Limit    int
+}
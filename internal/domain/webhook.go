@@ -0,0 +1,101 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Webhook event types.
+const (
+	WebhookTodoCreated   = "todo.created"
+	WebhookTodoUpdated   = "todo.updated"
+	WebhookTodoCompleted = "todo.completed"
+	WebhookTodoDeleted   = "todo.deleted"
+	WebhookTodoOverdue   = "todo.overdue" // sent by the worker once the due date has passed
+)
+
+// WebhookEvents lists the event types a webhook can subscribe to.
+var WebhookEvents = []string{WebhookTodoCreated, WebhookTodoUpdated, WebhookTodoCompleted, WebhookTodoDeleted, WebhookTodoOverdue}
+
+// Webhook delivery statuses.
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed" // every attempt failed
+)
+
+// Webhook is an HTTP endpoint of a user that receives the events of the todos
+// the user can see.
+type Webhook struct {
+	ID          int64
+	UserID      int64
+	URL         string
+	Description string
+	Events      []string
+	Secret      string // HMAC-SHA256 key deliveries are signed with
+	// FailureCount is the number of failed attempts in a row; the webhook is
+	// disabled when it reaches the limit.
+	FailureCount int
+	DisabledAt   *time.Time // nil = active
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+// WebhookEvent is one todo event to be queued for the webhooks of the users it
+// concerns. Payload is the request body.
+type WebhookEvent struct {
+	ID      string
+	Type    string
+	TodoID  int64
+	Payload json.RawMessage
+}
+
+// WebhookDelivery is one event queued for one webhook.
+type WebhookDelivery struct {
+	ID             int64
+	WebhookID      int64
+	EventID        string // the same for every webhook and redelivery of an event
+	Event          string
+	TodoID         int64
+	Payload        json.RawMessage
+	Status         string
+	Attempts       int
+	NextAttemptAt  time.Time
+	LastStatusCode *int
+	LastError      string
+	DeliveredAt    *time.Time
+	CreatedAt      time.Time
+
+	// URL and Secret of the webhook; set only on deliveries claimed by the worker.
+	URL    string
+	Secret string
+}
+
+// WebhookAttempt is one try to deliver.
+type WebhookAttempt struct {
+	ID         int64
+	DeliveryID int64
+	Attempt    int // 1 for the first try
+	StatusCode int // 0 = no response
+	Error      string
+	Response   string // start of the response body
+	Duration   time.Duration
+	CreatedAt  time.Time
+
+	// RetryAt is when a failed delivery is tried again; nil gives it up. Set by
+	// the sender, not stored.
+	RetryAt *time.Time
+}
+
+// OK reports whether the receiver accepted the delivery.
+func (a WebhookAttempt) OK() bool {
+	return a.Error == "" && a.StatusCode >= 200 && a.StatusCode < 300
+}
+
+// WebhookDeliveryQuery filters the deliveries of a webhook, newest first.
+type WebhookDeliveryQuery struct {
+	WebhookID int64
+	Status    string // "" = any
+	BeforeID  int64  // keyset cursor: return deliveries with a smaller ID
+	Limit     int
+}
@@ -0,0 +1,69 @@
+package domain
+
+import "time"
+
+// Workspace roles, weakest first. Each role may do everything the ones before it may.
+const (
+	WorkspaceRoleViewer = "viewer" // read todos, projects and tags of the workspace
+	WorkspaceRoleMember = "member" // also create and change them
+	WorkspaceRoleAdmin  = "admin"  // also manage members and invitations
+	WorkspaceRoleOwner  = "owner"  // also rename or delete the workspace and appoint owners
+)
+
+var workspaceRoleRank = map[string]int{
+	WorkspaceRoleViewer: 1,
+	WorkspaceRoleMember: 2,
+	WorkspaceRoleAdmin:  3,
+	WorkspaceRoleOwner:  4,
+}
+
+// IsValidWorkspaceRole reports whether role is one of the workspace roles.
+func IsValidWorkspaceRole(role string) bool {
+	_, ok := workspaceRoleRank[role]
+	return ok
+}
+
+// WorkspaceRoleAtLeast reports whether role includes min. Unknown roles include nothing.
+func WorkspaceRoleAtLeast(role, min string) bool {
+	r, ok := workspaceRoleRank[role]
+	return ok && r >= workspaceRoleRank[min]
+}
+
+// Workspace isolates a team's todos, projects and caches from other teams.
+// Every user has a personal workspace that cannot be deleted or shared.
+type Workspace struct {
+	ID             int64
+	Name           string
+	PersonalUserID *int64 // set for the personal workspace of that user
+	CreatedBy      *int64
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+
+	Role string // role of the user who read the workspace
+}
+
+// Personal reports whether w is a user's personal workspace.
+func (w Workspace) Personal() bool { return w.PersonalUserID != nil }
+
+// WorkspaceMember is a user's membership in a workspace.
+type WorkspaceMember struct {
+	UserID    int64
+	Username  string
+	Email     string
+	Role      string
+	CreatedAt time.Time
+}
+
+// WorkspaceInvitation lets whoever holds its token join the workspace with Role.
+// With Email set only the account with that e-mail may accept it.
+type WorkspaceInvitation struct {
+	ID          int64
+	WorkspaceID int64
+	Email       string
+	Role        string
+	InvitedBy   *int64
+	ExpiresAt   time.Time
+	AcceptedAt  *time.Time
+	AcceptedBy  *int64
+	CreatedAt   time.Time
+}
@@ -0,0 +1,89 @@
+package dto
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// ListUsersQuery holds query parameters for GET /admin/users.
+type ListUsersQuery struct {
+	Q        string `form:"q"` // substring of username or email
+	Role     string `form:"role" binding:"omitempty,oneof=user admin"`
+	Disabled *bool  `form:"disabled"`
+	Limit    int    `form:"limit" binding:"omitempty,min=1,max=200"`
+	After    int64  `form:"after" binding:"omitempty,min=0"` // next_cursor of the previous page
+}
+
+type AdminUserResponse struct {
+	ID         int64      `json:"id"`
+	Username   string     `json:"username"`
+	Email      string     `json:"email,omitempty"`
+	Role       string     `json:"role"`
+	Disabled   bool       `json:"disabled"`
+	DisabledAt *time.Time `json:"disabled_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type ListUsersResponse struct {
+	Items      []AdminUserResponse `json:"items"`
+	NextCursor string              `json:"next_cursor,omitempty"` // empty on the last page
+}
+
+// SetRoleRequest is the JSON body for PUT /admin/users/:id/role.
+type SetRoleRequest struct {
+	Role string `json:"role" binding:"required,oneof=user admin"`
+}
+
+// ImpersonateRequest is the JSON body for POST /admin/users/:id/impersonate.
+type ImpersonateRequest struct {
+	Reason string `json:"reason" binding:"required,max=500" example:"ticket #123"`
+}
+
+type ImpersonateResponse struct {
+	User      AdminUserResponse `json:"user"`
+	ExpiresAt time.Time         `json:"expires_at"`
+}
+
+// ForcePasswordResetResponse tells whether the reset link was mailed. If the
+// user has no email, the reset token is returned instead.
+type ForcePasswordResetResponse struct {
+	Emailed         bool   `json:"emailed"`
+	ResetToken      string `json:"reset_token,omitempty"`
+	RevokedSessions int    `json:"revoked_sessions"`
+}
+
+type StatsResponse struct {
+	Users            int64 `json:"users"`
+	Admins           int64 `json:"admins"`
+	DisabledUsers    int64 `json:"disabled_users"`
+	Todos            int64 `json:"todos"`
+	OpenTodos        int64 `json:"open_todos"`
+	OverdueTodos     int64 `json:"overdue_todos"`
+	Projects         int64 `json:"projects"`
+	Tags             int64 `json:"tags"`
+	ActiveAPITokens  int64 `json:"active_api_tokens"`
+	PendingReminders int64 `json:"pending_reminders"`
+}
+
+// ListAuditQuery holds query parameters for GET /admin/audit.
+type ListAuditQuery struct {
+	AdminID int64 `form:"admin_id" binding:"omitempty,min=1"`
+	UserID  int64 `form:"user_id" binding:"omitempty,min=1"` // target user
+	Limit   int   `form:"limit" binding:"omitempty,min=1,max=200"`
+	Before  int64 `form:"before" binding:"omitempty,min=0"` // next_cursor of the previous page
+}
+
+type AuditEntryResponse struct {
+	ID           int64           `json:"id"`
+	AdminID      *int64          `json:"admin_id"`
+	Action       string          `json:"action"`
+	TargetUserID *int64          `json:"target_user_id"`
+	Details      json.RawMessage `json:"details" swaggertype:"object"`
+	IP           string          `json:"ip"`
+	CreatedAt    time.Time       `json:"created_at"`
+}
+
+type ListAuditResponse struct {
+	Items      []AuditEntryResponse `json:"items"`
+	NextCursor string               `json:"next_cursor,omitempty"`
+}
@@ -0,0 +1,23 @@
+package dto
+
+import "time"
+
+// CalendarFeedResponse describes the calendar feed of the workspace.
+type CalendarFeedResponse struct {
+	Prefix     string     `json:"prefix"` // first characters of the secret
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// CreateCalendarFeedResponse carries the feed URL with its secret; it is
+// returned only once.
+type CreateCalendarFeedResponse struct {
+	CalendarFeedResponse
+	URL string `json:"url" example:"https://api.example.com/api/v1/calendar/cal_x1y2z3.ics"`
+}
+
+// CalendarQuery is the query of GET /calendar/:token.
+type CalendarQuery struct {
+	Type        string `form:"type" binding:"omitempty,oneof=vtodo vevent"` // default vtodo
+	IncludeDone bool   `form:"include_done"`                                // also done todos
+}
@@ -0,0 +1,20 @@
+package dto
+
+import "time"
+
+// TodoStreamEvent is one message of GET /events (SSE data) and GET /events/ws.
+type TodoStreamEvent struct {
+	ID          string        `json:"id,omitempty"` // передайте как Last-Event-ID / last_event_id, чтобы продолжить
+	Type        string        `json:"type"`         // created, updated, completed, deleted, reset; в WebSocket ещё ping
+	WorkspaceID int64         `json:"workspace_id,omitempty"`
+	TodoID      int64         `json:"todo_id,omitempty"`
+	ActorID     int64         `json:"actor_id,omitempty"`
+	Todo        *TodoResponse `json:"todo,omitempty"` // нет у deleted
+	At          time.Time     `json:"at"`
+}
+
+// EventStreamQuery is the query of GET /events and GET /events/ws.
+type EventStreamQuery struct {
+	LastEventID string `form:"last_event_id"`                          // вместо заголовка Last-Event-ID
+	WorkspaceID int64  `form:"workspace_id" binding:"omitempty,min=1"` // только события этого пространства
+}
@@ -0,0 +1,38 @@
+package dto
+
+import "time"
+
+// MFAChallengeResponse is returned by POST /auth/login when a second factor is required.
+type MFAChallengeResponse struct {
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token"`
+	ExpiresIn   int    `json:"expires_in"` // seconds
+}
+
+// LoginMFARequest is the JSON body for POST /auth/login/mfa.
+type LoginMFARequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	Code     string `json:"code" binding:"required"` // TOTP code or recovery code
+}
+
+// MFACodeRequest carries a TOTP or recovery code.
+type MFACodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type MFAStatusResponse struct {
+	Enabled           bool       `json:"enabled"`
+	EnabledAt         *time.Time `json:"enabled_at,omitempty"`
+	RecoveryCodesLeft int        `json:"recovery_codes_left"`
+}
+
+// MFAEnrollResponse is returned by POST /auth/mfa/enroll. Show the URI as a QR code.
+type MFAEnrollResponse struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+// RecoveryCodesResponse lists recovery codes; they are returned only once.
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
@@ -0,0 +1,32 @@
+package dto
+
+import "time"
+
+// CreateProjectRequest is the JSON body for POST /projects.
+type CreateProjectRequest struct {
+	Name     string `json:"name" binding:"required,min=1,max=120"`
+	Color    string `json:"color" binding:"omitempty,max=16" example:"#1e90ff"`
+	Archived bool   `json:"archived"`
+}
+
+// UpdateProjectRequest is the JSON body for PATCH /projects/:id. All fields are optional.
+type UpdateProjectRequest struct {
+	Name     *string `json:"name" binding:"omitempty,min=1,max=120"`
+	Color    *string `json:"color" binding:"omitempty,max=16" example:"#1e90ff"` // "" = убрать цвет
+	Archived *bool   `json:"archived"`
+}
+
+type ProjectResponse struct {
+	ID          int64     `json:"id"`
+	WorkspaceID int64     `json:"workspace_id"`
+	Name        string    `json:"name"`
+	Color       string    `json:"color,omitempty"`
+	Archived    bool      `json:"archived"`
+	IsInbox     bool      `json:"is_inbox"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type ListProjectsResponse struct {
+	Items []ProjectResponse `json:"items"`
+}
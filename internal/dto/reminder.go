@@ -0,0 +1,65 @@
+package dto
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseOffsets parses reminder offsets such as "15m", "1h30m", "1d" or "1w":
+// Go durations plus whole days (d) and weeks (w).
+func ParseOffsets(ss []string) ([]time.Duration, error) {
+	out := make([]time.Duration, 0, len(ss))
+	for _, s := range ss {
+		d, err := parseOffset(strings.TrimSpace(s))
+		if err != nil {
+			return nil, fmt.Errorf("reminders: %q: use e.g. 15m, 1h, 1d or 1w", s)
+		}
+		out = append(out, d)
+	}
+	return out, nil
+}
+
+func parseOffset(s string) (time.Duration, error) {
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			v, err := strconv.Atoi(n)
+			if err != nil {
+				return 0, err
+			}
+			return time.Duration(v) * unit, nil
+		}
+	}
+	return time.ParseDuration(s)
+}
+
+// FormatOffset is the inverse of ParseOffsets: "1d", "2h", "1h30m", "0s".
+func FormatOffset(d time.Duration) string {
+	day := 24 * time.Hour
+	switch {
+	case d == 0:
+		return "0s"
+	case d%(7*day) == 0:
+		return strconv.FormatInt(int64(d/(7*day)), 10) + "w"
+	case d%day == 0:
+		return strconv.FormatInt(int64(d/day), 10) + "d"
+	}
+	s := d.String() // "1h30m0s"
+	if strings.HasSuffix(s, "m0s") {
+		s = s[:len(s)-2]
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = s[:len(s)-2]
+	}
+	return s
+}
+
+// FormatOffsets formats a list of offsets; the result is never nil.
+func FormatOffsets(ds []time.Duration) []string {
+	out := make([]string, len(ds))
+	for i, d := range ds {
+		out[i] = FormatOffset(d)
+	}
+	return out
+}
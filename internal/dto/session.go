@@ -0,0 +1,24 @@
+package dto
+
+import "time"
+
+type SessionResponse struct {
+	ID         string    `json:"id"` // handle for DELETE /auth/sessions/:id
+	Current    bool      `json:"current"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	// Impersonated marks a support session opened by an administrator.
+	Impersonated bool `json:"impersonated"`
+}
+
+type ListSessionsResponse struct {
+	Items []SessionResponse `json:"items"`
+}
+
+// RevokeSessionsResponse reports how many sessions "log out everywhere" ended.
+type RevokeSessionsResponse struct {
+	Revoked int `json:"revoked"`
+}
@@ -0,0 +1,22 @@
+package dto
+
+import "time"
+
+// ShareRequest is the JSON body for POST /todos/:id/shares and /projects/:id/shares.
+// Sharing again with the same user changes their permission.
+type ShareRequest struct {
+	User       string `json:"user" binding:"required,max=255" example:"bob"` // username or email
+	Permission string `json:"permission" binding:"required,oneof=viewer editor owner" example:"editor"`
+}
+
+type ShareResponse struct {
+	UserID     int64     `json:"user_id"`
+	Username   string    `json:"username"`
+	Permission string    `json:"permission"` // viewer, editor or owner
+	GrantedBy  *int64    `json:"granted_by"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type ListSharesResponse struct {
+	Items []ShareResponse `json:"items"`
+}
@@ -0,0 +1,54 @@
+package dto
+
+import "time"
+
+// SyncQuery is the query of GET /sync.
+type SyncQuery struct {
+	Since string `form:"since"`                                    // token of the previous response; empty = full sync
+	Limit int    `form:"limit" binding:"omitempty,min=1,max=1000"` // default 200
+}
+
+// TodoTombstoneResponse reports a todo the client should drop.
+type TodoTombstoneResponse struct {
+	ID        int64     `json:"id"`
+	DeletedAt time.Time `json:"deleted_at"`
+	Purged    bool      `json:"purged,omitempty"` // deleted for good; otherwise it is in the trash
+}
+
+type SyncResponse struct {
+	Todos      []TodoResponse          `json:"todos"`      // created or changed; replace the local copy
+	Tombstones []TodoTombstoneResponse `json:"tombstones"` // deleted
+	Token      string                  `json:"token"`      // send as since next time
+	More       bool                    `json:"more"`       // more changes are waiting; ask again with token right away
+}
+
+// SyncPushRequest is the JSON body for POST /sync.
+type SyncPushRequest struct {
+	Strategy string       `json:"strategy" binding:"omitempty,oneof=version lww" example:"version"` // version (default) or lww — last writer wins
+	Changes  []SyncChange `json:"changes" binding:"required,min=1,max=200,dive"`
+}
+
+// SyncChange is one change made offline; which fields apply depends on op.
+type SyncChange struct {
+	Op          string             `json:"op" binding:"required,oneof=create update delete" example:"update"`
+	ID          int64              `json:"id" binding:"omitempty,min=1"`                 // update, delete
+	ClientID    string             `json:"client_id" binding:"max=64" example:"local-7"` // create: the client's ID of the todo, echoed back
+	BaseVersion int64              `json:"base_version" binding:"omitempty,min=1"`       // strategy version: the version the change was made to
+	ModifiedAt  *time.Time         `json:"modified_at"`                                  // strategy lww: when the change was made
+	Create      *CreateTodoRequest `json:"create"`                                       // create
+	Update      *UpdateTodoRequest `json:"update"`                                       // update
+}
+
+type SyncPushResult struct {
+	Index    int           `json:"index"`
+	Op       string        `json:"op"`
+	ID       int64         `json:"id,omitempty"`
+	ClientID string        `json:"client_id,omitempty"`
+	Status   string        `json:"status"`          // applied, conflict, not_found or rejected
+	Todo     *TodoResponse `json:"todo,omitempty"`  // applied: the todo now; conflict: the server's todo
+	Error    string        `json:"error,omitempty"` // rejected
+}
+
+type SyncPushResponse struct {
+	Results []SyncPushResult `json:"results"`
+}
@@ -0,0 +1,19 @@
+package dto
+
+import "time"
+
+// TagRequest is the JSON body for POST /tags and PATCH /tags/:id.
+type TagRequest struct {
+	Name string `json:"name" binding:"required,min=1,max=64"`
+}
+
+type TagResponse struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	TodoCount int       `json:"todo_count"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ListTagsResponse struct {
+	Items []TagResponse `json:"items"`
+}
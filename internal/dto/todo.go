@@ -20,7 +20,17 @@ func (d *DueAt) UnmarshalJSON(data []byte) error {
 		d.t = nil
 		return nil
 	}
-	s := strings.TrimSpace(*raw)
+	t, err := ParseDateOrTime(*raw)
+	if err != nil {
+		return fmt.Errorf("due_at: %w", err)
+	}
+	d.t = &t
+	return nil
+}
+
+// ParseDateOrTime parses s as date-only ("2006-01-02", start of that day in UTC) or RFC3339.
+func ParseDateOrTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
 	layouts := []string{
 		"2006-01-02",     // date only
 		time.RFC3339,     // 2006-01-02T15:04:05Z07:00
@@ -34,39 +44,134 @@ func (d *DueAt) UnmarshalJSON(data []byte) error {
 			if layout == "2006-01-02" {
 				parsed = time.Date(parsed.Year(), parsed.Month(), parsed.Day(), 0, 0, 0, 0, time.UTC)
 			}
-			d.t = &parsed
-			return nil
+			return parsed, nil
 		}
 	}
-	return fmt.Errorf("due_at: use date (YYYY-MM-DD) or RFC3339 datetime")
+	return time.Time{}, fmt.Errorf("use date (YYYY-MM-DD) or RFC3339 datetime")
 }
 
 // Ptr returns *time.Time for use in service/domain.
 func (d DueAt) Ptr() *time.Time { return d.t }
 
 type CreateTodoRequest struct {
-	Title       string `json:"title" binding:"required,min=1,max=120"`
-	Description string `json:"description" binding:"max=1000"`
-	DueAt       DueAt  `json:"due_at" swaggertype:"primitive,string" example:"2026-02-19"` // optional: "2026-02-19" or RFC3339
+	Title       string   `json:"title" binding:"required,min=1,max=120"`
+	Description string   `json:"description" binding:"max=1000"`
+	DueAt       DueAt    `json:"due_at" swaggertype:"primitive,string" example:"2026-02-19"`       // optional: "2026-02-19" or RFC3339
+	Tags        []string `json:"tags" binding:"omitempty,max=20,dive,min=1,max=64" example:"work"` // tag names; missing tags are created
+	Reminders   []string `json:"reminders" binding:"omitempty,max=10" example:"1h"`                // before due_at: 15m, 1h, 1d, 1w
+	ProjectID   *int64   `json:"project_id" binding:"omitempty,min=1"`
+	Recurrence  string   `json:"recurrence" binding:"max=255" example:"FREQ=WEEKLY;BYDAY=MO,TH"` // RFC 5545 RRULE; needs due_at
+	Timezone    string   `json:"timezone" binding:"max=64" example:"Europe/Berlin"`              // IANA zone for the rule; default UTC
 }
 
 type UpdateTodoRequest struct {
-	Title       *string `json:"title" binding:"omitempty,min=1,max=120"`
-	Description *string `json:"description" binding:"omitempty,max=1000"`
-	DueAt       *DueAt  `json:"due_at" swaggertype:"primitive,string" example:"2026-02-19"` // nil = не менять, значение = поставить
-	IsDone      *bool   `json:"is_done"`  // nil = не менять, true/false = статус
+	Title       *string   `json:"title" binding:"omitempty,min=1,max=120"`
+	Description *string   `json:"description" binding:"omitempty,max=1000"`
+	DueAt       *DueAt    `json:"due_at" swaggertype:"primitive,string" example:"2026-02-19"` // nil = не менять, значение = поставить
+	IsDone      *bool     `json:"is_done"`                                                    // nil = не менять, true/false = статус
+	Tags        *[]string `json:"tags" binding:"omitempty,max=20,dive,min=1,max=64"`          // nil = не менять, [] = снять все теги
+	Reminders   *[]string `json:"reminders" binding:"omitempty,max=10"`                       // nil = не менять, [] = убрать напоминания
+	ProjectID   *int64    `json:"project_id" binding:"omitempty,min=0"`                       // nil = не менять, 0 = убрать из проекта
+	Recurrence  *string   `json:"recurrence" binding:"omitempty,max=255"`                     // nil = не менять, "" = больше не повторять
+	Timezone    *string   `json:"timezone" binding:"omitempty,max=64"`                        // nil = не менять
+}
+
+// MoveTodoRequest is the JSON body for POST /todos/:id/move. Set at most one of
+// before_id/after_id; with neither the todo goes to the end of the project.
+type MoveTodoRequest struct {
+	ProjectID *int64 `json:"project_id" binding:"omitempty,min=0"` // nil = текущий проект, 0 = без проекта
+	BeforeID  int64  `json:"before_id" binding:"omitempty,min=1,excluded_with=AfterID"`
+	AfterID   int64  `json:"after_id" binding:"omitempty,min=1"`
 }
 
 type TodoResponse struct {
-	ID          int64      `json:"id"`
-	Title       string     `json:"title"`
-	Description string     `json:"description"`
-	IsDone      bool       `json:"is_done"`
-	DueAt       *time.Time `json:"due_at"`
-	CreatedAt   time.Time  `json:"created_at"`
-	UpdatedAt   time.Time  `json:"updated_at"`
+	ID          int64       `json:"id"`
+	WorkspaceID int64       `json:"workspace_id"`
+	Title       string      `json:"title"`
+	Description string      `json:"description"`
+	IsDone      bool        `json:"is_done"`
+	DueAt       *time.Time  `json:"due_at"`
+	Tags        []string    `json:"tags"`
+	Reminders   []string    `json:"reminders"` // offsets before due_at, e.g. "1h", "1d"
+	ProjectID   *int64      `json:"project_id"`
+	Position    int64       `json:"position"`
+	ParentID    *int64      `json:"parent_id"`
+	Progress    *Progress   `json:"progress,omitempty"`   // only for todos with subtasks
+	Recurrence  *Recurrence `json:"recurrence,omitempty"` // only for recurring todos
+	Permission  string      `json:"permission,omitempty"` // viewer, editor or owner; on single todos and GET /todos/shared
+	CreatedAt   time.Time   `json:"created_at"`
+	UpdatedAt   time.Time   `json:"updated_at"`
+	DeletedAt   *time.Time  `json:"deleted_at,omitempty"` // only in the trash
+	Version     int64       `json:"version"`              // bumped on every change; the ETag of GET /todos/:id
+}
+
+// Progress counts direct subtasks: done of total.
+type Progress struct {
+	Done    int `json:"done"`
+	Total   int `json:"total"`
+	Percent int `json:"percent"`
+}
+
+// Recurrence describes the series of a recurring todo.
+type Recurrence struct {
+	Rule     string    `json:"rule"`     // canonical RRULE
+	Timezone string    `json:"timezone"` // "UTC" when none was given
+	Start    time.Time `json:"start"`    // due date of the first todo of the series
+}
+
+// ReorderSubtasksRequest is the JSON body for POST /todos/:id/subtasks/reorder:
+// every subtask ID in the desired order.
+type ReorderSubtasksRequest struct {
+	IDs []int64 `json:"ids" binding:"required,min=1,dive,min=1"`
+}
+
+// ListTodosQuery is the query string of GET /todos. Dates accept the same formats as due_at.
+type ListTodosQuery struct {
+	Limit int `form:"limit" binding:"omitempty,min=1,max=200"`
+	// After is the opaque cursor from next_cursor of the previous page.
+	After        string `form:"after"`
+	IsDone       *bool  `form:"is_done"`
+	DueBefore    string `form:"due_before"`
+	DueAfter     string `form:"due_after"`
+	CreatedAfter string `form:"created_after"`
+	ProjectID    *int64 `form:"project_id" binding:"omitempty,min=1"`
+	TopLevel     bool   `form:"top_level"` // true = без подзадач
+	TagFilterQuery
+	// Sort is a whitelisted key; a "-" prefix means descending.
+	Sort string `form:"sort" binding:"omitempty,oneof=created_at -created_at updated_at -updated_at due_at -due_at title -title position -position"`
+}
+
+// ListTrashQuery is the query string of GET /todos/trash.
+type ListTrashQuery struct {
+	Limit int    `form:"limit" binding:"omitempty,min=1,max=200"`
+	After string `form:"after"` // opaque cursor from next_cursor of the previous page
+}
+
+// TagFilterQuery is the tag filter shared by list and search: ?tag=a&tag=b&tag_mode=and.
+// tag_mode "and" requires every tag, "or" (default) any of them.
+type TagFilterQuery struct {
+	Tag     []string `form:"tag"`
+	TagMode string   `form:"tag_mode" binding:"omitempty,oneof=and or"`
 }
 
 type ListTodosResponse struct {
-	Items []TodoResponse `json:"items"`
+	Items      []TodoResponse `json:"items"`
+	NextCursor string         `json:"next_cursor,omitempty"` // empty on the last page
+}
+
+// SearchHighlights holds HTML snippets with matched terms wrapped in <mark>; the rest is escaped.
+type SearchHighlights struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+}
+
+// SearchHitResponse is one result of GET /todos/search.
+type SearchHitResponse struct {
+	TodoResponse
+	Rank       float32          `json:"rank"`
+	Highlights SearchHighlights `json:"highlights"`
+}
+
+type SearchTodosResponse struct {
+	Items []SearchHitResponse `json:"items"`
 }
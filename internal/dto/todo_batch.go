@@ -0,0 +1,47 @@
+package dto
+
+// BatchRequest is the JSON body for POST /todos/batch: either a list of
+// operations, or a filter with one action applied to every matching todo.
+type BatchRequest struct {
+	Mode       string           `json:"mode" binding:"omitempty,oneof=atomic best_effort" example:"atomic"` // atomic (default) — всё или ничего
+	Operations []BatchOperation `json:"operations" binding:"omitempty,max=200,dive"`
+	Filter     *BatchFilter     `json:"filter"`
+	Action     *BatchOperation  `json:"action"` // with filter: op complete, delete or update; id is ignored
+}
+
+// BatchOperation is one operation of a batch; which fields apply depends on op.
+type BatchOperation struct {
+	Op        string             `json:"op" binding:"required,oneof=create update complete delete move" example:"complete"`
+	ID        int64              `json:"id" binding:"omitempty,min=1"`       // all but create
+	Create    *CreateTodoRequest `json:"create"`                             // create
+	Update    *UpdateTodoRequest `json:"update"`                             // update
+	Move      *MoveTodoRequest   `json:"move"`                               // move
+	IfMatch   int64              `json:"if_match" binding:"omitempty,min=1"` // update, complete, delete: expected version
+	Subtasks  bool               `json:"subtasks"`                           // complete: with all subtasks
+	Permanent bool               `json:"permanent"`                          // delete: skip the trash
+}
+
+// BatchFilter selects the caller's own todos for a filter batch.
+type BatchFilter struct {
+	Overdue   bool     `json:"overdue"` // open and past due_at
+	IsDone    *bool    `json:"is_done"`
+	DueBefore string   `json:"due_before" example:"2026-03-01"` // YYYY-MM-DD or RFC3339
+	Tag       []string `json:"tag" binding:"omitempty,max=20,dive,min=1,max=64"`
+	TagMode   string   `json:"tag_mode" binding:"omitempty,oneof=and or"`
+	ProjectID *int64   `json:"project_id" binding:"omitempty,min=1"`
+}
+
+type BatchItemResult struct {
+	Index  int           `json:"index"`
+	Op     string        `json:"op"`
+	ID     int64         `json:"id,omitempty"`
+	Status int           `json:"status"` // HTTP status the single-todo endpoint would have returned
+	Todo   *TodoResponse `json:"todo,omitempty"`
+	Error  string        `json:"error,omitempty"`
+}
+
+type BatchResponse struct {
+	Committed bool              `json:"committed"`      // false — пакет «всё или ничего» откатан
+	More      bool              `json:"more,omitempty"` // фильтр нашёл больше 200 задач; повторите запрос
+	Results   []BatchItemResult `json:"results"`
+}
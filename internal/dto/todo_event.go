@@ -0,0 +1,42 @@
+package dto
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type TodoEventResponse struct {
+	ID          int64  `json:"id"`
+	TodoID      int64  `json:"todo_id"`
+	WorkspaceID int64  `json:"workspace_id"`
+	OwnerID     *int64 `json:"owner_id"`
+	ActorID     *int64 `json:"actor_id"`
+	// Action is created, updated, completed, moved, reordered or deleted.
+	Action string `json:"action" example:"updated"`
+	// Changes maps each changed field to its old and new value: {"title": {"from": "a", "to": "b"}}.
+	Changes   json.RawMessage `json:"changes" swaggertype:"object"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+type ListTodoEventsResponse struct {
+	Items      []TodoEventResponse `json:"items"`
+	NextCursor string              `json:"next_cursor,omitempty"`
+}
+
+// ListTodoEventsQuery holds query parameters for GET /todos/:id/history and GET /activity.
+type ListTodoEventsQuery struct {
+	Limit  int   `form:"limit" binding:"omitempty,min=1,max=200"`
+	Before int64 `form:"before" binding:"omitempty,min=0"` // next_cursor of the previous page
+}
+
+// AdminTodoEventsQuery holds query parameters for GET /admin/todo-events.
+// From and To accept the same formats as due_at.
+type AdminTodoEventsQuery struct {
+	ActorID int64  `form:"actor_id" binding:"omitempty,min=1"`
+	OwnerID int64  `form:"owner_id" binding:"omitempty,min=1"`
+	TodoID  int64  `form:"todo_id" binding:"omitempty,min=1"`
+	From    string `form:"from"`
+	To      string `form:"to"`
+	Limit   int    `form:"limit" binding:"omitempty,min=1,max=200"`
+	Before  int64  `form:"before" binding:"omitempty,min=0"`
+}
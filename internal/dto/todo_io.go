@@ -0,0 +1,30 @@
+package dto
+
+// ExportQuery is the query of GET /todos/export.
+type ExportQuery struct {
+	Format         string `form:"format" binding:"omitempty,oneof=csv json md ics"` // default csv
+	IncludeDeleted bool   `form:"include_deleted"`                                  // also todos in the trash
+}
+
+// ImportQuery is the query of POST /todos/import.
+type ImportQuery struct {
+	Format string `form:"format" binding:"required,oneof=csv json md ics todoist"`
+	DryRun bool   `form:"dry_run"` // only check the file
+}
+
+// ImportProblem is an error or a warning about one todo of an import file.
+type ImportProblem struct {
+	Line    int    `json:"line"`            // CSV and Markdown: line of the file; JSON: position in the array
+	Field   string `json:"field,omitempty"` // empty: the todo as a whole
+	Message string `json:"message"`
+}
+
+type ImportResponse struct {
+	DryRun   bool            `json:"dry_run"`
+	Valid    bool            `json:"valid"`    // no errors; without dry_run the todos were imported
+	Todos    int             `json:"todos"`    // todos in the file
+	Created  int             `json:"created"`  // todos imported
+	Projects []string        `json:"projects"` // projects created, or with dry_run to be created
+	Errors   []ImportProblem `json:"errors"`   // any error keeps the whole file out
+	Warnings []ImportProblem `json:"warnings"` // imported anyway, without what the warning is about
+}
@@ -0,0 +1,30 @@
+package dto
+
+import "time"
+
+// CreateTokenRequest is the JSON body for POST /auth/tokens.
+type CreateTokenRequest struct {
+	Name          string   `json:"name" binding:"required,min=1,max=100" example:"ci"`
+	Scopes        []string `json:"scopes" binding:"required,min=1,dive,oneof=read write" example:"read"` // write включает read
+	ExpiresInDays int      `json:"expires_in_days" binding:"omitempty,min=1,max=365"`                    // 0 = бессрочный
+}
+
+type TokenResponse struct {
+	ID         int64      `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"` // first characters of the token
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// CreateTokenResponse carries the token itself; it is returned only once.
+type CreateTokenResponse struct {
+	TokenResponse
+	Token string `json:"token"`
+}
+
+type ListTokensResponse struct {
+	Items []TokenResponse `json:"items"`
+}
@@ -10,10 +10,30 @@ type LoginRequest struct {
 type RegisterRequest struct {
 	Username string `json:"username" binding:"required,min=1,max=120"`
 	Password string `json:"password" binding:"required,min=1"`
+	Email    string `json:"email" binding:"omitempty,email,max=255"` // optional; needed for password reset
 }
 
 // UserResponse is returned when user info is needed (e.g. after login).
 type UserResponse struct {
 	ID       int64  `json:"id"`
 	Username string `json:"username"`
+	Email    string `json:"email,omitempty"`
+	Role     string `json:"role"`
+}
+
+// ChangePasswordRequest is the JSON body for POST /auth/password.
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required"`
+}
+
+// ForgotPasswordRequest is the JSON body for POST /auth/password/forgot.
+type ForgotPasswordRequest struct {
+	Login string `json:"login" binding:"required"` // username or email
+}
+
+// ResetPasswordRequest is the JSON body for POST /auth/password/reset.
+type ResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
 }
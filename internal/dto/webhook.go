@@ -0,0 +1,87 @@
+package dto
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// CreateWebhookRequest is the JSON body for POST /webhooks.
+type CreateWebhookRequest struct {
+	URL         string   `json:"url" binding:"required,max=2048" example:"https://example.com/hooks/todos"`
+	Description string   `json:"description" binding:"max=255"`
+	Events      []string `json:"events" binding:"required,min=1,dive,oneof=todo.created todo.updated todo.completed todo.deleted todo.overdue" example:"todo.completed"`
+}
+
+// UpdateWebhookRequest is the JSON body for PATCH /webhooks/:id; absent fields stay unchanged.
+type UpdateWebhookRequest struct {
+	URL         *string   `json:"url" binding:"omitempty,max=2048"`
+	Description *string   `json:"description" binding:"omitempty,max=255"`
+	Events      *[]string `json:"events" binding:"omitempty,min=1,dive,oneof=todo.created todo.updated todo.completed todo.deleted todo.overdue"`
+	Enabled     *bool     `json:"enabled"` // true re-enables a webhook disabled after failures
+}
+
+type WebhookResponse struct {
+	ID           int64      `json:"id"`
+	URL          string     `json:"url"`
+	Description  string     `json:"description"`
+	Events       []string   `json:"events"`
+	Enabled      bool       `json:"enabled"`
+	FailureCount int        `json:"failure_count"` // failed attempts in a row
+	DisabledAt   *time.Time `json:"disabled_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// CreateWebhookResponse carries the signing secret; it is returned only once.
+type CreateWebhookResponse struct {
+	WebhookResponse
+	Secret string `json:"secret"`
+}
+
+type ListWebhooksResponse struct {
+	Items []WebhookResponse `json:"items"`
+}
+
+// ListWebhookDeliveriesQuery holds query parameters for GET /webhooks/:id/deliveries.
+type ListWebhookDeliveriesQuery struct {
+	Status string `form:"status" binding:"omitempty,oneof=pending succeeded failed"`
+	Limit  int    `form:"limit" binding:"omitempty,min=1,max=200"`
+	Before int64  `form:"before" binding:"omitempty,min=0"` // next_cursor of the previous page
+}
+
+type WebhookDeliveryResponse struct {
+	ID        int64  `json:"id"`
+	WebhookID int64  `json:"webhook_id"`
+	EventID   string `json:"event_id"`
+	Event     string `json:"event" example:"todo.completed"`
+	TodoID    int64  `json:"todo_id"`
+	// Status is pending, succeeded or failed (every attempt failed).
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at,omitempty"` // only while pending
+	LastStatusCode *int       `json:"last_status_code"`
+	LastError      string     `json:"last_error,omitempty"`
+	DeliveredAt    *time.Time `json:"delivered_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+type ListWebhookDeliveriesResponse struct {
+	Items      []WebhookDeliveryResponse `json:"items"`
+	NextCursor string                    `json:"next_cursor,omitempty"`
+}
+
+type WebhookAttemptResponse struct {
+	Attempt    int       `json:"attempt"`
+	StatusCode *int      `json:"status_code"` // null when there was no response
+	Error      string    `json:"error,omitempty"`
+	Response   string    `json:"response"` // first 1 KiB of the response body
+	DurationMs int64     `json:"duration_ms"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// WebhookDeliveryDetailResponse is a delivery with its body and every attempt.
+type WebhookDeliveryDetailResponse struct {
+	WebhookDeliveryResponse
+	Payload     json.RawMessage          `json:"payload" swaggertype:"object"`
+	AttemptsLog []WebhookAttemptResponse `json:"attempts_log"`
+}
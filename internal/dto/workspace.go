@@ -0,0 +1,68 @@
+package dto
+
+import "time"
+
+// WorkspaceRequest is the JSON body for POST /workspaces and PATCH /workspaces/:workspace_id.
+type WorkspaceRequest struct {
+	Name string `json:"name" binding:"required,min=1,max=120" example:"Acme"`
+}
+
+type WorkspaceResponse struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	Personal  bool      `json:"personal"` // личное пространство пользователя
+	Role      string    `json:"role"`     // роль текущего пользователя: owner, admin, member, viewer
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type ListWorkspacesResponse struct {
+	Items []WorkspaceResponse `json:"items"`
+}
+
+// SetMemberRoleRequest is the JSON body for PUT /workspaces/:workspace_id/members/:user_id.
+type SetMemberRoleRequest struct {
+	Role string `json:"role" binding:"required,oneof=owner admin member viewer" example:"member"`
+}
+
+type WorkspaceMemberResponse struct {
+	UserID    int64     `json:"user_id"`
+	Username  string    `json:"username"`
+	Email     string    `json:"email,omitempty"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ListWorkspaceMembersResponse struct {
+	Items []WorkspaceMemberResponse `json:"items"`
+}
+
+// CreateInvitationRequest is the JSON body for POST /workspaces/:workspace_id/invitations.
+type CreateInvitationRequest struct {
+	Email string `json:"email" binding:"omitempty,email,max=255" example:"bob@example.com"` // пусто — принять может любой с токеном
+	Role  string `json:"role" binding:"required,oneof=owner admin member viewer" example:"member"`
+}
+
+type InvitationResponse struct {
+	ID        int64     `json:"id"`
+	Email     string    `json:"email,omitempty"`
+	Role      string    `json:"role"`
+	InvitedBy *int64    `json:"invited_by"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CreateInvitationResponse carries the invitation token; it is returned only once.
+type CreateInvitationResponse struct {
+	InvitationResponse
+	Token string `json:"token"`
+}
+
+type ListInvitationsResponse struct {
+	Items []InvitationResponse `json:"items"`
+}
+
+// AcceptInvitationRequest is the JSON body for POST /workspaces/invitations/accept.
+type AcceptInvitationRequest struct {
+	Token string `json:"token" binding:"required"`
+}
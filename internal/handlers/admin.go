@@ -0,0 +1,426 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"Worker/internal/auth"
+	dom "Worker/internal/domain"
+	"Worker/internal/dto"
+	"Worker/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminHandler serves /admin endpoints. Routes must be guarded with
+// auth.RequireRole(domain.RoleAdmin).
+type AdminHandler struct {
+	svc              *service.AdminService
+	sessions         *auth.Store
+	impersonationTTL time.Duration
+}
+
+// NewAdminHandler returns a new AdminHandler.
+func NewAdminHandler(svc *service.AdminService, sessions *auth.Store, impersonationTTL time.Duration) *AdminHandler {
+	return &AdminHandler{svc: svc, sessions: sessions, impersonationTTL: impersonationTTL}
+}
+
+// ListUsers godoc
+// @Summary      List and search users
+// @Tags         admin
+// @Produce      json
+// @Security     CookieAuth
+// @Param        q         query     string  false  "Substring of username or email"
+// @Param        role      query     string  false  "user or admin"
+// @Param        disabled  query     bool    false  "Only disabled (true) or active (false) accounts"
+// @Param        limit     query     int     false  "Page size, 1-200 (default 50)"
+// @Param        after     query     int     false  "next_cursor from the previous page"
+// @Success      200       {object}  dto.ListUsersResponse
+// @Failure      400       {object}  map[string]string
+// @Failure      403       {object}  map[string]string
+// @Failure      500       {object}  map[string]string
+// @Router       /admin/users [get]
+func (h *AdminHandler) ListUsers(c *gin.Context) {
+	var req dto.ListUsersQuery
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	users, err := h.svc.ListUsers(c.Request.Context(), dom.UserQuery{
+		Search:   req.Q,
+		Role:     req.Role,
+		Disabled: req.Disabled,
+		AfterID:  req.After,
+		Limit:    req.Limit,
+	})
+	if err != nil {
+		writeAdminError(c, err)
+		return
+	}
+	resp := dto.ListUsersResponse{Items: make([]dto.AdminUserResponse, len(users))}
+	for i, u := range users {
+		resp.Items[i] = adminUserToResponse(u)
+	}
+	if n := len(users); n > 0 && n == effectiveLimit(req.Limit) {
+		resp.NextCursor = strconv.FormatInt(users[n-1].ID, 10)
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// GetUser godoc
+// @Summary      Get a user
+// @Tags         admin
+// @Produce      json
+// @Security     CookieAuth
+// @Param        id   path      int  true  "User ID"
+// @Success      200  {object}  dto.AdminUserResponse
+// @Failure      400  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /admin/users/{id} [get]
+func (h *AdminHandler) GetUser(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+	u, err := h.svc.GetUser(c.Request.Context(), id)
+	if err != nil {
+		writeAdminError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, adminUserToResponse(u))
+}
+
+// Disable godoc
+// @Summary      Disable an account
+// @Description  The user can no longer log in; their sessions end and their API tokens stop working.
+// @Tags         admin
+// @Produce      json
+// @Security     CookieAuth
+// @Param        id   path      int  true  "User ID"
+// @Success      200  {object}  dto.AdminUserResponse
+// @Failure      400  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /admin/users/{id}/disable [post]
+func (h *AdminHandler) Disable(c *gin.Context) {
+	h.setDisabled(c, true)
+}
+
+// Enable godoc
+// @Summary      Re-enable an account
+// @Tags         admin
+// @Produce      json
+// @Security     CookieAuth
+// @Param        id   path      int  true  "User ID"
+// @Success      200  {object}  dto.AdminUserResponse
+// @Failure      400  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /admin/users/{id}/enable [post]
+func (h *AdminHandler) Enable(c *gin.Context) {
+	h.setDisabled(c, false)
+}
+
+func (h *AdminHandler) setDisabled(c *gin.Context, disabled bool) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+	ctx := c.Request.Context()
+	u, err := h.svc.SetDisabled(ctx, adminActor(c), id, disabled)
+	if err != nil {
+		writeAdminError(c, err)
+		return
+	}
+	if disabled {
+		// RequireSession rejects them anyway; this just frees the keys.
+		_, _ = h.sessions.DeleteAll(ctx, id, "")
+	}
+	c.JSON(http.StatusOK, adminUserToResponse(u))
+}
+
+// SetRole godoc
+// @Summary      Change a user's role
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Security     CookieAuth
+// @Param        id    path      int                 true  "User ID"
+// @Param        body  body      dto.SetRoleRequest  true  "Role"
+// @Success      200   {object}  dto.AdminUserResponse
+// @Failure      400   {object}  map[string]string
+// @Failure      403   {object}  map[string]string
+// @Failure      404   {object}  map[string]string
+// @Failure      500   {object}  map[string]string
+// @Router       /admin/users/{id}/role [put]
+func (h *AdminHandler) SetRole(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+	var req dto.SetRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	u, err := h.svc.SetRole(c.Request.Context(), adminActor(c), id, req.Role)
+	if err != nil {
+		writeAdminError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, adminUserToResponse(u))
+}
+
+// ForcePasswordReset godoc
+// @Summary      Force a password reset
+// @Description  The current password stops working and all sessions end. A reset link is mailed; users without an email get the token in this response for support to pass on.
+// @Tags         admin
+// @Produce      json
+// @Security     CookieAuth
+// @Param        id   path      int  true  "User ID"
+// @Success      200  {object}  dto.ForcePasswordResetResponse
+// @Failure      400  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /admin/users/{id}/password-reset [post]
+func (h *AdminHandler) ForcePasswordReset(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+	ctx := c.Request.Context()
+	token, emailed, err := h.svc.ForcePasswordReset(ctx, adminActor(c), id)
+	if err != nil {
+		writeAdminError(c, err)
+		return
+	}
+	revoked, err := h.sessions.DeleteAll(ctx, id, "")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "password reset but sessions were not revoked"})
+		return
+	}
+	c.JSON(http.StatusOK, dto.ForcePasswordResetResponse{Emailed: emailed, ResetToken: token, RevokedSessions: revoked})
+}
+
+// Impersonate godoc
+// @Summary      Impersonate a user
+// @Description  Replaces the admin's session cookie with a session of the user for support. The session is audited, cannot be renewed, and cannot manage credentials, tokens or sessions. Log out to end it.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Security     CookieAuth
+// @Param        id    path      int                     true  "User ID"
+// @Param        body  body      dto.ImpersonateRequest  true  "Reason, stored in the audit log"
+// @Success      200   {object}  dto.ImpersonateResponse
+// @Failure      400   {object}  map[string]string
+// @Failure      403   {object}  map[string]string
+// @Failure      404   {object}  map[string]string
+// @Failure      500   {object}  map[string]string
+// @Router       /admin/users/{id}/impersonate [post]
+func (h *AdminHandler) Impersonate(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+	var req dto.ImpersonateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ctx := c.Request.Context()
+	actor := adminActor(c)
+	u, err := h.svc.Impersonate(ctx, actor, id, req.Reason)
+	if err != nil {
+		writeAdminError(c, err)
+		return
+	}
+	sessionID, err := h.sessions.CreateImpersonation(ctx, u.ID, actor.ID, c.Request.UserAgent(), c.ClientIP(), h.impersonationTTL)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create session"})
+		return
+	}
+	c.SetCookie(sessionCookieName, sessionID, int(h.impersonationTTL.Seconds()), "/", "", false, true) // httpOnly
+	c.JSON(http.StatusOK, dto.ImpersonateResponse{
+		User:      adminUserToResponse(u),
+		ExpiresAt: time.Now().UTC().Add(h.impersonationTTL),
+	})
+}
+
+// Stats godoc
+// @Summary      System statistics
+// @Tags         admin
+// @Produce      json
+// @Security     CookieAuth
+// @Success      200  {object}  dto.StatsResponse
+// @Failure      403  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /admin/stats [get]
+func (h *AdminHandler) Stats(c *gin.Context) {
+	s, err := h.svc.Stats(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, dto.StatsResponse{
+		Users:            s.Users,
+		Admins:           s.Admins,
+		DisabledUsers:    s.DisabledUsers,
+		Todos:            s.Todos,
+		OpenTodos:        s.OpenTodos,
+		OverdueTodos:     s.OverdueTodos,
+		Projects:         s.Projects,
+		Tags:             s.Tags,
+		ActiveAPITokens:  s.APITokens,
+		PendingReminders: s.PendingReminds,
+	})
+}
+
+// Audit godoc
+// @Summary      Admin audit log
+// @Tags         admin
+// @Produce      json
+// @Security     CookieAuth
+// @Param        admin_id  query     int  false  "Only actions of this admin"
+// @Param        user_id   query     int  false  "Only actions on this user"
+// @Param        limit     query     int  false  "Page size, 1-200 (default 50)"
+// @Param        before    query     int  false  "next_cursor from the previous page"
+// @Success      200       {object}  dto.ListAuditResponse
+// @Failure      400       {object}  map[string]string
+// @Failure      403       {object}  map[string]string
+// @Failure      500       {object}  map[string]string
+// @Router       /admin/audit [get]
+func (h *AdminHandler) Audit(c *gin.Context) {
+	var req dto.ListAuditQuery
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	entries, err := h.svc.Audit(c.Request.Context(), dom.AdminAuditQuery{
+		AdminID:      req.AdminID,
+		TargetUserID: req.UserID,
+		BeforeID:     req.Before,
+		Limit:        req.Limit,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	resp := dto.ListAuditResponse{Items: make([]dto.AuditEntryResponse, len(entries))}
+	for i, e := range entries {
+		resp.Items[i] = dto.AuditEntryResponse{
+			ID:           e.ID,
+			AdminID:      e.AdminID,
+			Action:       e.Action,
+			TargetUserID: e.TargetUserID,
+			Details:      e.Details,
+			IP:           e.IP,
+			CreatedAt:    e.CreatedAt,
+		}
+	}
+	if n := len(entries); n > 0 && n == effectiveLimit(req.Limit) {
+		resp.NextCursor = strconv.FormatInt(entries[n-1].ID, 10)
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// TodoEvents godoc
+// @Summary      Todo history of all users
+// @Description  Changes to todos, newest first, filtered by who made them, whose todos they touched and when.
+// @Tags         admin
+// @Produce      json
+// @Security     CookieAuth
+// @Param        actor_id  query     int     false  "Only changes made by this user"
+// @Param        owner_id  query     int     false  "Only changes to todos of this user"
+// @Param        todo_id   query     int     false  "Only changes to this todo"
+// @Param        from      query     string  false  "Not before (date or RFC3339)"
+// @Param        to        query     string  false  "Before (date or RFC3339)"
+// @Param        limit     query     int     false  "Page size, 1-200 (default 50)"
+// @Param        before    query     int     false  "next_cursor from the previous page"
+// @Success      200       {object}  dto.ListTodoEventsResponse
+// @Failure      400       {object}  map[string]string
+// @Failure      403       {object}  map[string]string
+// @Failure      500       {object}  map[string]string
+// @Router       /admin/todo-events [get]
+func (h *AdminHandler) TodoEvents(c *gin.Context) {
+	var req dto.AdminTodoEventsQuery
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	q := dom.TodoEventQuery{
+		ActorID:  req.ActorID,
+		OwnerID:  req.OwnerID,
+		TodoID:   req.TodoID,
+		BeforeID: req.Before,
+		Limit:    req.Limit,
+	}
+	for _, f := range []struct {
+		name string
+		raw  string
+		dst  **time.Time
+	}{{"from", req.From, &q.From}, {"to", req.To, &q.To}} {
+		if f.raw == "" {
+			continue
+		}
+		t, err := dto.ParseDateOrTime(f.raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": f.name + ": " + err.Error()})
+			return
+		}
+		*f.dst = &t
+	}
+	events, err := h.svc.TodoEvents(c.Request.Context(), q)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidTimeRange) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, todoEventsToResponse(events, req.Limit))
+}
+
+func adminActor(c *gin.Context) service.AdminActor {
+	return service.AdminActor{ID: auth.UserIDFromContext(c), IP: c.ClientIP()}
+}
+
+// effectiveLimit is the page size the service applies, to tell a full page.
+func effectiveLimit(n int) int {
+	if n <= 0 {
+		return service.DefaultAdminPageSize
+	}
+	return n
+}
+
+func adminUserToResponse(u dom.User) dto.AdminUserResponse {
+	return dto.AdminUserResponse{
+		ID:         u.ID,
+		Username:   u.Username,
+		Email:      u.Email,
+		Role:       u.Role,
+		Disabled:   u.Disabled(),
+		DisabledAt: u.DisabledAt,
+		CreatedAt:  u.CreatedAt,
+	}
+}
+
+func writeAdminError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	case errors.Is(err, service.ErrInvalidRole),
+		errors.Is(err, service.ErrSelfAction),
+		errors.Is(err, service.ErrCannotImpersonate):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -5,7 +5,9 @@ import (
 	"net/http"
 
 	"Worker/internal/auth"
+	dom "Worker/internal/domain"
 	"Worker/internal/dto"
+	"Worker/internal/ratelimit"
 	"Worker/internal/service"
 
 	"github.com/gin-gonic/gin"
@@ -13,26 +15,33 @@ import (
 
 const sessionCookieName = "session_id"
 
-// AuthHandler handles login, register and logout.
+// AuthHandler handles login, register, logout and passwords.
 type AuthHandler struct {
 	sessions *auth.Store
+	pending  *auth.PendingStore
 	userSvc  *service.UserService
+	mfa      *service.MFAService
+	guard    *ratelimit.LoginGuard // nil disables login throttling
 }
 
 // NewAuthHandler returns a new AuthHandler.
-func NewAuthHandler(sessions *auth.Store, userSvc *service.UserService) *AuthHandler {
-	return &AuthHandler{sessions: sessions, userSvc: userSvc}
+func NewAuthHandler(sessions *auth.Store, pending *auth.PendingStore, userSvc *service.UserService, mfa *service.MFAService, guard *ratelimit.LoginGuard) *AuthHandler {
+	return &AuthHandler{sessions: sessions, pending: pending, userSvc: userSvc, mfa: mfa, guard: guard}
 }
 
 // Login godoc
 // @Summary      Login
+// @Description  If the user has two-factor authentication enabled, no session is created; the response carries an mfa_token for POST /auth/login/mfa instead.
 // @Tags         auth
 // @Accept       json
 // @Produce      json
 // @Param        body  body  dto.LoginRequest  true  "Credentials"
 // @Success      200   {object}  map[string]bool
+// @Success      202   {object}  dto.MFAChallengeResponse
 // @Failure      400   {object}  map[string]string
 // @Failure      401   {object}  map[string]string
+// @Failure      403   {object}  map[string]string
+// @Failure      429   {object}  map[string]string
 // @Failure      500   {object}  map[string]string
 // @Router       /auth/login [post]
 func (h *AuthHandler) Login(c *gin.Context) {
@@ -41,22 +50,130 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	user, err := h.userSvc.ValidateCredentials(c.Request.Context(), req.Username, req.Password)
+	ctx := c.Request.Context()
+	if wait, _ := h.guard.Check(ctx, req.Username); wait > 0 {
+		ratelimit.TooManyRequests(c, wait)
+		return
+	}
+	user, err := h.userSvc.ValidateCredentials(ctx, req.Username, req.Password)
 	if err != nil {
 		if errors.Is(err, service.ErrInvalidCredentials) {
+			if lock, _ := h.guard.Failure(ctx, req.Username); lock > 0 {
+				ratelimit.TooManyRequests(c, lock)
+				return
+			}
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid username or password"})
 			return
 		}
+		if errors.Is(err, service.ErrAccountDisabled) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "login failed"})
 		return
 	}
-	sessionID, err := h.sessions.Create(c.Request.Context(), user.ID)
+	mfa, err := h.mfa.Enabled(ctx, user.ID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create session"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "login failed"})
+		return
+	}
+	if mfa {
+		token, err := h.pending.Create(ctx, user.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "login failed"})
+			return
+		}
+		c.JSON(http.StatusAccepted, dto.MFAChallengeResponse{
+			MFARequired: true,
+			MFAToken:    token,
+			ExpiresIn:   int(h.pending.TTL().Seconds()),
+		})
+		return
+	}
+	if !h.startSession(c, user.ID) {
+		return
+	}
+	_ = h.guard.Success(ctx, user.Username)
+	c.JSON(http.StatusOK, gin.H{"ok": true, "user": userToResponse(user)})
+}
+
+// LoginMFA godoc
+// @Summary      Complete login with a second factor
+// @Description  Takes the mfa_token from /auth/login and a TOTP or recovery code. A token allows 5 attempts.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        body  body  dto.LoginMFARequest  true  "Pending login and code"
+// @Success      200   {object}  map[string]bool
+// @Failure      400   {object}  map[string]string
+// @Failure      401   {object}  map[string]string
+// @Failure      403   {object}  map[string]string
+// @Failure      429   {object}  map[string]string
+// @Failure      500   {object}  map[string]string
+// @Router       /auth/login/mfa [post]
+func (h *AuthHandler) LoginMFA(c *gin.Context) {
+	var req dto.LoginMFARequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ctx := c.Request.Context()
+	userID, err := h.pending.Attempt(ctx, req.MFAToken)
+	if err != nil {
+		if errors.Is(err, auth.ErrMFAPendingInvalid) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "login failed"})
+		return
+	}
+	user, err := h.userSvc.GetByID(ctx, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "login failed"})
+		return
+	}
+	if user.Disabled() {
+		_ = h.pending.Delete(ctx, req.MFAToken)
+		c.JSON(http.StatusForbidden, gin.H{"error": service.ErrAccountDisabled.Error()})
+		return
+	}
+	// Codes count toward the same lockout as passwords; otherwise anyone with
+	// the password could guess codes by logging in again and again.
+	if wait, _ := h.guard.Check(ctx, user.Username); wait > 0 {
+		ratelimit.TooManyRequests(c, wait)
+		return
+	}
+	if err := h.mfa.Verify(ctx, userID, req.Code); err != nil {
+		if errors.Is(err, service.ErrInvalidMFACode) || errors.Is(err, service.ErrMFANotEnabled) {
+			if lock, _ := h.guard.Failure(ctx, user.Username); lock > 0 {
+				_ = h.pending.Delete(ctx, req.MFAToken)
+				ratelimit.TooManyRequests(c, lock)
+				return
+			}
+			c.JSON(http.StatusUnauthorized, gin.H{"error": service.ErrInvalidMFACode.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "login failed"})
+		return
+	}
+	_ = h.pending.Delete(ctx, req.MFAToken)
+	if !h.startSession(c, user.ID) {
 		return
 	}
-	c.SetCookie(sessionCookieName, sessionID, 24*60*60, "/", "", false, true) // 24h, httpOnly
-	c.JSON(http.StatusOK, gin.H{"ok": true, "user": dto.UserResponse{ID: user.ID, Username: user.Username}})
+	_ = h.guard.Success(ctx, user.Username)
+	c.JSON(http.StatusOK, gin.H{"ok": true, "user": userToResponse(user)})
+}
+
+// startSession creates a session and sets its cookie. On failure it writes
+// the error response and returns false.
+func (h *AuthHandler) startSession(c *gin.Context, userID int64) bool {
+	sessionID, err := h.sessions.Create(c.Request.Context(), userID, c.Request.UserAgent(), c.ClientIP())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create session"})
+		return false
+	}
+	c.SetCookie(sessionCookieName, sessionID, int(h.sessions.TTL().Seconds()), "/", "", false, true) // httpOnly
+	return true
 }
 
 // Register godoc
@@ -68,6 +185,7 @@ func (h *AuthHandler) Login(c *gin.Context) {
 // @Success      201   {object}  map[string]bool
 // @Failure      400   {object}  map[string]string
 // @Failure      409   {object}  map[string]string
+// @Failure      429   {object}  map[string]string
 // @Failure      500   {object}  map[string]string
 // @Router       /auth/register [post]
 func (h *AuthHandler) Register(c *gin.Context) {
@@ -76,7 +194,7 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	user, err := h.userSvc.Register(c.Request.Context(), req.Username, req.Password)
+	user, err := h.userSvc.Register(c.Request.Context(), req.Username, req.Email, req.Password)
 	if err != nil {
 		if errors.Is(err, service.ErrInvalidCredentials) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "username and password required"})
@@ -86,16 +204,17 @@ func (h *AuthHandler) Register(c *gin.Context) {
 			c.JSON(http.StatusConflict, gin.H{"error": "username already taken"})
 			return
 		}
+		if errors.Is(err, service.ErrEmailTaken) {
+			c.JSON(http.StatusConflict, gin.H{"error": "email already in use"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "registration failed"})
 		return
 	}
-	sessionID, err := h.sessions.Create(c.Request.Context(), user.ID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create session"})
+	if !h.startSession(c, user.ID) {
 		return
 	}
-	c.SetCookie(sessionCookieName, sessionID, 24*60*60, "/", "", false, true) // 24h, httpOnly
-	c.JSON(http.StatusCreated, gin.H{"ok": true, "user": dto.UserResponse{ID: user.ID, Username: user.Username}})
+	c.JSON(http.StatusCreated, gin.H{"ok": true, "user": userToResponse(user)})
 }
 
 // Logout godoc
@@ -111,3 +230,112 @@ func (h *AuthHandler) Logout(c *gin.Context) {
 	c.SetCookie(sessionCookieName, "", -1, "/", "", false, true)
 	c.Status(http.StatusNoContent)
 }
+
+// ChangePassword godoc
+// @Summary      Change password
+// @Description  Requires the current password. Ends every session of the user and starts a fresh one for this client.
+// @Tags         auth
+// @Accept       json
+// @Security     CookieAuth
+// @Param        body  body  dto.ChangePasswordRequest  true  "Passwords"
+// @Success      204
+// @Failure      400   {object}  map[string]string
+// @Failure      401   {object}  map[string]string
+// @Failure      403   {object}  map[string]string
+// @Failure      500   {object}  map[string]string
+// @Router       /auth/password [post]
+func (h *AuthHandler) ChangePassword(c *gin.Context) {
+	var req dto.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ctx := c.Request.Context()
+	userID := auth.UserIDFromContext(c)
+	if err := h.userSvc.ChangePassword(ctx, userID, req.CurrentPassword, req.NewPassword); err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidCredentials):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "current password is incorrect"})
+		case errors.Is(err, service.ErrWeakPassword):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to change password"})
+		}
+		return
+	}
+	if _, err := h.sessions.DeleteAll(ctx, userID, ""); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "password changed but sessions were not revoked"})
+		return
+	}
+	sessionID, err := h.sessions.Create(ctx, userID, c.Request.UserAgent(), c.ClientIP())
+	if err != nil {
+		c.SetCookie(sessionCookieName, "", -1, "/", "", false, true)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create session"})
+		return
+	}
+	c.SetCookie(sessionCookieName, sessionID, int(h.sessions.TTL().Seconds()), "/", "", false, true) // httpOnly
+	c.Status(http.StatusNoContent)
+}
+
+// ForgotPassword godoc
+// @Summary      Request a password reset
+// @Description  Mails a single-use reset token if the username or email belongs to an account with an email. Always answers 202.
+// @Tags         auth
+// @Accept       json
+// @Param        body  body  dto.ForgotPasswordRequest  true  "Username or email"
+// @Success      202
+// @Failure      400   {object}  map[string]string
+// @Failure      429   {object}  map[string]string
+// @Failure      500   {object}  map[string]string
+// @Router       /auth/password/forgot [post]
+func (h *AuthHandler) ForgotPassword(c *gin.Context) {
+	var req dto.ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.userSvc.RequestPasswordReset(c.Request.Context(), req.Login); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to request reset"})
+		return
+	}
+	c.Status(http.StatusAccepted)
+}
+
+// ResetPassword godoc
+// @Summary      Reset password with a token
+// @Description  Redeems a reset token and ends every session of the user.
+// @Tags         auth
+// @Accept       json
+// @Param        body  body  dto.ResetPasswordRequest  true  "Token and new password"
+// @Success      204
+// @Failure      400   {object}  map[string]string
+// @Failure      429   {object}  map[string]string
+// @Failure      500   {object}  map[string]string
+// @Router       /auth/password/reset [post]
+func (h *AuthHandler) ResetPassword(c *gin.Context) {
+	var req dto.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ctx := c.Request.Context()
+	userID, err := h.userSvc.ResetPassword(ctx, req.Token, req.NewPassword)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidResetToken) || errors.Is(err, service.ErrWeakPassword) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to reset password"})
+		return
+	}
+	if _, err := h.sessions.DeleteAll(ctx, userID, ""); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "password changed but sessions were not revoked"})
+		return
+	}
+	c.SetCookie(sessionCookieName, "", -1, "/", "", false, true)
+	c.Status(http.StatusNoContent)
+}
+
+func userToResponse(u dom.User) dto.UserResponse {
+	return dto.UserResponse{ID: u.ID, Username: u.Username, Email: u.Email, Role: u.Role}
+}
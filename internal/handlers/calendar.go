@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"Worker/internal/auth"
+	dom "Worker/internal/domain"
+	"Worker/internal/dto"
+	"Worker/internal/service"
+	"Worker/internal/todoio"
+
+	"github.com/gin-gonic/gin"
+)
+
+// calendarPath is where feeds are served, relative to the API base URL.
+const calendarPath = "/api/v1/calendar/"
+
+// CalendarHandler manages the current user's calendar feed and serves feeds.
+type CalendarHandler struct {
+	svc *service.CalendarService
+	// baseURL is the public URL of the API for feed links; "" = taken from the request.
+	baseURL string
+}
+
+// NewCalendarHandler returns a new CalendarHandler.
+func NewCalendarHandler(svc *service.CalendarService, baseURL string) *CalendarHandler {
+	return &CalendarHandler{svc: svc, baseURL: strings.TrimRight(baseURL, "/")}
+}
+
+// Get godoc
+// @Summary      Get my calendar feed
+// @Description  The feed of my todos with due dates in the workspace; its URL is shown only when it is created.
+// @Tags         calendar
+// @Produce      json
+// @Security     CookieAuth
+// @Success      200  {object}  dto.CalendarFeedResponse
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /calendar/feed [get]
+func (h *CalendarHandler) Get(c *gin.Context) {
+	f, err := h.svc.Feed(c.Request.Context(), auth.UserIDFromContext(c), auth.WorkspaceIDFromContext(c))
+	if err != nil {
+		if errors.Is(err, service.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, calendarFeedToResponse(f))
+}
+
+// Create godoc
+// @Summary      Create my calendar feed
+// @Description  Returns a secret URL of an iCalendar feed of my todos with due dates in the workspace, for calendar apps to subscribe to (replace https:// with webcal:// to open it in one). The URL is returned only in this response; creating the feed again replaces it and the old one stops working.
+// @Tags         calendar
+// @Produce      json
+// @Security     CookieAuth
+// @Success      201  {object}  dto.CreateCalendarFeedResponse
+// @Failure      403  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /calendar/feed [post]
+func (h *CalendarHandler) Create(c *gin.Context) {
+	f, secret, err := h.svc.CreateFeed(c.Request.Context(), auth.UserIDFromContext(c), auth.WorkspaceIDFromContext(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, dto.CreateCalendarFeedResponse{
+		CalendarFeedResponse: calendarFeedToResponse(f),
+		URL:                  h.feedURL(c, secret),
+	})
+}
+
+// Delete godoc
+// @Summary      Revoke my calendar feed
+// @Tags         calendar
+// @Security     CookieAuth
+// @Success      204
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /calendar/feed [delete]
+func (h *CalendarHandler) Delete(c *gin.Context) {
+	if err := h.svc.DeleteFeed(c.Request.Context(), auth.UserIDFromContext(c), auth.WorkspaceIDFromContext(c)); err != nil {
+		if errors.Is(err, service.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// Feed godoc
+// @Summary      Calendar feed
+// @Description  An iCalendar file of the feed owner's todos with due dates in the workspace, for calendar apps; the secret in the path is the credential, no session is needed. type=vtodo (default) writes todos, type=vevent events for apps that show no todos. Done todos are left out unless include_done is set; todos in the trash always are.
+// @Tags         calendar
+// @Produce      text/calendar
+// @Param        token         path      string  true   "Feed secret, optionally with .ics"
+// @Param        type          query     string  false  "vtodo (default) or vevent"
+// @Param        include_done  query     bool    false  "Also done todos"
+// @Success      200  {file}    file
+// @Failure      400  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /calendar/{token} [get]
+func (h *CalendarHandler) Feed(c *gin.Context) {
+	var req dto.CalendarQuery
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	secret := strings.TrimSuffix(c.Param("token"), ".ics")
+	f, ws, err := h.svc.Authenticate(c.Request.Context(), secret)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidToken) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	w := todoio.NewCalendarWriter(c.Writer, todoio.CalendarOptions{Name: ws.Name, Events: req.Type == "vevent"})
+	c.Header("Content-Type", todoio.ContentType(todoio.FormatICS))
+	c.Header("Cache-Control", "private, max-age=300")
+	err = h.svc.WriteFeed(c.Request.Context(), f, req.IncludeDone, w)
+	if err == nil {
+		err = w.Close()
+	}
+	if err != nil {
+		if c.Writer.Written() {
+			_ = c.Error(err)
+			return
+		}
+		c.Writer.Header().Del("Cache-Control")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// feedURL is the absolute URL of the feed with secret.
+func (h *CalendarHandler) feedURL(c *gin.Context, secret string) string {
+	base := h.baseURL
+	if base == "" {
+		scheme := "http"
+		if c.Request.TLS != nil || strings.EqualFold(c.GetHeader("X-Forwarded-Proto"), "https") {
+			scheme = "https"
+		}
+		base = scheme + "://" + c.Request.Host
+	}
+	return base + calendarPath + secret + ".ics"
+}
+
+func calendarFeedToResponse(f dom.CalendarFeed) dto.CalendarFeedResponse {
+	return dto.CalendarFeedResponse{
+		Prefix:     f.Prefix,
+		LastUsedAt: f.LastUsedAt,
+		CreatedAt:  f.CreatedAt,
+	}
+}
@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+
+	dom "Worker/internal/domain"
+	"Worker/internal/service"
+)
+
+// encodeCursor turns a page cursor into the opaque next_cursor string. nil → "".
+func encodeCursor(c *dom.TodoCursor) string {
+	if c == nil {
+		return ""
+	}
+	b, err := json.Marshal(c)
+	if err != nil {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// decodeCursor parses an opaque cursor from the "after" query parameter. "" → nil.
+func decodeCursor(s string) (*dom.TodoCursor, error) {
+	if s == "" {
+		return nil, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, service.ErrInvalidCursor
+	}
+	var c dom.TodoCursor
+	if err := json.Unmarshal(b, &c); err != nil || c.ID <= 0 {
+		return nil, service.ErrInvalidCursor
+	}
+	return &c, nil
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	dom "Worker/internal/domain"
+
+	"github.com/gin-gonic/gin"
+)
+
+// todoETag is the strong entity tag of a todo: its version and the caller's
+// permission, which is part of the response but not of the row. Progress is
+// covered by the version: a change to a subtask bumps its parent's.
+func todoETag(t dom.Todo) string {
+	return `"` + strconv.FormatInt(t.Version, 10) + "-" + t.Permission.String() + `"`
+}
+
+// writeTodo writes a single todo with its ETag, or 304 when the client's
+// If-None-Match already names it.
+func writeTodo(c *gin.Context, t dom.Todo) {
+	etag := todoETag(t)
+	c.Header("ETag", etag)
+	if c.Request.Method == http.MethodGet && etagMatches(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.JSON(http.StatusOK, todoToResponse(t))
+}
+
+// writeList writes a list response under a weak ETag derived from its body, or
+// 304 when the client's If-None-Match already names it.
+func writeList(c *gin.Context, body any) {
+	b, err := json.Marshal(body)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	sum := sha256.Sum256(b)
+	etag := `W/"` + hex.EncodeToString(sum[:16]) + `"`
+	c.Header("ETag", etag)
+	if etagMatches(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", b)
+}
+
+// etagMatches reports whether an If-None-Match header names etag, using the weak
+// comparison RFC 9110 prescribes for it.
+func etagMatches(header, etag string) bool {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return false
+	}
+	if header == "*" {
+		return true
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, tag := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(tag), "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// ifMatchVersion reads the If-Match header as the todo version the client
+// expects: 0 without the header or for "*", the version of a single strong tag
+// (todoETag's `"<version>-<permission>"`, or a bare `"<version>"`), and -1,
+// which matches no todo, for anything else (weak tags never match under
+// If-Match). The permission part is not compared: the service checks access.
+func ifMatchVersion(c *gin.Context) int64 {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" || header == "*" {
+		return 0
+	}
+	if len(header) < 2 || header[0] != '"' || header[len(header)-1] != '"' {
+		return -1
+	}
+	version, _, _ := strings.Cut(header[1:len(header)-1], "-")
+	v, err := strconv.ParseInt(version, 10, 64)
+	if err != nil || v <= 0 {
+		return -1
+	}
+	return v
+}
@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"Worker/internal/auth"
+	"Worker/internal/dto"
+	"Worker/internal/realtime"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+)
+
+// EventsHandler streams todo changes to connected clients.
+type EventsHandler struct {
+	broker      *realtime.Broker
+	heartbeat   time.Duration
+	maxDuration time.Duration
+}
+
+// NewEventsHandler returns a new EventsHandler. Streams send a heartbeat every
+// heartbeat and are closed after maxDuration, so a revoked session does not
+// keep one open; clients reconnect and resume with the last event ID.
+func NewEventsHandler(broker *realtime.Broker, heartbeat, maxDuration time.Duration) *EventsHandler {
+	if heartbeat <= 0 {
+		heartbeat = 25 * time.Second
+	}
+	if maxDuration <= 0 {
+		maxDuration = time.Hour
+	}
+	return &EventsHandler{broker: broker, heartbeat: heartbeat, maxDuration: maxDuration}
+}
+
+// Stream godoc
+// @Summary      Stream todo changes (Server-Sent Events)
+// @Description  Pushes created, updated, completed and deleted events for the caller's todos and todos shared with them. Each event has an id; send it back as Last-Event-ID (or last_event_id) to resume. A reset event means missed events are gone and the client should reload.
+// @Tags         events
+// @Produce      text/event-stream
+// @Security     CookieAuth
+// @Param        Last-Event-ID   header  string  false  "ID of the last event received"
+// @Param        last_event_id   query   string  false  "Same as Last-Event-ID"
+// @Param        workspace_id    query   int     false  "Only events of this workspace"
+// @Success      200  {object}  dto.TodoStreamEvent
+// @Failure      400  {object}  map[string]string
+// @Failure      503  {object}  map[string]string
+// @Router       /events [get]
+func (h *EventsHandler) Stream(c *gin.Context) {
+	q, sub, ok := h.subscribe(c, c.GetHeader("Last-Event-ID"))
+	if !ok {
+		return
+	}
+	defer sub.Close()
+
+	// The server's WriteTimeout would cut the stream off.
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.WriteHeaderNow()
+	c.Writer.Flush()
+
+	h.pump(c.Request.Context(), sub, q.WorkspaceID, func(e *dto.TodoStreamEvent) error {
+		if e == nil {
+			_, err := fmt.Fprint(c.Writer, ": ping\n\n")
+			c.Writer.Flush()
+			return err
+		}
+		data, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		if e.ID != "" {
+			fmt.Fprintf(c.Writer, "id: %s\n", e.ID)
+		}
+		_, err = fmt.Fprintf(c.Writer, "event: %s\ndata: %s\n\n", e.Type, data)
+		c.Writer.Flush()
+		return err
+	})
+}
+
+// WebSocket godoc
+// @Summary      Stream todo changes (WebSocket)
+// @Description  Same events as GET /events as JSON text messages, plus {"type":"ping"} heartbeats. Resume with last_event_id. Browsers on a session cookie must connect from the API's own origin.
+// @Tags         events
+// @Security     CookieAuth
+// @Param        last_event_id   query   string  false  "ID of the last event received"
+// @Param        workspace_id    query   int     false  "Only events of this workspace"
+// @Success      101
+// @Failure      400  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      503  {object}  map[string]string
+// @Router       /events/ws [get]
+func (h *EventsHandler) WebSocket(c *gin.Context) {
+	// A cookie rides along on cross-site WebSocket requests, so a session may
+	// only connect from this origin; API tokens are never sent implicitly.
+	if auth.TokenIDFromContext(c) == 0 && !sameOrigin(c.Request) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "cross-origin WebSocket requests need an API token"})
+		return
+	}
+	q, sub, ok := h.subscribe(c, "")
+	if !ok {
+		return
+	}
+	defer sub.Close()
+
+	server := websocket.Server{
+		Handshake: func(*websocket.Config, *http.Request) error { return nil },
+		Handler: func(ws *websocket.Conn) {
+			// The hijacked connection keeps the server's deadlines.
+			_ = ws.SetDeadline(time.Time{})
+			ctx, cancel := context.WithCancel(c.Request.Context())
+			defer cancel()
+			go func() {
+				// Incoming messages are ignored; a read error means the client left.
+				var msg string
+				for websocket.Message.Receive(ws, &msg) == nil {
+				}
+				cancel()
+			}()
+			h.pump(ctx, sub, q.WorkspaceID, func(e *dto.TodoStreamEvent) error {
+				if e == nil {
+					e = &dto.TodoStreamEvent{Type: "ping", At: time.Now().UTC()}
+				}
+				return websocket.JSON.Send(ws, e)
+			})
+		},
+	}
+	server.ServeHTTP(c.Writer, c.Request)
+}
+
+// subscribe binds the stream query and subscribes to the user's events,
+// resuming after lastID or the last_event_id parameter.
+func (h *EventsHandler) subscribe(c *gin.Context, lastID string) (dto.EventStreamQuery, *realtime.Subscription, bool) {
+	var q dto.EventStreamQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return q, nil, false
+	}
+	if lastID == "" {
+		lastID = q.LastEventID
+	}
+	sub, err := h.broker.Subscribe(c.Request.Context(), auth.UserIDFromContext(c), lastID)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "event stream unavailable"})
+		return q, nil, false
+	}
+	return q, sub, true
+}
+
+// pump hands events of the subscription to send until the client leaves, the
+// stream reaches its maximum duration or send fails. A nil event asks send
+// for a heartbeat.
+func (h *EventsHandler) pump(ctx context.Context, sub *realtime.Subscription, workspaceID int64, send func(*dto.TodoStreamEvent) error) {
+	deadline := time.NewTimer(h.maxDuration)
+	defer deadline.Stop()
+	heartbeat := time.NewTicker(h.heartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-deadline.C:
+			return
+		case <-heartbeat.C:
+			if send(nil) != nil {
+				return
+			}
+		case e, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			if workspaceID != 0 && e.Type != realtime.EventReset && e.WorkspaceID != workspaceID {
+				continue
+			}
+			if send(streamEventToResponse(e)) != nil {
+				return
+			}
+		}
+	}
+}
+
+func streamEventToResponse(e realtime.Event) *dto.TodoStreamEvent {
+	out := &dto.TodoStreamEvent{
+		ID:          e.ID,
+		Type:        e.Type,
+		WorkspaceID: e.WorkspaceID,
+		TodoID:      e.TodoID,
+		ActorID:     e.ActorID,
+		At:          e.At,
+	}
+	if e.Todo != nil {
+		t := todoToResponse(*e.Todo)
+		out.Todo = &t
+	}
+	return out
+}
+
+// sameOrigin reports whether a request has no Origin header or one naming the
+// host it was sent to.
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && u.Host == r.Host
+}
@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"Worker/internal/auth"
+	"Worker/internal/dto"
+	"Worker/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MFAHandler manages the current user's two-factor authentication.
+type MFAHandler struct {
+	svc   *service.MFAService
+	users *service.UserService
+}
+
+// NewMFAHandler returns a new MFAHandler.
+func NewMFAHandler(svc *service.MFAService, users *service.UserService) *MFAHandler {
+	return &MFAHandler{svc: svc, users: users}
+}
+
+// Status godoc
+// @Summary      Two-factor authentication status
+// @Tags         auth
+// @Produce      json
+// @Security     CookieAuth
+// @Success      200  {object}  dto.MFAStatusResponse
+// @Failure      403  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /auth/mfa [get]
+func (h *MFAHandler) Status(c *gin.Context) {
+	m, err := h.svc.Status(c.Request.Context(), auth.UserIDFromContext(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	resp := dto.MFAStatusResponse{Enabled: m.Enabled()}
+	if m.Enabled() {
+		resp.EnabledAt = m.EnabledAt
+		resp.RecoveryCodesLeft = m.RecoveryCodesLeft
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// Enroll godoc
+// @Summary      Start two-factor enrollment
+// @Description  Returns a new TOTP secret and otpauth:// URI. 2FA is enabled only after POST /auth/mfa/confirm.
+// @Tags         auth
+// @Produce      json
+// @Security     CookieAuth
+// @Success      200  {object}  dto.MFAEnrollResponse
+// @Failure      403  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Failure      503  {object}  map[string]string
+// @Router       /auth/mfa/enroll [post]
+func (h *MFAHandler) Enroll(c *gin.Context) {
+	ctx := c.Request.Context()
+	user, err := h.users.GetByID(ctx, auth.UserIDFromContext(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	secret, uri, err := h.svc.Enroll(ctx, user.ID, user.Username)
+	if err != nil {
+		writeMFAError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, dto.MFAEnrollResponse{Secret: secret, URI: uri})
+}
+
+// Confirm godoc
+// @Summary      Confirm two-factor enrollment
+// @Description  Enables 2FA if the code matches and returns one-time recovery codes (shown only once).
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Security     CookieAuth
+// @Param        body  body      dto.MFACodeRequest  true  "Code from the authenticator app"
+// @Success      200   {object}  dto.RecoveryCodesResponse
+// @Failure      400   {object}  map[string]string
+// @Failure      403   {object}  map[string]string
+// @Failure      409   {object}  map[string]string
+// @Failure      500   {object}  map[string]string
+// @Router       /auth/mfa/confirm [post]
+func (h *MFAHandler) Confirm(c *gin.Context) {
+	var req dto.MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	codes, err := h.svc.Confirm(c.Request.Context(), auth.UserIDFromContext(c), req.Code)
+	if err != nil {
+		writeMFAError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, dto.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// Disable godoc
+// @Summary      Disable two-factor authentication
+// @Tags         auth
+// @Accept       json
+// @Security     CookieAuth
+// @Param        body  body  dto.MFACodeRequest  true  "TOTP or recovery code"
+// @Success      204
+// @Failure      400   {object}  map[string]string
+// @Failure      403   {object}  map[string]string
+// @Failure      500   {object}  map[string]string
+// @Router       /auth/mfa/disable [post]
+func (h *MFAHandler) Disable(c *gin.Context) {
+	var req dto.MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.svc.Disable(c.Request.Context(), auth.UserIDFromContext(c), req.Code); err != nil {
+		writeMFAError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// RecoveryCodes godoc
+// @Summary      Regenerate recovery codes
+// @Description  Replaces all recovery codes; the old ones stop working.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Security     CookieAuth
+// @Param        body  body      dto.MFACodeRequest  true  "TOTP or recovery code"
+// @Success      200   {object}  dto.RecoveryCodesResponse
+// @Failure      400   {object}  map[string]string
+// @Failure      403   {object}  map[string]string
+// @Failure      500   {object}  map[string]string
+// @Router       /auth/mfa/recovery-codes [post]
+func (h *MFAHandler) RecoveryCodes(c *gin.Context) {
+	var req dto.MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	codes, err := h.svc.RegenerateRecoveryCodes(c.Request.Context(), auth.UserIDFromContext(c), req.Code)
+	if err != nil {
+		writeMFAError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, dto.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+func writeMFAError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidMFACode),
+		errors.Is(err, service.ErrMFANotEnabled),
+		errors.Is(err, service.ErrMFANotEnrolled):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrMFAAlreadyEnabled):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrMFAUnavailable):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
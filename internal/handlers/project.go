@@ -0,0 +1,211 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"Worker/internal/auth"
+	dom "Worker/internal/domain"
+	"Worker/internal/dto"
+	"Worker/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ProjectHandler handles CRUD for the current user's projects.
+type ProjectHandler struct {
+	svc *service.ProjectService
+}
+
+// NewProjectHandler returns a new ProjectHandler.
+func NewProjectHandler(svc *service.ProjectService) *ProjectHandler {
+	return &ProjectHandler{svc: svc}
+}
+
+// List godoc
+// @Summary      List projects
+// @Tags         projects
+// @Produce      json
+// @Security     CookieAuth
+// @Param        archived  query     bool  false  "Include archived projects"
+// @Success      200  {object}  dto.ListProjectsResponse
+// @Failure      500  {object}  map[string]string
+// @Router       /projects [get]
+func (h *ProjectHandler) List(c *gin.Context) {
+	userID := auth.UserIDFromContext(c)
+	workspaceID := auth.WorkspaceIDFromContext(c)
+	list, err := h.svc.List(c.Request.Context(), userID, workspaceID, c.Query("archived") == "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	out := make([]dto.ProjectResponse, len(list))
+	for i := range list {
+		out[i] = projectToResponse(list[i])
+	}
+	c.JSON(http.StatusOK, dto.ListProjectsResponse{Items: out})
+}
+
+// Create godoc
+// @Summary      Create a project
+// @Tags         projects
+// @Accept       json
+// @Produce      json
+// @Security     CookieAuth
+// @Param        body  body      dto.CreateProjectRequest  true  "Project"
+// @Success      201   {object}  dto.ProjectResponse
+// @Failure      400   {object}  map[string]string
+// @Failure      500   {object}  map[string]string
+// @Router       /projects [post]
+func (h *ProjectHandler) Create(c *gin.Context) {
+	userID := auth.UserIDFromContext(c)
+	workspaceID := auth.WorkspaceIDFromContext(c)
+	var req dto.CreateProjectRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	p, err := h.svc.Create(c.Request.Context(), userID, workspaceID, service.ProjectInput{
+		Name:     &req.Name,
+		Color:    &req.Color,
+		Archived: &req.Archived,
+	})
+	if err != nil {
+		writeProjectError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, projectToResponse(p))
+}
+
+// Inbox godoc
+// @Summary      Get the inbox project (created on first use)
+// @Tags         projects
+// @Produce      json
+// @Security     CookieAuth
+// @Success      200  {object}  dto.ProjectResponse
+// @Failure      500  {object}  map[string]string
+// @Router       /projects/inbox [get]
+func (h *ProjectHandler) Inbox(c *gin.Context) {
+	userID := auth.UserIDFromContext(c)
+	workspaceID := auth.WorkspaceIDFromContext(c)
+	p, err := h.svc.Inbox(c.Request.Context(), userID, workspaceID)
+	if err != nil {
+		writeProjectError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, projectToResponse(p))
+}
+
+// GetByID godoc
+// @Summary      Get a project by ID
+// @Tags         projects
+// @Produce      json
+// @Security     CookieAuth
+// @Param        id   path      int  true  "Project ID"
+// @Success      200  {object}  dto.ProjectResponse
+// @Failure      400  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /projects/{id} [get]
+func (h *ProjectHandler) GetByID(c *gin.Context) {
+	userID := auth.UserIDFromContext(c)
+	workspaceID := auth.WorkspaceIDFromContext(c)
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+	p, err := h.svc.GetByID(c.Request.Context(), userID, workspaceID, id)
+	if err != nil {
+		writeProjectError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, projectToResponse(p))
+}
+
+// Update godoc
+// @Summary      Update a project (rename, recolor, archive)
+// @Tags         projects
+// @Accept       json
+// @Produce      json
+// @Security     CookieAuth
+// @Param        id    path      int                       true  "Project ID"
+// @Param        body  body      dto.UpdateProjectRequest  true  "Partial update"
+// @Success      200   {object}  dto.ProjectResponse
+// @Failure      400   {object}  map[string]string
+// @Failure      404   {object}  map[string]string
+// @Failure      500   {object}  map[string]string
+// @Router       /projects/{id} [patch]
+func (h *ProjectHandler) Update(c *gin.Context) {
+	userID := auth.UserIDFromContext(c)
+	workspaceID := auth.WorkspaceIDFromContext(c)
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+	var req dto.UpdateProjectRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	p, err := h.svc.Update(c.Request.Context(), userID, workspaceID, id, service.ProjectInput{
+		Name:     req.Name,
+		Color:    req.Color,
+		Archived: req.Archived,
+	})
+	if err != nil {
+		writeProjectError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, projectToResponse(p))
+}
+
+// Delete godoc
+// @Summary      Delete a project
+// @Description  mode=cascade (default) deletes the project's todos too; mode=inbox moves them to the inbox project.
+// @Tags         projects
+// @Security     CookieAuth
+// @Param        id    path   int     true   "Project ID"
+// @Param        mode  query  string  false  "What to do with the todos"  Enums(cascade, inbox)
+// @Success      204
+// @Failure      400  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /projects/{id} [delete]
+func (h *ProjectHandler) Delete(c *gin.Context) {
+	userID := auth.UserIDFromContext(c)
+	workspaceID := auth.WorkspaceIDFromContext(c)
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+	if err := h.svc.Delete(c.Request.Context(), userID, workspaceID, id, c.Query("mode")); err != nil {
+		writeProjectError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func writeProjectError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	case errors.Is(err, service.ErrInvalidProjectName), errors.Is(err, service.ErrInvalidProjectColor),
+		errors.Is(err, service.ErrInboxProject), errors.Is(err, service.ErrInvalidDeleteMode):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+func projectToResponse(p dom.Project) dto.ProjectResponse {
+	return dto.ProjectResponse{
+		ID:          p.ID,
+		WorkspaceID: p.WorkspaceID,
+		Name:        p.Name,
+		Color:       p.Color,
+		Archived:    p.Archived,
+		IsInbox:     p.IsInbox,
+		CreatedAt:   p.CreatedAt,
+		UpdatedAt:   p.UpdatedAt,
+	}
+}
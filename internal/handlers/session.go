@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"net/http"
+
+	"Worker/internal/auth"
+	"Worker/internal/dto"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SessionHandler lets a user see and end their login sessions.
+type SessionHandler struct {
+	sessions *auth.Store
+}
+
+// NewSessionHandler returns a new SessionHandler.
+func NewSessionHandler(sessions *auth.Store) *SessionHandler {
+	return &SessionHandler{sessions: sessions}
+}
+
+// List godoc
+// @Summary      List active sessions
+// @Tags         auth
+// @Produce      json
+// @Security     CookieAuth
+// @Success      200  {object}  dto.ListSessionsResponse
+// @Failure      403  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /auth/sessions [get]
+func (h *SessionHandler) List(c *gin.Context) {
+	userID := auth.UserIDFromContext(c)
+	list, err := h.sessions.List(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	current := auth.SessionHandle(auth.SessionIDFromContext(c))
+	out := make([]dto.SessionResponse, len(list))
+	for i, s := range list {
+		out[i] = dto.SessionResponse{
+			ID:           s.Handle,
+			Current:      s.Handle == current,
+			CreatedAt:    s.CreatedAt,
+			LastSeenAt:   s.LastSeenAt,
+			ExpiresAt:    s.ExpiresAt,
+			UserAgent:    s.UserAgent,
+			IP:           s.IP,
+			Impersonated: s.ImpersonatorID != 0,
+		}
+	}
+	c.JSON(http.StatusOK, dto.ListSessionsResponse{Items: out})
+}
+
+// Revoke godoc
+// @Summary      End one session
+// @Tags         auth
+// @Security     CookieAuth
+// @Param        id   path  string  true  "Session ID from the list"
+// @Success      204
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /auth/sessions/{id} [delete]
+func (h *SessionHandler) Revoke(c *gin.Context) {
+	userID := auth.UserIDFromContext(c)
+	handle := c.Param("id")
+	found, err := h.sessions.DeleteByHandle(c.Request.Context(), userID, handle)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	if handle == auth.SessionHandle(auth.SessionIDFromContext(c)) {
+		c.SetCookie(sessionCookieName, "", -1, "/", "", false, true)
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// RevokeAll godoc
+// @Summary      Log out everywhere
+// @Description  Ends all sessions of the user; with keep_current=true the calling session stays.
+// @Tags         auth
+// @Produce      json
+// @Security     CookieAuth
+// @Param        keep_current  query     bool  false  "Keep the current session"
+// @Success      200  {object}  dto.RevokeSessionsResponse
+// @Failure      403  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /auth/sessions [delete]
+func (h *SessionHandler) RevokeAll(c *gin.Context) {
+	userID := auth.UserIDFromContext(c)
+	except := ""
+	if c.Query("keep_current") == "true" {
+		except = auth.SessionIDFromContext(c)
+	}
+	n, err := h.sessions.DeleteAll(c.Request.Context(), userID, except)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if except == "" {
+		c.SetCookie(sessionCookieName, "", -1, "/", "", false, true)
+	}
+	c.JSON(http.StatusOK, dto.RevokeSessionsResponse{Revoked: n})
+}
@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"Worker/internal/auth"
+	dom "Worker/internal/domain"
+	"Worker/internal/dto"
+	"Worker/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ShareHandler serves the /shares endpoints of todos and projects.
+type ShareHandler struct {
+	svc *service.ShareService
+}
+
+// NewShareHandler returns a new ShareHandler.
+func NewShareHandler(svc *service.ShareService) *ShareHandler {
+	return &ShareHandler{svc: svc}
+}
+
+// ListTodo godoc
+// @Summary      List who a todo is shared with
+// @Description  Only direct shares of this todo; access can also come from a parent todo or the project.
+// @Tags         sharing
+// @Produce      json
+// @Security     CookieAuth
+// @Param        id   path      int  true  "Todo ID"
+// @Success      200  {object}  dto.ListSharesResponse
+// @Failure      400  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /todos/{id}/shares [get]
+func (h *ShareHandler) ListTodo(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+	list, err := h.svc.TodoShares(c.Request.Context(), auth.UserIDFromContext(c), auth.WorkspaceIDFromContext(c), id)
+	if err != nil {
+		writeShareError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, sharesToResponse(list))
+}
+
+// ShareTodo godoc
+// @Summary      Share a todo with a user
+// @Description  The user gets access to the todo and all its subtasks. Sharing again changes the permission. Needs owner permission.
+// @Tags         sharing
+// @Accept       json
+// @Produce      json
+// @Security     CookieAuth
+// @Param        id    path      int               true  "Todo ID"
+// @Param        body  body      dto.ShareRequest  true  "User and permission"
+// @Success      200   {object}  dto.ShareResponse
+// @Failure      400   {object}  map[string]string
+// @Failure      403   {object}  map[string]string
+// @Failure      404   {object}  map[string]string
+// @Failure      500   {object}  map[string]string
+// @Router       /todos/{id}/shares [post]
+func (h *ShareHandler) ShareTodo(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+	var req dto.ShareRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	share, err := h.svc.ShareTodo(c.Request.Context(), auth.UserIDFromContext(c), auth.WorkspaceIDFromContext(c), id, service.ShareInput{
+		User:       req.User,
+		Permission: req.Permission,
+	})
+	if err != nil {
+		writeShareError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, shareToResponse(share))
+}
+
+// UnshareTodo godoc
+// @Summary      Revoke a user's access to a todo
+// @Description  Owners may revoke anyone; other collaborators may only leave (their own user_id).
+// @Tags         sharing
+// @Security     CookieAuth
+// @Param        id       path  int  true  "Todo ID"
+// @Param        user_id  path  int  true  "User ID"
+// @Success      204
+// @Failure      400  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /todos/{id}/shares/{user_id} [delete]
+func (h *ShareHandler) UnshareTodo(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+	granteeID, ok := parseID(c, "user_id")
+	if !ok {
+		return
+	}
+	if err := h.svc.UnshareTodo(c.Request.Context(), auth.UserIDFromContext(c), auth.WorkspaceIDFromContext(c), id, granteeID); err != nil {
+		writeShareError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ListProject godoc
+// @Summary      List who a project is shared with
+// @Tags         sharing
+// @Produce      json
+// @Security     CookieAuth
+// @Param        id   path      int  true  "Project ID"
+// @Success      200  {object}  dto.ListSharesResponse
+// @Failure      400  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /projects/{id}/shares [get]
+func (h *ShareHandler) ListProject(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+	list, err := h.svc.ProjectShares(c.Request.Context(), auth.UserIDFromContext(c), auth.WorkspaceIDFromContext(c), id)
+	if err != nil {
+		writeShareError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, sharesToResponse(list))
+}
+
+// ShareProject godoc
+// @Summary      Share a project with a user
+// @Description  The user gets access to every todo of the project, read through GET /projects/{id}/todos. Needs owner permission.
+// @Tags         sharing
+// @Accept       json
+// @Produce      json
+// @Security     CookieAuth
+// @Param        id    path      int               true  "Project ID"
+// @Param        body  body      dto.ShareRequest  true  "User and permission"
+// @Success      200   {object}  dto.ShareResponse
+// @Failure      400   {object}  map[string]string
+// @Failure      403   {object}  map[string]string
+// @Failure      404   {object}  map[string]string
+// @Failure      500   {object}  map[string]string
+// @Router       /projects/{id}/shares [post]
+func (h *ShareHandler) ShareProject(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+	var req dto.ShareRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	share, err := h.svc.ShareProject(c.Request.Context(), auth.UserIDFromContext(c), auth.WorkspaceIDFromContext(c), id, service.ShareInput{
+		User:       req.User,
+		Permission: req.Permission,
+	})
+	if err != nil {
+		writeShareError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, shareToResponse(share))
+}
+
+// UnshareProject godoc
+// @Summary      Revoke a user's access to a project
+// @Description  Owners may revoke anyone; other collaborators may only leave (their own user_id).
+// @Tags         sharing
+// @Security     CookieAuth
+// @Param        id       path  int  true  "Project ID"
+// @Param        user_id  path  int  true  "User ID"
+// @Success      204
+// @Failure      400  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /projects/{id}/shares/{user_id} [delete]
+func (h *ShareHandler) UnshareProject(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+	granteeID, ok := parseID(c, "user_id")
+	if !ok {
+		return
+	}
+	if err := h.svc.UnshareProject(c.Request.Context(), auth.UserIDFromContext(c), auth.WorkspaceIDFromContext(c), id, granteeID); err != nil {
+		writeShareError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func writeShareError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	case errors.Is(err, service.ErrForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidPermission),
+		errors.Is(err, service.ErrShareUserNotFound),
+		errors.Is(err, service.ErrShareWithOwner),
+		errors.Is(err, service.ErrShareWithSelf):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+func shareToResponse(s dom.Share) dto.ShareResponse {
+	return dto.ShareResponse{
+		UserID:     s.UserID,
+		Username:   s.Username,
+		Permission: s.Permission.String(),
+		GrantedBy:  s.GrantedBy,
+		CreatedAt:  s.CreatedAt,
+	}
+}
+
+func sharesToResponse(list []dom.Share) dto.ListSharesResponse {
+	resp := dto.ListSharesResponse{Items: make([]dto.ShareResponse, len(list))}
+	for i, s := range list {
+		resp.Items[i] = shareToResponse(s)
+	}
+	return resp
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"Worker/internal/auth"
+	"Worker/internal/dto"
+	"Worker/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SyncHandler serves delta sync for offline clients.
+type SyncHandler struct {
+	svc *service.SyncService
+}
+
+// NewSyncHandler returns a new SyncHandler.
+func NewSyncHandler(svc *service.SyncService) *SyncHandler {
+	return &SyncHandler{svc: svc}
+}
+
+// Changes godoc
+// @Summary      Changes of my todos since a sync token
+// @Description  Without since, all my live todos in the workspace (a full sync). With the token of the previous response, the todos created or changed since and tombstones of deleted ones. A todo may come again although unchanged; apply changes by id. While more is true, ask again with the new token right away. 410 means the token is too old: start a full sync.
+// @Tags         sync
+// @Produce      json
+// @Security     CookieAuth
+// @Param        since  query     string  false  "token from the previous response"
+// @Param        limit  query     int     false  "Changes per response, 1-1000 (default 200)"
+// @Success      200    {object}  dto.SyncResponse
+// @Failure      400    {object}  map[string]string
+// @Failure      410    {object}  map[string]string
+// @Failure      500    {object}  map[string]string
+// @Router       /sync [get]
+func (h *SyncHandler) Changes(c *gin.Context) {
+	var req dto.SyncQuery
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ch, token, err := h.svc.Changes(c.Request.Context(), auth.UserIDFromContext(c), auth.WorkspaceIDFromContext(c), req.Since, req.Limit)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidSyncToken):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrSyncTokenExpired):
+			c.JSON(http.StatusGone, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	resp := dto.SyncResponse{
+		Todos:      make([]dto.TodoResponse, len(ch.Todos)),
+		Tombstones: make([]dto.TodoTombstoneResponse, len(ch.Tombstones)),
+		Token:      token,
+		More:       ch.More,
+	}
+	for i, t := range ch.Todos {
+		resp.Todos[i] = todoToResponse(t)
+	}
+	for i, t := range ch.Tombstones {
+		resp.Tombstones[i] = dto.TodoTombstoneResponse{ID: t.ID, DeletedAt: t.DeletedAt, Purged: t.Purged}
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// Push godoc
+// @Summary      Push changes made offline
+// @Description  Creates, updates and deletes (to the trash) applied one by one, like a best_effort batch. With strategy version (default) an update or delete applies only if the todo still has base_version; with lww only if its modified_at is later than the todo's updated_at. A change that loses is not applied and comes back as conflict with the server's todo. Send an Idempotency-Key to retry safely.
+// @Tags         sync
+// @Accept       json
+// @Produce      json
+// @Security     CookieAuth
+// @Param        body  body      dto.SyncPushRequest  true  "Changes"
+// @Success      200   {object}  dto.SyncPushResponse
+// @Failure      400   {object}  map[string]string
+// @Failure      500   {object}  map[string]string
+// @Router       /sync [post]
+func (h *SyncHandler) Push(c *gin.Context) {
+	var req dto.SyncPushRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	changes := make([]service.SyncChange, len(req.Changes))
+	for i, ch := range req.Changes {
+		var err error
+		if changes[i], err = syncChange(ch); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "changes[" + strconv.Itoa(i) + "]: " + err.Error()})
+			return
+		}
+	}
+	strategy := req.Strategy
+	if strategy == "" {
+		strategy = service.SyncByVersion
+	}
+	results, err := h.svc.Push(c.Request.Context(), auth.UserIDFromContext(c), auth.WorkspaceIDFromContext(c), strategy, changes)
+	if err != nil {
+		if errors.Is(err, service.ErrBatchSize) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	resp := dto.SyncPushResponse{Results: make([]dto.SyncPushResult, len(results))}
+	for i, r := range results {
+		item := dto.SyncPushResult{Index: i, Op: r.Op, ID: r.ID, ClientID: r.ClientID, Status: r.Status}
+		if r.Err != nil {
+			item.Error = r.Err.Error()
+		}
+		if r.Todo != nil {
+			t := todoToResponse(*r.Todo)
+			item.Todo = &t
+		}
+		resp.Results[i] = item
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// syncChange converts one pushed change into the service form.
+func syncChange(ch dto.SyncChange) (service.SyncChange, error) {
+	out := service.SyncChange{
+		Op:          ch.Op,
+		ID:          ch.ID,
+		ClientID:    ch.ClientID,
+		BaseVersion: ch.BaseVersion,
+		ModifiedAt:  ch.ModifiedAt,
+	}
+	var err error
+	switch ch.Op {
+	case service.BatchCreate:
+		if ch.Create == nil {
+			return out, errors.New("create needs a create object")
+		}
+		out.Create, err = createTodoInput(*ch.Create)
+	case service.BatchUpdate:
+		if ch.Update == nil {
+			return out, errors.New("update needs an update object")
+		}
+		out.Update, err = updateTodoInput(*ch.Update)
+	}
+	return out, err
+}
@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"Worker/internal/auth"
+	dom "Worker/internal/domain"
+	"Worker/internal/dto"
+	"Worker/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TagHandler handles CRUD for the current user's tags.
+type TagHandler struct {
+	svc *service.TagService
+}
+
+// NewTagHandler returns a new TagHandler.
+func NewTagHandler(svc *service.TagService) *TagHandler {
+	return &TagHandler{svc: svc}
+}
+
+// List godoc
+// @Summary      List tags
+// @Tags         tags
+// @Produce      json
+// @Security     CookieAuth
+// @Success      200  {object}  dto.ListTagsResponse
+// @Failure      500  {object}  map[string]string
+// @Router       /tags [get]
+func (h *TagHandler) List(c *gin.Context) {
+	userID := auth.UserIDFromContext(c)
+	list, err := h.svc.List(c.Request.Context(), userID, auth.WorkspaceIDFromContext(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	out := make([]dto.TagResponse, len(list))
+	for i := range list {
+		out[i] = tagToResponse(list[i])
+	}
+	c.JSON(http.StatusOK, dto.ListTagsResponse{Items: out})
+}
+
+// Create godoc
+// @Summary      Create a tag
+// @Tags         tags
+// @Accept       json
+// @Produce      json
+// @Security     CookieAuth
+// @Param        body  body      dto.TagRequest  true  "Tag"
+// @Success      201   {object}  dto.TagResponse
+// @Failure      400   {object}  map[string]string
+// @Failure      409   {object}  map[string]string
+// @Failure      500   {object}  map[string]string
+// @Router       /tags [post]
+func (h *TagHandler) Create(c *gin.Context) {
+	userID := auth.UserIDFromContext(c)
+	var req dto.TagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	t, err := h.svc.Create(c.Request.Context(), userID, req.Name)
+	if err != nil {
+		writeTagError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, tagToResponse(t))
+}
+
+// GetByID godoc
+// @Summary      Get a tag by ID
+// @Tags         tags
+// @Produce      json
+// @Security     CookieAuth
+// @Param        id   path      int  true  "Tag ID"
+// @Success      200  {object}  dto.TagResponse
+// @Failure      400  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /tags/{id} [get]
+func (h *TagHandler) GetByID(c *gin.Context) {
+	userID := auth.UserIDFromContext(c)
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+	t, err := h.svc.GetByID(c.Request.Context(), userID, auth.WorkspaceIDFromContext(c), id)
+	if err != nil {
+		writeTagError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, tagToResponse(t))
+}
+
+// Rename godoc
+// @Summary      Rename a tag
+// @Tags         tags
+// @Accept       json
+// @Produce      json
+// @Security     CookieAuth
+// @Param        id    path      int             true  "Tag ID"
+// @Param        body  body      dto.TagRequest  true  "New name"
+// @Success      200   {object}  dto.TagResponse
+// @Failure      400   {object}  map[string]string
+// @Failure      404   {object}  map[string]string
+// @Failure      409   {object}  map[string]string
+// @Failure      500   {object}  map[string]string
+// @Router       /tags/{id} [patch]
+func (h *TagHandler) Rename(c *gin.Context) {
+	userID := auth.UserIDFromContext(c)
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+	var req dto.TagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	t, err := h.svc.Rename(c.Request.Context(), userID, auth.WorkspaceIDFromContext(c), id, req.Name)
+	if err != nil {
+		writeTagError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, tagToResponse(t))
+}
+
+// Delete godoc
+// @Summary      Delete a tag (detaches it from all todos)
+// @Tags         tags
+// @Security     CookieAuth
+// @Param        id   path  int  true  "Tag ID"
+// @Success      204
+// @Failure      400  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /tags/{id} [delete]
+func (h *TagHandler) Delete(c *gin.Context) {
+	userID := auth.UserIDFromContext(c)
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+	if err := h.svc.Delete(c.Request.Context(), userID, id); err != nil {
+		writeTagError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func writeTagError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	case errors.Is(err, service.ErrInvalidTagName):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrTagNameTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+func tagToResponse(t dom.Tag) dto.TagResponse {
+	return dto.TagResponse{ID: t.ID, Name: t.Name, TodoCount: t.TodoCount, CreatedAt: t.CreatedAt}
+}
@@ -1,8 +1,11 @@
 package handlers
 
 import (
+	"errors"
+	"html"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"Worker/internal/auth"
@@ -30,17 +33,28 @@ func NewTodoHandler(svc *service.TodoService) *TodoHandler {
 // @Param        body  body      dto.CreateTodoRequest  true  "Todo body"
 // @Success      201   {object}  dto.TodoResponse
 // @Failure      400   {object}  map[string]string
+// @Failure      403  {object}  map[string]string
 // @Router       /todos [post]
 func (h *TodoHandler) Create(c *gin.Context) {
 	userID := auth.UserIDFromContext(c)
+	workspaceID := auth.WorkspaceIDFromContext(c)
 	var req dto.CreateTodoRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	in, err := createTodoInput(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	t, err := h.svc.Create(c.Request.Context(), userID, req.Title, req.Description, req.DueAt.Ptr())
+	t, err := h.svc.Create(c.Request.Context(), userID, workspaceID, in)
 	if err != nil {
+		if errors.Is(err, service.ErrForbidden) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
 		if err == service.ErrInvalidDueDate {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
@@ -53,21 +67,126 @@ func (h *TodoHandler) Create(c *gin.Context) {
 }
 
 // List godoc
-// @Summary      List all todos
+// @Summary      List todos (cursor-paginated)
 // @Tags         todos
 // @Produce      json
 // @Security     CookieAuth
+// @Param        limit          query     int     false  "Page size (1-200, default 50)"
+// @Param        after          query     string  false  "Cursor from next_cursor of the previous page"
+// @Param        is_done        query     bool    false  "Filter by status"
+// @Param        due_before     query     string  false  "Due strictly before (YYYY-MM-DD or RFC3339)"
+// @Param        due_after      query     string  false  "Due strictly after (YYYY-MM-DD or RFC3339)"
+// @Param        created_after  query     string  false  "Created strictly after (YYYY-MM-DD or RFC3339)"
+// @Param        project_id     query     int     false  "Only todos of this project"
+// @Param        top_level      query     bool    false  "Skip subtasks"
+// @Param        tag            query     []string  false  "Tag name (repeatable)"  collectionFormat(multi)
+// @Param        tag_mode       query     string  false  "and = all tags, or = any tag (default)"  Enums(and, or)
+// @Param        sort           query     string  false  "created_at, updated_at, due_at, title or position; prefix with - for descending (default -created_at)"
 // @Success      200  {object}  dto.ListTodosResponse
+// @Failure      400  {object}  map[string]string
 // @Failure      500  {object}  map[string]string
 // @Router       /todos [get]
 func (h *TodoHandler) List(c *gin.Context) {
 	userID := auth.UserIDFromContext(c)
-	list, err := h.svc.List(c.Request.Context(), userID)
+	workspaceID := auth.WorkspaceIDFromContext(c)
+	q, ok := parseListQuery(c)
+	if !ok {
+		return
+	}
+	page, err := h.svc.List(c.Request.Context(), userID, workspaceID, q)
 	if err != nil {
+		if errors.Is(err, service.ErrInvalidCursor) || errors.Is(err, service.ErrInvalidSort) ||
+			errors.Is(err, service.ErrInvalidTagName) || errors.Is(err, service.ErrProjectNotFound) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, dto.ListTodosResponse{Items: todosToResponses(list)})
+	writeList(c, dto.ListTodosResponse{Items: todosToResponses(page.Items), NextCursor: encodeCursor(page.Next)})
+}
+
+// Shared godoc
+// @Summary      List todos shared with me (cursor-paginated)
+// @Description  Todos other users shared directly or through a project; accepts the filters of GET /todos.
+// @Tags         sharing
+// @Produce      json
+// @Security     CookieAuth
+// @Param        limit          query     int     false  "Page size (1-200, default 50)"
+// @Param        after          query     string  false  "Cursor from next_cursor of the previous page"
+// @Param        is_done        query     bool    false  "Filter by status"
+// @Param        project_id     query     int     false  "Only todos of this project"
+// @Param        top_level      query     bool    false  "Skip subtasks"
+// @Param        sort           query     string  false  "Same keys as GET /todos (default -created_at)"
+// @Success      200  {object}  dto.ListTodosResponse
+// @Failure      400  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /todos/shared [get]
+func (h *TodoHandler) Shared(c *gin.Context) {
+	userID := auth.UserIDFromContext(c)
+	workspaceID := auth.WorkspaceIDFromContext(c)
+	q, ok := parseListQuery(c)
+	if !ok {
+		return
+	}
+	page, err := h.svc.Shared(c.Request.Context(), userID, workspaceID, q)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidCursor) || errors.Is(err, service.ErrInvalidSort) || errors.Is(err, service.ErrInvalidTagName) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	writeList(c, dto.ListTodosResponse{Items: todosToResponses(page.Items), NextCursor: encodeCursor(page.Next)})
+}
+
+// ListByProject godoc
+// @Summary      List todos of a project (manual order by default)
+// @Tags         projects
+// @Produce      json
+// @Security     CookieAuth
+// @Param        id             path      int     true   "Project ID"
+// @Param        limit          query     int     false  "Page size (1-200, default 50)"
+// @Param        after          query     string  false  "Cursor from next_cursor of the previous page"
+// @Param        is_done        query     bool    false  "Filter by status"
+// @Param        tag            query     []string  false  "Tag name (repeatable)"  collectionFormat(multi)
+// @Param        tag_mode       query     string  false  "and = all tags, or = any tag (default)"  Enums(and, or)
+// @Param        sort           query     string  false  "Same keys as GET /todos (default position)"
+// @Success      200  {object}  dto.ListTodosResponse
+// @Failure      400  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /projects/{id}/todos [get]
+func (h *TodoHandler) ListByProject(c *gin.Context) {
+	userID := auth.UserIDFromContext(c)
+	workspaceID := auth.WorkspaceIDFromContext(c)
+	projectID, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+	q, ok := parseListQuery(c)
+	if !ok {
+		return
+	}
+	q.ProjectID = &projectID
+	if q.Sort == "" {
+		q.Sort = dom.SortPosition
+	}
+	page, err := h.svc.List(c.Request.Context(), userID, workspaceID, q)
+	if err != nil {
+		if errors.Is(err, service.ErrProjectNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		if errors.Is(err, service.ErrInvalidCursor) || errors.Is(err, service.ErrInvalidSort) || errors.Is(err, service.ErrInvalidTagName) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	writeList(c, dto.ListTodosResponse{Items: todosToResponses(page.Items), NextCursor: encodeCursor(page.Next)})
 }
 
 // GetByID godoc
@@ -76,18 +195,21 @@ func (h *TodoHandler) List(c *gin.Context) {
 // @Produce      json
 // @Security     CookieAuth
 // @Param        id   path      int  true  "Todo ID"
+// @Param        If-None-Match  header  string  false  "ETag of the copy the client has"
 // @Success      200  {object}  dto.TodoResponse
+// @Success      304  "If-None-Match matched"
 // @Failure      400  {object}  map[string]string
 // @Failure      404  {object}  map[string]string
 // @Failure      500  {object}  map[string]string
 // @Router       /todos/{id} [get]
 func (h *TodoHandler) GetByID(c *gin.Context) {
 	userID := auth.UserIDFromContext(c)
+	workspaceID := auth.WorkspaceIDFromContext(c)
 	id, ok := parseID(c, "id")
 	if !ok {
 		return
 	}
-	t, err := h.svc.GetByID(c.Request.Context(), userID, id)
+	t, err := h.svc.GetByID(c.Request.Context(), userID, workspaceID, id)
 	if err != nil {
 		if err == service.ErrNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
@@ -96,7 +218,7 @@ func (h *TodoHandler) GetByID(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, todoToResponse(t))
+	writeTodo(c, t)
 }
 
 // Update godoc
@@ -107,13 +229,17 @@ func (h *TodoHandler) GetByID(c *gin.Context) {
 // @Security     CookieAuth
 // @Param        id    path      int  true  "Todo ID"
 // @Param        body  body      dto.UpdateTodoRequest  true  "Partial update"
+// @Param        If-Match   header  string  false  "Strong ETag (version) the change is based on"
 // @Success      200   {object}  dto.TodoResponse
 // @Failure      400   {object}  map[string]string
+// @Failure      403  {object}  map[string]string
 // @Failure      404  {object}  map[string]string
+// @Failure      412  {object}  map[string]string
 // @Failure      500  {object}  map[string]string
 // @Router       /todos/{id} [patch]
 func (h *TodoHandler) Update(c *gin.Context) {
 	userID := auth.UserIDFromContext(c)
+	workspaceID := auth.WorkspaceIDFromContext(c)
 	id, ok := parseID(c, "id")
 	if !ok {
 		return
@@ -123,96 +249,344 @@ func (h *TodoHandler) Update(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	var duePtr *time.Time
-	if req.DueAt != nil {
-		duePtr = req.DueAt.Ptr()
+	in, err := updateTodoInput(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
-	t, err := h.svc.Update(c.Request.Context(), userID, id, req.Title, req.Description, duePtr, req.IsDone)
+	in.IfVersion = ifMatchVersion(c)
+	t, err := h.svc.Update(c.Request.Context(), userID, workspaceID, id, in)
 	if err != nil {
+		if errors.Is(err, service.ErrForbidden) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
 		if err == service.ErrNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
 			return
 		}
-		if err == service.ErrInvalidDueDate {
+		if err == service.ErrVersionConflict {
+			c.JSON(http.StatusPreconditionFailed, gin.H{"error": err.Error()})
+			return
+		}
+		if err == service.ErrInvalidDueDate || err == service.ErrInvalidTagName ||
+			err == service.ErrProjectNotFound || err == service.ErrSubtaskProject || isScheduleError(err) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, todoToResponse(t))
+	writeTodo(c, t)
 }
 
 // Delete godoc
-// @Summary      Delete a todo
+// @Summary      Delete a todo and its subtasks
+// @Description  Moves the todo to the trash; with permanent=true deletes it for good, also from the trash.
 // @Tags         todos
 // @Security     CookieAuth
-// @Param        id   path  int  true  "Todo ID"
+// @Param        id         path   int   true   "Todo ID"
+// @Param        permanent  query  bool  false  "Delete for good instead of moving to the trash"
+// @Param        If-Match   header  string  false  "Strong ETag (version) the change is based on"
 // @Success      204
 // @Failure      400  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      412  {object}  map[string]string
 // @Failure      500  {object}  map[string]string
 // @Router       /todos/{id} [delete]
 func (h *TodoHandler) Delete(c *gin.Context) {
 	userID := auth.UserIDFromContext(c)
+	workspaceID := auth.WorkspaceIDFromContext(c)
 	id, ok := parseID(c, "id")
 	if !ok {
 		return
 	}
-	err := h.svc.Delete(c.Request.Context(), userID, id)
+	err := h.svc.Delete(c.Request.Context(), userID, workspaceID, id, c.Query("permanent") == "true", ifMatchVersion(c))
 	if err != nil {
+		if errors.Is(err, service.ErrForbidden) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		if err == service.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		if err == service.ErrVersionConflict {
+			c.JSON(http.StatusPreconditionFailed, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 	c.Status(http.StatusNoContent)
 }
 
+// Move godoc
+// @Summary      Move a todo within or between projects
+// @Description  Places the todo right after after_id or right before before_id; with neither it goes to the end.
+// @Tags         todos
+// @Accept       json
+// @Produce      json
+// @Security     CookieAuth
+// @Param        id    path      int                  true  "Todo ID"
+// @Param        body  body      dto.MoveTodoRequest  true  "Target project and neighbour"
+// @Success      200   {object}  dto.TodoResponse
+// @Failure      400   {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404   {object}  map[string]string
+// @Failure      500   {object}  map[string]string
+// @Router       /todos/{id}/move [post]
+func (h *TodoHandler) Move(c *gin.Context) {
+	userID := auth.UserIDFromContext(c)
+	workspaceID := auth.WorkspaceIDFromContext(c)
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+	var req dto.MoveTodoRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	t, err := h.svc.Move(c.Request.Context(), userID, workspaceID, id, req.ProjectID, req.BeforeID, req.AfterID)
+	if err != nil {
+		if errors.Is(err, service.ErrForbidden) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		if err == service.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		if err == service.ErrProjectNotFound || err == service.ErrInvalidPosition || err == service.ErrSubtaskProject {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, todoToResponse(t))
+}
+
 // Complete godoc
 // @Summary      Mark a todo as done
+// @Description  Completing an open recurring todo creates its next occurrence.
 // @Tags         todos
 // @Produce      json
 // @Security     CookieAuth
-// @Param        id   path      int  true  "Todo ID"
+// @Param        id        path      int   true   "Todo ID"
+// @Param        subtasks  query     bool  false  "Also complete all subtasks"
+// @Param        If-Match   header  string  false  "Strong ETag (version) the change is based on"
 // @Success      200  {object}  dto.TodoResponse
 // @Failure      400  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
 // @Failure      404  {object}  map[string]string
+// @Failure      412  {object}  map[string]string
 // @Failure      500  {object}  map[string]string
 // @Router       /todos/{id}/complete [post]
 func (h *TodoHandler) Complete(c *gin.Context) {
 	userID := auth.UserIDFromContext(c)
+	workspaceID := auth.WorkspaceIDFromContext(c)
 	id, ok := parseID(c, "id")
 	if !ok {
 		return
 	}
-	t, err := h.svc.Complete(c.Request.Context(), userID, id)
+	t, err := h.svc.Complete(c.Request.Context(), userID, workspaceID, id, c.Query("subtasks") == "true", ifMatchVersion(c))
 	if err != nil {
+		if errors.Is(err, service.ErrForbidden) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
 		if err == service.ErrNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
 			return
 		}
+		if err == service.ErrVersionConflict {
+			c.JSON(http.StatusPreconditionFailed, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, todoToResponse(t))
+	writeTodo(c, t)
 }
 
-// Search godoc
-// @Summary      Search todos by query
+// Subtasks godoc
+// @Summary      List direct subtasks of a todo
 // @Tags         todos
 // @Produce      json
 // @Security     CookieAuth
-// @Param        q    query     string  true  "Search query (title/description)"
+// @Param        id   path      int  true  "Todo ID"
 // @Success      200  {object}  dto.ListTodosResponse
+// @Failure      400  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /todos/{id}/subtasks [get]
+func (h *TodoHandler) Subtasks(c *gin.Context) {
+	userID := auth.UserIDFromContext(c)
+	workspaceID := auth.WorkspaceIDFromContext(c)
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+	list, err := h.svc.Subtasks(c.Request.Context(), userID, workspaceID, id)
+	if err != nil {
+		if err == service.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	writeList(c, dto.ListTodosResponse{Items: todosToResponses(list)})
+}
+
+// CreateSubtask godoc
+// @Summary      Create a subtask
+// @Description  The subtask joins its parent's project and goes after its last sibling.
+// @Tags         todos
+// @Accept       json
+// @Produce      json
+// @Security     CookieAuth
+// @Param        id    path      int                    true  "Parent todo ID"
+// @Param        body  body      dto.CreateTodoRequest  true  "Todo body"
+// @Success      201   {object}  dto.TodoResponse
+// @Failure      400   {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404   {object}  map[string]string
+// @Failure      500   {object}  map[string]string
+// @Router       /todos/{id}/subtasks [post]
+func (h *TodoHandler) CreateSubtask(c *gin.Context) {
+	userID := auth.UserIDFromContext(c)
+	workspaceID := auth.WorkspaceIDFromContext(c)
+	parentID, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+	var req dto.CreateTodoRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	reminders, err := dto.ParseOffsets(req.Reminders)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	t, err := h.svc.Create(c.Request.Context(), userID, workspaceID, service.CreateTodoInput{
+		Title:       req.Title,
+		Description: req.Description,
+		DueAt:       req.DueAt.Ptr(),
+		Tags:        req.Tags,
+		Reminders:   reminders,
+		ProjectID:   req.ProjectID,
+		ParentID:    &parentID,
+		Recurrence:  req.Recurrence,
+		Timezone:    req.Timezone,
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrForbidden) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		if err == service.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		if err == service.ErrInvalidDueDate || err == service.ErrInvalidTagName ||
+			err == service.ErrMaxDepth || err == service.ErrSubtaskProject || isScheduleError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, todoToResponse(t))
+}
+
+// ReorderSubtasks godoc
+// @Summary      Reorder subtasks of a todo
+// @Tags         todos
+// @Accept       json
+// @Produce      json
+// @Security     CookieAuth
+// @Param        id    path      int                         true  "Parent todo ID"
+// @Param        body  body      dto.ReorderSubtasksRequest  true  "All subtask IDs in the new order"
+// @Success      200   {object}  dto.ListTodosResponse
+// @Failure      400   {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404   {object}  map[string]string
+// @Failure      500   {object}  map[string]string
+// @Router       /todos/{id}/subtasks/reorder [post]
+func (h *TodoHandler) ReorderSubtasks(c *gin.Context) {
+	userID := auth.UserIDFromContext(c)
+	workspaceID := auth.WorkspaceIDFromContext(c)
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+	var req dto.ReorderSubtasksRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	list, err := h.svc.ReorderSubtasks(c.Request.Context(), userID, workspaceID, id, req.IDs)
+	if err != nil {
+		if errors.Is(err, service.ErrForbidden) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		if err == service.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		if err == service.ErrInvalidReorder {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, dto.ListTodosResponse{Items: todosToResponses(list)})
+}
+
+// Search godoc
+// @Summary      Full-text search over todos
+// @Description  Terms are ANDed. "a b" matches a phrase, pre* a prefix, -word excludes a word. Results are ordered by relevance.
+// @Tags         todos
+// @Produce      json
+// @Security     CookieAuth
+// @Param        q         query     string    true   "Search query (title/description)"
+// @Param        tag       query     []string  false  "Tag name (repeatable)"  collectionFormat(multi)
+// @Param        tag_mode  query     string    false  "and = all tags, or = any tag (default)"  Enums(and, or)
+// @Success      200  {object}  dto.SearchTodosResponse
+// @Failure      400  {object}  map[string]string
 // @Failure      500  {object}  map[string]string
 // @Router       /todos/search [get]
 func (h *TodoHandler) Search(c *gin.Context) {
 	userID := auth.UserIDFromContext(c)
-	q := c.Query("q")
-	list, err := h.svc.Search(c.Request.Context(), userID, q)
+	workspaceID := auth.WorkspaceIDFromContext(c)
+	q := strings.TrimSpace(c.Query("q"))
+	if q == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "q is required"})
+		return
+	}
+	var tq dto.TagFilterQuery
+	if err := c.ShouldBindQuery(&tq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	hits, err := h.svc.Search(c.Request.Context(), userID, workspaceID, q, tagFilterFromQuery(tq))
 	if err != nil {
+		if errors.Is(err, service.ErrInvalidTagName) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, dto.ListTodosResponse{Items: todosToResponses(list)})
+	c.JSON(http.StatusOK, dto.SearchTodosResponse{Items: searchHitsToResponses(hits)})
 }
 
 // Overdue godoc
@@ -225,12 +599,63 @@ func (h *TodoHandler) Search(c *gin.Context) {
 // @Router       /todos/overdue [get]
 func (h *TodoHandler) Overdue(c *gin.Context) {
 	userID := auth.UserIDFromContext(c)
-	list, err := h.svc.Overdue(c.Request.Context(), userID)
+	workspaceID := auth.WorkspaceIDFromContext(c)
+	list, err := h.svc.Overdue(c.Request.Context(), userID, workspaceID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, dto.ListTodosResponse{Items: todosToResponses(list)})
+	writeList(c, dto.ListTodosResponse{Items: todosToResponses(list)})
+}
+
+// parseListQuery binds and converts GET /todos query parameters. On error it writes 400 and returns false.
+func parseListQuery(c *gin.Context) (dom.TodoListQuery, bool) {
+	var req dto.ListTodosQuery
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return dom.TodoListQuery{}, false
+	}
+	q := dom.TodoListQuery{
+		Limit:        req.Limit,
+		IsDone:       req.IsDone,
+		ProjectID:    req.ProjectID,
+		TopLevelOnly: req.TopLevel,
+		Tags:         tagFilterFromQuery(req.TagFilterQuery),
+	}
+	if req.Sort != "" {
+		q.Sort = strings.TrimPrefix(req.Sort, "-")
+		q.Desc = strings.HasPrefix(req.Sort, "-")
+	}
+	after, err := decodeCursor(req.After)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return dom.TodoListQuery{}, false
+	}
+	q.After = after
+	for _, f := range []struct {
+		name string
+		raw  string
+		dst  **time.Time
+	}{
+		{"due_before", req.DueBefore, &q.DueBefore},
+		{"due_after", req.DueAfter, &q.DueAfter},
+		{"created_after", req.CreatedAfter, &q.CreatedAfter},
+	} {
+		if f.raw == "" {
+			continue
+		}
+		t, err := dto.ParseDateOrTime(f.raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": f.name + ": " + err.Error()})
+			return dom.TodoListQuery{}, false
+		}
+		*f.dst = &t
+	}
+	return q, true
+}
+
+func tagFilterFromQuery(q dto.TagFilterQuery) dom.TagFilter {
+	return dom.TagFilter{Names: q.Tag, MatchAll: q.TagMode == "and"}
 }
 
 func parseID(c *gin.Context, name string) (int64, bool) {
@@ -246,13 +671,108 @@ func parseID(c *gin.Context, name string) (int64, bool) {
 func todoToResponse(t dom.Todo) dto.TodoResponse {
 	return dto.TodoResponse{
 		ID:          t.ID,
+		WorkspaceID: t.WorkspaceID,
 		Title:       t.Title,
 		Description: t.Description,
 		IsDone:      t.IsDone,
 		DueAt:       t.DueAt,
+		Tags:        tagsOrEmpty(t.Tags),
+		Reminders:   dto.FormatOffsets(t.Reminders),
+		ProjectID:   t.ProjectID,
+		Position:    t.Position,
+		ParentID:    t.ParentID,
+		Progress:    progressOf(t),
+		Recurrence:  recurrenceOf(t),
+		Permission:  t.Permission.String(),
 		CreatedAt:   t.CreatedAt,
 		UpdatedAt:   t.UpdatedAt,
+		DeletedAt:   t.DeletedAt,
+		Version:     t.Version,
+	}
+}
+
+// progressOf returns done/total of direct subtasks, or nil for a todo without any.
+func progressOf(t dom.Todo) *dto.Progress {
+	if t.ChildrenTotal == 0 {
+		return nil
+	}
+	return &dto.Progress{
+		Done:    t.ChildrenDone,
+		Total:   t.ChildrenTotal,
+		Percent: t.ChildrenDone * 100 / t.ChildrenTotal,
+	}
+}
+
+// recurrenceOf returns the series of a recurring todo, or nil for a one-off todo.
+func recurrenceOf(t dom.Todo) *dto.Recurrence {
+	if t.Recurrence == "" || t.RecurrenceStart == nil {
+		return nil
+	}
+	tz := t.RecurrenceTZ
+	if tz == "" {
+		tz = "UTC"
 	}
+	return &dto.Recurrence{Rule: t.Recurrence, Timezone: tz, Start: *t.RecurrenceStart}
+}
+
+// isScheduleError reports whether err is a client error in recurrence, timezone or reminders.
+// createTodoInput converts a create request into the service input.
+func createTodoInput(req dto.CreateTodoRequest) (service.CreateTodoInput, error) {
+	reminders, err := dto.ParseOffsets(req.Reminders)
+	if err != nil {
+		return service.CreateTodoInput{}, err
+	}
+	return service.CreateTodoInput{
+		Title:       req.Title,
+		Description: req.Description,
+		DueAt:       req.DueAt.Ptr(),
+		Tags:        req.Tags,
+		Reminders:   reminders,
+		ProjectID:   req.ProjectID,
+		Recurrence:  req.Recurrence,
+		Timezone:    req.Timezone,
+	}, nil
+}
+
+// updateTodoInput converts a partial update request into the service input.
+func updateTodoInput(req dto.UpdateTodoRequest) (service.UpdateTodoInput, error) {
+	var duePtr *time.Time
+	if req.DueAt != nil {
+		duePtr = req.DueAt.Ptr()
+	}
+	var reminders *[]time.Duration
+	if req.Reminders != nil {
+		list, err := dto.ParseOffsets(*req.Reminders)
+		if err != nil {
+			return service.UpdateTodoInput{}, err
+		}
+		reminders = &list
+	}
+	return service.UpdateTodoInput{
+		Title:       req.Title,
+		Description: req.Description,
+		DueAt:       duePtr,
+		IsDone:      req.IsDone,
+		Tags:        req.Tags,
+		Reminders:   reminders,
+		ProjectID:   req.ProjectID,
+		Recurrence:  req.Recurrence,
+		Timezone:    req.Timezone,
+	}, nil
+}
+
+func isScheduleError(err error) bool {
+	return errors.Is(err, service.ErrInvalidRecurrence) || errors.Is(err, service.ErrInvalidTimezone) ||
+		errors.Is(err, service.ErrRecurrenceNeedsDue) || errors.Is(err, service.ErrInvalidReminder) ||
+		errors.Is(err, service.ErrReminderNeedsDue)
+}
+
+// tagsOrEmpty keeps "tags" an array in JSON even when a todo has none.
+func tagsOrEmpty(tags []string) []string {
+	if tags == nil {
+		return []string{}
+	}
+	return tags
 }
 
 func todosToResponses(list []dom.Todo) []dto.TodoResponse {
@@ -262,3 +782,25 @@ func todosToResponses(list []dom.Todo) []dto.TodoResponse {
 	}
 	return out
 }
+
+func searchHitsToResponses(hits []dom.TodoSearchHit) []dto.SearchHitResponse {
+	out := make([]dto.SearchHitResponse, len(hits))
+	for i, h := range hits {
+		out[i] = dto.SearchHitResponse{
+			TodoResponse: todoToResponse(h.Todo),
+			Rank:         h.Rank,
+			Highlights: dto.SearchHighlights{
+				Title:       highlightToHTML(h.TitleHighlight),
+				Description: highlightToHTML(h.DescriptionHighlight),
+			},
+		}
+	}
+	return out
+}
+
+// highlightToHTML escapes a ts_headline snippet and turns the domain markers into <mark> tags.
+func highlightToHTML(s string) string {
+	s = html.EscapeString(s)
+	s = strings.ReplaceAll(s, dom.HighlightStart, "<mark>")
+	return strings.ReplaceAll(s, dom.HighlightStop, "</mark>")
+}
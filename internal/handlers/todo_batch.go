@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"Worker/internal/auth"
+	"Worker/internal/dto"
+	"Worker/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Batch godoc
+// @Summary      Run several todo operations in one transaction
+// @Description  Either operations (create, update, complete, delete, move; up to 200) or a filter with one action (complete, delete, update) applied to up to 200 of the caller's matching todos, oldest first. mode=atomic (default) rolls everything back on the first failure and answers 422; best_effort commits what succeeded. Each result carries the status its single-todo endpoint would have returned.
+// @Tags         todos
+// @Accept       json
+// @Produce      json
+// @Security     CookieAuth
+// @Param        body  body      dto.BatchRequest  true  "Operations or filter with action"
+// @Success      200   {object}  dto.BatchResponse
+// @Failure      400   {object}  map[string]string
+// @Failure      422   {object}  dto.BatchResponse
+// @Failure      500   {object}  map[string]string
+// @Router       /todos/batch [post]
+func (h *TodoHandler) Batch(c *gin.Context) {
+	userID := auth.UserIDFromContext(c)
+	workspaceID := auth.WorkspaceIDFromContext(c)
+	var req dto.BatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	atomic := req.Mode != "best_effort"
+
+	var out service.BatchOutcome
+	var err error
+	if req.Filter != nil {
+		if len(req.Operations) > 0 || req.Action == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "send either operations or filter with action"})
+			return
+		}
+		f, ferr := batchFilter(*req.Filter)
+		if ferr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": ferr.Error()})
+			return
+		}
+		op, oerr := batchOp(*req.Action)
+		if oerr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": oerr.Error()})
+			return
+		}
+		out, err = h.svc.BatchByFilter(c.Request.Context(), userID, workspaceID, f, op, atomic)
+	} else {
+		ops := make([]service.BatchOp, len(req.Operations))
+		for i, o := range req.Operations {
+			if ops[i], err = batchOp(o); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "operations[" + strconv.Itoa(i) + "]: " + err.Error()})
+				return
+			}
+		}
+		out, err = h.svc.Batch(c.Request.Context(), userID, workspaceID, ops, atomic)
+	}
+	if err != nil {
+		if errors.Is(err, service.ErrBatchSize) || errors.Is(err, service.ErrBatchFilter) || errors.Is(err, service.ErrInvalidTagName) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp := dto.BatchResponse{Committed: out.Committed, More: out.More, Results: make([]dto.BatchItemResult, len(out.Results))}
+	for i, r := range out.Results {
+		item := dto.BatchItemResult{Index: i, Op: r.Op, ID: r.ID, Status: batchItemStatus(r)}
+		if r.Err != nil {
+			item.Error = r.Err.Error()
+		}
+		if r.Todo != nil {
+			t := todoToResponse(*r.Todo)
+			item.Todo = &t
+		}
+		resp.Results[i] = item
+	}
+	status := http.StatusOK
+	if !out.Committed {
+		status = http.StatusUnprocessableEntity
+	}
+	c.JSON(status, resp)
+}
+
+// batchOp converts one operation of a batch request into the service form.
+func batchOp(o dto.BatchOperation) (service.BatchOp, error) {
+	op := service.BatchOp{Op: o.Op, ID: o.ID, IfVersion: o.IfMatch, Subtasks: o.Subtasks, Permanent: o.Permanent}
+	var err error
+	switch o.Op {
+	case service.BatchCreate:
+		if o.Create == nil {
+			return op, errors.New("create needs a create object")
+		}
+		op.Create, err = createTodoInput(*o.Create)
+	case service.BatchUpdate:
+		if o.Update == nil {
+			return op, errors.New("update needs an update object")
+		}
+		op.Update, err = updateTodoInput(*o.Update)
+	case service.BatchMove:
+		if o.Move == nil {
+			return op, errors.New("move needs a move object")
+		}
+		op.ProjectID, op.BeforeID, op.AfterID = o.Move.ProjectID, o.Move.BeforeID, o.Move.AfterID
+	}
+	return op, err
+}
+
+// batchFilter converts the filter of a batch request into the service form.
+func batchFilter(f dto.BatchFilter) (service.BatchFilter, error) {
+	out := service.BatchFilter{
+		Overdue:   f.Overdue,
+		IsDone:    f.IsDone,
+		Tags:      tagFilterFromQuery(dto.TagFilterQuery{Tag: f.Tag, TagMode: f.TagMode}),
+		ProjectID: f.ProjectID,
+	}
+	if f.DueBefore != "" {
+		t, err := dto.ParseDateOrTime(f.DueBefore)
+		if err != nil {
+			return out, errors.New("due_before: " + err.Error())
+		}
+		out.DueBefore = &t
+	}
+	return out, nil
+}
+
+// batchItemStatus is the status the single-todo endpoint would have answered.
+func batchItemStatus(r service.BatchResult) int {
+	err := r.Err
+	switch {
+	case err == nil && r.Op == service.BatchCreate:
+		return http.StatusCreated
+	case err == nil && r.Op == service.BatchDelete:
+		return http.StatusNoContent
+	case err == nil:
+		return http.StatusOK
+	case errors.Is(err, service.ErrBatchRolledBack):
+		return http.StatusFailedDependency
+	case errors.Is(err, service.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, service.ErrVersionConflict):
+		return http.StatusPreconditionFailed
+	case errors.Is(err, service.ErrInvalidDueDate), errors.Is(err, service.ErrInvalidTagName),
+		errors.Is(err, service.ErrProjectNotFound), errors.Is(err, service.ErrSubtaskProject),
+		errors.Is(err, service.ErrInvalidPosition), errors.Is(err, service.ErrMaxDepth),
+		errors.Is(err, service.ErrInvalidBatch), isScheduleError(err):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"Worker/internal/auth"
+	dom "Worker/internal/domain"
+	"Worker/internal/dto"
+	"Worker/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// History godoc
+// @Summary      Change history of a todo
+// @Description  Every change with who made it and the old and new value of each changed field, newest first.
+// @Tags         todos
+// @Produce      json
+// @Security     CookieAuth
+// @Param        id      path      int  true   "Todo ID"
+// @Param        limit   query     int  false  "Page size, 1-200 (default 50)"
+// @Param        before  query     int  false  "next_cursor from the previous page"
+// @Success      200     {object}  dto.ListTodoEventsResponse
+// @Failure      400     {object}  map[string]string
+// @Failure      404     {object}  map[string]string
+// @Failure      500     {object}  map[string]string
+// @Router       /todos/{id}/history [get]
+func (h *TodoHandler) History(c *gin.Context) {
+	userID := auth.UserIDFromContext(c)
+	workspaceID := auth.WorkspaceIDFromContext(c)
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+	var req dto.ListTodoEventsQuery
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	events, err := h.svc.History(c.Request.Context(), userID, workspaceID, id, req.Before, req.Limit)
+	if err != nil {
+		if err == service.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, todoEventsToResponse(events, req.Limit))
+}
+
+// Activity godoc
+// @Summary      Activity feed
+// @Description  Changes in the workspace made by me or to todos I own or that are shared with me, newest first.
+// @Tags         todos
+// @Produce      json
+// @Security     CookieAuth
+// @Param        limit   query     int  false  "Page size, 1-200 (default 50)"
+// @Param        before  query     int  false  "next_cursor from the previous page"
+// @Success      200     {object}  dto.ListTodoEventsResponse
+// @Failure      400     {object}  map[string]string
+// @Failure      500     {object}  map[string]string
+// @Router       /activity [get]
+func (h *TodoHandler) Activity(c *gin.Context) {
+	var req dto.ListTodoEventsQuery
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	events, err := h.svc.Activity(c.Request.Context(), auth.UserIDFromContext(c), auth.WorkspaceIDFromContext(c), req.Before, req.Limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, todoEventsToResponse(events, req.Limit))
+}
+
+// todoEventsToResponse converts one page of events; a full page gets a next_cursor.
+func todoEventsToResponse(events []dom.TodoEvent, limit int) dto.ListTodoEventsResponse {
+	resp := dto.ListTodoEventsResponse{Items: make([]dto.TodoEventResponse, len(events))}
+	for i, e := range events {
+		resp.Items[i] = dto.TodoEventResponse{
+			ID:          e.ID,
+			TodoID:      e.TodoID,
+			WorkspaceID: e.WorkspaceID,
+			OwnerID:     e.OwnerID,
+			ActorID:     e.ActorID,
+			Action:      e.Action,
+			Changes:     e.Changes,
+			CreatedAt:   e.CreatedAt,
+		}
+	}
+	if n := len(events); n > 0 && n == effectiveLimit(limit) {
+		resp.NextCursor = strconv.FormatInt(events[n-1].ID, 10)
+	}
+	return resp
+}
@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"Worker/internal/auth"
+	"Worker/internal/dto"
+	"Worker/internal/service"
+	"Worker/internal/todoio"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxImportBytes caps the size of an import file.
+const maxImportBytes = 10 << 20
+
+// Export godoc
+// @Summary      Export my todos
+// @Description  Streams all my todos in the workspace, open and done, as a file: csv (a row per todo, parent_id links subtasks), json (an array), md (a task list under project headings) or ics (iCalendar VTODOs, RELATED-TO links subtasks). Subtasks follow their parent. Shared todos of other users are not included.
+// @Tags         todos
+// @Produce      text/csv
+// @Produce      json
+// @Produce      text/markdown
+// @Produce      text/calendar
+// @Security     CookieAuth
+// @Param        format           query     string  false  "csv (default), json, md or ics"
+// @Param        include_deleted  query     bool    false  "Also todos in the trash"
+// @Success      200  {file}    file
+// @Failure      400  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /todos/export [get]
+func (h *TodoHandler) Export(c *gin.Context) {
+	var req dto.ExportQuery
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Format == "" {
+		req.Format = todoio.FormatCSV
+	}
+	w, err := todoio.NewWriter(c.Writer, req.Format)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("Content-Type", todoio.ContentType(req.Format))
+	c.Header("Content-Disposition", `attachment; filename="todos.`+req.Format+`"`)
+	err = h.svc.Export(c.Request.Context(), auth.UserIDFromContext(c), auth.WorkspaceIDFromContext(c), req.IncludeDeleted, w)
+	if err == nil {
+		err = w.Close()
+	}
+	if err != nil {
+		if c.Writer.Written() {
+			// The status is out; the client sees a cut-off file.
+			_ = c.Error(err)
+			return
+		}
+		c.Writer.Header().Del("Content-Disposition")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// Import godoc
+// @Summary      Import todos from a file
+// @Description  Adds the todos of a file (up to 10 MiB and 10000 todos) to mine in the workspace: csv, json, md or ics as written by export, ics also from calendar apps (VTODOs only), or todoist, a Todoist CSV export. Send the file as the body or as the file field of a multipart form. Projects are found by name or created; due dates in the past, done todos and creation times are kept. The file is imported as a whole or not at all: any error answers 422 with all errors and imports nothing. dry_run only checks the file. Imported todos are not sent to /events or webhooks.
+// @Tags         todos
+// @Accept       text/csv
+// @Accept       json
+// @Accept       text/markdown
+// @Accept       text/calendar
+// @Accept       multipart/form-data
+// @Produce      json
+// @Security     CookieAuth
+// @Param        format   query     string  true   "csv, json, md, ics or todoist"
+// @Param        dry_run  query     bool    false  "Only check the file"
+// @Success      200  {object}  dto.ImportResponse
+// @Success      201  {object}  dto.ImportResponse
+// @Failure      400  {object}  map[string]string
+// @Failure      413  {object}  map[string]string
+// @Failure      422  {object}  dto.ImportResponse
+// @Failure      500  {object}  map[string]string
+// @Router       /todos/import [post]
+func (h *TodoHandler) Import(c *gin.Context) {
+	var req dto.ImportQuery
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	file, err := importFile(c)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "the file must be at most 10 MiB"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	res, err := h.svc.Import(c.Request.Context(), auth.UserIDFromContext(c), auth.WorkspaceIDFromContext(c),
+		req.Format, bytes.NewReader(file), req.DryRun)
+	switch {
+	case errors.Is(err, service.ErrImportInvalid):
+		c.JSON(http.StatusUnprocessableEntity, importToResponse(res))
+	case errors.Is(err, todoio.ErrInvalidFile), errors.Is(err, service.ErrImportTooLarge):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	case res.Created > 0:
+		c.JSON(http.StatusCreated, importToResponse(res))
+	default:
+		c.JSON(http.StatusOK, importToResponse(res))
+	}
+}
+
+// importFile reads the file of an import request: the file field of a
+// multipart form, or else the whole body.
+func importFile(c *gin.Context) ([]byte, error) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportBytes)
+	if !strings.HasPrefix(c.ContentType(), "multipart/") {
+		return io.ReadAll(c.Request.Body)
+	}
+	fh, err := c.FormFile("file")
+	if err != nil {
+		return nil, err
+	}
+	f, err := fh.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+func importToResponse(res service.ImportResult) dto.ImportResponse {
+	out := dto.ImportResponse{
+		DryRun:   res.DryRun,
+		Todos:    res.Todos,
+		Created:  res.Created,
+		Projects: res.Projects,
+		Errors:   []dto.ImportProblem{},
+		Warnings: []dto.ImportProblem{},
+	}
+	if out.Projects == nil {
+		out.Projects = []string{}
+	}
+	for _, p := range res.Problems {
+		item := dto.ImportProblem{Line: p.Line, Field: p.Field, Message: p.Message}
+		if p.Warning {
+			out.Warnings = append(out.Warnings, item)
+		} else {
+			out.Errors = append(out.Errors, item)
+		}
+	}
+	out.Valid = len(out.Errors) == 0
+	return out
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"net/http"
+
+	"Worker/internal/auth"
+	"Worker/internal/dto"
+	"Worker/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Trash godoc
+// @Summary      List deleted todos (cursor-paginated)
+// @Description  My deleted todos in the workspace, most recently deleted first. Subtasks deleted with their parent are not listed; they come back with it.
+// @Tags         todos
+// @Produce      json
+// @Security     CookieAuth
+// @Param        limit  query     int     false  "Page size (1-200, default 50)"
+// @Param        after  query     string  false  "Cursor from next_cursor of the previous page"
+// @Success      200    {object}  dto.ListTodosResponse
+// @Failure      400    {object}  map[string]string
+// @Failure      500    {object}  map[string]string
+// @Router       /todos/trash [get]
+func (h *TodoHandler) Trash(c *gin.Context) {
+	var req dto.ListTrashQuery
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	after, err := decodeCursor(req.After)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	page, err := h.svc.Trash(c.Request.Context(), auth.UserIDFromContext(c), auth.WorkspaceIDFromContext(c), req.Limit, after)
+	if err != nil {
+		if err == service.ErrInvalidCursor {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, dto.ListTodosResponse{Items: todosToResponses(page.Items), NextCursor: encodeCursor(page.Next)})
+}
+
+// Restore godoc
+// @Summary      Restore a deleted todo
+// @Description  Brings the todo back at the end of its siblings, with the subtasks deleted together with it.
+// @Tags         todos
+// @Produce      json
+// @Security     CookieAuth
+// @Param        id   path      int  true  "Todo ID"
+// @Success      200  {object}  dto.TodoResponse
+// @Failure      400  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /todos/{id}/restore [post]
+func (h *TodoHandler) Restore(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+	t, err := h.svc.Restore(c.Request.Context(), auth.UserIDFromContext(c), auth.WorkspaceIDFromContext(c), id)
+	if err != nil {
+		switch err {
+		case service.ErrNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		case service.ErrParentInTrash:
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, todoToResponse(t))
+}
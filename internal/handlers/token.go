@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"Worker/internal/auth"
+	dom "Worker/internal/domain"
+	"Worker/internal/dto"
+	"Worker/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TokenHandler manages the current user's personal API tokens.
+type TokenHandler struct {
+	svc *service.TokenService
+}
+
+// NewTokenHandler returns a new TokenHandler.
+func NewTokenHandler(svc *service.TokenService) *TokenHandler {
+	return &TokenHandler{svc: svc}
+}
+
+// Create godoc
+// @Summary      Create a personal API token
+// @Description  The token is returned only in this response; use it as "Authorization: Bearer <token>".
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Security     CookieAuth
+// @Param        body  body      dto.CreateTokenRequest  true  "Token"
+// @Success      201   {object}  dto.CreateTokenResponse
+// @Failure      400   {object}  map[string]string
+// @Failure      403   {object}  map[string]string
+// @Failure      500   {object}  map[string]string
+// @Router       /auth/tokens [post]
+func (h *TokenHandler) Create(c *gin.Context) {
+	userID := auth.UserIDFromContext(c)
+	var req dto.CreateTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ttl := time.Duration(req.ExpiresInDays) * 24 * time.Hour
+	t, secret, err := h.svc.Create(c.Request.Context(), userID, req.Name, req.Scopes, ttl)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidTokenName) || errors.Is(err, service.ErrInvalidScopes) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, dto.CreateTokenResponse{TokenResponse: tokenToResponse(t), Token: secret})
+}
+
+// List godoc
+// @Summary      List personal API tokens
+// @Tags         auth
+// @Produce      json
+// @Security     CookieAuth
+// @Success      200  {object}  dto.ListTokensResponse
+// @Failure      403  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /auth/tokens [get]
+func (h *TokenHandler) List(c *gin.Context) {
+	userID := auth.UserIDFromContext(c)
+	list, err := h.svc.List(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	out := make([]dto.TokenResponse, len(list))
+	for i := range list {
+		out[i] = tokenToResponse(list[i])
+	}
+	c.JSON(http.StatusOK, dto.ListTokensResponse{Items: out})
+}
+
+// Revoke godoc
+// @Summary      Revoke a personal API token
+// @Tags         auth
+// @Security     CookieAuth
+// @Param        id   path  int  true  "Token ID"
+// @Success      204
+// @Failure      400  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /auth/tokens/{id} [delete]
+func (h *TokenHandler) Revoke(c *gin.Context) {
+	userID := auth.UserIDFromContext(c)
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+	if err := h.svc.Revoke(c.Request.Context(), userID, id); err != nil {
+		if errors.Is(err, service.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func tokenToResponse(t dom.APIToken) dto.TokenResponse {
+	return dto.TokenResponse{
+		ID:         t.ID,
+		Name:       t.Name,
+		Prefix:     t.Prefix,
+		Scopes:     t.Scopes,
+		ExpiresAt:  t.ExpiresAt,
+		LastUsedAt: t.LastUsedAt,
+		CreatedAt:  t.CreatedAt,
+	}
+}
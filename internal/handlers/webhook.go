@@ -0,0 +1,324 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"Worker/internal/auth"
+	dom "Worker/internal/domain"
+	"Worker/internal/dto"
+	"Worker/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WebhookHandler manages the current user's webhooks and their deliveries.
+type WebhookHandler struct {
+	svc *service.WebhookService
+}
+
+// NewWebhookHandler returns a new WebhookHandler.
+func NewWebhookHandler(svc *service.WebhookService) *WebhookHandler {
+	return &WebhookHandler{svc: svc}
+}
+
+// Create godoc
+// @Summary      Register a webhook
+// @Description  Events of my todos and todos shared with me are POSTed to url, signed with HMAC-SHA256 (see README). The secret is returned only in this response.
+// @Tags         webhooks
+// @Accept       json
+// @Produce      json
+// @Security     CookieAuth
+// @Param        body  body      dto.CreateWebhookRequest  true  "Webhook"
+// @Success      201   {object}  dto.CreateWebhookResponse
+// @Failure      400   {object}  map[string]string
+// @Failure      409   {object}  map[string]string
+// @Failure      500   {object}  map[string]string
+// @Router       /webhooks [post]
+func (h *WebhookHandler) Create(c *gin.Context) {
+	var req dto.CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	w, err := h.svc.Create(c.Request.Context(), auth.UserIDFromContext(c), service.CreateWebhookInput{
+		URL:         req.URL,
+		Description: req.Description,
+		Events:      req.Events,
+	})
+	if err != nil {
+		webhookError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, dto.CreateWebhookResponse{WebhookResponse: webhookToResponse(w), Secret: w.Secret})
+}
+
+// List godoc
+// @Summary      List webhooks
+// @Tags         webhooks
+// @Produce      json
+// @Security     CookieAuth
+// @Success      200  {object}  dto.ListWebhooksResponse
+// @Failure      500  {object}  map[string]string
+// @Router       /webhooks [get]
+func (h *WebhookHandler) List(c *gin.Context) {
+	list, err := h.svc.List(c.Request.Context(), auth.UserIDFromContext(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	out := make([]dto.WebhookResponse, len(list))
+	for i := range list {
+		out[i] = webhookToResponse(list[i])
+	}
+	c.JSON(http.StatusOK, dto.ListWebhooksResponse{Items: out})
+}
+
+// GetByID godoc
+// @Summary      Get a webhook
+// @Tags         webhooks
+// @Produce      json
+// @Security     CookieAuth
+// @Param        id   path      int  true  "Webhook ID"
+// @Success      200  {object}  dto.WebhookResponse
+// @Failure      400  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /webhooks/{id} [get]
+func (h *WebhookHandler) GetByID(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+	w, err := h.svc.Get(c.Request.Context(), auth.UserIDFromContext(c), id)
+	if err != nil {
+		webhookError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, webhookToResponse(w))
+}
+
+// Update godoc
+// @Summary      Update a webhook
+// @Description  Change url, description or events, or turn the webhook off and on. Turning it on resets the failure count.
+// @Tags         webhooks
+// @Accept       json
+// @Produce      json
+// @Security     CookieAuth
+// @Param        id    path      int                       true  "Webhook ID"
+// @Param        body  body      dto.UpdateWebhookRequest  true  "Fields to change"
+// @Success      200   {object}  dto.WebhookResponse
+// @Failure      400   {object}  map[string]string
+// @Failure      404   {object}  map[string]string
+// @Failure      500   {object}  map[string]string
+// @Router       /webhooks/{id} [patch]
+func (h *WebhookHandler) Update(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+	var req dto.UpdateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	w, err := h.svc.Update(c.Request.Context(), auth.UserIDFromContext(c), id, service.UpdateWebhookInput{
+		URL:         req.URL,
+		Description: req.Description,
+		Events:      req.Events,
+		Enabled:     req.Enabled,
+	})
+	if err != nil {
+		webhookError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, webhookToResponse(w))
+}
+
+// Delete godoc
+// @Summary      Delete a webhook
+// @Description  Pending deliveries are dropped.
+// @Tags         webhooks
+// @Security     CookieAuth
+// @Param        id   path  int  true  "Webhook ID"
+// @Success      204
+// @Failure      400  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /webhooks/{id} [delete]
+func (h *WebhookHandler) Delete(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+	if err := h.svc.Delete(c.Request.Context(), auth.UserIDFromContext(c), id); err != nil {
+		webhookError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// Deliveries godoc
+// @Summary      List deliveries of a webhook
+// @Tags         webhooks
+// @Produce      json
+// @Security     CookieAuth
+// @Param        id      path      int     true   "Webhook ID"
+// @Param        status  query     string  false  "pending, succeeded or failed"
+// @Param        limit   query     int     false  "Page size, 1-200 (default 50)"
+// @Param        before  query     int     false  "next_cursor from the previous page"
+// @Success      200     {object}  dto.ListWebhookDeliveriesResponse
+// @Failure      400     {object}  map[string]string
+// @Failure      404     {object}  map[string]string
+// @Failure      500     {object}  map[string]string
+// @Router       /webhooks/{id}/deliveries [get]
+func (h *WebhookHandler) Deliveries(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+	var req dto.ListWebhookDeliveriesQuery
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	list, err := h.svc.Deliveries(c.Request.Context(), auth.UserIDFromContext(c), id, req.Status, req.Before, req.Limit)
+	if err != nil {
+		webhookError(c, err)
+		return
+	}
+	resp := dto.ListWebhookDeliveriesResponse{Items: make([]dto.WebhookDeliveryResponse, len(list))}
+	for i, d := range list {
+		resp.Items[i] = deliveryToResponse(d)
+	}
+	if n := len(list); n > 0 && n == effectiveLimit(req.Limit) {
+		resp.NextCursor = strconv.FormatInt(list[n-1].ID, 10)
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// Delivery godoc
+// @Summary      Get a delivery with its body and attempts
+// @Tags         webhooks
+// @Produce      json
+// @Security     CookieAuth
+// @Param        id           path      int  true  "Webhook ID"
+// @Param        delivery_id  path      int  true  "Delivery ID"
+// @Success      200          {object}  dto.WebhookDeliveryDetailResponse
+// @Failure      400          {object}  map[string]string
+// @Failure      404          {object}  map[string]string
+// @Failure      500          {object}  map[string]string
+// @Router       /webhooks/{id}/deliveries/{delivery_id} [get]
+func (h *WebhookHandler) Delivery(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+	deliveryID, ok := parseID(c, "delivery_id")
+	if !ok {
+		return
+	}
+	d, attempts, err := h.svc.Delivery(c.Request.Context(), auth.UserIDFromContext(c), id, deliveryID)
+	if err != nil {
+		webhookError(c, err)
+		return
+	}
+	resp := dto.WebhookDeliveryDetailResponse{
+		WebhookDeliveryResponse: deliveryToResponse(d),
+		Payload:                 d.Payload,
+		AttemptsLog:             make([]dto.WebhookAttemptResponse, len(attempts)),
+	}
+	for i, a := range attempts {
+		resp.AttemptsLog[i] = dto.WebhookAttemptResponse{
+			Attempt:    a.Attempt,
+			Error:      a.Error,
+			Response:   a.Response,
+			DurationMs: a.Duration.Milliseconds(),
+			CreatedAt:  a.CreatedAt,
+		}
+		if a.StatusCode != 0 {
+			resp.AttemptsLog[i].StatusCode = &a.StatusCode
+		}
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// Redeliver godoc
+// @Summary      Send a delivery again
+// @Description  Queues the same event (same event_id and body) as a new delivery; it is signed anew when sent.
+// @Tags         webhooks
+// @Produce      json
+// @Security     CookieAuth
+// @Param        id           path      int  true  "Webhook ID"
+// @Param        delivery_id  path      int  true  "Delivery ID"
+// @Success      202          {object}  dto.WebhookDeliveryResponse
+// @Failure      400          {object}  map[string]string
+// @Failure      404          {object}  map[string]string
+// @Failure      409          {object}  map[string]string
+// @Failure      500          {object}  map[string]string
+// @Router       /webhooks/{id}/deliveries/{delivery_id}/redeliver [post]
+func (h *WebhookHandler) Redeliver(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+	deliveryID, ok := parseID(c, "delivery_id")
+	if !ok {
+		return
+	}
+	d, err := h.svc.Redeliver(c.Request.Context(), auth.UserIDFromContext(c), id, deliveryID)
+	if err != nil {
+		webhookError(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, deliveryToResponse(d))
+}
+
+// webhookError maps WebhookService errors to responses.
+func webhookError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	case errors.Is(err, service.ErrInvalidWebhookURL), errors.Is(err, service.ErrInvalidWebhookEvents):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrWebhookLimit), errors.Is(err, service.ErrWebhookDisabled), errors.Is(err, service.ErrDeliveryPending):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+func webhookToResponse(w dom.Webhook) dto.WebhookResponse {
+	return dto.WebhookResponse{
+		ID:           w.ID,
+		URL:          w.URL,
+		Description:  w.Description,
+		Events:       w.Events,
+		Enabled:      w.DisabledAt == nil,
+		FailureCount: w.FailureCount,
+		DisabledAt:   w.DisabledAt,
+		CreatedAt:    w.CreatedAt,
+		UpdatedAt:    w.UpdatedAt,
+	}
+}
+
+func deliveryToResponse(d dom.WebhookDelivery) dto.WebhookDeliveryResponse {
+	out := dto.WebhookDeliveryResponse{
+		ID:             d.ID,
+		WebhookID:      d.WebhookID,
+		EventID:        d.EventID,
+		Event:          d.Event,
+		TodoID:         d.TodoID,
+		Status:         d.Status,
+		Attempts:       d.Attempts,
+		LastStatusCode: d.LastStatusCode,
+		LastError:      d.LastError,
+		DeliveredAt:    d.DeliveredAt,
+		CreatedAt:      d.CreatedAt,
+	}
+	if d.Status == dom.DeliveryPending {
+		out.NextAttemptAt = &d.NextAttemptAt
+	}
+	return out
+}
@@ -0,0 +1,391 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"Worker/internal/auth"
+	dom "Worker/internal/domain"
+	"Worker/internal/dto"
+	"Worker/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WorkspaceHandler serves /workspaces: workspaces, members and invitations.
+// Routes under /workspaces/:workspace_id run after auth.RequireWorkspace, which
+// has already checked membership and the role the route needs.
+type WorkspaceHandler struct {
+	svc *service.WorkspaceService
+}
+
+// NewWorkspaceHandler returns a new WorkspaceHandler.
+func NewWorkspaceHandler(svc *service.WorkspaceService) *WorkspaceHandler {
+	return &WorkspaceHandler{svc: svc}
+}
+
+// List godoc
+// @Summary      List my workspaces
+// @Description  The personal workspace comes first. Any workspace can be made active with the X-Workspace-ID header or the /workspaces/{workspace_id} prefix.
+// @Tags         workspaces
+// @Produce      json
+// @Security     CookieAuth
+// @Success      200  {object}  dto.ListWorkspacesResponse
+// @Failure      500  {object}  map[string]string
+// @Router       /workspaces [get]
+func (h *WorkspaceHandler) List(c *gin.Context) {
+	list, err := h.svc.List(c.Request.Context(), auth.UserIDFromContext(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	resp := dto.ListWorkspacesResponse{Items: make([]dto.WorkspaceResponse, len(list))}
+	for i, w := range list {
+		resp.Items[i] = workspaceToResponse(w)
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// Create godoc
+// @Summary      Create a workspace
+// @Description  The creator becomes its owner.
+// @Tags         workspaces
+// @Accept       json
+// @Produce      json
+// @Security     CookieAuth
+// @Param        body  body      dto.WorkspaceRequest  true  "Workspace"
+// @Success      201   {object}  dto.WorkspaceResponse
+// @Failure      400   {object}  map[string]string
+// @Failure      500   {object}  map[string]string
+// @Router       /workspaces [post]
+func (h *WorkspaceHandler) Create(c *gin.Context) {
+	var req dto.WorkspaceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	w, err := h.svc.Create(c.Request.Context(), auth.UserIDFromContext(c), req.Name)
+	if err != nil {
+		writeWorkspaceError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, workspaceToResponse(w))
+}
+
+// Get godoc
+// @Summary      Get a workspace
+// @Tags         workspaces
+// @Produce      json
+// @Security     CookieAuth
+// @Param        workspace_id  path      int  true  "Workspace ID"
+// @Success      200           {object}  dto.WorkspaceResponse
+// @Failure      400           {object}  map[string]string
+// @Failure      404           {object}  map[string]string
+// @Failure      500           {object}  map[string]string
+// @Router       /workspaces/{workspace_id} [get]
+func (h *WorkspaceHandler) Get(c *gin.Context) {
+	w, err := h.svc.ResolveWorkspace(c.Request.Context(), auth.UserIDFromContext(c), auth.WorkspaceIDFromContext(c))
+	if err != nil {
+		writeWorkspaceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, workspaceToResponse(w))
+}
+
+// Rename godoc
+// @Summary      Rename a workspace
+// @Description  Needs the owner role.
+// @Tags         workspaces
+// @Accept       json
+// @Produce      json
+// @Security     CookieAuth
+// @Param        workspace_id  path      int                   true  "Workspace ID"
+// @Param        body          body      dto.WorkspaceRequest  true  "New name"
+// @Success      200           {object}  dto.WorkspaceResponse
+// @Failure      400           {object}  map[string]string
+// @Failure      403           {object}  map[string]string
+// @Failure      404           {object}  map[string]string
+// @Failure      500           {object}  map[string]string
+// @Router       /workspaces/{workspace_id} [patch]
+func (h *WorkspaceHandler) Rename(c *gin.Context) {
+	var req dto.WorkspaceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	w, err := h.svc.Rename(c.Request.Context(), auth.UserIDFromContext(c), auth.WorkspaceIDFromContext(c), req.Name)
+	if err != nil {
+		writeWorkspaceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, workspaceToResponse(w))
+}
+
+// Delete godoc
+// @Summary      Delete a workspace
+// @Description  Deletes all its projects and todos too. Needs the owner role; personal workspaces cannot be deleted.
+// @Tags         workspaces
+// @Security     CookieAuth
+// @Param        workspace_id  path  int  true  "Workspace ID"
+// @Success      204
+// @Failure      400  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /workspaces/{workspace_id} [delete]
+func (h *WorkspaceHandler) Delete(c *gin.Context) {
+	if err := h.svc.Delete(c.Request.Context(), auth.WorkspaceIDFromContext(c)); err != nil {
+		writeWorkspaceError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// Members godoc
+// @Summary      List workspace members
+// @Tags         workspaces
+// @Produce      json
+// @Security     CookieAuth
+// @Param        workspace_id  path      int  true  "Workspace ID"
+// @Success      200           {object}  dto.ListWorkspaceMembersResponse
+// @Failure      400           {object}  map[string]string
+// @Failure      404           {object}  map[string]string
+// @Failure      500           {object}  map[string]string
+// @Router       /workspaces/{workspace_id}/members [get]
+func (h *WorkspaceHandler) Members(c *gin.Context) {
+	list, err := h.svc.Members(c.Request.Context(), auth.WorkspaceIDFromContext(c))
+	if err != nil {
+		writeWorkspaceError(c, err)
+		return
+	}
+	resp := dto.ListWorkspaceMembersResponse{Items: make([]dto.WorkspaceMemberResponse, len(list))}
+	for i, m := range list {
+		resp.Items[i] = memberToResponse(m)
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// SetMemberRole godoc
+// @Summary      Change a member's role
+// @Description  Needs the admin role; appointing or changing an owner needs the owner role. The last owner cannot be demoted.
+// @Tags         workspaces
+// @Accept       json
+// @Produce      json
+// @Security     CookieAuth
+// @Param        workspace_id  path      int                       true  "Workspace ID"
+// @Param        user_id       path      int                       true  "User ID"
+// @Param        body          body      dto.SetMemberRoleRequest  true  "Role"
+// @Success      200           {object}  dto.WorkspaceMemberResponse
+// @Failure      400           {object}  map[string]string
+// @Failure      403           {object}  map[string]string
+// @Failure      404           {object}  map[string]string
+// @Failure      409           {object}  map[string]string
+// @Failure      500           {object}  map[string]string
+// @Router       /workspaces/{workspace_id}/members/{user_id} [put]
+func (h *WorkspaceHandler) SetMemberRole(c *gin.Context) {
+	memberID, ok := parseID(c, "user_id")
+	if !ok {
+		return
+	}
+	var req dto.SetMemberRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	m, err := h.svc.SetMemberRole(c.Request.Context(), auth.UserIDFromContext(c), auth.WorkspaceIDFromContext(c), memberID, req.Role)
+	if err != nil {
+		writeWorkspaceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, memberToResponse(m))
+}
+
+// RemoveMember godoc
+// @Summary      Remove a member or leave a workspace
+// @Description  Any member may remove themselves; removing others needs the admin role, removing an owner the owner role. The member's shares in the workspace are revoked.
+// @Tags         workspaces
+// @Security     CookieAuth
+// @Param        workspace_id  path  int  true  "Workspace ID"
+// @Param        user_id       path  int  true  "User ID"
+// @Success      204
+// @Failure      400  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /workspaces/{workspace_id}/members/{user_id} [delete]
+func (h *WorkspaceHandler) RemoveMember(c *gin.Context) {
+	memberID, ok := parseID(c, "user_id")
+	if !ok {
+		return
+	}
+	if err := h.svc.RemoveMember(c.Request.Context(), auth.UserIDFromContext(c), auth.WorkspaceIDFromContext(c), memberID); err != nil {
+		writeWorkspaceError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// Invitations godoc
+// @Summary      List pending invitations
+// @Description  Needs the admin role.
+// @Tags         workspaces
+// @Produce      json
+// @Security     CookieAuth
+// @Param        workspace_id  path      int  true  "Workspace ID"
+// @Success      200           {object}  dto.ListInvitationsResponse
+// @Failure      400           {object}  map[string]string
+// @Failure      403           {object}  map[string]string
+// @Failure      404           {object}  map[string]string
+// @Failure      500           {object}  map[string]string
+// @Router       /workspaces/{workspace_id}/invitations [get]
+func (h *WorkspaceHandler) Invitations(c *gin.Context) {
+	list, err := h.svc.Invitations(c.Request.Context(), auth.WorkspaceIDFromContext(c))
+	if err != nil {
+		writeWorkspaceError(c, err)
+		return
+	}
+	resp := dto.ListInvitationsResponse{Items: make([]dto.InvitationResponse, len(list))}
+	for i, inv := range list {
+		resp.Items[i] = invitationToResponse(inv)
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// Invite godoc
+// @Summary      Invite a user to a workspace
+// @Description  Returns the invitation token once; with an e-mail it is also mailed and only that account may accept it. Needs the admin role; inviting an owner needs the owner role.
+// @Tags         workspaces
+// @Accept       json
+// @Produce      json
+// @Security     CookieAuth
+// @Param        workspace_id  path      int                          true  "Workspace ID"
+// @Param        body          body      dto.CreateInvitationRequest  true  "Invitation"
+// @Success      201           {object}  dto.CreateInvitationResponse
+// @Failure      400           {object}  map[string]string
+// @Failure      403           {object}  map[string]string
+// @Failure      404           {object}  map[string]string
+// @Failure      500           {object}  map[string]string
+// @Router       /workspaces/{workspace_id}/invitations [post]
+func (h *WorkspaceHandler) Invite(c *gin.Context) {
+	var req dto.CreateInvitationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	inv, token, err := h.svc.Invite(c.Request.Context(), auth.UserIDFromContext(c), auth.WorkspaceIDFromContext(c), service.InviteInput{
+		Email: req.Email,
+		Role:  req.Role,
+	})
+	if err != nil {
+		writeWorkspaceError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, dto.CreateInvitationResponse{InvitationResponse: invitationToResponse(inv), Token: token})
+}
+
+// RevokeInvitation godoc
+// @Summary      Revoke an invitation
+// @Description  Needs the admin role.
+// @Tags         workspaces
+// @Security     CookieAuth
+// @Param        workspace_id  path  int  true  "Workspace ID"
+// @Param        id            path  int  true  "Invitation ID"
+// @Success      204
+// @Failure      400  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /workspaces/{workspace_id}/invitations/{id} [delete]
+func (h *WorkspaceHandler) RevokeInvitation(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+	if err := h.svc.RevokeInvitation(c.Request.Context(), auth.WorkspaceIDFromContext(c), id); err != nil {
+		writeWorkspaceError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// AcceptInvitation godoc
+// @Summary      Accept a workspace invitation
+// @Description  Adds the current user to the workspace with the invited role.
+// @Tags         workspaces
+// @Accept       json
+// @Produce      json
+// @Security     CookieAuth
+// @Param        body  body      dto.AcceptInvitationRequest  true  "Invitation token"
+// @Success      200   {object}  dto.WorkspaceResponse
+// @Failure      400   {object}  map[string]string
+// @Failure      403   {object}  map[string]string
+// @Failure      409   {object}  map[string]string
+// @Failure      500   {object}  map[string]string
+// @Router       /workspaces/invitations/accept [post]
+func (h *WorkspaceHandler) AcceptInvitation(c *gin.Context) {
+	var req dto.AcceptInvitationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	w, err := h.svc.AcceptInvitation(c.Request.Context(), auth.UserIDFromContext(c), req.Token)
+	if err != nil {
+		writeWorkspaceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, workspaceToResponse(w))
+}
+
+func writeWorkspaceError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrNotFound), errors.Is(err, service.ErrNotWorkspaceMember):
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	case errors.Is(err, service.ErrMemberNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrForbidden), errors.Is(err, service.ErrInvitationEmailMismatch):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrLastWorkspaceOwner), errors.Is(err, service.ErrAlreadyWorkspaceMember):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidWorkspaceName),
+		errors.Is(err, service.ErrInvalidWorkspaceRole),
+		errors.Is(err, service.ErrPersonalWorkspace),
+		errors.Is(err, service.ErrInvalidInvitation),
+		errors.Is(err, service.ErrInvalidInvitationAddress):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+func workspaceToResponse(w dom.Workspace) dto.WorkspaceResponse {
+	return dto.WorkspaceResponse{
+		ID:        w.ID,
+		Name:      w.Name,
+		Personal:  w.Personal(),
+		Role:      w.Role,
+		CreatedAt: w.CreatedAt,
+		UpdatedAt: w.UpdatedAt,
+	}
+}
+
+func memberToResponse(m dom.WorkspaceMember) dto.WorkspaceMemberResponse {
+	return dto.WorkspaceMemberResponse{
+		UserID:    m.UserID,
+		Username:  m.Username,
+		Email:     m.Email,
+		Role:      m.Role,
+		CreatedAt: m.CreatedAt,
+	}
+}
+
+func invitationToResponse(inv dom.WorkspaceInvitation) dto.InvitationResponse {
+	return dto.InvitationResponse{
+		ID:        inv.ID,
+		Email:     inv.Email,
+		Role:      inv.Role,
+		InvitedBy: inv.InvitedBy,
+		ExpiresAt: inv.ExpiresAt,
+		CreatedAt: inv.CreatedAt,
+	}
+}
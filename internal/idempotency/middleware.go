@@ -0,0 +1,146 @@
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"Worker/internal/auth"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// HeaderKey is the request header carrying the client's key.
+	HeaderKey = "Idempotency-Key"
+	// HeaderReplayed marks a response served from the store.
+	HeaderReplayed = "Idempotent-Replayed"
+
+	maxKeyLen = 255
+	// maxBodyBytes caps the body read for the fingerprint; it is as large as the
+	// largest body a data route takes, a todo import.
+	maxBodyBytes = 10 << 20
+)
+
+// replayedHeaders are the response headers kept with a stored response.
+var replayedHeaders = []string{"Content-Type", "ETag", "Location"}
+
+// Middleware makes POST requests carrying an Idempotency-Key safe to retry. The
+// first request with a key runs and its response is stored per user and
+// workspace; repeats with the same method, path and body get that response back.
+// A repeat while the first is still running gets 409, a key reused for a
+// different request 422. 5xx responses are not stored, so the client may retry
+// them. Use it after auth.RequireSession and, on workspace routes, after
+// auth.RequireWorkspace. If Redis is unavailable, requests run unprotected.
+func Middleware(s *Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := strings.TrimSpace(c.GetHeader(HeaderKey))
+		if c.Request.Method != http.MethodPost || key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxKeyLen {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Key must be at most 255 characters"})
+			return
+		}
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxBodyBytes))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{"error": "request body too large"})
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "cannot read request body"})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		// The same key may be used in two workspaces: the path does not tell them
+		// apart when X-Workspace-ID picks the workspace.
+		key = strconv.FormatInt(auth.UserIDFromContext(c), 10) + ":" +
+			strconv.FormatInt(auth.WorkspaceIDFromContext(c), 10) + ":" + key
+		fp := fingerprint(c.Request, body)
+		// The response is stored even if the client has gone: that is when it retries.
+		ctx := context.WithoutCancel(c.Request.Context())
+		prev, err := s.Begin(ctx, key, fp)
+		if err != nil {
+			log.Printf("idempotency: %v", err)
+			c.Next()
+			return
+		}
+		if prev != nil {
+			switch {
+			case prev.Fingerprint != fp:
+				c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "Idempotency-Key was already used for a different request"})
+			case !prev.Done:
+				c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "a request with this Idempotency-Key is still in progress"})
+			default:
+				replay(c, prev)
+			}
+			return
+		}
+
+		rec := &recorder{ResponseWriter: c.Writer}
+		c.Writer = rec
+		c.Next()
+
+		status := rec.Status()
+		if status >= http.StatusInternalServerError {
+			err = s.Release(ctx, key)
+		} else {
+			header := make(map[string]string)
+			for _, h := range replayedHeaders {
+				if v := rec.Header().Get(h); v != "" {
+					header[h] = v
+				}
+			}
+			err = s.Complete(ctx, key, Record{Fingerprint: fp, Status: status, Header: header, Body: rec.body.Bytes()})
+		}
+		if err != nil {
+			log.Printf("idempotency: %v", err)
+		}
+	}
+}
+
+// fingerprint identifies a request by method, path with query and body.
+func fingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(r.Method + " " + r.URL.RequestURI() + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// replay writes a stored response.
+func replay(c *gin.Context, rec *Record) {
+	for k, v := range rec.Header {
+		c.Header(k, v)
+	}
+	c.Header(HeaderReplayed, "true")
+	c.Writer.WriteHeader(rec.Status)
+	c.Writer.WriteHeaderNow()
+	_, _ = c.Writer.Write(rec.Body)
+	c.Abort()
+}
+
+// recorder tees the response body so it can be stored.
+type recorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *recorder) WriteString(s string) (int, error) {
+	r.body.WriteString(s)
+	return r.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,89 @@
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const keyPrefix = "idem:"
+
+// Record is what the store keeps under a key: the fingerprint of the request
+// that claimed it and, once that request has finished, its response.
+type Record struct {
+	Fingerprint string            `json:"fp"`
+	Done        bool              `json:"done"`
+	Status      int               `json:"status,omitempty"`
+	Header      map[string]string `json:"header,omitempty"`
+	Body        []byte            `json:"body,omitempty"`
+}
+
+// Store keeps idempotency keys in Redis as idem:<userID>:<workspaceID>:<key> JSON records.
+type Store struct {
+	rdb     *redis.Client
+	ttl     time.Duration
+	lockTTL time.Duration
+}
+
+// NewStore returns a new Store. Responses are kept for ttl; a request in flight
+// holds its key for at most lockTTL, so a crashed request does not block it forever.
+func NewStore(rdb *redis.Client, ttl, lockTTL time.Duration) *Store {
+	if ttl <= 0 {
+		ttl = 24 * time.Hour
+	}
+	if lockTTL <= 0 {
+		lockTTL = time.Minute
+	}
+	return &Store{rdb: rdb, ttl: ttl, lockTTL: lockTTL}
+}
+
+// Begin claims key for a request with the given fingerprint. It returns nil if
+// the claim succeeded and the request should run, or the record of the request
+// that got the key first.
+func (s *Store) Begin(ctx context.Context, key, fingerprint string) (*Record, error) {
+	pending, err := json.Marshal(Record{Fingerprint: fingerprint})
+	if err != nil {
+		return nil, err
+	}
+	// The second round covers a record that expired between SETNX and GET.
+	for range 2 {
+		ok, err := s.rdb.SetNX(ctx, keyPrefix+key, pending, s.lockTTL).Result()
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return nil, nil
+		}
+		b, err := s.rdb.Get(ctx, keyPrefix+key).Bytes()
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		var rec Record
+		if err := json.Unmarshal(b, &rec); err != nil {
+			return nil, err
+		}
+		return &rec, nil
+	}
+	return nil, errors.New("idempotency key keeps expiring")
+}
+
+// Complete stores the response of the request that claimed key.
+func (s *Store) Complete(ctx context.Context, key string, rec Record) error {
+	rec.Done = true
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	return s.rdb.Set(ctx, keyPrefix+key, b, s.ttl).Err()
+}
+
+// Release frees key without a response, so the request may be retried.
+func (s *Store) Release(ctx context.Context, key string) error {
+	return s.rdb.Del(ctx, keyPrefix+key).Err()
+}
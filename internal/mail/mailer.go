@@ -0,0 +1,69 @@
+// Package mail sends transactional e-mail (password resets). Real providers plug
+// in by implementing Mailer; LogMailer and FileMailer are stand-ins for
+// development and tests.
+package mail
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Message is a plain-text e-mail.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers messages.
+type Mailer interface {
+	Send(ctx context.Context, m Message) error
+}
+
+// LogMailer writes messages to the standard logger.
+type LogMailer struct{}
+
+// NewLogMailer returns a LogMailer.
+func NewLogMailer() *LogMailer { return &LogMailer{} }
+
+func (LogMailer) Send(_ context.Context, m Message) error {
+	log.Printf("mail to=%s subject=%q\n%s", m.To, m.Subject, m.Body)
+	return nil
+}
+
+// FileMailer writes each message as an .eml file into a directory.
+type FileMailer struct {
+	dir  string
+	from string
+}
+
+// NewFileMailer returns a FileMailer writing into dir, which is created if needed.
+func NewFileMailer(dir, from string) (*FileMailer, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("mail dir: %w", err)
+	}
+	return &FileMailer{dir: dir, from: from}, nil
+}
+
+func (f *FileMailer) Send(_ context.Context, m Message) error {
+	now := time.Now().UTC()
+	name := fmt.Sprintf("%s-%s.eml", now.Format("20060102T150405.000000000"), sanitizeFileName(m.To))
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\nTo: %s\r\nSubject: %s\r\nDate: %s\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n%s\r\n",
+		f.from, m.To, m.Subject, now.Format(time.RFC1123Z), m.Body)
+	return os.WriteFile(filepath.Join(f.dir, name), []byte(b.String()), 0o600)
+}
+
+func sanitizeFileName(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '@' || r == '.' || r == '-' || r == '_' || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') || ('0' <= r && r <= '9') {
+			return r
+		}
+		return '_'
+	}, s)
+}
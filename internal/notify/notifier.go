@@ -0,0 +1,65 @@
+// Package notify delivers reminders to users. Delivery channels (e-mail, push,
+// chat) plug in by implementing Notifier.
+package notify
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	dom "Worker/internal/domain"
+)
+
+// Notifier delivers one reminder. An error makes the worker retry it later.
+type Notifier interface {
+	Notify(ctx context.Context, r dom.Reminder) error
+}
+
+// LogNotifier writes reminders to a logger; it is the default channel.
+type LogNotifier struct {
+	logger *log.Logger
+}
+
+// NewLogNotifier returns a LogNotifier. If logger is nil, the standard logger is used.
+func NewLogNotifier(logger *log.Logger) *LogNotifier {
+	if logger == nil {
+		logger = log.Default()
+	}
+	return &LogNotifier{logger: logger}
+}
+
+func (n *LogNotifier) Notify(_ context.Context, r dom.Reminder) error {
+	n.logger.Printf("reminder: user=%d todo=%d %q is due at %s (in %s)",
+		r.UserID, r.TodoID, r.Title, r.DueAt.UTC().Format("2006-01-02 15:04 MST"), r.BeforeDue)
+	return nil
+}
+
+// MemoryNotifier records reminders in memory, for tests and local debugging.
+type MemoryNotifier struct {
+	mu   sync.Mutex
+	sent []dom.Reminder
+	// Err, if set, is returned instead of recording the reminder.
+	Err error
+}
+
+// NewMemoryNotifier returns an empty MemoryNotifier.
+func NewMemoryNotifier() *MemoryNotifier {
+	return &MemoryNotifier{}
+}
+
+func (n *MemoryNotifier) Notify(_ context.Context, r dom.Reminder) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.Err != nil {
+		return n.Err
+	}
+	n.sent = append(n.sent, r)
+	return nil
+}
+
+// Sent returns a copy of the reminders delivered so far, in delivery order.
+func (n *MemoryNotifier) Sent() []dom.Reminder {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return append([]dom.Reminder(nil), n.sent...)
+}
@@ -0,0 +1,118 @@
+// Package ratelimit provides Redis-backed request rate limiting (GCRA) and
+// progressive lockout after repeated failures. State lives in Redis so limits
+// hold across API instances.
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const keyPrefix = "rl:"
+
+// Limit allows Count requests per Period, all of which may arrive at once.
+// The zero Limit disables limiting.
+type Limit struct {
+	Count  int
+	Period time.Duration
+}
+
+// Disabled reports whether the limit lets everything through.
+func (l Limit) Disabled() bool { return l.Count <= 0 || l.Period <= 0 }
+
+func (l Limit) String() string {
+	if l.Disabled() {
+		return "off"
+	}
+	return fmt.Sprintf("%d/%s", l.Count, l.Period)
+}
+
+// ParseLimit parses "N/period", e.g. "10/1m" or "300/1h"; "0" or "off" disables.
+func ParseLimit(s string) (Limit, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "0" || s == "off" {
+		return Limit{}, nil
+	}
+	n, p, ok := strings.Cut(s, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("rate limit %q: want N/period, e.g. 10/1m", s)
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(n))
+	if err != nil || count < 0 {
+		return Limit{}, fmt.Errorf("rate limit %q: bad count", s)
+	}
+	period, err := time.ParseDuration(strings.TrimSpace(p))
+	if err != nil || period <= 0 {
+		return Limit{}, fmt.Errorf("rate limit %q: bad period", s)
+	}
+	return Limit{Count: count, Period: period}, nil
+}
+
+// Result is the outcome of one Allow call.
+type Result struct {
+	Allowed    bool
+	Remaining  int
+	RetryAfter time.Duration // when not allowed, how long until the next request fits
+}
+
+// Limiter implements the generic cell rate algorithm: each key stores only a
+// "theoretical arrival time", so the window slides smoothly without counters
+// per interval.
+type Limiter struct {
+	rdb *redis.Client
+}
+
+// NewLimiter returns a new Limiter.
+func NewLimiter(rdb *redis.Client) *Limiter {
+	return &Limiter{rdb: rdb}
+}
+
+// gcraScript takes the emission interval and burst tolerance in microseconds
+// and returns {allowed, remaining, retry_after_us}. It reads the clock from
+// Redis so API instances with skewed clocks agree. Times are formatted
+// explicitly because Lua would print 16-digit numbers in exponent form.
+var gcraScript = redis.NewScript(`
+local emission = tonumber(ARGV[1])
+local tolerance = tonumber(ARGV[2])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000000 + tonumber(t[2])
+local tat = tonumber(redis.call('GET', KEYS[1]) or now)
+if tat < now then tat = now end
+local new_tat = tat + emission
+local allow_at = new_tat - tolerance
+if allow_at > now then
+  return {0, 0, allow_at - now}
+end
+redis.call('SET', KEYS[1], string.format('%.0f', new_tat), 'PX', math.ceil((new_tat - now) / 1000))
+return {1, math.floor((tolerance - (new_tat - now)) / emission), 0}
+`)
+
+// Allow records one request for key under limit.
+func (l *Limiter) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	if limit.Disabled() {
+		return Result{Allowed: true, Remaining: -1}, nil
+	}
+	emission := limit.Period.Microseconds() / int64(limit.Count)
+	if emission <= 0 {
+		emission = 1
+	}
+	tolerance := emission * int64(limit.Count)
+	res, err := gcraScript.Run(ctx, l.rdb, []string{keyPrefix + key}, emission, tolerance).Int64Slice()
+	if err != nil {
+		return Result{}, err
+	}
+	if len(res) != 3 {
+		return Result{}, errors.New("ratelimit: unexpected script result")
+	}
+	return Result{
+		Allowed:    res[0] == 1,
+		Remaining:  int(res[1]),
+		RetryAfter: time.Duration(res[2]) * time.Microsecond,
+	}, nil
+}
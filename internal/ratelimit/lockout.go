@@ -0,0 +1,82 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	lockoutFailsPrefix = "lockout:fails:"
+	lockoutLockPrefix  = "lockout:lock:"
+)
+
+// LockoutPolicy locks a key after Threshold consecutive failures, for Base at
+// first and twice as long after every further failure, up to Max. Failures are
+// forgotten after Max without any.
+type LockoutPolicy struct {
+	Threshold int
+	Base      time.Duration
+	Max       time.Duration
+}
+
+// Lockout tracks failures per key in Redis.
+type Lockout struct {
+	rdb    *redis.Client
+	policy LockoutPolicy
+}
+
+// NewLockout returns a new Lockout. A policy with Threshold 0 never locks.
+func NewLockout(rdb *redis.Client, policy LockoutPolicy) *Lockout {
+	return &Lockout{rdb: rdb, policy: policy}
+}
+
+// Locked returns how long key stays locked; 0 if it is not.
+func (l *Lockout) Locked(ctx context.Context, key string) (time.Duration, error) {
+	if l.policy.Threshold <= 0 {
+		return 0, nil
+	}
+	d, err := l.rdb.PTTL(ctx, lockoutLockPrefix+key).Result()
+	if err != nil || d < 0 {
+		return 0, err
+	}
+	return d, nil
+}
+
+// Fail records a failure and returns the lock it caused, if any.
+func (l *Lockout) Fail(ctx context.Context, key string) (time.Duration, error) {
+	if l.policy.Threshold <= 0 {
+		return 0, nil
+	}
+	var incr *redis.IntCmd
+	_, err := l.rdb.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		incr = p.Incr(ctx, lockoutFailsPrefix+key)
+		p.Expire(ctx, lockoutFailsPrefix+key, l.policy.Max)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	over := incr.Val() - int64(l.policy.Threshold)
+	if over < 0 {
+		return 0, nil
+	}
+	d := l.policy.Base
+	for i := int64(0); i < over && d < l.policy.Max; i++ {
+		d *= 2
+	}
+	d = min(d, l.policy.Max)
+	if err := l.rdb.Set(ctx, lockoutLockPrefix+key, 1, d).Err(); err != nil {
+		return 0, err
+	}
+	return d, nil
+}
+
+// Reset forgets the failures of key after a success. An active lock stays.
+func (l *Lockout) Reset(ctx context.Context, key string) error {
+	if l.policy.Threshold <= 0 {
+		return nil
+	}
+	return l.rdb.Del(ctx, lockoutFailsPrefix+key).Err()
+}
@@ -0,0 +1,58 @@
+package ratelimit
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// LoginGuard throttles password attempts per username and locks a username
+// out progressively after repeated failures. Per-IP limits are applied
+// separately with Middleware. A nil *LoginGuard allows everything.
+type LoginGuard struct {
+	limiter *Limiter
+	lockout *Lockout
+	perUser Limit
+}
+
+// NewLoginGuard returns a new LoginGuard.
+func NewLoginGuard(limiter *Limiter, lockout *Lockout, perUser Limit) *LoginGuard {
+	return &LoginGuard{limiter: limiter, lockout: lockout, perUser: perUser}
+}
+
+func loginKey(username string) string {
+	return "login:user:" + strings.ToLower(strings.TrimSpace(username))
+}
+
+// Check is called before credentials are verified. A non-zero result means
+// the attempt must be refused and may be retried after that long.
+func (g *LoginGuard) Check(ctx context.Context, username string) (time.Duration, error) {
+	if g == nil {
+		return 0, nil
+	}
+	key := loginKey(username)
+	if d, err := g.lockout.Locked(ctx, key); err != nil || d > 0 {
+		return d, err
+	}
+	res, err := g.limiter.Allow(ctx, key, g.perUser)
+	if err != nil || res.Allowed {
+		return 0, err
+	}
+	return res.RetryAfter, nil
+}
+
+// Failure records a failed attempt and returns the lockout it triggered, if any.
+func (g *LoginGuard) Failure(ctx context.Context, username string) (time.Duration, error) {
+	if g == nil {
+		return 0, nil
+	}
+	return g.lockout.Fail(ctx, loginKey(username))
+}
+
+// Success clears the failure count after a completed login.
+func (g *LoginGuard) Success(ctx context.Context, username string) error {
+	if g == nil {
+		return nil
+	}
+	return g.lockout.Reset(ctx, loginKey(username))
+}
@@ -0,0 +1,59 @@
+package ratelimit
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"Worker/internal/auth"
+
+	"github.com/gin-gonic/gin"
+)
+
+// KeyFunc picks the bucket a request counts against.
+type KeyFunc func(c *gin.Context) string
+
+// ByIP keys requests by client IP.
+func ByIP(c *gin.Context) string { return "ip:" + c.ClientIP() }
+
+// ByUser keys requests by the authenticated user, or by IP before
+// authentication. Use it after auth.RequireSession.
+func ByUser(c *gin.Context) string {
+	if id := auth.UserIDFromContext(c); id != 0 {
+		return "user:" + strconv.FormatInt(id, 10)
+	}
+	return ByIP(c)
+}
+
+// Middleware limits requests per key under limit. name separates buckets of
+// different middlewares that share a key. If Redis is unavailable, requests
+// are let through: an outage should not take the API down with it.
+func Middleware(l *Limiter, name string, limit Limit, key KeyFunc) gin.HandlerFunc {
+	if limit.Disabled() {
+		return func(c *gin.Context) { c.Next() }
+	}
+	return func(c *gin.Context) {
+		res, err := l.Allow(c.Request.Context(), name+":"+key(c), limit)
+		if err != nil {
+			log.Printf("ratelimit %s: %v", name, err)
+			c.Next()
+			return
+		}
+		c.Header("X-RateLimit-Limit", strconv.Itoa(limit.Count))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		if !res.Allowed {
+			TooManyRequests(c, res.RetryAfter)
+			return
+		}
+		c.Next()
+	}
+}
+
+// TooManyRequests aborts with 429 and a Retry-After header in whole seconds.
+func TooManyRequests(c *gin.Context, retryAfter time.Duration) {
+	secs := max(int(math.Ceil(retryAfter.Seconds())), 1)
+	c.Header("Retry-After", strconv.Itoa(secs))
+	c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "too many requests, retry later"})
+}
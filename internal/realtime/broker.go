@@ -0,0 +1,230 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	dom "Worker/internal/domain"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Event types pushed to clients.
+const (
+	EventCreated   = "created"
+	EventUpdated   = "updated"
+	EventCompleted = "completed"
+	EventDeleted   = "deleted"
+	// EventReset tells a resuming client that events it missed are gone from
+	// the backlog and it has to reload its todos.
+	EventReset = "reset"
+)
+
+const (
+	streamKeyPrefix = "events:"
+	channelPrefix   = "events:live:"
+)
+
+// Event is one change of a todo as seen by the users it concerns. ID is the
+// Redis stream entry ID ("<ms>-<seq>"); it grows monotonically per user and is
+// what clients send back as Last-Event-ID.
+type Event struct {
+	ID          string    `json:"-"`
+	Type        string    `json:"type"`
+	WorkspaceID int64     `json:"workspace_id"`
+	TodoID      int64     `json:"todo_id"`
+	ActorID     int64     `json:"actor_id"`
+	Todo        *dom.Todo `json:"todo,omitempty"` // not set for deleted
+	At          time.Time `json:"at"`
+}
+
+// Broker fans todo events out to API replicas. Each user has a capped Redis
+// stream events:<userID> holding the recent events for resuming, and a pub/sub
+// channel events:live:<userID> for live delivery.
+type Broker struct {
+	rdb       *redis.Client
+	backlog   int
+	retention time.Duration
+}
+
+// NewBroker returns a new Broker keeping about backlog events per user for
+// retention after the user's last event.
+func NewBroker(rdb *redis.Client, backlog int, retention time.Duration) *Broker {
+	if backlog <= 0 {
+		backlog = 1000
+	}
+	if retention <= 0 {
+		retention = 24 * time.Hour
+	}
+	return &Broker{rdb: rdb, backlog: backlog, retention: retention}
+}
+
+// publishScript appends the event to the user's stream and publishes it with
+// its new ID, so live subscribers and the backlog agree on IDs.
+var publishScript = redis.NewScript(`
+local id = redis.call('XADD', KEYS[1], 'MAXLEN', '~', ARGV[1], '*', 'data', ARGV[2])
+redis.call('EXPIRE', KEYS[1], ARGV[3])
+redis.call('PUBLISH', ARGV[4], id .. ' ' .. ARGV[2])
+return id
+`)
+
+// Publish delivers e to every distinct user in userIDs.
+func (b *Broker) Publish(ctx context.Context, userIDs []int64, e Event) error {
+	if e.Todo != nil {
+		t := *e.Todo
+		t.Permission = dom.PermissionNone // differs per receiver
+		e.Todo = &t
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	seen := make(map[int64]bool, len(userIDs))
+	pipe := b.rdb.Pipeline()
+	for _, id := range userIDs {
+		if seen[id] || id == 0 {
+			continue
+		}
+		seen[id] = true
+		u := strconv.FormatInt(id, 10)
+		publishScript.Eval(ctx, pipe, []string{streamKeyPrefix + u},
+			b.backlog, data, int(b.retention.Seconds()), channelPrefix+u)
+	}
+	if len(seen) == 0 {
+		return nil
+	}
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// Subscription is a live feed of one user's events.
+type Subscription struct {
+	C      <-chan Event
+	pubsub *redis.PubSub
+	cancel context.CancelFunc
+}
+
+// Close stops the feed.
+func (s *Subscription) Close() error {
+	s.cancel()
+	return s.pubsub.Close()
+}
+
+// Subscribe starts a feed of the user's events. With lastID it first replays
+// the events after lastID from the backlog, or sends EventReset if some of
+// them are no longer there.
+func (b *Broker) Subscribe(ctx context.Context, userID int64, lastID string) (*Subscription, error) {
+	u := strconv.FormatInt(userID, 10)
+	// Subscribe before reading the backlog so nothing falls between the two.
+	ps := b.rdb.Subscribe(ctx, channelPrefix+u)
+	if _, err := ps.Receive(ctx); err != nil {
+		_ = ps.Close()
+		return nil, err
+	}
+	var backlog []Event
+	if lastID != "" {
+		var err error
+		if backlog, err = b.since(ctx, streamKeyPrefix+u, lastID); err != nil {
+			_ = ps.Close()
+			return nil, err
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	ch := make(chan Event, 16)
+	go func() {
+		defer close(ch)
+		last := lastID
+		send := func(e Event) bool {
+			select {
+			case ch <- e:
+				if e.ID != "" {
+					last = e.ID
+				}
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		for _, e := range backlog {
+			if !send(e) {
+				return
+			}
+		}
+		live := ps.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-live:
+				if !ok {
+					return
+				}
+				id, data, _ := strings.Cut(msg.Payload, " ")
+				if last != "" && !idAfter(id, last) {
+					continue // already sent from the backlog
+				}
+				var e Event
+				if json.Unmarshal([]byte(data), &e) != nil {
+					continue
+				}
+				e.ID = id
+				if !send(e) {
+					return
+				}
+			}
+		}
+	}()
+	return &Subscription{C: ch, pubsub: ps, cancel: cancel}, nil
+}
+
+// since returns the events after lastID. When lastID is no longer the oldest
+// entry it can be compared with (trimmed, expired or never issued), it returns
+// a single EventReset instead.
+func (b *Broker) since(ctx context.Context, key, lastID string) ([]Event, error) {
+	if _, _, ok := parseID(lastID); !ok {
+		return []Event{{Type: EventReset, At: time.Now().UTC()}}, nil
+	}
+	entries, err := b.rdb.XRange(ctx, key, lastID, "+").Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 || entries[0].ID != lastID {
+		return []Event{{Type: EventReset, At: time.Now().UTC()}}, nil
+	}
+	out := make([]Event, 0, len(entries)-1)
+	for _, en := range entries[1:] {
+		data, _ := en.Values["data"].(string)
+		var e Event
+		if json.Unmarshal([]byte(data), &e) != nil {
+			continue
+		}
+		e.ID = en.ID
+		out = append(out, e)
+	}
+	return out, nil
+}
+
+// idAfter reports whether stream ID a comes after b.
+func idAfter(a, b string) bool {
+	am, as, ok1 := parseID(a)
+	bm, bs, ok2 := parseID(b)
+	if !ok1 || !ok2 {
+		return true
+	}
+	return am > bm || am == bm && as > bs
+}
+
+// parseID splits a stream ID "<ms>-<seq>".
+func parseID(id string) (ms, seq uint64, ok bool) {
+	m, s, found := strings.Cut(id, "-")
+	if !found {
+		return 0, 0, false
+	}
+	ms, err1 := strconv.ParseUint(m, 10, 64)
+	seq, err2 := strconv.ParseUint(s, 10, 64)
+	return ms, seq, err1 == nil && err2 == nil
+}
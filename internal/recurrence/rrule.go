@@ -0,0 +1,443 @@
+// Package recurrence implements the subset of RFC 5545 recurrence rules (RRULE)
+// used by repeating todos: FREQ, INTERVAL, BYDAY, BYMONTHDAY, COUNT and UNTIL.
+//
+// Occurrences are computed on the wall clock of DTSTART's location, so a todo due
+// at 09:00 stays at 09:00 across DST changes. The package has no dependencies
+// beyond the standard library.
+package recurrence
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Freq is the FREQ rule part.
+type Freq int
+
+const (
+	Daily Freq = iota + 1
+	Weekly
+	Monthly
+	Yearly
+)
+
+var freqNames = map[string]Freq{"DAILY": Daily, "WEEKLY": Weekly, "MONTHLY": Monthly, "YEARLY": Yearly}
+
+func (f Freq) String() string {
+	for name, v := range freqNames {
+		if v == f {
+			return name
+		}
+	}
+	return "UNKNOWN"
+}
+
+// WeekdayNum is one BYDAY entry: a weekday with an optional ordinal
+// (1MO = first Monday, -1FR = last Friday; N = 0 means every such weekday).
+type WeekdayNum struct {
+	N   int
+	Day time.Weekday
+}
+
+var dayNames = map[string]time.Weekday{
+	"MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday, "TH": time.Thursday,
+	"FR": time.Friday, "SA": time.Saturday, "SU": time.Sunday,
+}
+
+var dayCodes = [...]string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+func (w WeekdayNum) String() string {
+	if w.N == 0 {
+		return dayCodes[w.Day]
+	}
+	return strconv.Itoa(w.N) + dayCodes[w.Day]
+}
+
+// Rule is a parsed recurrence rule.
+type Rule struct {
+	Freq       Freq
+	Interval   int // >= 1
+	ByDay      []WeekdayNum
+	ByMonthDay []int // 1..31 or -31..-1 (counted from the end of the month)
+	Count      int   // 0 = unlimited
+	Until      *time.Time
+	// UntilDate is set when UNTIL was a DATE value: it then includes every
+	// occurrence on that calendar day in DTSTART's location.
+	UntilDate bool
+}
+
+// ErrInvalidRule wraps every parse error.
+var ErrInvalidRule = errors.New("invalid recurrence rule")
+
+// maxPeriods bounds the search for the next occurrence, so a rule that can never
+// match (e.g. BYMONTHDAY=31 with FREQ=MONTHLY;INTERVAL=2 from a 30-day month)
+// ends instead of looping forever.
+const maxPeriods = 10000
+
+// Parse parses an RRULE value such as "FREQ=WEEKLY;BYDAY=MO,WE;COUNT=10".
+// An optional "RRULE:" prefix is accepted. Unsupported rule parts are rejected.
+func Parse(s string) (Rule, error) {
+	s = strings.TrimSpace(s)
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "RRULE:"), "rrule:")
+	if s == "" {
+		return Rule{}, fmt.Errorf("%w: empty", ErrInvalidRule)
+	}
+	r := Rule{Interval: 1}
+	seen := map[string]bool{}
+	for _, part := range strings.Split(s, ";") {
+		name, value, ok := strings.Cut(part, "=")
+		name = strings.ToUpper(strings.TrimSpace(name))
+		value = strings.ToUpper(strings.TrimSpace(value))
+		if !ok || name == "" || value == "" {
+			return Rule{}, fmt.Errorf("%w: malformed part %q", ErrInvalidRule, part)
+		}
+		if seen[name] {
+			return Rule{}, fmt.Errorf("%w: %s given twice", ErrInvalidRule, name)
+		}
+		seen[name] = true
+		var err error
+		switch name {
+		case "FREQ":
+			f, ok := freqNames[value]
+			if !ok {
+				err = fmt.Errorf("unsupported FREQ %q", value)
+			}
+			r.Freq = f
+		case "INTERVAL":
+			r.Interval, err = positiveInt(value)
+		case "COUNT":
+			r.Count, err = positiveInt(value)
+		case "UNTIL":
+			r.Until, r.UntilDate, err = parseUntil(value)
+		case "BYDAY":
+			r.ByDay, err = parseByDay(value)
+		case "BYMONTHDAY":
+			r.ByMonthDay, err = parseByMonthDay(value)
+		case "WKST":
+			// Weeks always start on Monday (the RFC default); other values are rejected.
+			if value != "MO" {
+				err = fmt.Errorf("only WKST=MO is supported")
+			}
+		default:
+			err = fmt.Errorf("unsupported rule part %s", name)
+		}
+		if err != nil {
+			return Rule{}, fmt.Errorf("%w: %v", ErrInvalidRule, err)
+		}
+	}
+	if err := r.validate(); err != nil {
+		return Rule{}, fmt.Errorf("%w: %v", ErrInvalidRule, err)
+	}
+	return r, nil
+}
+
+func (r Rule) validate() error {
+	if r.Freq == 0 {
+		return errors.New("FREQ is required")
+	}
+	if r.Count > 0 && r.Until != nil {
+		return errors.New("COUNT and UNTIL cannot be combined")
+	}
+	for _, d := range r.ByDay {
+		if d.N != 0 && r.Freq != Monthly {
+			return errors.New("BYDAY ordinals (e.g. 1MO) are only supported with FREQ=MONTHLY")
+		}
+	}
+	if len(r.ByDay) > 0 && r.Freq == Yearly {
+		return errors.New("BYDAY is not supported with FREQ=YEARLY")
+	}
+	if len(r.ByMonthDay) > 0 && r.Freq == Weekly {
+		return errors.New("BYMONTHDAY cannot be used with FREQ=WEEKLY")
+	}
+	return nil
+}
+
+// String renders the rule in canonical form (no "RRULE:" prefix).
+func (r Rule) String() string {
+	parts := []string{"FREQ=" + r.Freq.String()}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, len(r.ByDay))
+		for i, d := range r.ByDay {
+			days[i] = d.String()
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		days := make([]string, len(r.ByMonthDay))
+		for i, d := range r.ByMonthDay {
+			days[i] = strconv.Itoa(d)
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if r.Until != nil {
+		if r.UntilDate {
+			parts = append(parts, "UNTIL="+r.Until.Format("20060102"))
+		} else {
+			parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+		}
+	}
+	return strings.Join(parts, ";")
+}
+
+// Next returns the first occurrence strictly after after, for a series that starts
+// at dtstart. dtstart is the first occurrence and fixes the wall-clock time and the
+// location. ok is false when the series has ended (COUNT/UNTIL) or no occurrence
+// exists within a bounded search.
+func (r Rule) Next(dtstart, after time.Time) (next time.Time, ok bool) {
+	n := 0
+	for period := 0; period < maxPeriods; period++ {
+		for _, t := range r.candidates(dtstart, period) {
+			if t.Before(dtstart) {
+				continue
+			}
+			if r.pastUntil(t, dtstart.Location()) {
+				return time.Time{}, false
+			}
+			n++
+			if r.Count > 0 && n > r.Count {
+				return time.Time{}, false
+			}
+			if t.After(after) {
+				return t, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+func (r Rule) pastUntil(t time.Time, loc *time.Location) bool {
+	if r.Until == nil {
+		return false
+	}
+	if r.UntilDate {
+		y, m, d := t.In(loc).Date()
+		uy, um, ud := r.Until.Date()
+		return time.Date(y, m, d, 0, 0, 0, 0, time.UTC).After(time.Date(uy, um, ud, 0, 0, 0, 0, time.UTC))
+	}
+	return t.After(*r.Until)
+}
+
+// candidates returns the occurrences of the period-th period (0 = the period of
+// dtstart), in chronological order. Some may precede dtstart.
+func (r Rule) candidates(dtstart time.Time, period int) []time.Time {
+	loc := dtstart.Location()
+	y, m, d := dtstart.Date()
+	hh, mm, ss := dtstart.Clock()
+	var days []civilDate
+	switch r.Freq {
+	case Daily:
+		day := civilDate{y, m, d}.addDays(period * r.Interval)
+		if r.matchesDay(day) {
+			days = append(days, day)
+		}
+	case Weekly:
+		// Monday of dtstart's week, then whole weeks.
+		offset := (int(dtstart.Weekday()) + 6) % 7
+		monday := civilDate{y, m, d}.addDays(-offset + period*r.Interval*7)
+		if len(r.ByDay) == 0 {
+			days = append(days, monday.addDays(offset))
+		}
+		for i := 0; i < 7 && len(r.ByDay) > 0; i++ {
+			day := monday.addDays(i)
+			if r.hasWeekday(day.weekday()) {
+				days = append(days, day)
+			}
+		}
+	case Monthly:
+		first := time.Date(y, m+time.Month(period*r.Interval), 1, 0, 0, 0, 0, time.UTC)
+		days = r.monthDays(first.Year(), first.Month(), d)
+	case Yearly:
+		year := y + period*r.Interval
+		if len(r.ByMonthDay) > 0 {
+			days = r.monthDays(year, m, d)
+		} else if validDate(year, m, d) {
+			// Feb 29 only repeats in leap years (RFC 5545: invalid dates are skipped).
+			days = append(days, civilDate{year, m, d})
+		}
+	}
+	out := make([]time.Time, 0, len(days))
+	for _, day := range days {
+		out = append(out, wallClock(day, hh, mm, ss, loc))
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Before(out[j]) })
+	return out
+}
+
+// monthDays returns the matching days of one month. Without BYDAY/BYMONTHDAY the
+// series repeats on dtstart's day of month, skipping months that are too short.
+func (r Rule) monthDays(year int, month time.Month, dtDay int) []civilDate {
+	last := daysIn(year, month)
+	var out []civilDate
+	if len(r.ByDay) == 0 && len(r.ByMonthDay) == 0 {
+		if dtDay <= last {
+			out = append(out, civilDate{year, month, dtDay})
+		}
+		return out
+	}
+	for day := 1; day <= last; day++ {
+		c := civilDate{year, month, day}
+		if r.matchesMonthDay(c, last) && r.matchesMonthWeekday(c, last) {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// matchesDay applies BYDAY/BYMONTHDAY as filters (FREQ=DAILY).
+func (r Rule) matchesDay(c civilDate) bool {
+	if len(r.ByDay) > 0 && !r.hasWeekday(c.weekday()) {
+		return false
+	}
+	return r.matchesMonthDay(c, daysIn(c.y, c.m))
+}
+
+func (r Rule) hasWeekday(wd time.Weekday) bool {
+	for _, d := range r.ByDay {
+		if d.Day == wd {
+			return true
+		}
+	}
+	return false
+}
+
+func (r Rule) matchesMonthDay(c civilDate, last int) bool {
+	if len(r.ByMonthDay) == 0 {
+		return true
+	}
+	for _, md := range r.ByMonthDay {
+		if md == c.d || (md < 0 && last+md+1 == c.d) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesMonthWeekday checks BYDAY inside a month: "MO" is every Monday,
+// "2MO" the second, "-1MO" the last.
+func (r Rule) matchesMonthWeekday(c civilDate, last int) bool {
+	if len(r.ByDay) == 0 {
+		return true
+	}
+	wd := c.weekday()
+	nth := (c.d-1)/7 + 1              // 1-based from the start
+	nthFromEnd := -((last-c.d)/7 + 1) // -1 = last
+	for _, d := range r.ByDay {
+		if d.Day == wd && (d.N == 0 || d.N == nth || d.N == nthFromEnd) {
+			return true
+		}
+	}
+	return false
+}
+
+// wallClock returns the instant of the given local date and time in loc following
+// RFC 5545 §3.3.5: a time skipped by a DST gap is moved forward by the length of
+// the gap, and a time that occurs twice refers to its first occurrence.
+func wallClock(c civilDate, hh, mm, ss int, loc *time.Location) time.Time {
+	naive := time.Date(c.y, c.m, c.d, hh, mm, ss, 0, time.UTC)
+	// Offset in effect a day earlier, i.e. before any transition on this date.
+	_, before := naive.Add(-24 * time.Hour).In(loc).Zone()
+	early := naive.Add(-time.Duration(before) * time.Second)
+	if sameWallClock(early.In(loc), c, hh, mm, ss) {
+		return early.In(loc)
+	}
+	if t := time.Date(c.y, c.m, c.d, hh, mm, ss, 0, loc); sameWallClock(t, c, hh, mm, ss) {
+		return t
+	}
+	// Nonexistent local time: early reads as the requested time shifted by the gap.
+	return early.In(loc)
+}
+
+func sameWallClock(t time.Time, c civilDate, hh, mm, ss int) bool {
+	y, m, d := t.Date()
+	h, mi, s := t.Clock()
+	return y == c.y && m == c.m && d == c.d && h == hh && mi == mm && s == ss
+}
+
+// civilDate is a calendar date without time or location.
+type civilDate struct {
+	y int
+	m time.Month
+	d int
+}
+
+func (c civilDate) addDays(n int) civilDate {
+	t := time.Date(c.y, c.m, c.d+n, 0, 0, 0, 0, time.UTC)
+	y, m, d := t.Date()
+	return civilDate{y, m, d}
+}
+
+func (c civilDate) weekday() time.Weekday {
+	return time.Date(c.y, c.m, c.d, 0, 0, 0, 0, time.UTC).Weekday()
+}
+
+func daysIn(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+func validDate(year int, month time.Month, day int) bool {
+	return day >= 1 && day <= daysIn(year, month)
+}
+
+func positiveInt(s string) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("%q must be a positive integer", s)
+	}
+	return n, nil
+}
+
+func parseUntil(s string) (*time.Time, bool, error) {
+	if t, err := time.Parse("20060102T150405Z", s); err == nil {
+		return &t, false, nil
+	}
+	if t, err := time.Parse("20060102", s); err == nil {
+		return &t, true, nil
+	}
+	return nil, false, fmt.Errorf("UNTIL must be YYYYMMDD or YYYYMMDDTHHMMSSZ, got %q", s)
+}
+
+func parseByDay(s string) ([]WeekdayNum, error) {
+	var out []WeekdayNum
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if len(item) < 2 {
+			return nil, fmt.Errorf("bad BYDAY value %q", item)
+		}
+		day, ok := dayNames[item[len(item)-2:]]
+		if !ok {
+			return nil, fmt.Errorf("bad BYDAY weekday %q", item)
+		}
+		w := WeekdayNum{Day: day}
+		if num := item[:len(item)-2]; num != "" {
+			n, err := strconv.Atoi(num)
+			if err != nil || n == 0 || n < -5 || n > 5 {
+				return nil, fmt.Errorf("bad BYDAY ordinal %q", item)
+			}
+			w.N = n
+		}
+		out = append(out, w)
+	}
+	return out, nil
+}
+
+func parseByMonthDay(s string) ([]int, error) {
+	var out []int
+	for _, item := range strings.Split(s, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(item))
+		if err != nil || n == 0 || n < -31 || n > 31 {
+			return nil, fmt.Errorf("bad BYMONTHDAY value %q", item)
+		}
+		out = append(out, n)
+	}
+	return out, nil
+}
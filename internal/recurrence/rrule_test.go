@@ -0,0 +1,135 @@
+package recurrence
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		rule    string
+		want    string // canonical form; "" = invalid
+		wantErr bool
+	}{
+		{rule: "FREQ=DAILY", want: "FREQ=DAILY"},
+		{rule: "RRULE:freq=weekly;interval=2;byday=mo,we", want: "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE"},
+		{rule: "FREQ=MONTHLY;BYDAY=-1FR", want: "FREQ=MONTHLY;BYDAY=-1FR"},
+		{rule: "FREQ=MONTHLY;BYMONTHDAY=1,-1", want: "FREQ=MONTHLY;BYMONTHDAY=1,-1"},
+		{rule: "FREQ=YEARLY;COUNT=3", want: "FREQ=YEARLY;COUNT=3"},
+		{rule: "FREQ=WEEKLY;UNTIL=20261231", want: "FREQ=WEEKLY;UNTIL=20261231"},
+		{rule: "FREQ=WEEKLY;UNTIL=20261231T120000Z", want: "FREQ=WEEKLY;UNTIL=20261231T120000Z"},
+		{rule: "FREQ=WEEKLY;INTERVAL=1;WKST=MO", want: "FREQ=WEEKLY"},
+		{rule: "", wantErr: true},
+		{rule: "INTERVAL=2", wantErr: true},
+		{rule: "FREQ=HOURLY", wantErr: true},
+		{rule: "FREQ=DAILY;INTERVAL=0", wantErr: true},
+		{rule: "FREQ=DAILY;COUNT=-1", wantErr: true},
+		{rule: "FREQ=DAILY;FREQ=WEEKLY", wantErr: true},
+		{rule: "FREQ=DAILY;COUNT=2;UNTIL=20261231", wantErr: true},
+		{rule: "FREQ=DAILY;UNTIL=tomorrow", wantErr: true},
+		{rule: "FREQ=WEEKLY;BYDAY=1MO", wantErr: true},
+		{rule: "FREQ=MONTHLY;BYDAY=6MO", wantErr: true},
+		{rule: "FREQ=MONTHLY;BYDAY=XX", wantErr: true},
+		{rule: "FREQ=YEARLY;BYDAY=MO", wantErr: true},
+		{rule: "FREQ=WEEKLY;BYMONTHDAY=1", wantErr: true},
+		{rule: "FREQ=MONTHLY;BYMONTHDAY=32", wantErr: true},
+		{rule: "FREQ=YEARLY;BYMONTH=2", wantErr: true},
+		{rule: "FREQ=WEEKLY;WKST=SU", wantErr: true},
+		{rule: "FREQ", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			r, err := Parse(tt.rule)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidRule) {
+					t.Fatalf("Parse(%q) error = %v, want ErrInvalidRule", tt.rule, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.rule, err)
+			}
+			if got := r.String(); got != tt.want {
+				t.Errorf("Parse(%q).String() = %q, want %q", tt.rule, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNext(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("no tzdata: %v", err)
+	}
+	utc := func(y int, m time.Month, d, hh, mm int) time.Time { return time.Date(y, m, d, hh, mm, 0, 0, time.UTC) }
+	local := func(y int, m time.Month, d, hh, mm int) time.Time { return time.Date(y, m, d, hh, mm, 0, 0, berlin) }
+
+	tests := []struct {
+		name    string
+		rule    string
+		dtstart time.Time
+		after   time.Time
+		want    time.Time // zero = the series has ended
+	}{
+		{name: "daily", rule: "FREQ=DAILY", dtstart: utc(2026, 1, 1, 9, 0), after: utc(2026, 1, 1, 9, 0), want: utc(2026, 1, 2, 9, 0)},
+		{name: "daily interval", rule: "FREQ=DAILY;INTERVAL=3", dtstart: utc(2026, 1, 1, 9, 0), after: utc(2026, 1, 2, 0, 0), want: utc(2026, 1, 4, 9, 0)},
+		{name: "daily from before dtstart", rule: "FREQ=DAILY", dtstart: utc(2026, 1, 5, 9, 0), after: utc(2026, 1, 1, 0, 0), want: utc(2026, 1, 5, 9, 0)},
+		{name: "daily byday as filter", rule: "FREQ=DAILY;BYDAY=MO,FR", dtstart: utc(2026, 1, 5, 9, 0), after: utc(2026, 1, 5, 9, 0), want: utc(2026, 1, 9, 9, 0)},
+		{name: "weekly", rule: "FREQ=WEEKLY", dtstart: utc(2026, 1, 7, 9, 0), after: utc(2026, 1, 7, 9, 0), want: utc(2026, 1, 14, 9, 0)},
+		{name: "weekly byday within week", rule: "FREQ=WEEKLY;BYDAY=MO,WE", dtstart: utc(2026, 1, 5, 9, 0), after: utc(2026, 1, 5, 9, 0), want: utc(2026, 1, 7, 9, 0)},
+		{name: "weekly byday next week", rule: "FREQ=WEEKLY;BYDAY=MO,WE", dtstart: utc(2026, 1, 5, 9, 0), after: utc(2026, 1, 7, 9, 0), want: utc(2026, 1, 12, 9, 0)},
+		{name: "biweekly byday", rule: "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO", dtstart: utc(2026, 1, 5, 9, 0), after: utc(2026, 1, 5, 9, 0), want: utc(2026, 1, 19, 9, 0)},
+		{name: "monthly same day", rule: "FREQ=MONTHLY", dtstart: utc(2026, 1, 15, 9, 0), after: utc(2026, 1, 15, 9, 0), want: utc(2026, 2, 15, 9, 0)},
+		{name: "monthly on 31st skips short months", rule: "FREQ=MONTHLY", dtstart: utc(2026, 1, 31, 9, 0), after: utc(2026, 1, 31, 9, 0), want: utc(2026, 3, 31, 9, 0)},
+		{name: "bymonthday 31 skips short months", rule: "FREQ=MONTHLY;BYMONTHDAY=31", dtstart: utc(2026, 3, 31, 9, 0), after: utc(2026, 3, 31, 9, 0), want: utc(2026, 5, 31, 9, 0)},
+		{name: "bymonthday last day", rule: "FREQ=MONTHLY;BYMONTHDAY=-1", dtstart: utc(2026, 1, 31, 9, 0), after: utc(2026, 1, 31, 9, 0), want: utc(2026, 2, 28, 9, 0)},
+		{name: "bymonthday several", rule: "FREQ=MONTHLY;BYMONTHDAY=1,15", dtstart: utc(2026, 1, 1, 9, 0), after: utc(2026, 1, 1, 9, 0), want: utc(2026, 1, 15, 9, 0)},
+		{name: "monthly last friday", rule: "FREQ=MONTHLY;BYDAY=-1FR", dtstart: utc(2026, 1, 30, 9, 0), after: utc(2026, 1, 30, 9, 0), want: utc(2026, 2, 27, 9, 0)},
+		{name: "monthly second monday", rule: "FREQ=MONTHLY;BYDAY=2MO", dtstart: utc(2026, 1, 12, 9, 0), after: utc(2026, 1, 12, 9, 0), want: utc(2026, 2, 9, 9, 0)},
+		{name: "yearly", rule: "FREQ=YEARLY", dtstart: utc(2026, 6, 1, 9, 0), after: utc(2026, 6, 1, 9, 0), want: utc(2027, 6, 1, 9, 0)},
+		{name: "yearly on feb 29", rule: "FREQ=YEARLY", dtstart: utc(2024, 2, 29, 9, 0), after: utc(2024, 2, 29, 9, 0), want: utc(2028, 2, 29, 9, 0)},
+		{name: "count reached", rule: "FREQ=DAILY;COUNT=3", dtstart: utc(2026, 1, 1, 9, 0), after: utc(2026, 1, 3, 9, 0)},
+		{name: "count not reached", rule: "FREQ=DAILY;COUNT=3", dtstart: utc(2026, 1, 1, 9, 0), after: utc(2026, 1, 2, 9, 0), want: utc(2026, 1, 3, 9, 0)},
+		{name: "until time inclusive", rule: "FREQ=DAILY;UNTIL=20260103T090000Z", dtstart: utc(2026, 1, 1, 9, 0), after: utc(2026, 1, 2, 9, 0), want: utc(2026, 1, 3, 9, 0)},
+		{name: "until time passed", rule: "FREQ=DAILY;UNTIL=20260103T085959Z", dtstart: utc(2026, 1, 1, 9, 0), after: utc(2026, 1, 2, 9, 0)},
+		{name: "until date includes the day", rule: "FREQ=DAILY;UNTIL=20260103", dtstart: local(2026, 1, 1, 23, 30), after: local(2026, 1, 2, 23, 30), want: local(2026, 1, 3, 23, 30)},
+		{name: "until date passed", rule: "FREQ=DAILY;UNTIL=20260103", dtstart: local(2026, 1, 1, 23, 30), after: local(2026, 1, 3, 23, 30)},
+		{name: "wall clock kept across DST", rule: "FREQ=WEEKLY", dtstart: local(2026, 3, 23, 9, 0), after: local(2026, 3, 23, 9, 0), want: local(2026, 3, 30, 9, 0)},
+		// 2026-03-29 02:00 CET jumps to 03:00 CEST: 02:30 does not exist and
+		// moves forward by the length of the gap.
+		{name: "spring forward gap", rule: "FREQ=DAILY", dtstart: local(2026, 3, 28, 2, 30), after: local(2026, 3, 28, 2, 30), want: utc(2026, 3, 29, 1, 30)},
+		{name: "after the gap", rule: "FREQ=DAILY", dtstart: local(2026, 3, 28, 2, 30), after: utc(2026, 3, 29, 1, 30), want: utc(2026, 3, 30, 0, 30)},
+		// 2026-10-25 03:00 CEST falls back to 02:00 CET: 02:30 happens twice and
+		// the first instance (CEST, 00:30 UTC) is taken.
+		{name: "fall back overlap", rule: "FREQ=DAILY", dtstart: local(2026, 10, 24, 2, 30), after: local(2026, 10, 24, 2, 30), want: utc(2026, 10, 25, 0, 30)},
+		{name: "after the overlap", rule: "FREQ=DAILY", dtstart: local(2026, 10, 24, 2, 30), after: utc(2026, 10, 25, 0, 30), want: utc(2026, 10, 26, 1, 30)},
+		// April has 30 days, so a yearly April 31st never occurs: the search
+		// gives up after maxPeriods instead of looping.
+		{name: "never matches", rule: "FREQ=MONTHLY;INTERVAL=12;BYMONTHDAY=31", dtstart: utc(2026, 4, 1, 9, 0), after: utc(2026, 4, 1, 9, 0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := Parse(tt.rule)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.rule, err)
+			}
+			got, ok := r.Next(tt.dtstart, tt.after)
+			if tt.want.IsZero() {
+				if ok {
+					t.Fatalf("Next = %v, want the series to have ended", got)
+				}
+				return
+			}
+			if !ok {
+				t.Fatalf("Next: series ended, want %v", tt.want)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("Next = %v, want %v", got, tt.want)
+			}
+			if got.Location() != tt.dtstart.Location() {
+				t.Errorf("Next location = %v, want %v", got.Location(), tt.dtstart.Location())
+			}
+		})
+	}
+}
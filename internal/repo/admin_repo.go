@@ -0,0 +1,86 @@
+package repo
+
+import (
+	"context"
+	"strings"
+
+	dom "Worker/internal/domain"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// AdminRepo provides system statistics and the admin audit log.
+type AdminRepo interface {
+	Stats(ctx context.Context) (dom.SystemStats, error)
+	AddAudit(ctx context.Context, e dom.AdminAuditEntry) error
+	ListAudit(ctx context.Context, q dom.AdminAuditQuery) ([]dom.AdminAuditEntry, error)
+}
+
+// PGAdminRepo implements AdminRepo with Postgres.
+type PGAdminRepo struct {
+	db DBTX
+}
+
+// NewPGAdminRepo returns a new PGAdminRepo.
+func NewPGAdminRepo(db *pgxpool.Pool) *PGAdminRepo {
+	return &PGAdminRepo{db: db}
+}
+
+func (r *PGAdminRepo) Stats(ctx context.Context) (dom.SystemStats, error) {
+	var s dom.SystemStats
+	err := r.db.QueryRow(ctx, `
+		SELECT
+			(SELECT COUNT(*) FROM users),
+			(SELECT COUNT(*) FROM users WHERE role = 'admin'),
+			(SELECT COUNT(*) FROM users WHERE disabled_at IS NOT NULL),
+			(SELECT COUNT(*) FROM todos WHERE deleted_at IS NULL),
+			(SELECT COUNT(*) FROM todos WHERE deleted_at IS NULL AND NOT is_done),
+			(SELECT COUNT(*) FROM todos WHERE deleted_at IS NULL AND NOT is_done AND due_at < NOW()),
+			(SELECT COUNT(*) FROM projects),
+			(SELECT COUNT(*) FROM tags),
+			(SELECT COUNT(*) FROM api_tokens WHERE expires_at IS NULL OR expires_at > NOW()),
+			(SELECT COUNT(*) FROM todo_reminders WHERE sent_at IS NULL)`,
+	).Scan(&s.Users, &s.Admins, &s.DisabledUsers, &s.Todos, &s.OpenTodos, &s.OverdueTodos,
+		&s.Projects, &s.Tags, &s.APITokens, &s.PendingReminds)
+	return s, err
+}
+
+func (r *PGAdminRepo) AddAudit(ctx context.Context, e dom.AdminAuditEntry) error {
+	details := e.Details
+	if len(details) == 0 {
+		details = []byte("{}")
+	}
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO admin_audit_log (admin_id, action, target_user_id, details, ip)
+		VALUES ($1, $2, $3, $4, $5)`, e.AdminID, e.Action, e.TargetUserID, details, e.IP)
+	return err
+}
+
+// ListAudit returns entries newest first.
+func (r *PGAdminRepo) ListAudit(ctx context.Context, q dom.AdminAuditQuery) ([]dom.AdminAuditEntry, error) {
+	var args sqlArgs
+	where := []string{"TRUE"}
+	if q.AdminID != 0 {
+		where = append(where, "admin_id = "+args.add(q.AdminID))
+	}
+	if q.TargetUserID != 0 {
+		where = append(where, "target_user_id = "+args.add(q.TargetUserID))
+	}
+	if q.BeforeID != 0 {
+		where = append(where, "id < "+args.add(q.BeforeID))
+	}
+	query := `
+		SELECT id, admin_id, action, target_user_id, details, ip, created_at
+		FROM admin_audit_log WHERE ` + strings.Join(where, " AND ") + `
+		ORDER BY id DESC LIMIT ` + args.add(q.Limit)
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (dom.AdminAuditEntry, error) {
+		var e dom.AdminAuditEntry
+		err := row.Scan(&e.ID, &e.AdminID, &e.Action, &e.TargetUserID, &e.Details, &e.IP, &e.CreatedAt)
+		return e, err
+	})
+}
@@ -0,0 +1,83 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	dom "Worker/internal/domain"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// APITokenRepo provides personal access token persistence.
+type APITokenRepo interface {
+	Create(ctx context.Context, t dom.APIToken, hash string) (dom.APIToken, error)
+	List(ctx context.Context, userID int64) ([]dom.APIToken, error)
+	Delete(ctx context.Context, userID, id int64) error
+	GetByHash(ctx context.Context, hash string) (dom.APIToken, error)
+	Touch(ctx context.Context, id int64, at time.Time) error
+}
+
+// PGAPITokenRepo implements APITokenRepo with Postgres.
+type PGAPITokenRepo struct {
+	db DBTX
+}
+
+// NewPGAPITokenRepo returns a new PGAPITokenRepo.
+func NewPGAPITokenRepo(db *pgxpool.Pool) *PGAPITokenRepo {
+	return &PGAPITokenRepo{db: db}
+}
+
+const apiTokenColumns = `id, user_id, name, prefix, scopes, expires_at, last_used_at, created_at`
+
+func (r *PGAPITokenRepo) Create(ctx context.Context, t dom.APIToken, hash string) (dom.APIToken, error) {
+	return scanAPIToken(r.db.QueryRow(ctx, `
+		INSERT INTO api_tokens (user_id, name, token_hash, prefix, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING `+apiTokenColumns, t.UserID, t.Name, hash, t.Prefix, t.Scopes, t.ExpiresAt))
+}
+
+// List returns the user's tokens, newest first, including expired ones.
+func (r *PGAPITokenRepo) List(ctx context.Context, userID int64) ([]dom.APIToken, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+apiTokenColumns+` FROM api_tokens WHERE user_id = $1 ORDER BY created_at DESC, id DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (dom.APIToken, error) {
+		return scanAPIToken(row)
+	})
+}
+
+// Delete revokes a token. It returns pgx.ErrNoRows if the user has no such token.
+func (r *PGAPITokenRepo) Delete(ctx context.Context, userID, id int64) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM api_tokens WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func (r *PGAPITokenRepo) GetByHash(ctx context.Context, hash string) (dom.APIToken, error) {
+	return scanAPIToken(r.db.QueryRow(ctx, `
+		SELECT `+apiTokenColumns+` FROM api_tokens WHERE token_hash = $1`, hash))
+}
+
+// Touch records a use of the token. Writes are throttled to one per minute so
+// that busy scripts do not update the row on every request.
+func (r *PGAPITokenRepo) Touch(ctx context.Context, id int64, at time.Time) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE api_tokens SET last_used_at = $2
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < $2 - INTERVAL '1 minute')`, id, at)
+	return err
+}
+
+func scanAPIToken(row pgx.Row) (dom.APIToken, error) {
+	var t dom.APIToken
+	err := row.Scan(&t.ID, &t.UserID, &t.Name, &t.Prefix, &t.Scopes, &t.ExpiresAt, &t.LastUsedAt, &t.CreatedAt)
+	return t, err
+}
@@ -0,0 +1,80 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	dom "Worker/internal/domain"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// CalendarFeedRepo provides calendar feed persistence.
+type CalendarFeedRepo interface {
+	Get(ctx context.Context, userID, workspaceID int64) (dom.CalendarFeed, error)
+	Put(ctx context.Context, f dom.CalendarFeed, hash string) (dom.CalendarFeed, error)
+	Delete(ctx context.Context, userID, workspaceID int64) error
+	GetByHash(ctx context.Context, hash string) (dom.CalendarFeed, error)
+	Touch(ctx context.Context, id int64, at time.Time) error
+}
+
+// PGCalendarFeedRepo implements CalendarFeedRepo with Postgres.
+type PGCalendarFeedRepo struct {
+	db DBTX
+}
+
+// NewPGCalendarFeedRepo returns a new PGCalendarFeedRepo.
+func NewPGCalendarFeedRepo(db *pgxpool.Pool) *PGCalendarFeedRepo {
+	return &PGCalendarFeedRepo{db: db}
+}
+
+const calendarFeedColumns = `id, user_id, workspace_id, prefix, last_used_at, created_at`
+
+// Get returns the user's feed of the workspace, or pgx.ErrNoRows if there is none.
+func (r *PGCalendarFeedRepo) Get(ctx context.Context, userID, workspaceID int64) (dom.CalendarFeed, error) {
+	return scanCalendarFeed(r.db.QueryRow(ctx, `
+		SELECT `+calendarFeedColumns+` FROM calendar_feeds WHERE user_id = $1 AND workspace_id = $2`, userID, workspaceID))
+}
+
+// Put creates the user's feed of the workspace, or gives the existing one a new
+// secret; the old secret stops working.
+func (r *PGCalendarFeedRepo) Put(ctx context.Context, f dom.CalendarFeed, hash string) (dom.CalendarFeed, error) {
+	return scanCalendarFeed(r.db.QueryRow(ctx, `
+		INSERT INTO calendar_feeds (user_id, workspace_id, token_hash, prefix)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, workspace_id) DO UPDATE
+		SET token_hash = EXCLUDED.token_hash, prefix = EXCLUDED.prefix, last_used_at = NULL, created_at = NOW()
+		RETURNING `+calendarFeedColumns, f.UserID, f.WorkspaceID, hash, f.Prefix))
+}
+
+// Delete revokes the feed. It returns pgx.ErrNoRows if there is none.
+func (r *PGCalendarFeedRepo) Delete(ctx context.Context, userID, workspaceID int64) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM calendar_feeds WHERE user_id = $1 AND workspace_id = $2`, userID, workspaceID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func (r *PGCalendarFeedRepo) GetByHash(ctx context.Context, hash string) (dom.CalendarFeed, error) {
+	return scanCalendarFeed(r.db.QueryRow(ctx, `
+		SELECT `+calendarFeedColumns+` FROM calendar_feeds WHERE token_hash = $1`, hash))
+}
+
+// Touch records a fetch of the feed, at most once a minute.
+func (r *PGCalendarFeedRepo) Touch(ctx context.Context, id int64, at time.Time) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE calendar_feeds SET last_used_at = $2
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < $2 - INTERVAL '1 minute')`, id, at)
+	return err
+}
+
+func scanCalendarFeed(row pgx.Row) (dom.CalendarFeed, error) {
+	var f dom.CalendarFeed
+	err := row.Scan(&f.ID, &f.UserID, &f.WorkspaceID, &f.Prefix, &f.LastUsedAt, &f.CreatedAt)
+	return f, err
+}
@@ -0,0 +1,138 @@
+package repo
+
+import (
+	"context"
+
+	dom "Worker/internal/domain"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// MFARepo provides TOTP enrollment and recovery code persistence.
+type MFARepo interface {
+	Get(ctx context.Context, userID int64) (dom.UserMFA, error)
+	// SavePending stores a new unconfirmed secret. It returns pgx.ErrNoRows if
+	// the user already has 2FA enabled.
+	SavePending(ctx context.Context, userID int64, secretEnc []byte) error
+	// Enable confirms enrollment and replaces the recovery codes.
+	Enable(ctx context.Context, userID, step int64, codeHashes []string) error
+	// UseStep records an accepted time step; false means it was already used.
+	UseStep(ctx context.Context, userID, step int64) (bool, error)
+	// RecoveryCodes returns the user's unused recovery codes.
+	RecoveryCodes(ctx context.Context, userID int64) ([]dom.RecoveryCode, error)
+	// UseRecoveryCode consumes a recovery code; false means it was already used.
+	UseRecoveryCode(ctx context.Context, userID, id int64) (bool, error)
+	ReplaceRecoveryCodes(ctx context.Context, userID int64, codeHashes []string) error
+	Delete(ctx context.Context, userID int64) error
+}
+
+// PGMFARepo implements MFARepo with Postgres.
+type PGMFARepo struct {
+	db DBTX
+}
+
+// NewPGMFARepo returns a new PGMFARepo.
+func NewPGMFARepo(db *pgxpool.Pool) *PGMFARepo {
+	return &PGMFARepo{db: db}
+}
+
+func (r *PGMFARepo) Get(ctx context.Context, userID int64) (dom.UserMFA, error) {
+	var m dom.UserMFA
+	err := r.db.QueryRow(ctx, `
+		SELECT user_id, secret_enc, enabled_at, last_used_step, created_at,
+			(SELECT COUNT(*) FROM mfa_recovery_codes c WHERE c.user_id = m.user_id AND c.used_at IS NULL)
+		FROM user_mfa m WHERE user_id = $1`, userID,
+	).Scan(&m.UserID, &m.SecretEnc, &m.EnabledAt, &m.LastUsedStep, &m.CreatedAt, &m.RecoveryCodesLeft)
+	return m, err
+}
+
+func (r *PGMFARepo) SavePending(ctx context.Context, userID int64, secretEnc []byte) error {
+	tag, err := r.db.Exec(ctx, `
+		INSERT INTO user_mfa (user_id, secret_enc) VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET secret_enc = EXCLUDED.secret_enc, last_used_step = 0, created_at = NOW()
+		WHERE user_mfa.enabled_at IS NULL`, userID, secretEnc)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// Enable returns pgx.ErrNoRows if there is no pending enrollment.
+func (r *PGMFARepo) Enable(ctx context.Context, userID, step int64, codeHashes []string) error {
+	return pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, `
+			UPDATE user_mfa SET enabled_at = NOW(), last_used_step = $2
+			WHERE user_id = $1 AND enabled_at IS NULL`, userID, step)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return pgx.ErrNoRows
+		}
+		return replaceRecoveryCodes(ctx, tx, userID, codeHashes)
+	})
+}
+
+func (r *PGMFARepo) UseStep(ctx context.Context, userID, step int64) (bool, error) {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE user_mfa SET last_used_step = $2 WHERE user_id = $1 AND last_used_step < $2`, userID, step)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+func (r *PGMFARepo) RecoveryCodes(ctx context.Context, userID int64) ([]dom.RecoveryCode, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT id, code_hash FROM mfa_recovery_codes
+		WHERE user_id = $1 AND used_at IS NULL ORDER BY id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (dom.RecoveryCode, error) {
+		var c dom.RecoveryCode
+		err := row.Scan(&c.ID, &c.Hash)
+		return c, err
+	})
+}
+
+func (r *PGMFARepo) UseRecoveryCode(ctx context.Context, userID, id int64) (bool, error) {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE mfa_recovery_codes SET used_at = NOW()
+		WHERE id = $2 AND user_id = $1 AND used_at IS NULL`, userID, id)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+func (r *PGMFARepo) ReplaceRecoveryCodes(ctx context.Context, userID int64, codeHashes []string) error {
+	return pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		return replaceRecoveryCodes(ctx, tx, userID, codeHashes)
+	})
+}
+
+// Delete turns 2FA off and drops the recovery codes.
+func (r *PGMFARepo) Delete(ctx context.Context, userID int64) error {
+	return pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx, `DELETE FROM user_mfa WHERE user_id = $1`, userID)
+		return err
+	})
+}
+
+func replaceRecoveryCodes(ctx context.Context, tx pgx.Tx, userID int64, codeHashes []string) error {
+	if _, err := tx.Exec(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	_, err := tx.Exec(ctx, `
+		INSERT INTO mfa_recovery_codes (user_id, code_hash)
+		SELECT $1, h FROM unnest($2::text[]) AS h`, userID, codeHashes)
+	return err
+}
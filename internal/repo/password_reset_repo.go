@@ -0,0 +1,56 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PasswordResetRepo stores password reset tokens by hash.
+type PasswordResetRepo interface {
+	Create(ctx context.Context, userID int64, hash string, expiresAt time.Time) error
+	// Reset redeems the token and sets the owner's password in one transaction.
+	// It returns pgx.ErrNoRows if the token is unknown, used or expired.
+	Reset(ctx context.Context, hash string, now time.Time, passwordHash string) (int64, error)
+}
+
+// PGPasswordResetRepo implements PasswordResetRepo with Postgres.
+type PGPasswordResetRepo struct {
+	db DBTX
+}
+
+// NewPGPasswordResetRepo returns a new PGPasswordResetRepo.
+func NewPGPasswordResetRepo(db *pgxpool.Pool) *PGPasswordResetRepo {
+	return &PGPasswordResetRepo{db: db}
+}
+
+func (r *PGPasswordResetRepo) Create(ctx context.Context, userID int64, hash string, expiresAt time.Time) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO password_reset_tokens (user_id, token_hash, expires_at) VALUES ($1, $2, $3)`,
+		userID, hash, expiresAt)
+	return err
+}
+
+// Reset marks the token used, so it works once even under concurrent requests,
+// updates the password and voids the user's other outstanding tokens.
+func (r *PGPasswordResetRepo) Reset(ctx context.Context, hash string, now time.Time, passwordHash string) (int64, error) {
+	var userID int64
+	err := pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx, `
+			UPDATE password_reset_tokens SET used_at = $2
+			WHERE token_hash = $1 AND used_at IS NULL AND expires_at > $2
+			RETURNING user_id`, hash, now).Scan(&userID)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, `UPDATE users SET password_hash = $2 WHERE id = $1`, userID, passwordHash); err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx, `
+			UPDATE password_reset_tokens SET used_at = $2 WHERE user_id = $1 AND used_at IS NULL`, userID, now)
+		return err
+	})
+	return userID, err
+}
@@ -0,0 +1,181 @@
+package repo
+
+import (
+	"context"
+	"strconv"
+
+	dom "Worker/internal/domain"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ProjectRepo provides project persistence. All methods except Access and
+// Collaborators are scoped to the owning user.
+type ProjectRepo interface {
+	Create(ctx context.Context, p dom.Project) (dom.Project, error)
+	GetByID(ctx context.Context, userID, id int64) (dom.Project, error)
+	List(ctx context.Context, userID, workspaceID int64, includeArchived bool) ([]dom.Project, error)
+	Update(ctx context.Context, userID, id int64, patch dom.Project) (dom.Project, error)
+	Delete(ctx context.Context, userID, id int64, mode string) error
+	Inbox(ctx context.Context, userID, workspaceID int64) (dom.Project, error)
+	Access(ctx context.Context, userID, id int64) (dom.Project, error)
+	Collaborators(ctx context.Context, id int64) ([]int64, error)
+}
+
+const projectColumns = `id, user_id, workspace_id, name, COALESCE(color, ''), archived, is_inbox, created_at, updated_at`
+
+// PGProjectRepo implements ProjectRepo with Postgres.
+type PGProjectRepo struct {
+	db DBTX
+}
+
+// NewPGProjectRepo returns a new PGProjectRepo.
+func NewPGProjectRepo(db *pgxpool.Pool) *PGProjectRepo {
+	return &PGProjectRepo{db: db}
+}
+
+// Create inserts a regular (non-inbox) project.
+func (r *PGProjectRepo) Create(ctx context.Context, p dom.Project) (dom.Project, error) {
+	return scanProject(r.db.QueryRow(ctx, `
+		INSERT INTO projects (user_id, workspace_id, name, color, archived)
+		VALUES ($1, $2, $3, NULLIF($4, ''), $5)
+		RETURNING `+projectColumns, p.UserID, p.WorkspaceID, p.Name, p.Color, p.Archived))
+}
+
+// GetByID returns one project of the user.
+func (r *PGProjectRepo) GetByID(ctx context.Context, userID, id int64) (dom.Project, error) {
+	return scanProject(r.db.QueryRow(ctx, `
+		SELECT `+projectColumns+` FROM projects WHERE id = $1 AND user_id = $2`, id, userID))
+}
+
+// List returns the user's projects in a workspace, inbox first, then by name.
+func (r *PGProjectRepo) List(ctx context.Context, userID, workspaceID int64, includeArchived bool) ([]dom.Project, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+projectColumns+` FROM projects
+		WHERE user_id = $1 AND workspace_id = $3 AND ($2 OR NOT archived)
+		ORDER BY is_inbox DESC, lower(name), id`, userID, includeArchived, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []dom.Project
+	for rows.Next() {
+		p, err := scanProject(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, p)
+	}
+	return list, rows.Err()
+}
+
+// Update writes name, color and archived flag.
+func (r *PGProjectRepo) Update(ctx context.Context, userID, id int64, patch dom.Project) (dom.Project, error) {
+	return scanProject(r.db.QueryRow(ctx, `
+		UPDATE projects SET name = $3, color = NULLIF($4, ''), archived = $5, updated_at = NOW()
+		WHERE id = $1 AND user_id = $2
+		RETURNING `+projectColumns, id, userID, patch.Name, patch.Color, patch.Archived))
+}
+
+// Delete removes a project. With dom.ProjectDeleteCascade its todos are soft-deleted;
+// with dom.ProjectDeleteToInbox they are appended to the inbox in their current order.
+// The inbox is the one of the project's workspace. The caller must not pass the inbox itself.
+// Each affected todo gets a history event with userID as the actor.
+func (r *PGProjectRepo) Delete(ctx context.Context, userID, id int64, mode string) error {
+	return pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		var workspaceID int64
+		if err := tx.QueryRow(ctx, `SELECT workspace_id FROM projects WHERE id = $1 AND user_id = $2 FOR UPDATE`,
+			id, userID).Scan(&workspaceID); err != nil {
+			return err
+		}
+		switch mode {
+		case dom.ProjectDeleteToInbox:
+			inbox, err := inbox(ctx, tx, userID, workspaceID)
+			if err != nil {
+				return err
+			}
+			if _, err := tx.Exec(ctx, `
+				WITH moved AS (
+					UPDATE todos t SET project_id = $3, updated_at = NOW(),
+						position = `+nextPositionSQL("$1", "t.workspace_id", "$3", "NULL")+` + s.rn * `+strconv.Itoa(positionGap)+`
+					FROM (SELECT id, position, row_number() OVER (ORDER BY position, id) - 1 AS rn
+						FROM todos WHERE user_id = $1 AND project_id = $2 AND deleted_at IS NULL) s
+					WHERE t.id = s.id
+					RETURNING t.id, t.workspace_id, t.user_id, jsonb_build_object(
+						'project_id', jsonb_build_object('from', $2::bigint, 'to', t.project_id),
+						'position', jsonb_build_object('from', s.position, 'to', t.position)) AS changes
+				)`+insertEventsSQL("moved", "$1", dom.TodoEventMoved), userID, id, inbox.ID); err != nil {
+				return err
+			}
+		default:
+			if _, err := tx.Exec(ctx, `
+				WITH deleted AS (
+					UPDATE todos SET deleted_at = NOW(), updated_at = NOW()
+					WHERE user_id = $1 AND project_id = $2 AND deleted_at IS NULL
+					RETURNING id, workspace_id, user_id,
+						jsonb_build_object('deleted_at', jsonb_build_object('from', NULL, 'to', deleted_at)) AS changes
+				)`+insertEventsSQL("deleted", "$1", dom.TodoEventDeleted), userID, id); err != nil {
+				return err
+			}
+		}
+		_, err := tx.Exec(ctx, `DELETE FROM projects WHERE id = $1 AND user_id = $2`, id, userID)
+		return err
+	})
+}
+
+// Inbox returns the user's inbox project in a workspace, creating it on first use.
+func (r *PGProjectRepo) Inbox(ctx context.Context, userID, workspaceID int64) (dom.Project, error) {
+	return inbox(ctx, r.db, userID, workspaceID)
+}
+
+// Access returns a project with the permission userID has on it: owner for its
+// owner, otherwise the user's share. A project the user cannot see is pgx.ErrNoRows.
+func (r *PGProjectRepo) Access(ctx context.Context, userID, id int64) (dom.Project, error) {
+	var p dom.Project
+	var level int16
+	err := r.db.QueryRow(ctx, `
+		SELECT `+projectColumns+`,
+			CASE WHEN user_id = $2 THEN share_level('owner')
+			ELSE COALESCE((SELECT share_level(s.permission) FROM project_shares s
+				WHERE s.project_id = projects.id AND s.user_id = $2), 0) END
+		FROM projects WHERE id = $1`, id, userID).Scan(&p.ID, &p.UserID, &p.WorkspaceID, &p.Name, &p.Color, &p.Archived,
+		&p.IsInbox, &p.CreatedAt, &p.UpdatedAt, &level)
+	if err != nil {
+		return dom.Project{}, err
+	}
+	if level == 0 {
+		return dom.Project{}, pgx.ErrNoRows
+	}
+	p.Permission = dom.Permission(level)
+	return p, nil
+}
+
+// Collaborators returns the owner of a project, the users it is shared with and
+// the users any of its todos are shared with.
+func (r *PGProjectRepo) Collaborators(ctx context.Context, id int64) ([]int64, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT user_id FROM projects WHERE id = $1
+		UNION
+		SELECT user_id FROM project_shares WHERE project_id = $1
+		UNION
+		SELECT s.user_id FROM todo_shares s JOIN todos t ON t.id = s.todo_id WHERE t.project_id = $1`, id)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[int64])
+}
+
+func inbox(ctx context.Context, db DBTX, userID, workspaceID int64) (dom.Project, error) {
+	// DO UPDATE (a no-op) instead of DO NOTHING so RETURNING yields the existing row.
+	return scanProject(db.QueryRow(ctx, `
+		INSERT INTO projects (user_id, workspace_id, name, is_inbox) VALUES ($1, $2, 'Inbox', TRUE)
+		ON CONFLICT (user_id, workspace_id) WHERE is_inbox DO UPDATE SET is_inbox = TRUE
+		RETURNING `+projectColumns, userID, workspaceID))
+}
+
+func scanProject(row pgx.Row) (dom.Project, error) {
+	var p dom.Project
+	err := row.Scan(&p.ID, &p.UserID, &p.WorkspaceID, &p.Name, &p.Color, &p.Archived, &p.IsInbox, &p.CreatedAt, &p.UpdatedAt)
+	return p, err
+}
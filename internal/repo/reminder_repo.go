@@ -0,0 +1,131 @@
+package repo
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	dom "Worker/internal/domain"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// MaxReminderAttempts is how often delivery of one reminder is tried before it is
+// given up; failed reminders are retried on the next poll.
+const MaxReminderAttempts = 5
+
+// ReminderRepo is the worker's view of todo_reminders. Reminders of a todo are
+// written together with the todo by TodoRepo.
+type ReminderRepo interface {
+	// ClaimDue leases up to limit reminders due at now to the caller until the
+	// given time and returns them in remind_at order. Reminders leased by another
+	// worker are skipped until their lease runs out, so several workers can poll
+	// concurrently. The claim commits at once: no lock is held while sending.
+	ClaimDue(ctx context.Context, now, until time.Time, limit int) ([]dom.Reminder, error)
+	// Record stores the outcome of sending reminders leased until the given
+	// time: errs[i] is the delivery error of list[i], nil if it was delivered.
+	// Failed reminders are tried again on a later poll, up to MaxReminderAttempts
+	// in all. Reminders whose lease was lost or that were re-armed meanwhile are
+	// left alone.
+	Record(ctx context.Context, now, until time.Time, list []dom.Reminder, errs []error) error
+}
+
+// PGReminderRepo implements ReminderRepo with Postgres.
+type PGReminderRepo struct {
+	db DBTX
+}
+
+// NewPGReminderRepo returns a new PGReminderRepo.
+func NewPGReminderRepo(db *pgxpool.Pool) *PGReminderRepo {
+	return &PGReminderRepo{db: db}
+}
+
+func (r *PGReminderRepo) ClaimDue(ctx context.Context, now, until time.Time, limit int) ([]dom.Reminder, error) {
+	rows, err := r.db.Query(ctx, `
+		WITH due AS (
+			SELECT rm.id FROM todo_reminders rm JOIN todos t ON t.id = rm.todo_id
+			WHERE rm.sent_at IS NULL AND rm.remind_at <= $1 AND rm.attempts < $2
+				AND (rm.leased_until IS NULL OR rm.leased_until <= $1)
+				AND t.is_done = FALSE AND t.deleted_at IS NULL
+			ORDER BY rm.remind_at, rm.id
+			LIMIT $3
+			FOR UPDATE OF rm SKIP LOCKED
+		)
+		UPDATE todo_reminders rm SET leased_until = $4
+		FROM due, todos t
+		WHERE rm.id = due.id AND t.id = rm.todo_id
+		RETURNING rm.id, rm.todo_id, t.user_id, t.title, t.due_at, rm.before_due, rm.remind_at, rm.attempts`,
+		now, MaxReminderAttempts, limit, until)
+	if err != nil {
+		return nil, err
+	}
+	list, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (dom.Reminder, error) {
+		var rm dom.Reminder
+		err := row.Scan(&rm.ID, &rm.TodoID, &rm.UserID, &rm.Title, &rm.DueAt, &rm.BeforeDue, &rm.RemindAt, &rm.Attempts)
+		return rm, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	// UPDATE ... RETURNING does not keep the order of the claim.
+	sort.Slice(list, func(i, j int) bool {
+		if !list[i].RemindAt.Equal(list[j].RemindAt) {
+			return list[i].RemindAt.Before(list[j].RemindAt)
+		}
+		return list[i].ID < list[j].ID
+	})
+	return list, nil
+}
+
+func (r *PGReminderRepo) Record(ctx context.Context, now, until time.Time, list []dom.Reminder, errs []error) error {
+	return pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		for i, rm := range list {
+			var err error
+			if errs[i] != nil {
+				_, err = tx.Exec(ctx, `
+					UPDATE todo_reminders SET attempts = attempts + 1, last_error = $2, leased_until = NULL
+					WHERE id = $1 AND leased_until = $3`, rm.ID, errs[i].Error(), until)
+			} else {
+				_, err = tx.Exec(ctx, `
+					UPDATE todo_reminders SET sent_at = $2, last_error = NULL, leased_until = NULL
+					WHERE id = $1 AND leased_until = $3`, rm.ID, now, until)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// setTodoReminders replaces the reminder offsets of a todo. Offsets that stay keep
+// their delivery state, so re-saving a todo does not repeat sent reminders.
+func setTodoReminders(ctx context.Context, db DBTX, todoID int64, offsets []time.Duration) error {
+	if offsets == nil {
+		offsets = []time.Duration{}
+	}
+	if _, err := db.Exec(ctx, `
+		DELETE FROM todo_reminders WHERE todo_id = $1 AND NOT before_due = ANY($2::interval[])`,
+		todoID, offsets); err != nil {
+		return err
+	}
+	if _, err := db.Exec(ctx, `
+		INSERT INTO todo_reminders (todo_id, before_due) SELECT $1, unnest($2::interval[])
+		ON CONFLICT (todo_id, before_due) DO NOTHING`, todoID, offsets); err != nil {
+		return err
+	}
+	return syncReminders(ctx, db, todoID)
+}
+
+// syncReminders recomputes remind_at from the todo's due date. A reminder whose
+// time changed is re-armed: it will be sent (again) at the new time, and a
+// worker sending it right now does not record the outcome.
+func syncReminders(ctx context.Context, db DBTX, todoID int64) error {
+	_, err := db.Exec(ctx, `
+		UPDATE todo_reminders rm SET remind_at = t.due_at - rm.before_due, sent_at = NULL, attempts = 0, last_error = NULL,
+			leased_until = NULL
+		FROM todos t
+		WHERE t.id = rm.todo_id AND rm.todo_id = $1 AND rm.remind_at IS DISTINCT FROM t.due_at - rm.before_due`, todoID)
+	return err
+}
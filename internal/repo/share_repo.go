@@ -0,0 +1,119 @@
+package repo
+
+import (
+	"context"
+
+	dom "Worker/internal/domain"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ShareRepo stores who a todo or project is shared with. Callers check that the
+// acting user may manage the shares; the repo does not.
+type ShareRepo interface {
+	ListTodo(ctx context.Context, todoID int64) ([]dom.Share, error)
+	PutTodo(ctx context.Context, todoID int64, s dom.Share) (dom.Share, error)
+	DeleteTodo(ctx context.Context, todoID, userID int64) error
+	ListProject(ctx context.Context, projectID int64) ([]dom.Share, error)
+	PutProject(ctx context.Context, projectID int64, s dom.Share) (dom.Share, error)
+	DeleteProject(ctx context.Context, projectID, userID int64) error
+}
+
+// shareTable names a share table and the column of the shared object.
+type shareTable struct {
+	name, key string
+}
+
+var (
+	todoShares    = shareTable{name: "todo_shares", key: "todo_id"}
+	projectShares = shareTable{name: "project_shares", key: "project_id"}
+)
+
+// PGShareRepo implements ShareRepo with Postgres.
+type PGShareRepo struct {
+	db DBTX
+}
+
+// NewPGShareRepo returns a new PGShareRepo.
+func NewPGShareRepo(db *pgxpool.Pool) *PGShareRepo {
+	return &PGShareRepo{db: db}
+}
+
+// ListTodo returns the shares of one todo (not those inherited from ancestors or the project).
+func (r *PGShareRepo) ListTodo(ctx context.Context, todoID int64) ([]dom.Share, error) {
+	return r.list(ctx, todoShares, todoID)
+}
+
+// PutTodo grants s.UserID access to a todo, replacing an earlier grant.
+func (r *PGShareRepo) PutTodo(ctx context.Context, todoID int64, s dom.Share) (dom.Share, error) {
+	return r.put(ctx, todoShares, todoID, s)
+}
+
+// DeleteTodo removes a user's share of a todo; pgx.ErrNoRows if there is none.
+func (r *PGShareRepo) DeleteTodo(ctx context.Context, todoID, userID int64) error {
+	return r.delete(ctx, todoShares, todoID, userID)
+}
+
+// ListProject returns the shares of a project.
+func (r *PGShareRepo) ListProject(ctx context.Context, projectID int64) ([]dom.Share, error) {
+	return r.list(ctx, projectShares, projectID)
+}
+
+// PutProject grants s.UserID access to every todo of a project, replacing an earlier grant.
+func (r *PGShareRepo) PutProject(ctx context.Context, projectID int64, s dom.Share) (dom.Share, error) {
+	return r.put(ctx, projectShares, projectID, s)
+}
+
+// DeleteProject removes a user's share of a project; pgx.ErrNoRows if there is none.
+func (r *PGShareRepo) DeleteProject(ctx context.Context, projectID, userID int64) error {
+	return r.delete(ctx, projectShares, projectID, userID)
+}
+
+func (r *PGShareRepo) list(ctx context.Context, t shareTable, id int64) ([]dom.Share, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT s.user_id, u.username, s.permission, s.granted_by, s.created_at
+		FROM `+t.name+` s JOIN users u ON u.id = s.user_id
+		WHERE s.`+t.key+` = $1
+		ORDER BY lower(u.username)`, id)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (dom.Share, error) {
+		return scanShare(row)
+	})
+}
+
+func (r *PGShareRepo) put(ctx context.Context, t shareTable, id int64, s dom.Share) (dom.Share, error) {
+	return scanShare(r.db.QueryRow(ctx, `
+		WITH s AS (
+			INSERT INTO `+t.name+` (`+t.key+`, user_id, permission, granted_by)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (`+t.key+`, user_id) DO UPDATE
+				SET permission = EXCLUDED.permission, granted_by = EXCLUDED.granted_by
+			RETURNING user_id, permission, granted_by, created_at
+		)
+		SELECT s.user_id, u.username, s.permission, s.granted_by, s.created_at
+		FROM s JOIN users u ON u.id = s.user_id`, id, s.UserID, s.Permission.String(), s.GrantedBy))
+}
+
+func (r *PGShareRepo) delete(ctx context.Context, t shareTable, id, userID int64) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM `+t.name+` WHERE `+t.key+` = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func scanShare(row pgx.Row) (dom.Share, error) {
+	var s dom.Share
+	var perm string
+	if err := row.Scan(&s.UserID, &s.Username, &perm, &s.GrantedBy, &s.CreatedAt); err != nil {
+		return dom.Share{}, err
+	}
+	s.Permission, _ = dom.ParsePermission(perm)
+	return s, nil
+}
@@ -0,0 +1,101 @@
+package repo
+
+import (
+	"context"
+
+	dom "Worker/internal/domain"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// TagRepo provides tag persistence. All methods are scoped to the owning user;
+// tags are shared by all of the user's workspaces, todo counts are per workspace.
+type TagRepo interface {
+	Create(ctx context.Context, userID int64, name string) (dom.Tag, error)
+	GetByID(ctx context.Context, userID, workspaceID, id int64) (dom.Tag, error)
+	List(ctx context.Context, userID, workspaceID int64) ([]dom.Tag, error)
+	Rename(ctx context.Context, userID, id int64, name string) (dom.Tag, error)
+	Delete(ctx context.Context, userID, id int64) error
+}
+
+// PGTagRepo implements TagRepo with Postgres.
+type PGTagRepo struct {
+	db DBTX
+}
+
+// NewPGTagRepo returns a new PGTagRepo.
+func NewPGTagRepo(db *pgxpool.Pool) *PGTagRepo {
+	return &PGTagRepo{db: db}
+}
+
+// Create inserts a new tag. A name clash (case-insensitive) is a unique violation.
+func (r *PGTagRepo) Create(ctx context.Context, userID int64, name string) (dom.Tag, error) {
+	var t dom.Tag
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO tags (user_id, name) VALUES ($1, $2)
+		RETURNING id, user_id, name, created_at`, userID, name,
+	).Scan(&t.ID, &t.UserID, &t.Name, &t.CreatedAt)
+	return t, err
+}
+
+// GetByID returns the tag with its live todo count in a workspace.
+func (r *PGTagRepo) GetByID(ctx context.Context, userID, workspaceID, id int64) (dom.Tag, error) {
+	var t dom.Tag
+	err := r.db.QueryRow(ctx, `
+		SELECT tg.id, tg.user_id, tg.name, tg.created_at, count(t.id)
+		FROM tags tg
+		LEFT JOIN todo_tags tt ON tt.tag_id = tg.id
+		LEFT JOIN todos t ON t.id = tt.todo_id AND t.deleted_at IS NULL AND t.workspace_id = $3
+		WHERE tg.id = $1 AND tg.user_id = $2
+		GROUP BY tg.id`, id, userID, workspaceID,
+	).Scan(&t.ID, &t.UserID, &t.Name, &t.CreatedAt, &t.TodoCount)
+	return t, err
+}
+
+// List returns all tags of the user sorted by name, with live todo counts in a workspace.
+func (r *PGTagRepo) List(ctx context.Context, userID, workspaceID int64) ([]dom.Tag, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT tg.id, tg.user_id, tg.name, tg.created_at, count(t.id)
+		FROM tags tg
+		LEFT JOIN todo_tags tt ON tt.tag_id = tg.id
+		LEFT JOIN todos t ON t.id = tt.todo_id AND t.deleted_at IS NULL AND t.workspace_id = $2
+		WHERE tg.user_id = $1
+		GROUP BY tg.id
+		ORDER BY lower(tg.name)`, userID, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []dom.Tag
+	for rows.Next() {
+		var t dom.Tag
+		if err := rows.Scan(&t.ID, &t.UserID, &t.Name, &t.CreatedAt, &t.TodoCount); err != nil {
+			return nil, err
+		}
+		list = append(list, t)
+	}
+	return list, rows.Err()
+}
+
+// Rename changes the tag name. Returns pgx.ErrNoRows if the tag does not exist.
+func (r *PGTagRepo) Rename(ctx context.Context, userID, id int64, name string) (dom.Tag, error) {
+	var t dom.Tag
+	err := r.db.QueryRow(ctx, `
+		UPDATE tags SET name = $3 WHERE id = $1 AND user_id = $2
+		RETURNING id, user_id, name, created_at`, id, userID, name,
+	).Scan(&t.ID, &t.UserID, &t.Name, &t.CreatedAt)
+	return t, err
+}
+
+// Delete removes the tag and detaches it from all todos. Returns pgx.ErrNoRows if it does not exist.
+func (r *PGTagRepo) Delete(ctx context.Context, userID, id int64) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM tags WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
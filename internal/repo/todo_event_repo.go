@@ -0,0 +1,190 @@
+package repo
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+
+	dom "Worker/internal/domain"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// TodoEventRepo reads the todo history. Events are written by PGTodoRepo (and
+// PGProjectRepo for todos of a deleted project) in the mutating transaction.
+type TodoEventRepo interface {
+	List(ctx context.Context, q dom.TodoEventQuery) ([]dom.TodoEvent, error)
+}
+
+// PGTodoEventRepo implements TodoEventRepo with Postgres.
+type PGTodoEventRepo struct {
+	db DBTX
+}
+
+// NewPGTodoEventRepo returns a new PGTodoEventRepo.
+func NewPGTodoEventRepo(db *pgxpool.Pool) *PGTodoEventRepo {
+	return &PGTodoEventRepo{db: db}
+}
+
+// List returns events matching q, newest first.
+func (r *PGTodoEventRepo) List(ctx context.Context, q dom.TodoEventQuery) ([]dom.TodoEvent, error) {
+	var args sqlArgs
+	where := []string{"TRUE"}
+	if q.TodoID != 0 {
+		where = append(where, "todo_id = "+args.add(q.TodoID))
+	}
+	if q.WorkspaceID != 0 {
+		where = append(where, "workspace_id = "+args.add(q.WorkspaceID))
+	}
+	if q.ActorID != 0 {
+		where = append(where, "actor_id = "+args.add(q.ActorID))
+	}
+	if q.OwnerID != 0 {
+		where = append(where, "owner_id = "+args.add(q.OwnerID))
+	}
+	if q.VisibleTo != 0 {
+		u := args.add(q.VisibleTo)
+		where = append(where, "(owner_id = "+u+" OR actor_id = "+u+" OR todo_permission(todo_id, "+u+") > 0)")
+	}
+	if q.From != nil {
+		where = append(where, "created_at >= "+args.add(*q.From))
+	}
+	if q.To != nil {
+		where = append(where, "created_at < "+args.add(*q.To))
+	}
+	if q.BeforeID != 0 {
+		where = append(where, "id < "+args.add(q.BeforeID))
+	}
+	query := `
+		SELECT id, todo_id, workspace_id, owner_id, actor_id, action, changes, created_at
+		FROM todo_events WHERE ` + strings.Join(where, " AND ") + `
+		ORDER BY id DESC LIMIT ` + args.add(q.Limit)
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (dom.TodoEvent, error) {
+		var e dom.TodoEvent
+		err := row.Scan(&e.ID, &e.TodoID, &e.WorkspaceID, &e.OwnerID, &e.ActorID, &e.Action, &e.Changes, &e.CreatedAt)
+		return e, err
+	})
+}
+
+// fieldChange is one entry of todo_events.changes.
+type fieldChange struct {
+	From any `json:"from"`
+	To   any `json:"to"`
+}
+
+// todoFields returns the fields of t the history tracks, in their JSON form.
+func todoFields(t dom.Todo) map[string]any {
+	reminders := make([]string, len(t.Reminders))
+	for i, d := range t.Reminders {
+		reminders[i] = d.String()
+	}
+	tags := t.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	return map[string]any{
+		"title":         t.Title,
+		"description":   t.Description,
+		"is_done":       t.IsDone,
+		"due_at":        timeOrNil(t.DueAt),
+		"project_id":    idOrNil(t.ProjectID),
+		"parent_id":     idOrNil(t.ParentID),
+		"position":      t.Position,
+		"tags":          tags,
+		"reminders":     reminders,
+		"recurrence":    t.Recurrence,
+		"recurrence_tz": t.RecurrenceTZ,
+	}
+}
+
+// todoChanges returns the tracked fields that differ between before and after.
+func todoChanges(before, after dom.Todo) map[string]fieldChange {
+	b, a := todoFields(before), todoFields(after)
+	out := make(map[string]fieldChange)
+	for k, v := range a {
+		if !reflect.DeepEqual(b[k], v) {
+			out[k] = fieldChange{From: b[k], To: v}
+		}
+	}
+	return out
+}
+
+// createdChanges lists the fields a new todo was created with; empty ones are left out.
+func createdChanges(t dom.Todo) map[string]fieldChange {
+	out := make(map[string]fieldChange)
+	for k, v := range todoFields(t) {
+		if v == nil {
+			continue
+		}
+		if rv := reflect.ValueOf(v); rv.IsZero() || rv.Kind() == reflect.Slice && rv.Len() == 0 {
+			continue
+		}
+		out[k] = fieldChange{To: v}
+	}
+	return out
+}
+
+// lockTodo locks the user's live todo for a change and returns it as it is before.
+func lockTodo(ctx context.Context, tx pgx.Tx, userID, id int64) (dom.Todo, error) {
+	var locked int64
+	if err := tx.QueryRow(ctx, `
+		SELECT id FROM todos WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL FOR UPDATE`,
+		id, userID).Scan(&locked); err != nil {
+		return dom.Todo{}, err
+	}
+	return scanTodo(tx.QueryRow(ctx, `SELECT `+todoColumns+` FROM todos WHERE id = $1`, id))
+}
+
+// recordTodoChanges records action with the fields that differ between before
+// and after; a change that left every tracked field as it was is not recorded.
+func recordTodoChanges(ctx context.Context, db DBTX, actorID int64, before, after dom.Todo, action string) error {
+	changes := todoChanges(before, after)
+	if len(changes) == 0 {
+		return nil
+	}
+	return recordTodoEvent(ctx, db, actorID, after, action, changes)
+}
+
+// recordTodoEvent appends one event for t. Changes may be empty only for
+// created events; callers skip mutations that changed nothing.
+func recordTodoEvent(ctx context.Context, db DBTX, actorID int64, t dom.Todo, action string, changes map[string]fieldChange) error {
+	b, err := json.Marshal(changes)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(ctx, `
+		INSERT INTO todo_events (todo_id, workspace_id, owner_id, actor_id, action, changes)
+		VALUES ($1, $2, $3, NULLIF($4::bigint, 0), $5, $6)`,
+		t.ID, t.WorkspaceID, t.UserID, actorID, action, b)
+	return err
+}
+
+// insertEventsSQL returns an INSERT recording action for every row of the CTE
+// named cte, which must yield id, workspace_id, user_id and changes (jsonb).
+// actorArg is the placeholder of the acting user.
+func insertEventsSQL(cte, actorArg, action string) string {
+	return `
+		INSERT INTO todo_events (todo_id, workspace_id, owner_id, actor_id, action, changes)
+		SELECT id, workspace_id, user_id, NULLIF(` + actorArg + `::bigint, 0), '` + action + `', changes FROM ` + cte
+}
+
+func timeOrNil(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func idOrNil(id *int64) any {
+	if id == nil {
+		return nil
+	}
+	return *id
+}
@@ -0,0 +1,222 @@
+package repo
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	dom "Worker/internal/domain"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// exportChunk is how many todos Export reads per query; the connection is
+// released while they are handed out.
+const exportChunk = 500
+
+// Export calls fn for every todo of the user in the workspace, open and done,
+// with includeDeleted also those in the trash. Todos come grouped by project
+// (no project first) and in tree order: every todo is followed by its subtasks,
+// siblings by position. Pages are read with keyset pagination on that order,
+// so fn may be slow without holding a connection; a todo changed meanwhile
+// shows up as it is when its page is read.
+func (r *PGTodoRepo) Export(ctx context.Context, userID, workspaceID int64, includeDeleted bool, fn func(dom.Todo) error) error {
+	afterProject, afterPath := int64(-1), []int64{}
+	for {
+		rows, err := r.db.Query(ctx, `
+			WITH RECURSIVE tree AS (
+				SELECT id AS todo_id, COALESCE(project_id, 0) AS project_key, ARRAY[position, id] AS path
+				FROM todos
+				WHERE user_id = $1 AND workspace_id = $2 AND parent_id IS NULL AND ($3 OR deleted_at IS NULL)
+				UNION ALL
+				SELECT c.id, tree.project_key, tree.path || ARRAY[c.position, c.id]
+				FROM todos c JOIN tree ON c.parent_id = tree.todo_id
+				WHERE $3 OR c.deleted_at IS NULL
+			)
+			SELECT tree.project_key, tree.path, `+todoColumns+`
+			FROM tree JOIN todos ON todos.id = tree.todo_id
+			WHERE (tree.project_key, tree.path) > ($4, $5::bigint[])
+			ORDER BY tree.project_key, tree.path
+			LIMIT $6`, userID, workspaceID, includeDeleted, afterProject, afterPath, exportChunk)
+		if err != nil {
+			return err
+		}
+		list, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (dom.Todo, error) {
+			var t dom.Todo
+			err := row.Scan(append([]any{&afterProject, &afterPath}, todoDest(&t)...)...)
+			return t, err
+		})
+		if err != nil {
+			return err
+		}
+		for _, t := range list {
+			if err := fn(t); err != nil {
+				return err
+			}
+		}
+		if len(list) < exportChunk {
+			return nil
+		}
+	}
+}
+
+// ReserveIDs draws n IDs for new todos, so that an import can link subtasks to
+// their parents before any of them is inserted.
+func (r *PGTodoRepo) ReserveIDs(ctx context.Context, n int) ([]int64, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT nextval(pg_get_serial_sequence('todos', 'id')) FROM generate_series(1, $1)`, n)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[int64])
+}
+
+// ImportProjects creates a project of the user in the workspace for each of
+// names, which must differ, and returns their IDs in the same order; an import
+// creates its projects in the transaction of its todos.
+func (r *PGTodoRepo) ImportProjects(ctx context.Context, userID, workspaceID int64, names []string) ([]int64, error) {
+	rows, err := r.db.Query(ctx, `
+		INSERT INTO projects (user_id, workspace_id, name)
+		SELECT $1, $2, unnest($3::text[])
+		RETURNING id, name`, userID, workspaceID, names)
+	if err != nil {
+		return nil, err
+	}
+	type project struct {
+		id   int64
+		name string
+	}
+	created, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (project, error) {
+		var p project
+		err := row.Scan(&p.id, &p.name)
+		return p, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	// RETURNING does not promise the order of the input.
+	byName := make(map[string]int64, len(created))
+	for _, p := range created {
+		byName[p.name] = p.id
+	}
+	ids := make([]int64, len(names))
+	for i, name := range names {
+		ids[i] = byName[name]
+	}
+	return ids, nil
+}
+
+// Import inserts todos that already carry their IDs (see ReserveIDs) together
+// with their tags and reminders, and records a created event for each. Parents
+// must come before their subtasks, in this call or an earlier one of the same
+// transaction. Todos go to the end of their siblings in the order given.
+//
+// Unlike Create it keeps IsDone, CreatedAt and DeletedAt as given, and
+// reminders whose time has already passed are stored as sent, so that
+// historical data does not set off a burst of reminders.
+func (r *PGTodoRepo) Import(ctx context.Context, actorID int64, list []dom.Todo) error {
+	n := len(list)
+	var (
+		ids, users, workspaces = make([]int64, n), make([]int64, n), make([]int64, n)
+		titles, descs          = make([]string, n), make([]string, n)
+		done                   = make([]bool, n)
+		due, starts            = make([]*time.Time, n), make([]*time.Time, n)
+		created, deleted       = make([]time.Time, n), make([]*time.Time, n)
+		projects, parents      = make([]*int64, n), make([]*int64, n)
+		rules, zones           = make([]string, n), make([]string, n)
+
+		tagTodos, tagUsers []int64
+		tagNames           []string
+		remTodos           []int64
+		remOffsets         []time.Duration
+	)
+	now := time.Now().UTC()
+	for i, t := range list {
+		ids[i], users[i], workspaces[i] = t.ID, t.UserID, t.WorkspaceID
+		titles[i], descs[i], done[i] = t.Title, t.Description, t.IsDone
+		due[i], starts[i], deleted[i] = t.DueAt, t.RecurrenceStart, t.DeletedAt
+		projects[i], parents[i] = t.ProjectID, t.ParentID
+		rules[i], zones[i] = t.Recurrence, t.RecurrenceTZ
+		created[i] = t.CreatedAt
+		if created[i].IsZero() {
+			created[i] = now
+		}
+		for _, name := range t.Tags {
+			tagTodos, tagUsers, tagNames = append(tagTodos, t.ID), append(tagUsers, t.UserID), append(tagNames, name)
+		}
+		for _, d := range t.Reminders {
+			remTodos, remOffsets = append(remTodos, t.ID), append(remOffsets, d)
+		}
+	}
+	return pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		// Each todo lands one gap after the last sibling already stored or
+		// imported before it.
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO todos (id, user_id, workspace_id, title, description, is_done, due_at, project_id, parent_id,
+				recurrence, recurrence_tz, recurrence_start, created_at, updated_at, deleted_at, position)
+			SELECT i.id, i.user_id, i.workspace_id, i.title, i.description, i.is_done, i.due_at, i.project_id, i.parent_id,
+				NULLIF(i.recurrence, ''), NULLIF(i.recurrence_tz, ''), i.recurrence_start, i.created_at, $15, i.deleted_at,
+				`+nextPositionSQL("i.user_id", "i.workspace_id", "i.project_id", "i.parent_id")+` + `+strconv.Itoa(positionGap)+` *
+					(row_number() OVER (PARTITION BY i.user_id, i.workspace_id, i.project_id, i.parent_id ORDER BY i.ord) - 1)
+			FROM unnest($1::bigint[], $2::bigint[], $3::bigint[], $4::text[], $5::text[], $6::boolean[],
+				$7::timestamptz[], $8::bigint[], $9::bigint[], $10::text[], $11::text[], $12::timestamptz[],
+				$13::timestamptz[], $14::timestamptz[])
+				WITH ORDINALITY AS i(id, user_id, workspace_id, title, description, is_done,
+					due_at, project_id, parent_id, recurrence, recurrence_tz, recurrence_start,
+					created_at, deleted_at, ord)
+			ORDER BY i.ord`,
+			ids, users, workspaces, titles, descs, done, due, projects, parents, rules, zones, starts,
+			created, deleted, now); err != nil {
+			return err
+		}
+		if len(tagNames) > 0 {
+			if _, err := tx.Exec(ctx, `
+				INSERT INTO tags (user_id, name)
+				SELECT DISTINCT ON (user_id, lower(name)) user_id, name
+				FROM unnest($1::bigint[], $2::text[]) WITH ORDINALITY AS x(user_id, name, ord)
+				ORDER BY user_id, lower(name), ord
+				ON CONFLICT (user_id, lower(name)) DO NOTHING`, tagUsers, tagNames); err != nil {
+				return err
+			}
+			if _, err := tx.Exec(ctx, `
+				INSERT INTO todo_tags (todo_id, tag_id)
+				SELECT x.todo_id, tg.id
+				FROM unnest($1::bigint[], $2::bigint[], $3::text[]) AS x(todo_id, user_id, name)
+				JOIN tags tg ON tg.user_id = x.user_id AND lower(tg.name) = lower(x.name)
+				ON CONFLICT DO NOTHING`, tagTodos, tagUsers, tagNames); err != nil {
+				return err
+			}
+		}
+		if len(remTodos) > 0 {
+			if _, err := tx.Exec(ctx, `
+				INSERT INTO todo_reminders (todo_id, before_due, remind_at, sent_at)
+				SELECT x.todo_id, x.before_due, t.due_at - x.before_due,
+					CASE WHEN t.due_at - x.before_due <= $3 THEN $3::timestamptz END
+				FROM unnest($1::bigint[], $2::interval[]) AS x(todo_id, before_due)
+				JOIN todos t ON t.id = x.todo_id
+				ON CONFLICT (todo_id, before_due) DO NOTHING`, remTodos, remOffsets, now); err != nil {
+				return err
+			}
+		}
+		stored, err := collectTodos(tx.Query(ctx, `SELECT `+todoColumns+` FROM todos WHERE id = ANY($1) ORDER BY id`, ids))
+		if err != nil {
+			return err
+		}
+		changes := make([]string, len(stored))
+		storedIDs := make([]int64, len(stored))
+		for i, t := range stored {
+			b, err := json.Marshal(createdChanges(t))
+			if err != nil {
+				return err
+			}
+			changes[i], storedIDs[i] = string(b), t.ID
+		}
+		_, err = tx.Exec(ctx, `
+			WITH imported AS (
+				SELECT t.id, t.workspace_id, t.user_id, x.changes
+				FROM unnest($1::bigint[], $2::jsonb[]) AS x(id, changes) JOIN todos t ON t.id = x.id
+			)`+insertEventsSQL("imported", "$3", dom.TodoEventCreated), storedIDs, changes, actorID)
+		return err
+	})
+}
@@ -0,0 +1,160 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	dom "Worker/internal/domain"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// positionGap is the distance between neighbours after (re)numbering. A move puts
+// the todo halfway between its new neighbours, so about log2(positionGap) moves
+// into the same spot fit before the list has to be renumbered.
+const positionGap = 1024
+
+// ErrAnchorNotFound is returned by Move when before/after does not name a live todo
+// of the user in the target project.
+var ErrAnchorNotFound = errors.New("anchor todo not found in target project")
+
+// nextPositionSQL returns an expression for the position after the last sibling
+// (same user, workspace, project and parent); the arguments are the caller's SQL expressions.
+func nextPositionSQL(userArg, workspaceArg, projectArg, parentArg string) string {
+	return `COALESCE((SELECT max(p.position) FROM todos p
+		WHERE p.user_id = ` + userArg + ` AND p.workspace_id = ` + workspaceArg + ` AND p.project_id IS NOT DISTINCT FROM ` + projectArg + `
+		AND p.parent_id IS NOT DISTINCT FROM ` + parentArg + ` AND p.deleted_at IS NULL), 0) + ` + strconv.Itoa(positionGap)
+}
+
+// Move puts the todo into projectID (nil = no project) right after afterID or right
+// before beforeID (0 = unset), among its siblings (todos with the same parent).
+// With neither it goes to the end. Only the moved row is written unless its
+// neighbours have no room left, in which case just those siblings are renumbered.
+// Subtasks follow the todo into the new project.
+func (r *PGTodoRepo) Move(ctx context.Context, actorID, userID, id int64, projectID *int64, beforeID, afterID int64) (dom.Todo, error) {
+	var out dom.Todo
+	err := pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		before, err := lockTodo(ctx, tx, userID, id)
+		if err != nil {
+			return err
+		}
+		g := siblings{userID: userID, workspaceID: before.WorkspaceID, projectID: projectID, parentID: before.ParentID}
+		pos, ok, err := freePosition(ctx, tx, g, id, beforeID, afterID)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			if err := renumberPositions(ctx, tx, g); err != nil {
+				return err
+			}
+			if pos, _, err = freePosition(ctx, tx, g, id, beforeID, afterID); err != nil {
+				return err
+			}
+		}
+		if _, err := tx.Exec(ctx, `
+			UPDATE todos SET project_id = $3, position = $4, updated_at = NOW()
+			WHERE id = $1 AND user_id = $2`, id, userID, projectID, pos); err != nil {
+			return err
+		}
+		if err := setSubtreeProject(ctx, tx, actorID, id, projectID); err != nil {
+			return err
+		}
+		if out, err = scanTodo(tx.QueryRow(ctx, `SELECT `+todoColumns+` FROM todos WHERE id = $1`, id)); err != nil {
+			return err
+		}
+		return recordTodoChanges(ctx, tx, actorID, before, out, dom.TodoEventMoved)
+	})
+	return out, err
+}
+
+// siblings identifies one manually ordered list: todos of a user in a workspace
+// and project under a parent.
+type siblings struct {
+	userID      int64
+	workspaceID int64
+	projectID   *int64
+	parentID    *int64
+}
+
+// where returns the condition selecting the live members of the list except excludeID.
+func (g siblings) where(args *sqlArgs, excludeID int64) string {
+	return "user_id = " + args.add(g.userID) +
+		" AND workspace_id = " + args.add(g.workspaceID) +
+		" AND project_id IS NOT DISTINCT FROM " + args.add(g.projectID) +
+		" AND parent_id IS NOT DISTINCT FROM " + args.add(g.parentID) +
+		" AND deleted_at IS NULL AND id <> " + args.add(excludeID)
+}
+
+// freePosition finds a position for todo id between its requested neighbours.
+// ok is false when the neighbours are adjacent and the list needs renumbering.
+func freePosition(ctx context.Context, db DBTX, g siblings, id int64, beforeID, afterID int64) (int64, bool, error) {
+	// scalar runs "SELECT <expr> FROM todos WHERE <siblings> <extra>"; extra may use args.
+	scalar := func(dst any, expr string, extra func(args *sqlArgs) string) error {
+		var args sqlArgs
+		where := g.where(&args, id)
+		return db.QueryRow(ctx, "SELECT "+expr+" FROM todos WHERE "+where+extra(&args), args...).Scan(dst)
+	}
+	anchor := func(anchorID int64) (int64, error) {
+		var p int64
+		err := scalar(&p, "position", func(a *sqlArgs) string { return " AND id = " + a.add(anchorID) })
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, ErrAnchorNotFound
+		}
+		return p, err
+	}
+	var prev, next *int64
+	switch {
+	case afterID != 0:
+		p, err := anchor(afterID)
+		if err != nil {
+			return 0, false, err
+		}
+		prev = &p
+		if err := scalar(&next, "min(position)", func(a *sqlArgs) string {
+			return " AND position >= " + a.add(p) + " AND id <> " + a.add(afterID)
+		}); err != nil {
+			return 0, false, err
+		}
+	case beforeID != 0:
+		n, err := anchor(beforeID)
+		if err != nil {
+			return 0, false, err
+		}
+		next = &n
+		if err := scalar(&prev, "max(position)", func(a *sqlArgs) string {
+			return " AND position <= " + a.add(n) + " AND id <> " + a.add(beforeID)
+		}); err != nil {
+			return 0, false, err
+		}
+	default:
+		if err := scalar(&prev, "max(position)", func(*sqlArgs) string { return "" }); err != nil {
+			return 0, false, err
+		}
+	}
+	switch {
+	case prev == nil && next == nil:
+		return positionGap, true, nil
+	case prev == nil:
+		return *next - positionGap, true, nil
+	case next == nil:
+		return *prev + positionGap, true, nil
+	case *next-*prev >= 2:
+		return *prev + (*next-*prev)/2, true, nil
+	}
+	return 0, false, nil
+}
+
+// renumberPositions spreads one list of siblings positionGap apart, keeping their
+// current order. Other lists are not touched.
+func renumberPositions(ctx context.Context, db DBTX, g siblings) error {
+	_, err := db.Exec(ctx, `
+		UPDATE todos t SET position = s.rn * $4
+		FROM (
+			SELECT id, row_number() OVER (ORDER BY position, id) AS rn
+			FROM todos WHERE user_id = $1 AND workspace_id = $5 AND project_id IS NOT DISTINCT FROM $2
+				AND parent_id IS NOT DISTINCT FROM $3 AND deleted_at IS NULL
+		) s
+		WHERE t.id = s.id`, g.userID, g.projectID, g.parentID, positionGap, g.workspaceID)
+	return err
+}
@@ -2,151 +2,540 @@ package repo
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	dom "Worker/internal/domain"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// TodoRepo stores todos. userID is the owner the todo must belong to; actorID,
+// the user making a change, is recorded in the todo history (see TodoEventRepo)
+// together with the changed fields, in the same transaction.
 type TodoRepo interface {
-	Create(ctx context.Context, t dom.Todo) (dom.Todo, error)
+	Create(ctx context.Context, actorID int64, t dom.Todo) (dom.Todo, error)
 	GetByID(ctx context.Context, userID, id int64) (dom.Todo, error)
-	List(ctx context.Context, userID int64) ([]dom.Todo, error)
-	Update(ctx context.Context, userID, id int64, patch dom.Todo) (dom.Todo, error)
-	SoftDelete(ctx context.Context, userID, id int64) error
-	MarkDone(ctx context.Context, userID, id int64, done bool) (dom.Todo, error)
-	Search(ctx context.Context, userID int64, q string) ([]dom.Todo, error)
-	Overdue(ctx context.Context, userID int64) ([]dom.Todo, error)
+	List(ctx context.Context, userID int64, q dom.TodoListQuery) (dom.TodoPage, error)
+	Update(ctx context.Context, actorID, userID, id int64, patch dom.Todo) (dom.Todo, error)
+	SoftDelete(ctx context.Context, actorID, userID, id, ifVersion int64) error
+	MarkDone(ctx context.Context, actorID, userID, id int64, done bool, ifVersion int64) (dom.Todo, error)
+	Search(ctx context.Context, userID, workspaceID int64, q string, tags dom.TagFilter) ([]dom.TodoSearchHit, error)
+	Overdue(ctx context.Context, userID, workspaceID int64) ([]dom.Todo, error)
+	Access(ctx context.Context, userID, id int64) (dom.Todo, error)
+	ListShared(ctx context.Context, userID int64, q dom.TodoListQuery) (dom.TodoPage, error)
+	Collaborators(ctx context.Context, id int64) ([]int64, error)
+	Move(ctx context.Context, actorID, userID, id int64, projectID *int64, beforeID, afterID int64) (dom.Todo, error)
+	Children(ctx context.Context, userID, parentID int64) ([]dom.Todo, error)
+	Depth(ctx context.Context, userID, id int64) (int, error)
+	ReorderChildren(ctx context.Context, actorID, userID, parentID int64, ids []int64) error
+	CompleteSubtree(ctx context.Context, actorID, userID, id, ifVersion int64) (dom.Todo, error)
+	CompleteAndRepeat(ctx context.Context, actorID, userID, id, ifVersion int64, next dom.Todo) (dom.Todo, error)
+	Trashed(ctx context.Context, userID, id int64) (dom.Todo, error)
+	Restore(ctx context.Context, actorID, userID, id int64) (dom.Todo, error)
+	Purge(ctx context.Context, actorID, userID, id, ifVersion int64) error
+	QueueWebhooks(ctx context.Context, userIDs []int64, e dom.WebhookEvent) error
+	Changes(ctx context.Context, userID int64, q dom.TodoChangesQuery) (dom.TodoChanges, error)
+	Export(ctx context.Context, userID, workspaceID int64, includeDeleted bool, fn func(dom.Todo) error) error
+	ReserveIDs(ctx context.Context, n int) ([]int64, error)
+	ImportProjects(ctx context.Context, userID, workspaceID int64, names []string) ([]int64, error)
+	Import(ctx context.Context, actorID int64, list []dom.Todo) error
+	InTx(ctx context.Context, fn func(r TodoRepo) error) error
 }
 
+// ErrVersionConflict is returned by the writes that take an expected version
+// (Update, MarkDone, SoftDelete, CompleteSubtree, CompleteAndRepeat, Purge) when
+// the todo no longer has it. An expected version of 0 matches any.
+var ErrVersionConflict = errors.New("todo version mismatch")
+
+// versionMissed explains a conditional write that changed no todo: the todo is
+// there (live, or with trashed also in the trash), so its version did not
+// match, or it is gone.
+func versionMissed(ctx context.Context, db DBTX, userID, id int64, trashed bool) error {
+	var exists bool
+	if err := db.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM todos WHERE id = $1 AND user_id = $2 AND ($3 OR deleted_at IS NULL))`,
+		id, userID, trashed).Scan(&exists); err != nil {
+		return err
+	}
+	if exists {
+		return ErrVersionConflict
+	}
+	return pgx.ErrNoRows
+}
+
+const todoColumns = `id, user_id, workspace_id, title, description, is_done, due_at, created_at, updated_at, deleted_at,
+	version, project_id, position, parent_id,
+	COALESCE(recurrence, ''), COALESCE(recurrence_tz, ''), recurrence_start,
+	(SELECT count(*) FILTER (WHERE c.is_done) FROM todos c WHERE c.parent_id = todos.id AND c.deleted_at IS NULL) AS children_done,
+	(SELECT count(*) FROM todos c WHERE c.parent_id = todos.id AND c.deleted_at IS NULL) AS children_total,
+	COALESCE((SELECT array_agg(tg.name ORDER BY lower(tg.name)) FROM todo_tags tt JOIN tags tg ON tg.id = tt.tag_id
+		WHERE tt.todo_id = todos.id), '{}') AS tags,
+	COALESCE((SELECT array_agg(rm.before_due ORDER BY rm.before_due) FROM todo_reminders rm
+		WHERE rm.todo_id = todos.id), '{}') AS reminders`
+
 type PGTodoRepo struct {
-	db *pgxpool.Pool
+	db DBTX
 }
 
 func NewPGTodoRepo(db *pgxpool.Pool) *PGTodoRepo {
 	return &PGTodoRepo{db: db}
 }
 
-func (r *PGTodoRepo) Create(ctx context.Context, t dom.Todo) (dom.Todo, error) {
-	query := `
-		INSERT INTO todos (user_id, title, description, due_at)
-		VALUES ($1, $2, $3, $4)
-		RETURNING id, user_id, title, description, is_done, due_at, created_at, updated_at, deleted_at`
+// InTx runs fn with a repo whose calls all share one transaction, committed if
+// fn returns nil. Called on such a repo it opens a savepoint instead, so one
+// step can fail without losing the others.
+func (r *PGTodoRepo) InTx(ctx context.Context, fn func(r TodoRepo) error) error {
+	return pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		return fn(&PGTodoRepo{db: tx})
+	})
+}
+
+// Create inserts the todo after its last sibling (same project and parent) and
+// attaches its tags (creating missing ones) and reminders in one transaction.
+func (r *PGTodoRepo) Create(ctx context.Context, actorID int64, t dom.Todo) (dom.Todo, error) {
 	var out dom.Todo
-	err := r.db.QueryRow(ctx, query, t.UserID, t.Title, t.Description, t.DueAt).Scan(
-		&out.ID, &out.UserID, &out.Title, &out.Description, &out.IsDone, &out.DueAt,
-		&out.CreatedAt, &out.UpdatedAt, &out.DeletedAt,
-	)
+	err := pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		var id int64
+		err := tx.QueryRow(ctx, `
+			INSERT INTO todos (user_id, workspace_id, title, description, due_at, project_id, parent_id,
+				recurrence, recurrence_tz, recurrence_start, position)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''), NULLIF($9, ''), $10, `+nextPositionSQL("$1", "$2", "$6", "$7")+`)
+			RETURNING id`, t.UserID, t.WorkspaceID, t.Title, t.Description, t.DueAt, t.ProjectID, t.ParentID,
+			t.Recurrence, t.RecurrenceTZ, t.RecurrenceStart).Scan(&id)
+		if err != nil {
+			return err
+		}
+		if err := setTodoTags(ctx, tx, t.UserID, id, t.Tags); err != nil {
+			return err
+		}
+		if err := setTodoReminders(ctx, tx, id, t.Reminders); err != nil {
+			return err
+		}
+		if out, err = scanTodo(tx.QueryRow(ctx, `SELECT `+todoColumns+` FROM todos WHERE id = $1`, id)); err != nil {
+			return err
+		}
+		return recordTodoEvent(ctx, tx, actorID, out, dom.TodoEventCreated, createdChanges(out))
+	})
 	return out, err
 }
 
 func (r *PGTodoRepo) GetByID(ctx context.Context, userID, id int64) (dom.Todo, error) {
 	query := `
-		SELECT id, user_id, title, description, is_done, due_at, created_at, updated_at, deleted_at
+		SELECT ` + todoColumns + `
 		FROM todos WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL`
-	var t dom.Todo
-	err := r.db.QueryRow(ctx, query, id, userID).Scan(
-		&t.ID, &t.UserID, &t.Title, &t.Description, &t.IsDone, &t.DueAt,
-		&t.CreatedAt, &t.UpdatedAt, &t.DeletedAt,
-	)
-	return t, err
+	return scanTodo(r.db.QueryRow(ctx, query, id, userID))
+}
+
+// todoSortKey maps a whitelisted sort key to its SQL expression, the type its
+// cursor value is cast to, and how that value is read back from a row.
+type todoSortKey struct {
+	expr  string
+	cast  string
+	value func(t dom.Todo) string
+}
+
+var todoSortKeys = map[string]todoSortKey{
+	dom.SortCreatedAt: {
+		expr:  "created_at",
+		cast:  "timestamptz",
+		value: func(t dom.Todo) string { return t.CreatedAt.UTC().Format(time.RFC3339Nano) },
+	},
+	dom.SortUpdatedAt: {
+		expr:  "updated_at",
+		cast:  "timestamptz",
+		value: func(t dom.Todo) string { return t.UpdatedAt.UTC().Format(time.RFC3339Nano) },
+	},
+	// Todos without a due date sort after every dated one.
+	dom.SortDueAt: {
+		expr: "COALESCE(due_at, 'infinity'::timestamptz)",
+		cast: "timestamptz",
+		value: func(t dom.Todo) string {
+			if t.DueAt == nil {
+				return "infinity"
+			}
+			return t.DueAt.UTC().Format(time.RFC3339Nano)
+		},
+	},
+	dom.SortTitle: {
+		expr:  "title",
+		cast:  "text",
+		value: func(t dom.Todo) string { return t.Title },
+	},
+	dom.SortPosition: {
+		expr:  "position",
+		cast:  "bigint",
+		value: func(t dom.Todo) string { return strconv.FormatInt(t.Position, 10) },
+	},
+	dom.SortDeletedAt: {
+		expr: "deleted_at",
+		cast: "timestamptz",
+		value: func(t dom.Todo) string {
+			if t.DeletedAt == nil {
+				return ""
+			}
+			return t.DeletedAt.UTC().Format(time.RFC3339Nano)
+		},
+	},
 }
 
-func (r *PGTodoRepo) List(ctx context.Context, userID int64) ([]dom.Todo, error) {
+// List returns one page of the user's todos in q.WorkspaceID using keyset
+// pagination on (sort key, id). It fetches one extra row to know whether a next page exists.
+func (r *PGTodoRepo) List(ctx context.Context, userID int64, q dom.TodoListQuery) (dom.TodoPage, error) {
+	var args sqlArgs
+	scope := "user_id = " + args.add(userID)
+	return r.listPage(ctx, todoColumns, scanTodo, args, scope, q)
+}
+
+// listPage runs a List-style query: scope is the condition selecting whose todos
+// are listed, cols and scan the selected columns and how a row is read back.
+func (r *PGTodoRepo) listPage(ctx context.Context, cols string, scan func(pgx.Row) (dom.Todo, error),
+	args sqlArgs, scope string, q dom.TodoListQuery) (dom.TodoPage, error) {
+	sk, ok := todoSortKeys[q.Sort]
+	if !ok {
+		return dom.TodoPage{}, fmt.Errorf("unknown sort key %q", q.Sort)
+	}
+	where := []string{scope, "workspace_id = " + args.add(q.WorkspaceID), "deleted_at IS NULL"}
+	if q.Trashed {
+		where[2] = `deleted_at IS NOT NULL AND NOT EXISTS (
+			SELECT 1 FROM todos p WHERE p.id = todos.parent_id AND p.deleted_at = todos.deleted_at)`
+	}
+	if q.IsDone != nil {
+		where = append(where, "is_done = "+args.add(*q.IsDone))
+	}
+	if q.DueBefore != nil {
+		where = append(where, "due_at < "+args.add(*q.DueBefore))
+	}
+	if q.DueAfter != nil {
+		where = append(where, "due_at > "+args.add(*q.DueAfter))
+	}
+	if q.CreatedAfter != nil {
+		where = append(where, "created_at > "+args.add(*q.CreatedAfter))
+	}
+	if q.ProjectID != nil {
+		where = append(where, "project_id = "+args.add(*q.ProjectID))
+	}
+	if q.TopLevelOnly {
+		where = append(where, "parent_id IS NULL")
+	}
+	if cond := tagFilterSQL(&args, q.Tags); cond != "" {
+		where = append(where, cond)
+	}
+	dir, cmp := "ASC", ">"
+	if q.Desc {
+		dir, cmp = "DESC", "<"
+	}
+	if q.After != nil {
+		where = append(where, fmt.Sprintf("(%s, id) %s (%s::%s, %s)",
+			sk.expr, cmp, args.add(q.After.Value), sk.cast, args.add(q.After.ID)))
+	}
 	query := `
-		SELECT id, user_id, title, description, is_done, due_at, created_at, updated_at, deleted_at
-		FROM todos WHERE user_id = $1 AND deleted_at IS NULL ORDER BY created_at DESC`
-	rows, err := r.db.Query(ctx, query, userID)
+		SELECT ` + cols + `
+		FROM todos WHERE ` + strings.Join(where, " AND ") + `
+		ORDER BY ` + sk.expr + ` ` + dir + `, id ` + dir + `
+		LIMIT ` + args.add(q.Limit+1)
+	rows, err := r.db.Query(ctx, query, args...)
 	if err != nil {
-		return nil, err
+		return dom.TodoPage{}, err
 	}
 	defer rows.Close()
 	var list []dom.Todo
 	for rows.Next() {
-		var t dom.Todo
-		if err := rows.Scan(&t.ID, &t.UserID, &t.Title, &t.Description, &t.IsDone, &t.DueAt,
-			&t.CreatedAt, &t.UpdatedAt, &t.DeletedAt); err != nil {
-			return nil, err
+		t, err := scan(rows)
+		if err != nil {
+			return dom.TodoPage{}, err
 		}
 		list = append(list, t)
 	}
-	return list, rows.Err()
+	if err := rows.Err(); err != nil {
+		return dom.TodoPage{}, err
+	}
+	page := dom.TodoPage{Items: list}
+	if len(list) > q.Limit {
+		page.Items = list[:q.Limit]
+		last := page.Items[q.Limit-1]
+		page.Next = &dom.TodoCursor{Sort: q.Sort, Desc: q.Desc, Value: sk.value(last), ID: last.ID}
+	}
+	return page, nil
 }
 
-func (r *PGTodoRepo) Update(ctx context.Context, userID, id int64, patch dom.Todo) (dom.Todo, error) {
-	query := `
-		UPDATE todos SET title = $3, description = $4, due_at = $5, is_done = $6, updated_at = NOW()
-		WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
-		RETURNING id, user_id, title, description, is_done, due_at, created_at, updated_at, deleted_at`
-	var t dom.Todo
-	err := r.db.QueryRow(ctx, query, id, userID, patch.Title, patch.Description, patch.DueAt, patch.IsDone).Scan(
-		&t.ID, &t.UserID, &t.Title, &t.Description, &t.IsDone, &t.DueAt,
-		&t.CreatedAt, &t.UpdatedAt, &t.DeletedAt,
-	)
-	return t, err
+// Update writes all fields of patch. Tags and reminders are replaced only when
+// non-nil; reminders follow a changed due date either way. A non-zero
+// patch.Version makes the write conditional: ErrVersionConflict if the todo has
+// been changed since.
+// A todo moved to another project goes to the end of that project and takes its
+// subtasks along.
+func (r *PGTodoRepo) Update(ctx context.Context, actorID, userID, id int64, patch dom.Todo) (dom.Todo, error) {
+	var out dom.Todo
+	err := pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		before, err := lockTodo(ctx, tx, userID, id)
+		if err != nil {
+			return err
+		}
+		tag, err := tx.Exec(ctx, `
+			UPDATE todos SET title = $3, description = $4, due_at = $5, is_done = $6, project_id = $7,
+				position = CASE WHEN project_id IS DISTINCT FROM $7 THEN `+nextPositionSQL("$2", "todos.workspace_id", "$7", "todos.parent_id")+` ELSE position END,
+				recurrence = NULLIF($8, ''), recurrence_tz = NULLIF($9, ''), recurrence_start = $10,
+				updated_at = NOW()
+			WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL AND ($11::bigint = 0 OR version = $11)`,
+			id, userID, patch.Title, patch.Description, patch.DueAt, patch.IsDone, patch.ProjectID,
+			patch.Recurrence, patch.RecurrenceTZ, patch.RecurrenceStart, patch.Version)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			// The row is locked and live, so only the version can have missed.
+			return ErrVersionConflict
+		}
+		if err := setSubtreeProject(ctx, tx, actorID, id, patch.ProjectID); err != nil {
+			return err
+		}
+		if patch.Tags != nil {
+			if err := setTodoTags(ctx, tx, userID, id, patch.Tags); err != nil {
+				return err
+			}
+		}
+		if patch.Reminders != nil {
+			err = setTodoReminders(ctx, tx, id, patch.Reminders)
+		} else {
+			err = syncReminders(ctx, tx, id)
+		}
+		if err != nil {
+			return err
+		}
+		if out, err = scanTodo(tx.QueryRow(ctx, `SELECT `+todoColumns+` FROM todos WHERE id = $1`, id)); err != nil {
+			return err
+		}
+		return recordTodoChanges(ctx, tx, actorID, before, out, dom.TodoEventUpdated)
+	})
+	return out, err
 }
 
-func (r *PGTodoRepo) SoftDelete(ctx context.Context, userID, id int64) error {
+// SoftDelete marks the todo and its whole subtree deleted in one statement and
+// records a deleted event for each of them. A non-zero ifVersion must be the
+// todo's version, or ErrVersionConflict.
+func (r *PGTodoRepo) SoftDelete(ctx context.Context, actorID, userID, id, ifVersion int64) error {
 	now := time.Now().UTC()
-	_, err := r.db.Exec(ctx, `UPDATE todos SET deleted_at = $3, updated_at = $3 WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL`, id, userID, now)
-	return err
+	tag, err := r.db.Exec(ctx, `
+		WITH RECURSIVE sub AS (
+			SELECT id FROM todos WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL AND ($5::bigint = 0 OR version = $5)
+			UNION ALL
+			SELECT t.id FROM todos t JOIN sub ON t.parent_id = sub.id WHERE t.deleted_at IS NULL
+		), deleted AS (
+			UPDATE todos SET deleted_at = $3, updated_at = $3 WHERE id IN (SELECT id FROM sub)
+			RETURNING id, workspace_id, user_id,
+				jsonb_build_object('deleted_at', jsonb_build_object('from', NULL, 'to', $3::timestamptz)) AS changes
+		)`+insertEventsSQL("deleted", "$4", dom.TodoEventDeleted), id, userID, now, actorID, ifVersion)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		// Every deleted todo records an event, so none was deleted.
+		return versionMissed(ctx, r.db, userID, id, false)
+	}
+	return nil
 }
 
-func (r *PGTodoRepo) MarkDone(ctx context.Context, userID, id int64, done bool) (dom.Todo, error) {
-	query := `
-		UPDATE todos SET is_done = $3, updated_at = NOW()
-		WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
-		RETURNING id, user_id, title, description, is_done, due_at, created_at, updated_at, deleted_at`
-	var t dom.Todo
-	err := r.db.QueryRow(ctx, query, id, userID, done).Scan(
-		&t.ID, &t.UserID, &t.Title, &t.Description, &t.IsDone, &t.DueAt,
-		&t.CreatedAt, &t.UpdatedAt, &t.DeletedAt,
-	)
-	return t, err
+// MarkDone sets whether the todo is done. A non-zero ifVersion must be the
+// todo's version, or ErrVersionConflict.
+func (r *PGTodoRepo) MarkDone(ctx context.Context, actorID, userID, id int64, done bool, ifVersion int64) (dom.Todo, error) {
+	var out dom.Todo
+	err := pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		before, err := lockTodo(ctx, tx, userID, id)
+		if err != nil {
+			return err
+		}
+		if out, err = scanTodo(tx.QueryRow(ctx, `
+			UPDATE todos SET is_done = $2, updated_at = NOW() WHERE id = $1 AND ($3::bigint = 0 OR version = $3)
+			RETURNING `+todoColumns, id, done, ifVersion)); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				// The row is locked and live, so only the version can have missed.
+				return ErrVersionConflict
+			}
+			return err
+		}
+		action := dom.TodoEventCompleted
+		if !done {
+			action = dom.TodoEventUpdated
+		}
+		return recordTodoChanges(ctx, tx, actorID, before, out, action)
+	})
+	return out, err
 }
 
-func (r *PGTodoRepo) Search(ctx context.Context, userID int64, q string) ([]dom.Todo, error) {
-	pattern := "%" + q + "%"
+// CompleteAndRepeat marks a recurring todo done and creates next, its following
+// occurrence, in one transaction. A todo that is already done is returned as is
+// without a new occurrence, so completing it twice does not repeat it twice.
+// A non-zero ifVersion must be the todo's version, or ErrVersionConflict.
+func (r *PGTodoRepo) CompleteAndRepeat(ctx context.Context, actorID, userID, id, ifVersion int64, next dom.Todo) (dom.Todo, error) {
+	var out dom.Todo
+	err := pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		before, err := lockTodo(ctx, tx, userID, id)
+		if err != nil {
+			return err
+		}
+		if before.IsDone {
+			if ifVersion != 0 && ifVersion != before.Version {
+				return ErrVersionConflict
+			}
+			out = before
+			return nil
+		}
+		tag, err := tx.Exec(ctx, `
+			UPDATE todos SET is_done = TRUE, updated_at = NOW() WHERE id = $1 AND ($2::bigint = 0 OR version = $2)`,
+			id, ifVersion)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			// The row is locked and live, so only the version can have missed.
+			return ErrVersionConflict
+		}
+		if _, err := (&PGTodoRepo{db: tx}).Create(ctx, actorID, next); err != nil {
+			return err
+		}
+		if out, err = scanTodo(tx.QueryRow(ctx, `SELECT `+todoColumns+` FROM todos WHERE id = $1`, id)); err != nil {
+			return err
+		}
+		return recordTodoChanges(ctx, tx, actorID, before, out, dom.TodoEventCompleted)
+	})
+	return out, err
+}
+
+// searchLimit caps full-text results; ts_headline runs only on these rows.
+const searchLimit = 100
+
+// Search runs a full-text query (see buildTSQuery for the syntax) over title and
+// description of the user's todos in a workspace, most relevant first, with highlighted snippets.
+func (r *PGTodoRepo) Search(ctx context.Context, userID, workspaceID int64, q string, tags dom.TagFilter) ([]dom.TodoSearchHit, error) {
+	tsq := buildTSQuery(q)
+	if tsq == "" {
+		return nil, nil
+	}
+	args := sqlArgs{userID, tsq, searchLimit, workspaceID}
+	tagCond := ""
+	if cond := tagFilterSQL(&args, tags); cond != "" {
+		tagCond = " AND " + cond
+	}
+	titleOpts := "StartSel=" + dom.HighlightStart + ", StopSel=" + dom.HighlightStop + ", HighlightAll=true"
+	descOpts := "StartSel=" + dom.HighlightStart + ", StopSel=" + dom.HighlightStop + ", MaxFragments=2, MaxWords=20, MinWords=5"
 	query := `
-		SELECT id, user_id, title, description, is_done, due_at, created_at, updated_at, deleted_at
-		FROM todos WHERE user_id = $1 AND deleted_at IS NULL AND (title ILIKE $2 OR description ILIKE $2)
-		ORDER BY created_at DESC`
-	rows, err := r.db.Query(ctx, query, userID, pattern)
+		SELECT ` + todoColumns + `, rank,
+			ts_headline('simple', title, query, ` + args.add(titleOpts) + `),
+			ts_headline('simple', COALESCE(description, ''), query, ` + args.add(descOpts) + `)
+		FROM (
+			SELECT todos.*, query, ts_rank(search_vector, query) AS rank
+			FROM todos, to_tsquery('simple', $2) AS query
+			WHERE user_id = $1 AND workspace_id = $4 AND deleted_at IS NULL AND search_vector @@ query` + tagCond + `
+			ORDER BY rank DESC, created_at DESC, id DESC
+			LIMIT $3
+		) AS todos
+		ORDER BY rank DESC, created_at DESC, id DESC`
+	rows, err := r.db.Query(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var list []dom.Todo
+	var list []dom.TodoSearchHit
 	for rows.Next() {
-		var t dom.Todo
-		if err := rows.Scan(&t.ID, &t.UserID, &t.Title, &t.Description, &t.IsDone, &t.DueAt,
-			&t.CreatedAt, &t.UpdatedAt, &t.DeletedAt); err != nil {
+		var h dom.TodoSearchHit
+		dest := append(todoDest(&h.Todo), &h.Rank, &h.TitleHighlight, &h.DescriptionHighlight)
+		if err := rows.Scan(dest...); err != nil {
 			return nil, err
 		}
-		list = append(list, t)
+		list = append(list, h)
 	}
 	return list, rows.Err()
 }
 
-func (r *PGTodoRepo) Overdue(ctx context.Context, userID int64) ([]dom.Todo, error) {
+func (r *PGTodoRepo) Overdue(ctx context.Context, userID, workspaceID int64) ([]dom.Todo, error) {
 	query := `
-		SELECT id, user_id, title, description, is_done, due_at, created_at, updated_at, deleted_at
-		FROM todos WHERE user_id = $1 AND deleted_at IS NULL AND is_done = FALSE AND due_at IS NOT NULL AND due_at < NOW()
+		SELECT ` + todoColumns + `
+		FROM todos WHERE user_id = $1 AND workspace_id = $2 AND deleted_at IS NULL
+			AND is_done = FALSE AND due_at IS NOT NULL AND due_at < NOW()
 		ORDER BY due_at ASC`
-	rows, err := r.db.Query(ctx, query, userID)
+	return collectTodos(r.db.Query(ctx, query, userID, workspaceID))
+}
+
+// setTodoTags replaces the tags of a todo with names, creating tags the user does not have yet.
+// Names are matched case-insensitively; an existing tag keeps its original spelling.
+func setTodoTags(ctx context.Context, db DBTX, userID, todoID int64, names []string) error {
+	if _, err := db.Exec(ctx, `DELETE FROM todo_tags WHERE todo_id = $1`, todoID); err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	if _, err := db.Exec(ctx, `
+		INSERT INTO tags (user_id, name) SELECT $1, unnest($2::text[])
+		ON CONFLICT (user_id, lower(name)) DO NOTHING`, userID, names); err != nil {
+		return err
+	}
+	_, err := db.Exec(ctx, `
+		INSERT INTO todo_tags (todo_id, tag_id)
+		SELECT $1, id FROM tags WHERE user_id = $2 AND lower(name) = ANY(SELECT lower(unnest($3::text[])))
+		ON CONFLICT DO NOTHING`, todoID, userID, names)
+	return err
+}
+
+// tagFilterSQL returns a condition on todos.id for the tag filter, or "" if it is empty.
+func tagFilterSQL(args *sqlArgs, f dom.TagFilter) string {
+	if len(f.Names) == 0 {
+		return ""
+	}
+	lower := make([]string, len(f.Names))
+	for i, n := range f.Names {
+		lower[i] = strings.ToLower(n)
+	}
+	match := `FROM todo_tags tt JOIN tags tg ON tg.id = tt.tag_id
+		WHERE tt.todo_id = todos.id AND lower(tg.name) = ANY(` + args.add(lower) + `)`
+	if f.MatchAll {
+		return `(SELECT count(DISTINCT lower(tg.name)) ` + match + `) = ` + args.add(len(lower))
+	}
+	return `EXISTS (SELECT 1 ` + match + `)`
+}
+
+// todoDest returns scan destinations matching todoColumns, in order.
+func todoDest(t *dom.Todo) []any {
+	return []any{&t.ID, &t.UserID, &t.WorkspaceID, &t.Title, &t.Description, &t.IsDone, &t.DueAt,
+		&t.CreatedAt, &t.UpdatedAt, &t.DeletedAt, &t.Version, &t.ProjectID, &t.Position, &t.ParentID,
+		&t.Recurrence, &t.RecurrenceTZ, &t.RecurrenceStart, &t.ChildrenDone, &t.ChildrenTotal, &t.Tags,
+		&t.Reminders}
+}
+
+// scanTodo reads one row selected with todoColumns.
+func scanTodo(row pgx.Row) (dom.Todo, error) {
+	var t dom.Todo
+	err := row.Scan(todoDest(&t)...)
+	return t, err
+}
+
+// collectTodos reads all rows selected with todoColumns.
+func collectTodos(rows pgx.Rows, err error) ([]dom.Todo, error) {
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 	var list []dom.Todo
 	for rows.Next() {
-		var t dom.Todo
-		if err := rows.Scan(&t.ID, &t.UserID, &t.Title, &t.Description, &t.IsDone, &t.DueAt,
-			&t.CreatedAt, &t.UpdatedAt, &t.DeletedAt); err != nil {
+		t, err := scanTodo(rows)
+		if err != nil {
 			return nil, err
 		}
 		list = append(list, t)
 	}
 	return list, rows.Err()
 }
+
+// sqlArgs collects positional arguments for a dynamically built query.
+type sqlArgs []any
+
+// add appends v and returns its placeholder ("$1", "$2", ...).
+func (a *sqlArgs) add(v any) string {
+	*a = append(*a, v)
+	return "$" + strconv.Itoa(len(*a))
+}
@@ -0,0 +1,72 @@
+package repo
+
+import (
+	"context"
+
+	dom "Worker/internal/domain"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Access returns a live todo with the permission userID has on it (see the
+// todo_permission SQL function). A todo the user cannot see is pgx.ErrNoRows.
+func (r *PGTodoRepo) Access(ctx context.Context, userID, id int64) (dom.Todo, error) {
+	t, err := scanTodoWithPermission(r.db.QueryRow(ctx, `
+		SELECT `+todoColumns+`, todo_permission(id, $2)
+		FROM todos WHERE id = $1 AND deleted_at IS NULL`, id, userID))
+	if err != nil {
+		return dom.Todo{}, err
+	}
+	if t.Permission == dom.PermissionNone {
+		return dom.Todo{}, pgx.ErrNoRows
+	}
+	return t, nil
+}
+
+// ListShared returns one page of todos other users shared with userID, directly or
+// through a project. Subtasks shared only through their parent are not listed.
+func (r *PGTodoRepo) ListShared(ctx context.Context, userID int64, q dom.TodoListQuery) (dom.TodoPage, error) {
+	var args sqlArgs
+	uid := args.add(userID)
+	scope := `user_id <> ` + uid + ` AND (
+		id IN (SELECT todo_id FROM todo_shares WHERE user_id = ` + uid + `)
+		OR project_id IN (SELECT project_id FROM project_shares WHERE user_id = ` + uid + `))`
+	return r.listPage(ctx, todoColumns+`, todo_permission(id, `+uid+`)`, scanTodoWithPermission, args, scope, q)
+}
+
+// collaboratorsCTE defines, after WITH RECURSIVE, the CTE collaborators: the
+// owner of todo $1 and every user it is shared with, through the todo itself,
+// one of its ancestors or its project.
+const collaboratorsCTE = `chain AS (
+		SELECT id, parent_id, user_id, project_id FROM todos WHERE id = $1
+		UNION ALL
+		SELECT t.id, t.parent_id, t.user_id, t.project_id FROM todos t JOIN chain c ON t.id = c.parent_id
+	), collaborators AS (
+		SELECT user_id FROM chain
+		UNION
+		SELECT s.user_id FROM todo_shares s JOIN chain c ON c.id = s.todo_id
+		UNION
+		SELECT s.user_id FROM project_shares s JOIN chain c ON c.project_id = s.project_id
+	)`
+
+// Collaborators returns the owner of a todo and every user it is shared with,
+// through the todo itself, one of its ancestors or its project.
+func (r *PGTodoRepo) Collaborators(ctx context.Context, id int64) ([]int64, error) {
+	rows, err := r.db.Query(ctx, `
+		WITH RECURSIVE `+collaboratorsCTE+`
+		SELECT user_id FROM collaborators`, id)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[int64])
+}
+
+// scanTodoWithPermission reads a row selected with todoColumns followed by a
+// todo_permission level.
+func scanTodoWithPermission(row pgx.Row) (dom.Todo, error) {
+	var t dom.Todo
+	var level int16
+	err := row.Scan(append(todoDest(&t), &level)...)
+	t.Permission = dom.Permission(level)
+	return t, err
+}
@@ -0,0 +1,122 @@
+package repo
+
+import (
+	"context"
+	"errors"
+
+	dom "Worker/internal/domain"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrChildrenMismatch is returned by ReorderChildren when ids is not exactly the
+// set of live children of the parent.
+var ErrChildrenMismatch = errors.New("ids must list every subtask exactly once")
+
+// Children returns the live direct subtasks of a todo in manual order.
+func (r *PGTodoRepo) Children(ctx context.Context, userID, parentID int64) ([]dom.Todo, error) {
+	query := `
+		SELECT ` + todoColumns + `
+		FROM todos WHERE parent_id = $1 AND user_id = $2 AND deleted_at IS NULL
+		ORDER BY position, id`
+	return collectTodos(r.db.Query(ctx, query, parentID, userID))
+}
+
+// Depth returns how many ancestors the todo has (0 for a top-level todo).
+func (r *PGTodoRepo) Depth(ctx context.Context, userID, id int64) (int, error) {
+	var depth *int
+	err := r.db.QueryRow(ctx, `
+		WITH RECURSIVE up AS (
+			SELECT id, parent_id, 0 AS depth FROM todos WHERE id = $1 AND user_id = $2
+			UNION ALL
+			SELECT t.id, t.parent_id, up.depth + 1 FROM todos t JOIN up ON t.id = up.parent_id
+		)
+		SELECT max(depth) FROM up`, id, userID).Scan(&depth)
+	if err != nil {
+		return 0, err
+	}
+	if depth == nil {
+		return 0, pgx.ErrNoRows
+	}
+	return *depth, nil
+}
+
+// ReorderChildren sets the manual order of a todo's subtasks to the order of ids.
+func (r *PGTodoRepo) ReorderChildren(ctx context.Context, actorID, userID, parentID int64, ids []int64) error {
+	return pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		var locked int64
+		if err := tx.QueryRow(ctx, `
+			SELECT id FROM todos WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL FOR UPDATE`,
+			parentID, userID).Scan(&locked); err != nil {
+			return err
+		}
+		var live int
+		var matched int
+		if err := tx.QueryRow(ctx, `
+			SELECT count(*), count(*) FILTER (WHERE id = ANY($3))
+			FROM todos WHERE parent_id = $1 AND user_id = $2 AND deleted_at IS NULL`,
+			parentID, userID, ids).Scan(&live, &matched); err != nil {
+			return err
+		}
+		if live != len(ids) || matched != len(ids) {
+			return ErrChildrenMismatch
+		}
+		_, err := tx.Exec(ctx, `
+			WITH reordered AS (
+				UPDATE todos t SET position = s.ord * $3, updated_at = NOW()
+				FROM unnest($2::bigint[]) WITH ORDINALITY AS s(id, ord), todos o
+				WHERE t.id = s.id AND t.parent_id = $1 AND o.id = t.id
+				RETURNING t.id, t.workspace_id, t.user_id, o.position AS old_position, t.position,
+					jsonb_build_object('position', jsonb_build_object('from', o.position, 'to', t.position)) AS changes
+			)`+insertEventsSQL("reordered WHERE old_position <> position", "$4", dom.TodoEventReordered),
+			parentID, ids, positionGap, actorID)
+		return err
+	})
+}
+
+// CompleteSubtree marks the todo and every live descendant done in one statement,
+// records a completed event for each todo that was open, and returns the todo.
+// A non-zero ifVersion must be the todo's version, or ErrVersionConflict.
+func (r *PGTodoRepo) CompleteSubtree(ctx context.Context, actorID, userID, id, ifVersion int64) (dom.Todo, error) {
+	var found bool
+	err := r.db.QueryRow(ctx, `
+		WITH RECURSIVE sub AS (
+			SELECT id FROM todos WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL AND ($4::bigint = 0 OR version = $4)
+			UNION ALL
+			SELECT t.id FROM todos t JOIN sub ON t.parent_id = sub.id WHERE t.deleted_at IS NULL
+		), completed AS (
+			UPDATE todos t SET is_done = TRUE, updated_at = NOW()
+			FROM todos o
+			WHERE o.id = t.id AND t.id IN (SELECT id FROM sub) AND (NOT t.is_done OR t.id = $1)
+			RETURNING t.id, t.workspace_id, t.user_id, o.is_done AS was_done,
+				'{"is_done": {"from": false, "to": true}}'::jsonb AS changes
+		), events AS (`+insertEventsSQL("completed WHERE NOT was_done", "$3", dom.TodoEventCompleted)+`
+		)
+		SELECT EXISTS (SELECT 1 FROM completed WHERE id = $1)`, id, userID, actorID, ifVersion).Scan(&found)
+	if err != nil {
+		return dom.Todo{}, err
+	}
+	if !found {
+		// The todo itself is always updated, even when it was done already.
+		return dom.Todo{}, versionMissed(ctx, r.db, userID, id, false)
+	}
+	return r.GetByID(ctx, userID, id)
+}
+
+// setSubtreeProject moves all descendants of a todo into projectID, so subtasks
+// always live in their root's project, and records a moved event for each.
+func setSubtreeProject(ctx context.Context, db DBTX, actorID, id int64, projectID *int64) error {
+	_, err := db.Exec(ctx, `
+		WITH RECURSIVE sub AS (
+			SELECT id FROM todos WHERE parent_id = $1
+			UNION ALL
+			SELECT t.id FROM todos t JOIN sub ON t.parent_id = sub.id
+		), moved AS (
+			UPDATE todos t SET project_id = $2
+			FROM todos o
+			WHERE o.id = t.id AND t.id IN (SELECT id FROM sub) AND t.project_id IS DISTINCT FROM $2
+			RETURNING t.id, t.workspace_id, t.user_id,
+				jsonb_build_object('project_id', jsonb_build_object('from', o.project_id, 'to', t.project_id)) AS changes
+		)`+insertEventsSQL("moved", "$3", dom.TodoEventMoved), id, projectID, actorID)
+	return err
+}
@@ -0,0 +1,149 @@
+package repo
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strconv"
+	"time"
+
+	dom "Worker/internal/domain"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// TombstoneRepo is the worker's view of the tombstones of purged todos.
+type TombstoneRepo interface {
+	// PurgeTombstones deletes up to limit tombstones of todos purged before
+	// cutoff and returns how many it deleted.
+	PurgeTombstones(ctx context.Context, cutoff time.Time, limit int) (int, error)
+}
+
+// todoChange is one entry of the change feed: a live todo or a tombstone.
+type todoChange struct {
+	seq  int64
+	todo *dom.Todo
+	tomb *dom.TodoTombstone
+}
+
+// Changes returns up to q.Limit todos and tombstones of the user in
+// q.WorkspaceID written after q.AfterSeq, in sequence order, with the Seq the
+// page ends at and the XMin of its snapshot.
+//
+// Sequence values are drawn before commit, so a transaction that was running
+// when the previous token was issued may have committed rows at or below
+// q.AfterSeq since. Such rows have a transaction ID of at least q.XMin, the
+// oldest transaction that token's snapshot saw running, and are returned again
+// in addition to the page. Rows of transactions committed before that snapshot
+// may come along too; applying a change twice does no harm.
+func (r *PGTodoRepo) Changes(ctx context.Context, userID int64, q dom.TodoChangesQuery) (dom.TodoChanges, error) {
+	out := dom.TodoChanges{Seq: q.AfterSeq}
+	err := pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		// One snapshot for every query and for the xmin of the next token.
+		if _, err := tx.Exec(ctx, `SET TRANSACTION ISOLATION LEVEL REPEATABLE READ READ ONLY`); err != nil {
+			return err
+		}
+		if err := tx.QueryRow(ctx, `SELECT pg_snapshot_xmin(pg_current_snapshot())::text::bigint`).Scan(&out.XMin); err != nil {
+			return err
+		}
+		var late []todoChange
+		if q.AfterSeq > 0 {
+			var err error
+			late, err = queryChanges(ctx, tx, true, `change_seq <= $3 AND change_xid >= $4::text::xid8`, 0,
+				userID, q.WorkspaceID, q.AfterSeq, strconv.FormatInt(q.XMin, 10))
+			if err != nil {
+				return err
+			}
+		}
+		// A full sync has nothing to delete on the client.
+		page, err := queryChanges(ctx, tx, q.AfterSeq > 0, `change_seq > $3`, q.Limit+1,
+			userID, q.WorkspaceID, q.AfterSeq)
+		if err != nil {
+			return err
+		}
+		if len(page) > q.Limit {
+			page, out.More = page[:q.Limit], true
+		}
+		if len(page) > 0 {
+			out.Seq = page[len(page)-1].seq
+		}
+		for _, c := range append(late, page...) {
+			if c.todo != nil {
+				out.Todos = append(out.Todos, *c.todo)
+			} else {
+				out.Tombstones = append(out.Tombstones, *c.tomb)
+			}
+		}
+		return nil
+	})
+	return out, err
+}
+
+// queryChanges selects the todos and, with tombstones, the tombstones matching
+// cond, merged in sequence order; limit 0 means all of them. $1 and $2 are the
+// user and the workspace.
+func queryChanges(ctx context.Context, tx pgx.Tx, tombstones bool, cond string, limit int, args ...any) ([]todoChange, error) {
+	tail := ` ORDER BY change_seq`
+	if limit > 0 {
+		tail += ` LIMIT ` + strconv.Itoa(limit)
+	}
+	live := ` AND deleted_at IS NULL`
+	if tombstones {
+		live = ""
+	}
+	rows, err := tx.Query(ctx, `
+		SELECT change_seq, `+todoColumns+`
+		FROM todos WHERE user_id = $1 AND workspace_id = $2 AND `+cond+live+tail, args...)
+	if err != nil {
+		return nil, err
+	}
+	out, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (todoChange, error) {
+		var c todoChange
+		var t dom.Todo
+		if err := row.Scan(append([]any{&c.seq}, todoDest(&t)...)...); err != nil {
+			return c, err
+		}
+		if t.DeletedAt != nil {
+			c.tomb = &dom.TodoTombstone{ID: t.ID, DeletedAt: *t.DeletedAt}
+		} else {
+			c.todo = &t
+		}
+		return c, nil
+	})
+	if err != nil || !tombstones {
+		return out, err
+	}
+	rows, err = tx.Query(ctx, `
+		SELECT change_seq, todo_id, purged_at
+		FROM todo_tombstones WHERE user_id = $1 AND workspace_id = $2 AND `+cond+tail, args...)
+	if err != nil {
+		return nil, err
+	}
+	purged, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (todoChange, error) {
+		c := todoChange{tomb: &dom.TodoTombstone{Purged: true}}
+		err := row.Scan(&c.seq, &c.tomb.ID, &c.tomb.DeletedAt)
+		return c, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	out = append(out, purged...)
+	slices.SortFunc(out, func(a, b todoChange) int { return cmp.Compare(a.seq, b.seq) })
+	if limit > 0 && len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+// PurgeTombstones implements TombstoneRepo.
+func (r *PGTodoRepo) PurgeTombstones(ctx context.Context, cutoff time.Time, limit int) (int, error) {
+	tag, err := r.db.Exec(ctx, `
+		DELETE FROM todo_tombstones WHERE todo_id IN (
+			SELECT todo_id FROM todo_tombstones WHERE purged_at < $1
+			ORDER BY purged_at LIMIT $2
+		)`, cutoff, limit)
+	if err != nil {
+		return 0, err
+	}
+	return int(tag.RowsAffected()), nil
+}
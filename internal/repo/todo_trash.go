@@ -0,0 +1,109 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	dom "Worker/internal/domain"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrParentTrashed is returned by Restore for a subtask whose parent is still in the trash.
+var ErrParentTrashed = errors.New("parent todo is in the trash")
+
+// TrashRepo is the worker's view of the trash.
+type TrashRepo interface {
+	// PurgeTrashed hard-deletes up to limit todos deleted before cutoff, with
+	// their subtrees, and returns how many it deleted.
+	PurgeTrashed(ctx context.Context, cutoff time.Time, limit int) (int, error)
+}
+
+// Trashed returns a deleted todo of the user.
+func (r *PGTodoRepo) Trashed(ctx context.Context, userID, id int64) (dom.Todo, error) {
+	return scanTodo(r.db.QueryRow(ctx, `
+		SELECT `+todoColumns+` FROM todos WHERE id = $1 AND user_id = $2 AND deleted_at IS NOT NULL`, id, userID))
+}
+
+// Restore brings a deleted todo back at the end of its siblings, together with
+// the subtasks that were deleted with it. Subtasks deleted earlier on their own
+// stay in the trash.
+func (r *PGTodoRepo) Restore(ctx context.Context, actorID, userID, id int64) (dom.Todo, error) {
+	var out dom.Todo
+	err := pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		var deletedAt time.Time
+		var parentTrashed bool
+		if err := tx.QueryRow(ctx, `
+			SELECT t.deleted_at, p.deleted_at IS NOT NULL
+			FROM todos t LEFT JOIN todos p ON p.id = t.parent_id
+			WHERE t.id = $1 AND t.user_id = $2 AND t.deleted_at IS NOT NULL
+			FOR UPDATE OF t`, id, userID).Scan(&deletedAt, &parentTrashed); err != nil {
+			return err
+		}
+		if parentTrashed {
+			return ErrParentTrashed
+		}
+		if _, err := tx.Exec(ctx, `
+			WITH RECURSIVE sub AS (
+				SELECT id FROM todos WHERE id = $1
+				UNION ALL
+				SELECT t.id FROM todos t JOIN sub ON t.parent_id = sub.id WHERE t.deleted_at = $2
+			), restored AS (
+				UPDATE todos t SET deleted_at = NULL, updated_at = NOW(),
+					position = CASE WHEN t.id = $1 THEN `+nextPositionSQL("t.user_id", "t.workspace_id", "t.project_id", "t.parent_id")+` ELSE t.position END
+				WHERE t.id IN (SELECT id FROM sub)
+				RETURNING t.id, t.workspace_id, t.user_id,
+					jsonb_build_object('deleted_at', jsonb_build_object('from', $2::timestamptz, 'to', NULL)) AS changes
+			)`+insertEventsSQL("restored", "$3", dom.TodoEventRestored), id, deletedAt, actorID); err != nil {
+			return err
+		}
+		var err error
+		out, err = scanTodo(tx.QueryRow(ctx, `SELECT `+todoColumns+` FROM todos WHERE id = $1`, id))
+		return err
+	})
+	return out, err
+}
+
+// Purge hard-deletes a todo of the user, live or in the trash, with its whole
+// subtree. The history keeps a purged event for each deleted todo. A non-zero
+// ifVersion must be the todo's version, or ErrVersionConflict.
+func (r *PGTodoRepo) Purge(ctx context.Context, actorID, userID, id, ifVersion int64) error {
+	tag, err := r.db.Exec(ctx, `
+		WITH RECURSIVE sub AS (
+			SELECT id FROM todos WHERE id = $1 AND user_id = $2 AND ($4::bigint = 0 OR version = $4)
+			UNION ALL
+			SELECT t.id FROM todos t JOIN sub ON t.parent_id = sub.id
+		), purged AS (
+			DELETE FROM todos WHERE id IN (SELECT id FROM sub)
+			RETURNING id, workspace_id, user_id, '{}'::jsonb AS changes
+		)`+insertEventsSQL("purged", "$3", dom.TodoEventPurged), id, userID, actorID, ifVersion)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return versionMissed(ctx, r.db, userID, id, true)
+	}
+	return nil
+}
+
+// PurgeTrashed implements TrashRepo. Rows locked by another worker are skipped.
+func (r *PGTodoRepo) PurgeTrashed(ctx context.Context, cutoff time.Time, limit int) (int, error) {
+	tag, err := r.db.Exec(ctx, `
+		WITH RECURSIVE doomed AS (
+			SELECT id FROM todos WHERE deleted_at < $1
+			ORDER BY deleted_at, id LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		), sub AS (
+			SELECT id FROM doomed
+			UNION
+			SELECT t.id FROM todos t JOIN sub ON t.parent_id = sub.id
+		), purged AS (
+			DELETE FROM todos WHERE id IN (SELECT id FROM sub)
+			RETURNING id, workspace_id, user_id, '{}'::jsonb AS changes
+		)`+insertEventsSQL("purged", "0", dom.TodoEventPurged), cutoff, limit)
+	if err != nil {
+		return 0, err
+	}
+	return int(tag.RowsAffected()), nil
+}
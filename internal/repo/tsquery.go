@@ -0,0 +1,109 @@
+package repo
+
+import (
+	"strings"
+	"unicode"
+)
+
+// buildTSQuery converts a user search string into to_tsquery syntax.
+//
+//	word      → word (all terms are ANDed)
+//	"a b"     → a <-> b (phrase)
+//	pre*      → pre:* (prefix)
+//	-word     → !word (negation, also for phrases: -"a b")
+//
+// Only letters and digits survive as lexeme characters, so user input can never
+// produce tsquery syntax errors. Returns "" if nothing searchable is left.
+func buildTSQuery(q string) string {
+	var terms []string
+	for _, tok := range splitSearchTokens(q) {
+		negate := false
+		if strings.HasPrefix(tok, "-") {
+			negate = true
+			tok = tok[1:]
+		}
+		var term string
+		if strings.HasPrefix(tok, `"`) {
+			term = phraseTerm(strings.Fields(strings.Trim(tok, `"`)))
+		} else {
+			term = wordTerm(tok)
+		}
+		if term == "" {
+			continue
+		}
+		if negate {
+			term = "!" + term
+		}
+		terms = append(terms, term)
+	}
+	return strings.Join(terms, " & ")
+}
+
+// splitSearchTokens splits on whitespace, keeping "quoted phrases" (optionally
+// prefixed with -) as single tokens. An unterminated quote runs to the end.
+func splitSearchTokens(q string) []string {
+	var out []string
+	var cur strings.Builder
+	inQuote := false
+	flush := func() {
+		if cur.Len() > 0 {
+			out = append(out, cur.String())
+			cur.Reset()
+		}
+	}
+	for _, r := range q {
+		switch {
+		case r == '"':
+			cur.WriteRune(r)
+			if inQuote {
+				flush()
+			}
+			inQuote = !inQuote
+		case unicode.IsSpace(r) && !inQuote:
+			flush()
+		default:
+			cur.WriteRune(r)
+		}
+	}
+	flush()
+	return out
+}
+
+// wordTerm turns one bare word into a tsquery term. Punctuation inside the word
+// splits it into a phrase ("e-mail" → e <-> mail); a trailing * makes the last lexeme a prefix.
+func wordTerm(word string) string {
+	prefix := strings.HasSuffix(word, "*")
+	lexemes := lexemesOf(word)
+	if len(lexemes) == 0 {
+		return ""
+	}
+	if prefix {
+		lexemes[len(lexemes)-1] += ":*"
+	}
+	if len(lexemes) == 1 {
+		return lexemes[0]
+	}
+	return "(" + strings.Join(lexemes, " <-> ") + ")"
+}
+
+// phraseTerm joins the lexemes of all words into one phrase.
+func phraseTerm(words []string) string {
+	var lexemes []string
+	for _, w := range words {
+		lexemes = append(lexemes, lexemesOf(w)...)
+	}
+	switch len(lexemes) {
+	case 0:
+		return ""
+	case 1:
+		return lexemes[0]
+	}
+	return "(" + strings.Join(lexemes, " <-> ") + ")"
+}
+
+// lexemesOf lowercases s and splits it on everything that is not a letter or digit.
+func lexemesOf(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
@@ -2,16 +2,24 @@ package repo
 
 import (
 	"context"
+	"strings"
 
 	dom "Worker/internal/domain"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 // UserRepo provides user persistence.
 type UserRepo interface {
 	GetByUsername(ctx context.Context, username string) (dom.User, error)
-	Create(ctx context.Context, username, passwordHash string) (dom.User, error)
+	GetByID(ctx context.Context, id int64) (dom.User, error)
+	GetByLogin(ctx context.Context, login string) (dom.User, error)
+	Create(ctx context.Context, username, email, passwordHash string) (dom.User, error)
+	UpdatePassword(ctx context.Context, id int64, passwordHash string) error
+	List(ctx context.Context, q dom.UserQuery) ([]dom.User, error)
+	SetDisabled(ctx context.Context, id int64, disabled bool) (dom.User, error)
+	SetRole(ctx context.Context, id int64, role string) (dom.User, error)
 }
 
 // PGUserRepo implements UserRepo with Postgres.
@@ -24,25 +32,102 @@ func NewPGUserRepo(db *pgxpool.Pool) *PGUserRepo {
 	return &PGUserRepo{db: db}
 }
 
+const userColumns = `id, username, COALESCE(email, ''), password_hash, role, disabled_at, created_at`
+
 // GetByUsername returns the user by username.
 func (r *PGUserRepo) GetByUsername(ctx context.Context, username string) (dom.User, error) {
-	var u dom.User
-	err := r.db.QueryRow(ctx,
-		`SELECT id, username, password_hash, created_at FROM users WHERE username = $1`,
+	return scanUser(r.db.QueryRow(ctx,
+		`SELECT `+userColumns+` FROM users WHERE username = $1`,
 		username,
-	).Scan(&u.ID, &u.Username, &u.PasswordHash, &u.CreatedAt)
-	return u, err
+	))
+}
+
+// GetByID returns the user by ID.
+func (r *PGUserRepo) GetByID(ctx context.Context, id int64) (dom.User, error) {
+	return scanUser(r.db.QueryRow(ctx, `SELECT `+userColumns+` FROM users WHERE id = $1`, id))
 }
 
-// Create inserts a new user and returns it.
-func (r *PGUserRepo) Create(ctx context.Context, username, passwordHash string) (dom.User, error) {
+// GetByLogin returns the user whose username or e-mail (case-insensitive) is login.
+func (r *PGUserRepo) GetByLogin(ctx context.Context, login string) (dom.User, error) {
+	return scanUser(r.db.QueryRow(ctx, `
+		SELECT `+userColumns+` FROM users WHERE username = $1 OR lower(email) = lower($1)
+		ORDER BY username = $1 DESC LIMIT 1`, login))
+}
+
+// Create inserts a new user and returns it. An empty email is stored as NULL.
+func (r *PGUserRepo) Create(ctx context.Context, username, email, passwordHash string) (dom.User, error) {
 	query := `
-		INSERT INTO users (username, password_hash)
-		VALUES ($1, $2)
-		RETURNING id, username, password_hash, created_at`
+		INSERT INTO users (username, email, password_hash)
+		VALUES ($1, NULLIF($2, ''), $3)
+		RETURNING ` + userColumns
+	return scanUser(r.db.QueryRow(ctx, query, username, email, passwordHash))
+}
+
+// UpdatePassword sets a new password hash and voids the user's outstanding reset tokens.
+func (r *PGUserRepo) UpdatePassword(ctx context.Context, id int64, passwordHash string) error {
+	return pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, `UPDATE users SET password_hash = $2 WHERE id = $1`, id, passwordHash)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return pgx.ErrNoRows
+		}
+		_, err = tx.Exec(ctx, `
+			UPDATE password_reset_tokens SET used_at = NOW() WHERE user_id = $1 AND used_at IS NULL`, id)
+		return err
+	})
+}
+
+// List returns users matching q in ID order.
+func (r *PGUserRepo) List(ctx context.Context, q dom.UserQuery) ([]dom.User, error) {
+	var args sqlArgs
+	where := []string{"id > " + args.add(q.AfterID)}
+	if q.Search != "" {
+		p := args.add("%" + escapeLike(q.Search) + "%")
+		where = append(where, "(username ILIKE "+p+" OR email ILIKE "+p+")")
+	}
+	if q.Role != "" {
+		where = append(where, "role = "+args.add(q.Role))
+	}
+	if q.Disabled != nil {
+		if *q.Disabled {
+			where = append(where, "disabled_at IS NOT NULL")
+		} else {
+			where = append(where, "disabled_at IS NULL")
+		}
+	}
+	query := `SELECT ` + userColumns + ` FROM users WHERE ` + strings.Join(where, " AND ") +
+		` ORDER BY id LIMIT ` + args.add(q.Limit)
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (dom.User, error) {
+		return scanUser(row)
+	})
+}
+
+// SetDisabled disables or re-enables an account. Disabling keeps the original
+// timestamp if the account is already disabled.
+func (r *PGUserRepo) SetDisabled(ctx context.Context, id int64, disabled bool) (dom.User, error) {
+	return scanUser(r.db.QueryRow(ctx, `
+		UPDATE users SET disabled_at = CASE WHEN $2 THEN COALESCE(disabled_at, NOW()) END
+		WHERE id = $1
+		RETURNING `+userColumns, id, disabled))
+}
+
+func (r *PGUserRepo) SetRole(ctx context.Context, id int64, role string) (dom.User, error) {
+	return scanUser(r.db.QueryRow(ctx, `UPDATE users SET role = $2 WHERE id = $1 RETURNING `+userColumns, id, role))
+}
+
+func scanUser(row pgx.Row) (dom.User, error) {
 	var u dom.User
-	err := r.db.QueryRow(ctx, query, username, passwordHash).Scan(
-		&u.ID, &u.Username, &u.PasswordHash, &u.CreatedAt,
-	)
+	err := row.Scan(&u.ID, &u.Username, &u.Email, &u.PasswordHash, &u.Role, &u.DisabledAt, &u.CreatedAt)
 	return u, err
 }
+
+// escapeLike escapes LIKE wildcards so s matches literally.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
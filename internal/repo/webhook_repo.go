@@ -0,0 +1,333 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	dom "Worker/internal/domain"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// WebhookRepo stores webhooks and their deliveries. Deliveries of todo changes
+// are queued by TodoRepo.QueueWebhooks in the transaction of the change.
+type WebhookRepo interface {
+	Create(ctx context.Context, w dom.Webhook) (dom.Webhook, error)
+	Count(ctx context.Context, userID int64) (int, error)
+	List(ctx context.Context, userID int64) ([]dom.Webhook, error)
+	Get(ctx context.Context, userID, id int64) (dom.Webhook, error)
+	Update(ctx context.Context, w dom.Webhook) (dom.Webhook, error)
+	Delete(ctx context.Context, userID, id int64) error
+	Deliveries(ctx context.Context, q dom.WebhookDeliveryQuery) ([]dom.WebhookDelivery, error)
+	Delivery(ctx context.Context, webhookID, id int64) (dom.WebhookDelivery, error)
+	Attempts(ctx context.Context, deliveryID int64) ([]dom.WebhookAttempt, error)
+	Redeliver(ctx context.Context, webhookID, id int64) (dom.WebhookDelivery, error)
+
+	// ClaimDue leases up to limit pending deliveries of active webhooks that are
+	// due at now to the caller until the given time, by moving their
+	// next_attempt_at there, and returns them oldest first with the URL and
+	// secret of their webhook. The claim commits at once: no lock is held while
+	// sending, and deliveries of a worker that dies mid-batch are due again when
+	// the lease runs out.
+	ClaimDue(ctx context.Context, now, until time.Time, limit int) ([]dom.WebhookDelivery, error)
+	// RecordAttempts stores attempts[i], the try to send list[i], of deliveries
+	// leased until the given time and updates the deliveries; deliveries whose
+	// lease was lost are left alone. It then applies failures to the webhooks'
+	// counts of failed attempts in a row and disables each active webhook that
+	// failed again and whose new count satisfies disable.
+	RecordAttempts(ctx context.Context, until time.Time, list []dom.WebhookDelivery, attempts []dom.WebhookAttempt,
+		failures []WebhookFailures, disable func(failures int) bool) error
+	// QueueOverdue finds up to limit open todos whose due date passed by now
+	// and was not reported yet, and queues the event build returns for each.
+	// It returns how many todos it reported.
+	QueueOverdue(ctx context.Context, now time.Time, limit int, build func(dom.Todo) (dom.WebhookEvent, error)) (int, error)
+}
+
+// WebhookFailures is what a batch of attempts does to a webhook's count of
+// failed attempts in a row: with Reset it starts over at 0, then Add is added.
+type WebhookFailures struct {
+	WebhookID int64
+	Reset     bool
+	Add       int
+}
+
+// PGWebhookRepo implements WebhookRepo with Postgres.
+type PGWebhookRepo struct {
+	db DBTX
+}
+
+// NewPGWebhookRepo returns a new PGWebhookRepo.
+func NewPGWebhookRepo(db *pgxpool.Pool) *PGWebhookRepo {
+	return &PGWebhookRepo{db: db}
+}
+
+const webhookColumns = `id, user_id, url, description, events, secret, failure_count, disabled_at, created_at, updated_at`
+
+const deliveryColumns = `id, webhook_id, event_id, event, todo_id, payload, status, attempts, next_attempt_at,
+	last_status_code, COALESCE(last_error, ''), delivered_at, created_at`
+
+func (r *PGWebhookRepo) Create(ctx context.Context, w dom.Webhook) (dom.Webhook, error) {
+	return scanWebhook(r.db.QueryRow(ctx, `
+		INSERT INTO webhooks (user_id, url, description, events, secret)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING `+webhookColumns, w.UserID, w.URL, w.Description, w.Events, w.Secret))
+}
+
+func (r *PGWebhookRepo) Count(ctx context.Context, userID int64) (int, error) {
+	var n int
+	err := r.db.QueryRow(ctx, `SELECT count(*) FROM webhooks WHERE user_id = $1`, userID).Scan(&n)
+	return n, err
+}
+
+// List returns the user's webhooks, oldest first.
+func (r *PGWebhookRepo) List(ctx context.Context, userID int64) ([]dom.Webhook, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+webhookColumns+` FROM webhooks WHERE user_id = $1 ORDER BY id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (dom.Webhook, error) {
+		return scanWebhook(row)
+	})
+}
+
+// Get returns a webhook of the user; pgx.ErrNoRows if there is none.
+func (r *PGWebhookRepo) Get(ctx context.Context, userID, id int64) (dom.Webhook, error) {
+	return scanWebhook(r.db.QueryRow(ctx, `
+		SELECT `+webhookColumns+` FROM webhooks WHERE id = $1 AND user_id = $2`, id, userID))
+}
+
+// Update saves URL, description, events, failure count and disabled state.
+func (r *PGWebhookRepo) Update(ctx context.Context, w dom.Webhook) (dom.Webhook, error) {
+	return scanWebhook(r.db.QueryRow(ctx, `
+		UPDATE webhooks SET url = $3, description = $4, events = $5, failure_count = $6, disabled_at = $7, updated_at = NOW()
+		WHERE id = $1 AND user_id = $2
+		RETURNING `+webhookColumns, w.ID, w.UserID, w.URL, w.Description, w.Events, w.FailureCount, w.DisabledAt))
+}
+
+// Delete removes a webhook with its deliveries. It returns pgx.ErrNoRows if the
+// user has no such webhook.
+func (r *PGWebhookRepo) Delete(ctx context.Context, userID, id int64) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM webhooks WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// Deliveries returns deliveries of a webhook matching q, newest first.
+func (r *PGWebhookRepo) Deliveries(ctx context.Context, q dom.WebhookDeliveryQuery) ([]dom.WebhookDelivery, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+deliveryColumns+` FROM webhook_deliveries
+		WHERE webhook_id = $1 AND ($2::text = '' OR status = $2) AND ($3::bigint = 0 OR id < $3)
+		ORDER BY id DESC LIMIT $4`, q.WebhookID, q.Status, q.BeforeID, q.Limit)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (dom.WebhookDelivery, error) {
+		return scanDelivery(row)
+	})
+}
+
+func (r *PGWebhookRepo) Delivery(ctx context.Context, webhookID, id int64) (dom.WebhookDelivery, error) {
+	return scanDelivery(r.db.QueryRow(ctx, `
+		SELECT `+deliveryColumns+` FROM webhook_deliveries WHERE id = $1 AND webhook_id = $2`, id, webhookID))
+}
+
+// Attempts returns the attempts of a delivery, first to last.
+func (r *PGWebhookRepo) Attempts(ctx context.Context, deliveryID int64) ([]dom.WebhookAttempt, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT id, delivery_id, attempt, COALESCE(status_code, 0), COALESCE(error, ''), response, duration_ms, created_at
+		FROM webhook_delivery_attempts WHERE delivery_id = $1 ORDER BY id`, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (dom.WebhookAttempt, error) {
+		var a dom.WebhookAttempt
+		var ms int64
+		err := row.Scan(&a.ID, &a.DeliveryID, &a.Attempt, &a.StatusCode, &a.Error, &a.Response, &ms, &a.CreatedAt)
+		a.Duration = time.Duration(ms) * time.Millisecond
+		return a, err
+	})
+}
+
+// Redeliver queues the event of a delivery again as a new delivery with the
+// same event ID. It returns pgx.ErrNoRows if the webhook has no such delivery.
+func (r *PGWebhookRepo) Redeliver(ctx context.Context, webhookID, id int64) (dom.WebhookDelivery, error) {
+	return scanDelivery(r.db.QueryRow(ctx, `
+		INSERT INTO webhook_deliveries (webhook_id, event_id, event, todo_id, payload)
+		SELECT webhook_id, event_id, event, todo_id, payload FROM webhook_deliveries WHERE id = $1 AND webhook_id = $2
+		RETURNING `+deliveryColumns, id, webhookID))
+}
+
+func (r *PGWebhookRepo) ClaimDue(ctx context.Context, now, until time.Time, limit int) ([]dom.WebhookDelivery, error) {
+	rows, err := r.db.Query(ctx, `
+		WITH due AS (
+			SELECT d.id FROM webhook_deliveries d JOIN webhooks w ON w.id = d.webhook_id
+			WHERE d.status = 'pending' AND d.next_attempt_at <= $1 AND w.disabled_at IS NULL
+			ORDER BY d.next_attempt_at, d.id
+			LIMIT $2
+			FOR UPDATE OF d SKIP LOCKED
+		)
+		UPDATE webhook_deliveries d SET next_attempt_at = $3
+		FROM due, webhooks w
+		WHERE d.id = due.id AND w.id = d.webhook_id
+		RETURNING d.id, d.webhook_id, d.event_id, d.event, d.todo_id, d.payload, d.attempts, w.url, w.secret`,
+		now, limit, until)
+	if err != nil {
+		return nil, err
+	}
+	list, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (dom.WebhookDelivery, error) {
+		var d dom.WebhookDelivery
+		err := row.Scan(&d.ID, &d.WebhookID, &d.EventID, &d.Event, &d.TodoID, &d.Payload, &d.Attempts, &d.URL, &d.Secret)
+		d.Status, d.NextAttemptAt = dom.DeliveryPending, until
+		return d, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	// UPDATE ... RETURNING does not keep the order of the claim.
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list, nil
+}
+
+func (r *PGWebhookRepo) RecordAttempts(ctx context.Context, until time.Time, list []dom.WebhookDelivery, attempts []dom.WebhookAttempt,
+	failures []WebhookFailures, disable func(failures int) bool) error {
+	return pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		for i, d := range list {
+			if err := recordAttempt(ctx, tx, d, attempts[i], until); err != nil {
+				return err
+			}
+		}
+		// The attempts were made even if a lease was lost, so they all count.
+		for _, f := range failures {
+			var count int
+			var active bool
+			err := tx.QueryRow(ctx, `
+				UPDATE webhooks SET failure_count = CASE WHEN $2 THEN 0 ELSE failure_count END + $3
+				WHERE id = $1
+				RETURNING failure_count, disabled_at IS NULL`, f.WebhookID, f.Reset, f.Add).Scan(&count, &active)
+			if errors.Is(err, pgx.ErrNoRows) {
+				continue // deleted meanwhile
+			}
+			if err != nil {
+				return err
+			}
+			if active && f.Add > 0 && disable(count) {
+				if _, err := tx.Exec(ctx, `UPDATE webhooks SET disabled_at = NOW() WHERE id = $1`, f.WebhookID); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// recordAttempt logs one attempt and updates its delivery, unless the delivery
+// is no longer leased until the given time.
+func recordAttempt(ctx context.Context, tx pgx.Tx, d dom.WebhookDelivery, a dom.WebhookAttempt, until time.Time) error {
+	var tag pgconn.CommandTag
+	var err error
+	if a.OK() {
+		tag, err = tx.Exec(ctx, `
+			UPDATE webhook_deliveries SET status = 'succeeded', attempts = attempts + 1,
+				last_status_code = $2, last_error = NULL, delivered_at = $3
+			WHERE id = $1 AND status = 'pending' AND next_attempt_at = $4`, d.ID, a.StatusCode, a.CreatedAt, until)
+	} else {
+		tag, err = tx.Exec(ctx, `
+			UPDATE webhook_deliveries SET attempts = attempts + 1, last_status_code = NULLIF($2, 0), last_error = $3,
+				status = CASE WHEN $4::timestamptz IS NULL THEN 'failed' ELSE 'pending' END,
+				next_attempt_at = COALESCE($4, next_attempt_at)
+			WHERE id = $1 AND status = 'pending' AND next_attempt_at = $5`, d.ID, a.StatusCode, a.Error, a.RetryAt, until)
+	}
+	if err != nil || tag.RowsAffected() == 0 {
+		return err
+	}
+	_, err = tx.Exec(ctx, `
+		INSERT INTO webhook_delivery_attempts (delivery_id, attempt, status_code, error, response, duration_ms, created_at)
+		VALUES ($1, $2, NULLIF($3, 0), NULLIF($4, ''), $5, $6, $7)`,
+		d.ID, a.Attempt, a.StatusCode, a.Error, a.Response, a.Duration.Milliseconds(), a.CreatedAt)
+	return err
+}
+
+func (r *PGWebhookRepo) QueueOverdue(ctx context.Context, now time.Time, limit int, build func(dom.Todo) (dom.WebhookEvent, error)) (int, error) {
+	var n int
+	err := pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		// The upsert claims each todo and due date once, also against
+		// concurrent workers: a loser sees the same due_at and returns nothing.
+		rows, err := tx.Query(ctx, `
+			INSERT INTO todo_overdue_notices (todo_id, due_at)
+			SELECT t.id, t.due_at FROM todos t
+			WHERE t.due_at <= $1 AND t.is_done = FALSE AND t.deleted_at IS NULL
+				AND NOT EXISTS (SELECT 1 FROM todo_overdue_notices o WHERE o.todo_id = t.id AND o.due_at = t.due_at)
+			ORDER BY t.due_at, t.id
+			LIMIT $2
+			ON CONFLICT (todo_id) DO UPDATE SET due_at = EXCLUDED.due_at
+				WHERE todo_overdue_notices.due_at <> EXCLUDED.due_at
+			RETURNING todo_id`, now, limit)
+		if err != nil {
+			return err
+		}
+		ids, err := pgx.CollectRows(rows, pgx.RowTo[int64])
+		if err != nil {
+			return err
+		}
+		n = len(ids)
+		todos := &PGTodoRepo{db: tx}
+		for _, id := range ids {
+			t, err := scanTodo(tx.QueryRow(ctx, `SELECT `+todoColumns+` FROM todos WHERE id = $1`, id))
+			if err != nil {
+				return err
+			}
+			e, err := build(t)
+			if err != nil {
+				return err
+			}
+			if err := todos.QueueWebhooks(ctx, nil, e); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return n, err
+}
+
+// QueueWebhooks adds a delivery of e for every active webhook subscribed to its
+// type whose user is a collaborator of the todo or one of userIDs (users who
+// lost access with the change). Called on a repo from InTx, the deliveries
+// commit or roll back together with the change: webhook_deliveries is the
+// outbox the worker sends from.
+func (r *PGTodoRepo) QueueWebhooks(ctx context.Context, userIDs []int64, e dom.WebhookEvent) error {
+	if userIDs == nil {
+		userIDs = []int64{}
+	}
+	_, err := r.db.Exec(ctx, `
+		WITH RECURSIVE `+collaboratorsCTE+`
+		INSERT INTO webhook_deliveries (webhook_id, event_id, event, todo_id, payload)
+		SELECT w.id, $3, $4, $1, $5 FROM webhooks w
+		WHERE w.disabled_at IS NULL AND $4 = ANY(w.events)
+			AND (w.user_id = ANY($2::bigint[]) OR w.user_id IN (SELECT user_id FROM collaborators))`,
+		e.TodoID, userIDs, e.ID, e.Type, e.Payload)
+	return err
+}
+
+func scanWebhook(row pgx.Row) (dom.Webhook, error) {
+	var w dom.Webhook
+	err := row.Scan(&w.ID, &w.UserID, &w.URL, &w.Description, &w.Events, &w.Secret, &w.FailureCount,
+		&w.DisabledAt, &w.CreatedAt, &w.UpdatedAt)
+	return w, err
+}
+
+func scanDelivery(row pgx.Row) (dom.WebhookDelivery, error) {
+	var d dom.WebhookDelivery
+	err := row.Scan(&d.ID, &d.WebhookID, &d.EventID, &d.Event, &d.TodoID, &d.Payload, &d.Status, &d.Attempts,
+		&d.NextAttemptAt, &d.LastStatusCode, &d.LastError, &d.DeliveredAt, &d.CreatedAt)
+	return d, err
+}
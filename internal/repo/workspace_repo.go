@@ -0,0 +1,319 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	dom "Worker/internal/domain"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrLastOwner is returned by SetMemberRole and RemoveMember when the change
+// would leave the workspace without an owner.
+var ErrLastOwner = errors.New("workspace must keep at least one owner")
+
+// WorkspaceRepo stores workspaces, their members and invitations. Callers check
+// that the acting user may make the change; the repo only keeps a workspace from
+// losing its last owner.
+type WorkspaceRepo interface {
+	Personal(ctx context.Context, userID int64) (dom.Workspace, error)
+	Create(ctx context.Context, name string, ownerID int64) (dom.Workspace, error)
+	ListForUser(ctx context.Context, userID int64) ([]dom.Workspace, error)
+	Membership(ctx context.Context, workspaceID, userID int64) (dom.Workspace, error)
+	Rename(ctx context.Context, workspaceID int64, name string) (dom.Workspace, error)
+	Delete(ctx context.Context, workspaceID int64) error
+
+	Members(ctx context.Context, workspaceID int64) ([]dom.WorkspaceMember, error)
+	SetMemberRole(ctx context.Context, workspaceID, userID int64, role string) (dom.WorkspaceMember, error)
+	RemoveMember(ctx context.Context, workspaceID, userID int64) error
+
+	CreateInvitation(ctx context.Context, inv dom.WorkspaceInvitation, hash string) (dom.WorkspaceInvitation, error)
+	Invitations(ctx context.Context, workspaceID int64) ([]dom.WorkspaceInvitation, error)
+	DeleteInvitation(ctx context.Context, workspaceID, id int64) error
+	GetInvitation(ctx context.Context, hash string) (dom.WorkspaceInvitation, error)
+	AcceptInvitation(ctx context.Context, id, userID int64, now time.Time) error
+}
+
+const workspaceColumns = `w.id, w.name, w.personal_user_id, w.created_by, w.created_at, w.updated_at`
+
+const memberColumns = `m.user_id, u.username, COALESCE(u.email, ''), m.role, m.created_at`
+
+const invitationColumns = `id, workspace_id, COALESCE(email, ''), role, invited_by, expires_at,
+	accepted_at, accepted_by, created_at`
+
+// PGWorkspaceRepo implements WorkspaceRepo with Postgres.
+type PGWorkspaceRepo struct {
+	db DBTX
+}
+
+// NewPGWorkspaceRepo returns a new PGWorkspaceRepo.
+func NewPGWorkspaceRepo(db *pgxpool.Pool) *PGWorkspaceRepo {
+	return &PGWorkspaceRepo{db: db}
+}
+
+// Personal returns the user's personal workspace, creating it (with the user as
+// owner) on first use.
+func (r *PGWorkspaceRepo) Personal(ctx context.Context, userID int64) (dom.Workspace, error) {
+	// Every request without a workspace lands here, so try a plain read first.
+	w, err := scanWorkspace(r.db.QueryRow(ctx, `
+		SELECT `+workspaceColumns+`, 'owner' FROM workspaces w WHERE w.personal_user_id = $1`, userID))
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return w, err
+	}
+	var out dom.Workspace
+	err = pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		// DO UPDATE (a no-op) instead of DO NOTHING so RETURNING yields the existing row.
+		w, err := scanWorkspace(tx.QueryRow(ctx, `
+			INSERT INTO workspaces AS w (name, personal_user_id, created_by) VALUES ('Personal', $1, $1)
+			ON CONFLICT (personal_user_id) DO UPDATE SET personal_user_id = EXCLUDED.personal_user_id
+			RETURNING `+workspaceColumns+`, 'owner'`, userID))
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO workspace_members (workspace_id, user_id, role) VALUES ($1, $2, 'owner')
+			ON CONFLICT DO NOTHING`, w.ID, userID); err != nil {
+			return err
+		}
+		out = w
+		return nil
+	})
+	return out, err
+}
+
+// Create adds a shared workspace with ownerID as its only member.
+func (r *PGWorkspaceRepo) Create(ctx context.Context, name string, ownerID int64) (dom.Workspace, error) {
+	var out dom.Workspace
+	err := pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		w, err := scanWorkspace(tx.QueryRow(ctx, `
+			INSERT INTO workspaces AS w (name, created_by) VALUES ($1, $2)
+			RETURNING `+workspaceColumns+`, 'owner'`, name, ownerID))
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO workspace_members (workspace_id, user_id, role) VALUES ($1, $2, 'owner')`,
+			w.ID, ownerID); err != nil {
+			return err
+		}
+		out = w
+		return nil
+	})
+	return out, err
+}
+
+// ListForUser returns the workspaces the user belongs to with their role in
+// each, the personal one first, then by name.
+func (r *PGWorkspaceRepo) ListForUser(ctx context.Context, userID int64) ([]dom.Workspace, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+workspaceColumns+`, m.role
+		FROM workspaces w JOIN workspace_members m ON m.workspace_id = w.id
+		WHERE m.user_id = $1
+		ORDER BY w.personal_user_id IS NULL, lower(w.name), w.id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (dom.Workspace, error) {
+		return scanWorkspace(row)
+	})
+}
+
+// Membership returns the workspace with the user's role in it, or pgx.ErrNoRows
+// if the user is not a member.
+func (r *PGWorkspaceRepo) Membership(ctx context.Context, workspaceID, userID int64) (dom.Workspace, error) {
+	return scanWorkspace(r.db.QueryRow(ctx, `
+		SELECT `+workspaceColumns+`, m.role
+		FROM workspaces w JOIN workspace_members m ON m.workspace_id = w.id
+		WHERE w.id = $1 AND m.user_id = $2`, workspaceID, userID))
+}
+
+// Rename changes the workspace name. The returned workspace has no Role.
+func (r *PGWorkspaceRepo) Rename(ctx context.Context, workspaceID int64, name string) (dom.Workspace, error) {
+	return scanWorkspace(r.db.QueryRow(ctx, `
+		UPDATE workspaces AS w SET name = $2, updated_at = NOW() WHERE id = $1
+		RETURNING `+workspaceColumns+`, ''`, workspaceID, name))
+}
+
+// Delete removes a shared workspace with all its projects, todos, members and
+// invitations. Personal workspaces are never deleted; they are pgx.ErrNoRows.
+func (r *PGWorkspaceRepo) Delete(ctx context.Context, workspaceID int64) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM workspaces WHERE id = $1 AND personal_user_id IS NULL`, workspaceID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// Members returns the members of a workspace ordered by username.
+func (r *PGWorkspaceRepo) Members(ctx context.Context, workspaceID int64) ([]dom.WorkspaceMember, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+memberColumns+`
+		FROM workspace_members m JOIN users u ON u.id = m.user_id
+		WHERE m.workspace_id = $1
+		ORDER BY lower(u.username)`, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (dom.WorkspaceMember, error) {
+		return scanMember(row)
+	})
+}
+
+// SetMemberRole changes a member's role; pgx.ErrNoRows if the user is not a member.
+func (r *PGWorkspaceRepo) SetMemberRole(ctx context.Context, workspaceID, userID int64, role string) (dom.WorkspaceMember, error) {
+	var out dom.WorkspaceMember
+	err := pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		if role != dom.WorkspaceRoleOwner {
+			if err := keepOwner(ctx, tx, workspaceID, userID); err != nil {
+				return err
+			}
+		}
+		var err error
+		out, err = scanMember(tx.QueryRow(ctx, `
+			WITH m AS (
+				UPDATE workspace_members SET role = $3 WHERE workspace_id = $1 AND user_id = $2
+				RETURNING user_id, role, created_at
+			)
+			SELECT `+memberColumns+` FROM m JOIN users u ON u.id = m.user_id`, workspaceID, userID, role))
+		return err
+	})
+	return out, err
+}
+
+// RemoveMember takes a user out of a workspace and drops every share they hold on
+// its todos and projects. Their own todos stay in the workspace. It returns
+// pgx.ErrNoRows if the user is not a member.
+func (r *PGWorkspaceRepo) RemoveMember(ctx context.Context, workspaceID, userID int64) error {
+	return pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		if err := keepOwner(ctx, tx, workspaceID, userID); err != nil {
+			return err
+		}
+		tag, err := tx.Exec(ctx, `DELETE FROM workspace_members WHERE workspace_id = $1 AND user_id = $2`,
+			workspaceID, userID)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return pgx.ErrNoRows
+		}
+		if _, err := tx.Exec(ctx, `
+			DELETE FROM todo_shares WHERE user_id = $2
+				AND todo_id IN (SELECT id FROM todos WHERE workspace_id = $1)`, workspaceID, userID); err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx, `
+			DELETE FROM project_shares WHERE user_id = $2
+				AND project_id IN (SELECT id FROM projects WHERE workspace_id = $1)`, workspaceID, userID)
+		return err
+	})
+}
+
+// keepOwner locks the workspace against concurrent membership changes and fails
+// with ErrLastOwner if userID is its only owner.
+func keepOwner(ctx context.Context, tx pgx.Tx, workspaceID, userID int64) error {
+	var locked int64
+	if err := tx.QueryRow(ctx, `SELECT id FROM workspaces WHERE id = $1 FOR UPDATE`, workspaceID).Scan(&locked); err != nil {
+		return err
+	}
+	var others int
+	var isOwner bool
+	if err := tx.QueryRow(ctx, `
+		SELECT count(*) FILTER (WHERE user_id <> $2), COALESCE(bool_or(user_id = $2), FALSE)
+		FROM workspace_members WHERE workspace_id = $1 AND role = 'owner'`,
+		workspaceID, userID).Scan(&others, &isOwner); err != nil {
+		return err
+	}
+	if isOwner && others == 0 {
+		return ErrLastOwner
+	}
+	return nil
+}
+
+// CreateInvitation stores an invitation under the hash of its token.
+func (r *PGWorkspaceRepo) CreateInvitation(ctx context.Context, inv dom.WorkspaceInvitation, hash string) (dom.WorkspaceInvitation, error) {
+	return scanInvitation(r.db.QueryRow(ctx, `
+		INSERT INTO workspace_invitations (workspace_id, email, role, token_hash, invited_by, expires_at)
+		VALUES ($1, NULLIF($2, ''), $3, $4, $5, $6)
+		RETURNING `+invitationColumns, inv.WorkspaceID, inv.Email, inv.Role, hash, inv.InvitedBy, inv.ExpiresAt))
+}
+
+// Invitations returns the invitations of a workspace that were not accepted yet,
+// newest first, including expired ones.
+func (r *PGWorkspaceRepo) Invitations(ctx context.Context, workspaceID int64) ([]dom.WorkspaceInvitation, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+invitationColumns+` FROM workspace_invitations
+		WHERE workspace_id = $1 AND accepted_at IS NULL
+		ORDER BY created_at DESC, id DESC`, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (dom.WorkspaceInvitation, error) {
+		return scanInvitation(row)
+	})
+}
+
+// DeleteInvitation revokes an invitation; pgx.ErrNoRows if the workspace has no such one.
+func (r *PGWorkspaceRepo) DeleteInvitation(ctx context.Context, workspaceID, id int64) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM workspace_invitations WHERE id = $1 AND workspace_id = $2`, id, workspaceID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// GetInvitation looks an invitation up by the hash of its token.
+func (r *PGWorkspaceRepo) GetInvitation(ctx context.Context, hash string) (dom.WorkspaceInvitation, error) {
+	return scanInvitation(r.db.QueryRow(ctx, `
+		SELECT `+invitationColumns+` FROM workspace_invitations WHERE token_hash = $1`, hash))
+}
+
+// AcceptInvitation marks the invitation accepted, so it works once even under
+// concurrent requests, and adds the user to the workspace. It returns
+// pgx.ErrNoRows if the invitation is unknown, accepted or expired. A user who
+// already is a member keeps their role.
+func (r *PGWorkspaceRepo) AcceptInvitation(ctx context.Context, id, userID int64, now time.Time) error {
+	return pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		var workspaceID int64
+		var role string
+		err := tx.QueryRow(ctx, `
+			UPDATE workspace_invitations SET accepted_at = $3, accepted_by = $2
+			WHERE id = $1 AND accepted_at IS NULL AND expires_at > $3
+			RETURNING workspace_id, role`, id, userID, now).Scan(&workspaceID, &role)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx, `
+			INSERT INTO workspace_members (workspace_id, user_id, role) VALUES ($1, $2, $3)
+			ON CONFLICT DO NOTHING`, workspaceID, userID, role)
+		return err
+	})
+}
+
+// scanWorkspace reads workspaceColumns followed by a role.
+func scanWorkspace(row pgx.Row) (dom.Workspace, error) {
+	var w dom.Workspace
+	err := row.Scan(&w.ID, &w.Name, &w.PersonalUserID, &w.CreatedBy, &w.CreatedAt, &w.UpdatedAt, &w.Role)
+	return w, err
+}
+
+func scanMember(row pgx.Row) (dom.WorkspaceMember, error) {
+	var m dom.WorkspaceMember
+	err := row.Scan(&m.UserID, &m.Username, &m.Email, &m.Role, &m.CreatedAt)
+	return m, err
+}
+
+func scanInvitation(row pgx.Row) (dom.WorkspaceInvitation, error) {
+	var inv dom.WorkspaceInvitation
+	err := row.Scan(&inv.ID, &inv.WorkspaceID, &inv.Email, &inv.Role, &inv.InvitedBy, &inv.ExpiresAt,
+		&inv.AcceptedAt, &inv.AcceptedBy, &inv.CreatedAt)
+	return inv, err
+}
@@ -0,0 +1,74 @@
+// Package secretbox encrypts small secrets for storage with AES-256-GCM.
+package secretbox
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+// ErrKeySize is returned for keys that are not 32 bytes.
+var ErrKeySize = errors.New("secretbox: key must be 32 bytes (64 hex or 44 base64 characters)")
+
+// ErrDecrypt is returned when a ciphertext is corrupt or was sealed with another key.
+var ErrDecrypt = errors.New("secretbox: decryption failed")
+
+// Box seals and opens secrets with one key. Ciphertexts are nonce || sealed data.
+type Box struct {
+	aead cipher.AEAD
+}
+
+// New returns a Box for a 32-byte key.
+func New(key []byte) (*Box, error) {
+	if len(key) != 32 {
+		return nil, ErrKeySize
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Box{aead: aead}, nil
+}
+
+// ParseKey decodes a key given as hex or standard base64.
+func ParseKey(s string) ([]byte, error) {
+	s = strings.TrimSpace(s)
+	if b, err := hex.DecodeString(s); err == nil && len(b) == 32 {
+		return b, nil
+	}
+	if b, err := base64.StdEncoding.DecodeString(s); err == nil && len(b) == 32 {
+		return b, nil
+	}
+	return nil, ErrKeySize
+}
+
+// Seal encrypts plaintext. ad is authenticated but not stored; pass the same
+// value to Open (e.g. the owning row's ID) so ciphertexts cannot be swapped.
+func (b *Box) Seal(plaintext, ad []byte) ([]byte, error) {
+	nonce := make([]byte, b.aead.NonceSize(), b.aead.NonceSize()+len(plaintext)+b.aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return b.aead.Seal(nonce, nonce, plaintext, ad), nil
+}
+
+// Open decrypts a ciphertext produced by Seal with the same ad.
+func (b *Box) Open(ciphertext, ad []byte) ([]byte, error) {
+	n := b.aead.NonceSize()
+	if len(ciphertext) < n {
+		return nil, ErrDecrypt
+	}
+	out, err := b.aead.Open(nil, ciphertext[:n], ciphertext[n:], ad)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return out, nil
+}
@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	dom "Worker/internal/domain"
+	"Worker/internal/repo"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrInvalidRole       = errors.New("role must be user or admin")
+	ErrSelfAction        = errors.New("administrators cannot do this to their own account")
+	ErrCannotImpersonate = errors.New("cannot impersonate an administrator or a disabled account")
+	ErrInvalidTimeRange  = errors.New("from must be before to")
+)
+
+const (
+	// DefaultAdminPageSize is the page size of admin lists when none is given.
+	DefaultAdminPageSize = 50
+	maxAdminPageSize     = 200
+)
+
+// AdminActor identifies the administrator performing an action, for the audit log.
+type AdminActor struct {
+	ID int64
+	IP string
+}
+
+// AdminService implements user administration. Every change to an account is
+// recorded in the admin audit log.
+type AdminService struct {
+	users   repo.UserRepo
+	admin   repo.AdminRepo
+	events  repo.TodoEventRepo
+	userSvc *UserService
+}
+
+// NewAdminService returns a new AdminService.
+func NewAdminService(users repo.UserRepo, admin repo.AdminRepo, events repo.TodoEventRepo, userSvc *UserService) *AdminService {
+	return &AdminService{users: users, admin: admin, events: events, userSvc: userSvc}
+}
+
+// ListUsers returns users matching q in ID order.
+func (s *AdminService) ListUsers(ctx context.Context, q dom.UserQuery) ([]dom.User, error) {
+	q.Limit = clampPageSize(q.Limit)
+	if q.Role != "" && q.Role != dom.RoleUser && q.Role != dom.RoleAdmin {
+		return nil, ErrInvalidRole
+	}
+	return s.users.List(ctx, q)
+}
+
+func (s *AdminService) GetUser(ctx context.Context, id int64) (dom.User, error) {
+	return s.userSvc.GetByID(ctx, id)
+}
+
+// SetDisabled disables or re-enables an account. The caller is responsible
+// for ending the sessions of a disabled user.
+func (s *AdminService) SetDisabled(ctx context.Context, actor AdminActor, id int64, disabled bool) (dom.User, error) {
+	if id == actor.ID {
+		return dom.User{}, ErrSelfAction
+	}
+	u, err := s.users.SetDisabled(ctx, id, disabled)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return dom.User{}, ErrNotFound
+		}
+		return dom.User{}, err
+	}
+	action := dom.AuditUserEnabled
+	if disabled {
+		action = dom.AuditUserDisabled
+	}
+	return u, s.audit(ctx, actor, action, id, nil)
+}
+
+// SetRole changes an account's role. Admins cannot demote themselves, so at
+// least one admin always remains.
+func (s *AdminService) SetRole(ctx context.Context, actor AdminActor, id int64, role string) (dom.User, error) {
+	if role != dom.RoleUser && role != dom.RoleAdmin {
+		return dom.User{}, ErrInvalidRole
+	}
+	if id == actor.ID {
+		return dom.User{}, ErrSelfAction
+	}
+	before, err := s.userSvc.GetByID(ctx, id)
+	if err != nil {
+		return dom.User{}, err
+	}
+	u, err := s.users.SetRole(ctx, id, role)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return dom.User{}, ErrNotFound
+		}
+		return dom.User{}, err
+	}
+	return u, s.audit(ctx, actor, dom.AuditUserRoleChanged, id, map[string]string{"from": before.Role, "to": role})
+}
+
+// ForcePasswordReset invalidates the user's password and issues a reset
+// token; see UserService.ForcePasswordReset. The caller ends the sessions.
+func (s *AdminService) ForcePasswordReset(ctx context.Context, actor AdminActor, id int64) (token string, emailed bool, err error) {
+	token, emailed, err = s.userSvc.ForcePasswordReset(ctx, id)
+	if err != nil {
+		return "", false, err
+	}
+	return token, emailed, s.audit(ctx, actor, dom.AuditUserPasswordReset, id, map[string]bool{"emailed": emailed})
+}
+
+// Impersonate checks that the admin may act as the user and records it. The
+// audit entry is written before the caller creates the session, so no
+// impersonation goes unrecorded.
+func (s *AdminService) Impersonate(ctx context.Context, actor AdminActor, id int64, reason string) (dom.User, error) {
+	if id == actor.ID {
+		return dom.User{}, ErrSelfAction
+	}
+	u, err := s.userSvc.GetByID(ctx, id)
+	if err != nil {
+		return dom.User{}, err
+	}
+	if u.IsAdmin() || u.Disabled() {
+		return dom.User{}, ErrCannotImpersonate
+	}
+	if err := s.audit(ctx, actor, dom.AuditUserImpersonated, id, map[string]string{"reason": reason}); err != nil {
+		return dom.User{}, err
+	}
+	return u, nil
+}
+
+func (s *AdminService) Stats(ctx context.Context) (dom.SystemStats, error) {
+	return s.admin.Stats(ctx)
+}
+
+// Audit returns audit entries newest first.
+func (s *AdminService) Audit(ctx context.Context, q dom.AdminAuditQuery) ([]dom.AdminAuditEntry, error) {
+	q.Limit = clampPageSize(q.Limit)
+	return s.admin.ListAudit(ctx, q)
+}
+
+// TodoEvents returns todo history events of all users newest first, filtered by
+// q (actor, owner, todo, time range).
+func (s *AdminService) TodoEvents(ctx context.Context, q dom.TodoEventQuery) ([]dom.TodoEvent, error) {
+	if q.From != nil && q.To != nil && !q.From.Before(*q.To) {
+		return nil, ErrInvalidTimeRange
+	}
+	q.Limit = clampPageSize(q.Limit)
+	return s.events.List(ctx, q)
+}
+
+func (s *AdminService) audit(ctx context.Context, actor AdminActor, action string, target int64, details any) error {
+	e := dom.AdminAuditEntry{AdminID: &actor.ID, Action: action, TargetUserID: &target, IP: actor.IP}
+	if details != nil {
+		b, err := json.Marshal(details)
+		if err != nil {
+			return err
+		}
+		e.Details = b
+	}
+	return s.admin.AddAudit(ctx, e)
+}
+
+func clampPageSize(n int) int {
+	if n <= 0 {
+		return DefaultAdminPageSize
+	}
+	return min(n, maxAdminPageSize)
+}
@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+
+	dom "Worker/internal/domain"
+	"Worker/internal/repo"
+	"Worker/internal/todoio"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	// calendarSecretPrefix marks feed secrets, like tokenSecretPrefix API tokens.
+	calendarSecretPrefix = "cal_"
+	calendarDisplayLen   = len(calendarSecretPrefix) + 8
+)
+
+// CalendarService manages iCalendar feeds: a secret URL per user and workspace
+// that calendar apps subscribe to without a session.
+type CalendarService struct {
+	feeds      repo.CalendarFeedRepo
+	todos      *TodoService
+	workspaces repo.WorkspaceRepo
+	users      repo.UserRepo
+}
+
+// NewCalendarService returns a new CalendarService.
+func NewCalendarService(feeds repo.CalendarFeedRepo, todos *TodoService, workspaces repo.WorkspaceRepo, users repo.UserRepo) *CalendarService {
+	return &CalendarService{feeds: feeds, todos: todos, workspaces: workspaces, users: users}
+}
+
+// Feed returns the user's feed of the workspace, or ErrNotFound.
+func (s *CalendarService) Feed(ctx context.Context, userID, workspaceID int64) (dom.CalendarFeed, error) {
+	f, err := s.feeds.Get(ctx, userID, workspaceID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return dom.CalendarFeed{}, ErrNotFound
+	}
+	return f, err
+}
+
+// CreateFeed issues a new secret for the user's feed of the workspace, creating
+// the feed if needed; a previous secret stops working. The secret is shown to
+// the user once; only its hash is stored.
+func (s *CalendarService) CreateFeed(ctx context.Context, userID, workspaceID int64) (dom.CalendarFeed, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return dom.CalendarFeed{}, "", err
+	}
+	secret := calendarSecretPrefix + base64.RawURLEncoding.EncodeToString(b)
+	f := dom.CalendarFeed{UserID: userID, WorkspaceID: workspaceID, Prefix: secret[:calendarDisplayLen]}
+	f, err := s.feeds.Put(ctx, f, hashToken(secret))
+	if err != nil {
+		return dom.CalendarFeed{}, "", err
+	}
+	return f, secret, nil
+}
+
+// DeleteFeed revokes the feed; subscribed calendars stop getting updates.
+func (s *CalendarService) DeleteFeed(ctx context.Context, userID, workspaceID int64) error {
+	if err := s.feeds.Delete(ctx, userID, workspaceID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// Authenticate resolves a feed secret to its feed and workspace and records
+// the fetch. A feed of a disabled account, or of a workspace the user has
+// left, answers ErrInvalidToken like an unknown secret.
+func (s *CalendarService) Authenticate(ctx context.Context, secret string) (dom.CalendarFeed, dom.Workspace, error) {
+	if !strings.HasPrefix(secret, calendarSecretPrefix) {
+		return dom.CalendarFeed{}, dom.Workspace{}, ErrInvalidToken
+	}
+	f, err := s.feeds.GetByHash(ctx, hashToken(secret))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return dom.CalendarFeed{}, dom.Workspace{}, ErrInvalidToken
+		}
+		return dom.CalendarFeed{}, dom.Workspace{}, err
+	}
+	u, err := s.users.GetByID(ctx, f.UserID)
+	if err != nil {
+		return dom.CalendarFeed{}, dom.Workspace{}, err
+	}
+	if u.Disabled() {
+		return dom.CalendarFeed{}, dom.Workspace{}, ErrInvalidToken
+	}
+	ws, err := s.workspaces.Membership(ctx, f.WorkspaceID, f.UserID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return dom.CalendarFeed{}, dom.Workspace{}, ErrInvalidToken
+		}
+		return dom.CalendarFeed{}, dom.Workspace{}, err
+	}
+	_ = s.feeds.Touch(ctx, f.ID, time.Now().UTC())
+	return f, ws, nil
+}
+
+// WriteFeed writes the todos of a feed to w: the user's own todos in the
+// workspace that have a due date, open ones and with includeDone also done
+// ones. Todos in the trash are left out.
+func (s *CalendarService) WriteFeed(ctx context.Context, f dom.CalendarFeed, includeDone bool, w todoio.Writer) error {
+	return s.todos.Export(ctx, f.UserID, f.WorkspaceID, false, &feedFilter{w: w, includeDone: includeDone})
+}
+
+// feedFilter passes the todos of a feed on to a writer.
+type feedFilter struct {
+	w           todoio.Writer
+	includeDone bool
+}
+
+func (ff *feedFilter) Write(t dom.Todo, project string) error {
+	if t.DueAt == nil || (t.IsDone && !ff.includeDone) {
+		return nil
+	}
+	return ff.w.Write(t, project)
+}
+
+func (ff *feedFilter) Close() error {
+	return ff.w.Close()
+}
@@ -0,0 +1,251 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	dom "Worker/internal/domain"
+	"Worker/internal/repo"
+	"Worker/internal/secretbox"
+	"Worker/internal/totp"
+
+	"github.com/jackc/pgx/v5"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrMFAUnavailable    = errors.New("two-factor authentication is not configured on this server")
+	ErrMFAAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrMFANotEnabled     = errors.New("two-factor authentication is not enabled")
+	ErrMFANotEnrolled    = errors.New("start enrollment first")
+	ErrInvalidMFACode    = errors.New("invalid authentication code")
+)
+
+// RecoveryCodeCount is how many recovery codes are issued at a time.
+const RecoveryCodeCount = 10
+
+// MFAService manages TOTP two-factor authentication.
+type MFAService struct {
+	repo   repo.MFARepo
+	box    *secretbox.Box // nil when no encryption key is configured
+	issuer string
+}
+
+// NewMFAService returns a new MFAService. With a nil box, enrollment is
+// refused and users without 2FA are unaffected.
+func NewMFAService(r repo.MFARepo, box *secretbox.Box, issuer string) *MFAService {
+	return &MFAService{repo: r, box: box, issuer: issuer}
+}
+
+// Status returns the user's enrollment; a zero value if there is none.
+func (s *MFAService) Status(ctx context.Context, userID int64) (dom.UserMFA, error) {
+	m, err := s.repo.Get(ctx, userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return dom.UserMFA{UserID: userID}, nil
+	}
+	return m, err
+}
+
+// Enabled reports whether login requires a second factor for the user.
+func (s *MFAService) Enabled(ctx context.Context, userID int64) (bool, error) {
+	m, err := s.Status(ctx, userID)
+	return m.Enabled(), err
+}
+
+// Enroll generates a new secret and stores it unconfirmed. It returns the
+// secret and an otpauth:// URI for authenticator apps. Enrolling again before
+// confirming replaces the secret.
+func (s *MFAService) Enroll(ctx context.Context, userID int64, account string) (secret, uri string, err error) {
+	if s.box == nil {
+		return "", "", ErrMFAUnavailable
+	}
+	secret, err = totp.GenerateSecret()
+	if err != nil {
+		return "", "", err
+	}
+	enc, err := s.box.Seal([]byte(secret), secretAD(userID))
+	if err != nil {
+		return "", "", err
+	}
+	if err := s.repo.SavePending(ctx, userID, enc); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", "", ErrMFAAlreadyEnabled
+		}
+		return "", "", err
+	}
+	return secret, totp.URI(s.issuer, account, secret), nil
+}
+
+// Confirm enables 2FA once the user proves their app produces valid codes. It
+// returns the recovery codes, which are shown only this once.
+func (s *MFAService) Confirm(ctx context.Context, userID int64, code string) ([]string, error) {
+	m, err := s.repo.Get(ctx, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrMFANotEnrolled
+		}
+		return nil, err
+	}
+	if m.Enabled() {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	secret, err := s.secret(m)
+	if err != nil {
+		return nil, err
+	}
+	step, ok := totp.Validate(secret, code, time.Now())
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.Enable(ctx, userID, step, hashes); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrMFAAlreadyEnabled
+		}
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Verify checks a TOTP code or, failing that, consumes a recovery code.
+// Each TOTP code is accepted at most once.
+func (s *MFAService) Verify(ctx context.Context, userID int64, code string) error {
+	m, err := s.repo.Get(ctx, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrMFANotEnabled
+		}
+		return err
+	}
+	if !m.Enabled() {
+		return ErrMFANotEnabled
+	}
+	secret, err := s.secret(m)
+	if err != nil {
+		return err
+	}
+	if step, ok := totp.Validate(secret, code, time.Now()); ok {
+		if step <= m.LastUsedStep {
+			return ErrInvalidMFACode
+		}
+		used, err := s.repo.UseStep(ctx, userID, step)
+		if err != nil {
+			return err
+		}
+		if !used {
+			return ErrInvalidMFACode
+		}
+		return nil
+	}
+	norm := normalizeRecoveryCode(code)
+	if norm == "" {
+		return ErrInvalidMFACode
+	}
+	codes, err := s.repo.RecoveryCodes(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, rc := range codes {
+		if !recoveryCodeMatches(rc.Hash, norm) {
+			continue
+		}
+		used, err := s.repo.UseRecoveryCode(ctx, userID, rc.ID)
+		if err != nil {
+			return err
+		}
+		if !used {
+			return ErrInvalidMFACode
+		}
+		return nil
+	}
+	return ErrInvalidMFACode
+}
+
+// Disable turns 2FA off after verifying a code.
+func (s *MFAService) Disable(ctx context.Context, userID int64, code string) error {
+	if err := s.Verify(ctx, userID, code); err != nil {
+		return err
+	}
+	return s.repo.Delete(ctx, userID)
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes after verifying a code.
+func (s *MFAService) RegenerateRecoveryCodes(ctx context.Context, userID int64, code string) ([]string, error) {
+	if err := s.Verify(ctx, userID, code); err != nil {
+		return nil, err
+	}
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func (s *MFAService) secret(m dom.UserMFA) (string, error) {
+	if s.box == nil {
+		return "", ErrMFAUnavailable
+	}
+	b, err := s.box.Open(m.SecretEnc, secretAD(m.UserID))
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// secretAD binds a ciphertext to its user so it cannot be copied to another row.
+func secretAD(userID int64) []byte {
+	return []byte("user_mfa:" + strconv.FormatInt(userID, 10))
+}
+
+var recoveryEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// newRecoveryCodes returns codes like "abcde-fghij" (50 random bits) and their
+// bcrypt hashes; 50 bits are too few for a fast hash like API tokens get.
+func newRecoveryCodes() (codes, hashes []string, err error) {
+	codes = make([]string, RecoveryCodeCount)
+	hashes = make([]string, RecoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, 7)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		raw := strings.ToLower(recoveryEncoding.EncodeToString(b))[:10]
+		codes[i] = raw[:5] + "-" + raw[5:]
+		hash, err := bcrypt.GenerateFromPassword([]byte(raw), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, nil, err
+		}
+		hashes[i] = string(hash)
+	}
+	return codes, hashes, nil
+}
+
+// recoveryCodeMatches reports whether the normalized code is the one hashed.
+// Codes issued before bcrypt was used have a bare SHA-256.
+func recoveryCodeMatches(hash, code string) bool {
+	if len(hash) == 2*sha256.Size {
+		return subtle.ConstantTimeCompare([]byte(hash), []byte(hashToken(code))) == 1
+	}
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(code)) == nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	if len(code) != 10 {
+		return ""
+	}
+	return code
+}
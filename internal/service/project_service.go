@@ -0,0 +1,171 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"Worker/internal/cache"
+	dom "Worker/internal/domain"
+	"Worker/internal/repo"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrInvalidProjectName  = errors.New("project name must be 1-120 characters")
+	ErrInvalidProjectColor = errors.New("color must be a hex value like #1e90ff")
+	ErrInboxProject        = errors.New("the inbox project cannot be renamed, archived or deleted")
+	ErrInvalidDeleteMode   = errors.New("mode must be cascade or inbox")
+)
+
+var projectColorRe = regexp.MustCompile(`^#(?:[0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// ProjectService manages projects. Every project lives in one workspace and is
+// reachable only through it. Changes that affect todos (delete, archive)
+// invalidate the todo caches of the owner and everyone the project or its todos
+// are shared with.
+type ProjectService struct {
+	repo  repo.ProjectRepo
+	cache *cache.TodoCache
+}
+
+// NewProjectService creates a ProjectService. If c is nil, no cache invalidation is done.
+func NewProjectService(r repo.ProjectRepo, c *cache.TodoCache) *ProjectService {
+	return &ProjectService{repo: r, cache: c}
+}
+
+// ProjectInput holds project fields; on update nil fields are left unchanged.
+type ProjectInput struct {
+	Name     *string
+	Color    *string // "" clears the color
+	Archived *bool
+}
+
+// List returns the user's projects. Archived ones are included only on request.
+func (s *ProjectService) List(ctx context.Context, userID, workspaceID int64, includeArchived bool) ([]dom.Project, error) {
+	return s.repo.List(ctx, userID, workspaceID, includeArchived)
+}
+
+// GetByID returns one project of the user in the workspace.
+func (s *ProjectService) GetByID(ctx context.Context, userID, workspaceID, id int64) (dom.Project, error) {
+	p, err := s.repo.GetByID(ctx, userID, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return dom.Project{}, ErrNotFound
+		}
+		return dom.Project{}, err
+	}
+	if p.WorkspaceID != workspaceID {
+		return dom.Project{}, ErrNotFound
+	}
+	return p, nil
+}
+
+// Create adds a project. Name is required.
+func (s *ProjectService) Create(ctx context.Context, userID, workspaceID int64, in ProjectInput) (dom.Project, error) {
+	p := dom.Project{UserID: userID, WorkspaceID: workspaceID}
+	if in.Name == nil {
+		return dom.Project{}, ErrInvalidProjectName
+	}
+	if err := applyProjectInput(&p, in); err != nil {
+		return dom.Project{}, err
+	}
+	return s.repo.Create(ctx, p)
+}
+
+// Update changes name, color or archived flag of a project.
+func (s *ProjectService) Update(ctx context.Context, userID, workspaceID, id int64, in ProjectInput) (dom.Project, error) {
+	p, err := s.GetByID(ctx, userID, workspaceID, id)
+	if err != nil {
+		return dom.Project{}, err
+	}
+	if p.IsInbox && (in.Name != nil || in.Archived != nil) {
+		return dom.Project{}, ErrInboxProject
+	}
+	if err := applyProjectInput(&p, in); err != nil {
+		return dom.Project{}, err
+	}
+	p, err = s.repo.Update(ctx, userID, id, p)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return dom.Project{}, ErrNotFound
+		}
+		return dom.Project{}, err
+	}
+	s.invalidateCache(ctx, workspaceID, s.collaborators(ctx, userID, id))
+	return p, nil
+}
+
+// Delete removes a project. mode is dom.ProjectDeleteCascade (default) or dom.ProjectDeleteToInbox.
+func (s *ProjectService) Delete(ctx context.Context, userID, workspaceID, id int64, mode string) error {
+	if mode == "" {
+		mode = dom.ProjectDeleteCascade
+	}
+	if mode != dom.ProjectDeleteCascade && mode != dom.ProjectDeleteToInbox {
+		return ErrInvalidDeleteMode
+	}
+	p, err := s.GetByID(ctx, userID, workspaceID, id)
+	if err != nil {
+		return err
+	}
+	if p.IsInbox {
+		return ErrInboxProject
+	}
+	// Collect before deleting: the shares go with the project.
+	users := s.collaborators(ctx, userID, id)
+	if err := s.repo.Delete(ctx, userID, id, mode); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNotFound
+		}
+		return err
+	}
+	s.invalidateCache(ctx, workspaceID, users)
+	return nil
+}
+
+// Inbox returns the user's inbox project in the workspace, creating it on first use.
+func (s *ProjectService) Inbox(ctx context.Context, userID, workspaceID int64) (dom.Project, error) {
+	return s.repo.Inbox(ctx, userID, workspaceID)
+}
+
+// collaborators returns the users whose cached lists may show todos of the project.
+func (s *ProjectService) collaborators(ctx context.Context, userID, id int64) []int64 {
+	if s.cache == nil {
+		return nil
+	}
+	ids, err := s.repo.Collaborators(ctx, id)
+	if err != nil {
+		return []int64{userID}
+	}
+	return append(ids, userID)
+}
+
+func (s *ProjectService) invalidateCache(ctx context.Context, workspaceID int64, userIDs []int64) {
+	if s.cache != nil {
+		_ = s.cache.InvalidateUsers(ctx, workspaceID, userIDs...)
+	}
+}
+
+func applyProjectInput(p *dom.Project, in ProjectInput) error {
+	if in.Name != nil {
+		name := strings.TrimSpace(*in.Name)
+		if name == "" || utf8.RuneCountInString(name) > 120 {
+			return ErrInvalidProjectName
+		}
+		p.Name = name
+	}
+	if in.Color != nil {
+		color := strings.TrimSpace(*in.Color)
+		if color != "" && !projectColorRe.MatchString(color) {
+			return ErrInvalidProjectColor
+		}
+		p.Color = strings.ToLower(color)
+	}
+	if in.Archived != nil {
+		p.Archived = *in.Archived
+	}
+	return nil
+}
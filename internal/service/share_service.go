@@ -0,0 +1,237 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"Worker/internal/cache"
+	dom "Worker/internal/domain"
+	"Worker/internal/repo"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	// ErrForbidden: the user can see the todo or project but lacks the permission for the action.
+	ErrForbidden         = errors.New("insufficient permission")
+	ErrInvalidPermission = errors.New("permission must be viewer, editor or owner")
+	ErrShareUserNotFound = errors.New("user not found")
+	ErrShareWithOwner    = errors.New("the owner always has full access")
+	ErrShareWithSelf     = errors.New("you cannot change your own access")
+	ErrShareNotMember    = errors.New("user is not a member of this workspace")
+)
+
+// ShareService manages who a todo or project is shared with. Only users with
+// owner permission may grant or change access; anyone may drop their own share.
+// Shares never cross workspaces: the grantee must be a member of the workspace
+// the todo or project lives in. Every change invalidates the todo caches of all
+// collaborators in that workspace.
+type ShareService struct {
+	repo       repo.ShareRepo
+	todos      repo.TodoRepo
+	projects   repo.ProjectRepo
+	users      repo.UserRepo
+	workspaces repo.WorkspaceRepo
+	cache      *cache.TodoCache
+}
+
+// NewShareService creates a ShareService. If c is nil, no cache invalidation is done.
+func NewShareService(r repo.ShareRepo, todos repo.TodoRepo, projects repo.ProjectRepo, users repo.UserRepo,
+	workspaces repo.WorkspaceRepo, c *cache.TodoCache) *ShareService {
+	return &ShareService{repo: r, todos: todos, projects: projects, users: users, workspaces: workspaces, cache: c}
+}
+
+// ShareInput names the user to share with (username or e-mail) and the permission.
+type ShareInput struct {
+	User       string
+	Permission string
+}
+
+// TodoShares lists the direct shares of a todo; any collaborator may see them.
+func (s *ShareService) TodoShares(ctx context.Context, userID, workspaceID, todoID int64) ([]dom.Share, error) {
+	if _, err := s.todo(ctx, userID, workspaceID, todoID, dom.PermissionViewer); err != nil {
+		return nil, err
+	}
+	return s.repo.ListTodo(ctx, todoID)
+}
+
+// ShareTodo grants a user access to a todo and its subtasks, or changes an existing grant.
+func (s *ShareService) ShareTodo(ctx context.Context, userID, workspaceID, todoID int64, in ShareInput) (dom.Share, error) {
+	t, err := s.todo(ctx, userID, workspaceID, todoID, dom.PermissionOwner)
+	if err != nil {
+		return dom.Share{}, err
+	}
+	share, err := s.newShare(ctx, userID, workspaceID, t.UserID, in)
+	if err != nil {
+		return dom.Share{}, err
+	}
+	if share, err = s.repo.PutTodo(ctx, todoID, share); err != nil {
+		return dom.Share{}, err
+	}
+	s.invalidate(ctx, workspaceID, s.todoCollaborators(ctx, todoID, userID))
+	return share, nil
+}
+
+// UnshareTodo revokes a user's direct share of a todo. Owners may revoke anyone;
+// other collaborators only themselves.
+func (s *ShareService) UnshareTodo(ctx context.Context, userID, workspaceID, todoID, granteeID int64) error {
+	need := dom.PermissionOwner
+	if granteeID == userID {
+		need = dom.PermissionViewer
+	}
+	if _, err := s.todo(ctx, userID, workspaceID, todoID, need); err != nil {
+		return err
+	}
+	// Collect before deleting: afterwards the grantee is no longer a collaborator.
+	users := s.todoCollaborators(ctx, todoID, userID)
+	if err := s.repo.DeleteTodo(ctx, todoID, granteeID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNotFound
+		}
+		return err
+	}
+	s.invalidate(ctx, workspaceID, users)
+	return nil
+}
+
+// ProjectShares lists the shares of a project; any collaborator may see them.
+func (s *ShareService) ProjectShares(ctx context.Context, userID, workspaceID, projectID int64) ([]dom.Share, error) {
+	if _, err := s.project(ctx, userID, workspaceID, projectID, dom.PermissionViewer); err != nil {
+		return nil, err
+	}
+	return s.repo.ListProject(ctx, projectID)
+}
+
+// ShareProject grants a user access to every todo of a project, or changes an existing grant.
+func (s *ShareService) ShareProject(ctx context.Context, userID, workspaceID, projectID int64, in ShareInput) (dom.Share, error) {
+	p, err := s.project(ctx, userID, workspaceID, projectID, dom.PermissionOwner)
+	if err != nil {
+		return dom.Share{}, err
+	}
+	share, err := s.newShare(ctx, userID, workspaceID, p.UserID, in)
+	if err != nil {
+		return dom.Share{}, err
+	}
+	if share, err = s.repo.PutProject(ctx, projectID, share); err != nil {
+		return dom.Share{}, err
+	}
+	s.invalidate(ctx, workspaceID, s.projectCollaborators(ctx, projectID, userID))
+	return share, nil
+}
+
+// UnshareProject revokes a user's share of a project. Owners may revoke anyone;
+// other collaborators only themselves.
+func (s *ShareService) UnshareProject(ctx context.Context, userID, workspaceID, projectID, granteeID int64) error {
+	need := dom.PermissionOwner
+	if granteeID == userID {
+		need = dom.PermissionViewer
+	}
+	if _, err := s.project(ctx, userID, workspaceID, projectID, need); err != nil {
+		return err
+	}
+	users := s.projectCollaborators(ctx, projectID, userID)
+	if err := s.repo.DeleteProject(ctx, projectID, granteeID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNotFound
+		}
+		return err
+	}
+	s.invalidate(ctx, workspaceID, users)
+	return nil
+}
+
+// newShare validates in and resolves the grantee, who must belong to the
+// workspace. ownerID owns the shared object.
+func (s *ShareService) newShare(ctx context.Context, userID, workspaceID, ownerID int64, in ShareInput) (dom.Share, error) {
+	perm, ok := dom.ParsePermission(strings.ToLower(strings.TrimSpace(in.Permission)))
+	if !ok {
+		return dom.Share{}, ErrInvalidPermission
+	}
+	u, err := s.users.GetByLogin(ctx, strings.TrimSpace(in.User))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return dom.Share{}, ErrShareUserNotFound
+		}
+		return dom.Share{}, err
+	}
+	switch u.ID {
+	case ownerID:
+		return dom.Share{}, ErrShareWithOwner
+	case userID:
+		return dom.Share{}, ErrShareWithSelf
+	}
+	if _, err := s.workspaces.Membership(ctx, workspaceID, u.ID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return dom.Share{}, ErrShareNotMember
+		}
+		return dom.Share{}, err
+	}
+	return dom.Share{UserID: u.ID, Permission: perm, GrantedBy: &userID}, nil
+}
+
+// todo returns the todo if it lives in the workspace and the user holds at least
+// need on it (see TodoService.access).
+func (s *ShareService) todo(ctx context.Context, userID, workspaceID, id int64, need dom.Permission) (dom.Todo, error) {
+	t, err := s.todos.Access(ctx, userID, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return dom.Todo{}, ErrNotFound
+		}
+		return dom.Todo{}, err
+	}
+	if t.WorkspaceID != workspaceID {
+		return dom.Todo{}, ErrNotFound
+	}
+	if t.Permission < need {
+		return dom.Todo{}, ErrForbidden
+	}
+	return t, nil
+}
+
+// project returns the project if it lives in the workspace and the user holds
+// at least need on it.
+func (s *ShareService) project(ctx context.Context, userID, workspaceID, id int64, need dom.Permission) (dom.Project, error) {
+	p, err := s.projects.Access(ctx, userID, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return dom.Project{}, ErrNotFound
+		}
+		return dom.Project{}, err
+	}
+	if p.WorkspaceID != workspaceID {
+		return dom.Project{}, ErrNotFound
+	}
+	if p.Permission < need {
+		return dom.Project{}, ErrForbidden
+	}
+	return p, nil
+}
+
+func (s *ShareService) todoCollaborators(ctx context.Context, todoID, userID int64) []int64 {
+	if s.cache == nil {
+		return nil
+	}
+	ids, err := s.todos.Collaborators(ctx, todoID)
+	if err != nil {
+		return []int64{userID}
+	}
+	return append(ids, userID)
+}
+
+func (s *ShareService) projectCollaborators(ctx context.Context, projectID, userID int64) []int64 {
+	if s.cache == nil {
+		return nil
+	}
+	ids, err := s.projects.Collaborators(ctx, projectID)
+	if err != nil {
+		return []int64{userID}
+	}
+	return append(ids, userID)
+}
+
+func (s *ShareService) invalidate(ctx context.Context, workspaceID int64, userIDs []int64) {
+	if s.cache != nil {
+		_ = s.cache.InvalidateUsers(ctx, workspaceID, userIDs...)
+	}
+}
@@ -0,0 +1,235 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	dom "Worker/internal/domain"
+	"Worker/internal/repo"
+)
+
+const (
+	// DefaultSyncLimit is how many changes GET /sync returns when no limit is given.
+	DefaultSyncLimit = 200
+	maxSyncLimit     = 1000
+)
+
+// Conflict strategies of a push.
+const (
+	SyncByVersion = "version" // a change applies only to the version it was based on
+	SyncLastWrite = "lww"     // the change modified last wins
+)
+
+// Outcomes of one pushed change.
+const (
+	SyncApplied  = "applied"
+	SyncConflict = "conflict"  // not applied; Todo is the server's version
+	SyncNotFound = "not_found" // the todo is gone; drop the change
+	SyncRejected = "rejected"  // invalid or not allowed; Err says why
+)
+
+var (
+	ErrInvalidSyncToken = errors.New("invalid sync token")
+	// ErrSyncTokenExpired: the token is older than the tombstones kept for it; the client has to sync from scratch.
+	ErrSyncTokenExpired  = errors.New("sync token expired; start a full sync")
+	ErrSyncBaseVersion   = errors.New("base_version is required for update and delete")
+	ErrSyncModifiedAt    = errors.New("modified_at is required for update and delete")
+	ErrSyncChangeMissing = errors.New("update and delete need the id of the todo")
+)
+
+// SyncService implements delta sync for offline clients: a feed of the changes
+// of the user's own todos in a workspace since a token, and a push of changes
+// made offline.
+type SyncService struct {
+	todos    *TodoService
+	repo     repo.TodoRepo
+	tokenTTL time.Duration
+}
+
+// NewSyncService returns a new SyncService. Tokens older than tokenTTL are
+// refused: the tombstones of purged todos are kept only that long.
+func NewSyncService(todos *TodoService, r repo.TodoRepo, tokenTTL time.Duration) *SyncService {
+	return &SyncService{todos: todos, repo: r, tokenTTL: tokenTTL}
+}
+
+// Changes returns the changes after since ("" = a full sync) and the token to
+// send next time.
+func (s *SyncService) Changes(ctx context.Context, userID, workspaceID int64, since string, limit int) (dom.TodoChanges, string, error) {
+	q := dom.TodoChangesQuery{WorkspaceID: workspaceID, Limit: DefaultSyncLimit}
+	if limit > 0 {
+		q.Limit = min(limit, maxSyncLimit)
+	}
+	if since != "" {
+		tok, err := s.decodeToken(since, workspaceID)
+		if err != nil {
+			return dom.TodoChanges{}, "", err
+		}
+		q.AfterSeq, q.XMin = tok.Seq, tok.XMin
+	}
+	ch, err := s.repo.Changes(ctx, userID, q)
+	if err != nil {
+		return dom.TodoChanges{}, "", err
+	}
+	next, err := encodeSyncToken(dom.SyncToken{
+		WorkspaceID: workspaceID,
+		Seq:         ch.Seq,
+		XMin:        ch.XMin,
+		IssuedAt:    time.Now().Unix(),
+	})
+	return ch, next, err
+}
+
+// SyncChange is one change made offline. ID is the todo for update and
+// delete; ClientID is the client's own ID of a new todo, echoed in the result.
+type SyncChange struct {
+	Op          string // BatchCreate, BatchUpdate or BatchDelete
+	ID          int64
+	ClientID    string
+	BaseVersion int64      // SyncByVersion: the version the change was made to
+	ModifiedAt  *time.Time // SyncLastWrite: when the change was made on the client
+	Create      CreateTodoInput
+	Update      UpdateTodoInput
+}
+
+// SyncResult is the outcome of one pushed change.
+type SyncResult struct {
+	Op       string
+	ID       int64
+	ClientID string
+	Status   string
+	Todo     *dom.Todo // applied: the todo now; conflict: the server's todo
+	Err      error
+}
+
+// Push applies changes made offline, each on its own, and reports a conflict
+// for every update or delete that lost under strategy instead of applying it.
+func (s *SyncService) Push(ctx context.Context, userID, workspaceID int64, strategy string, changes []SyncChange) ([]SyncResult, error) {
+	if len(changes) == 0 || len(changes) > MaxBatchSize {
+		return nil, ErrBatchSize
+	}
+	results := make([]SyncResult, len(changes))
+	var ops []BatchOp
+	var index []int // position in changes of each op
+	for i, c := range changes {
+		results[i] = SyncResult{Op: c.Op, ID: c.ID, ClientID: c.ClientID}
+		op, err := s.pushOp(ctx, userID, workspaceID, strategy, c, &results[i])
+		if err != nil {
+			return nil, err
+		}
+		if op != nil {
+			ops = append(ops, *op)
+			index = append(index, i)
+		}
+	}
+	if len(ops) == 0 {
+		return results, nil
+	}
+	out, err := s.todos.Batch(ctx, userID, workspaceID, ops, false)
+	if err != nil {
+		return nil, err
+	}
+	for k, r := range out.Results {
+		res := &results[index[k]]
+		res.ID = r.ID
+		switch {
+		case r.Err == nil:
+			res.Status, res.Todo = SyncApplied, r.Todo
+		case errors.Is(r.Err, ErrVersionConflict):
+			s.conflict(ctx, userID, workspaceID, res)
+		case errors.Is(r.Err, ErrNotFound):
+			res.Status = SyncNotFound
+		default:
+			res.Status, res.Err = SyncRejected, r.Err
+		}
+	}
+	return results, nil
+}
+
+// pushOp turns a change into a batch operation, or settles res without one
+// when the change cannot or must not be applied.
+func (s *SyncService) pushOp(ctx context.Context, userID, workspaceID int64, strategy string, c SyncChange, res *SyncResult) (*BatchOp, error) {
+	op := BatchOp{Op: c.Op, ID: c.ID, Create: c.Create, Update: c.Update}
+	switch c.Op {
+	case BatchCreate:
+		return &op, nil
+	case BatchUpdate, BatchDelete:
+	default:
+		res.Status, res.Err = SyncRejected, ErrInvalidBatch
+		return nil, nil
+	}
+	if c.ID == 0 {
+		res.Status, res.Err = SyncRejected, ErrSyncChangeMissing
+		return nil, nil
+	}
+	if strategy != SyncLastWrite {
+		if c.BaseVersion == 0 {
+			res.Status, res.Err = SyncRejected, ErrSyncBaseVersion
+			return nil, nil
+		}
+		op.IfVersion = c.BaseVersion
+		return &op, nil
+	}
+	if c.ModifiedAt == nil {
+		res.Status, res.Err = SyncRejected, ErrSyncModifiedAt
+		return nil, nil
+	}
+	cur, err := s.todos.GetByID(ctx, userID, workspaceID, c.ID)
+	switch {
+	case errors.Is(err, ErrNotFound):
+		res.Status = SyncNotFound
+		return nil, nil
+	case errors.Is(err, ErrForbidden):
+		res.Status, res.Err = SyncRejected, err
+		return nil, nil
+	case err != nil:
+		return nil, err
+	}
+	if !c.ModifiedAt.After(cur.UpdatedAt) {
+		res.Status, res.Todo = SyncConflict, &cur
+		return nil, nil
+	}
+	// Pinned to the version just read: a write in between is a conflict too.
+	op.IfVersion = cur.Version
+	return &op, nil
+}
+
+// conflict settles res as a conflict carrying the server's todo, or as
+// not found if the todo has gone in the meantime.
+func (s *SyncService) conflict(ctx context.Context, userID, workspaceID int64, res *SyncResult) {
+	cur, err := s.todos.GetByID(ctx, userID, workspaceID, res.ID)
+	switch {
+	case err == nil:
+		res.Status, res.Todo = SyncConflict, &cur
+	case errors.Is(err, ErrNotFound):
+		res.Status = SyncNotFound
+	default:
+		res.Status, res.Err = SyncConflict, ErrVersionConflict
+	}
+}
+
+func encodeSyncToken(t dom.SyncToken) (string, error) {
+	b, err := json.Marshal(t)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// decodeToken parses a token issued for the workspace and checks its age.
+func (s *SyncService) decodeToken(raw string, workspaceID int64) (dom.SyncToken, error) {
+	b, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return dom.SyncToken{}, ErrInvalidSyncToken
+	}
+	var t dom.SyncToken
+	if err := json.Unmarshal(b, &t); err != nil || t.WorkspaceID != workspaceID || t.Seq < 0 || t.IssuedAt <= 0 {
+		return dom.SyncToken{}, ErrInvalidSyncToken
+	}
+	if s.tokenTTL > 0 && time.Since(time.Unix(t.IssuedAt, 0)) > s.tokenTTL {
+		return dom.SyncToken{}, ErrSyncTokenExpired
+	}
+	return t, nil
+}
@@ -0,0 +1,158 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"Worker/internal/cache"
+	dom "Worker/internal/domain"
+	"Worker/internal/repo"
+	"Worker/internal/utils"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrInvalidTagName = errors.New("tag name must be 1-64 characters")
+	ErrTagNameTaken   = errors.New("tag name already taken")
+)
+
+const maxTagNameLen = 64
+
+// TagService manages user tags. Tags belong to the user, not to a workspace; todo
+// counts are those of the active workspace. Renaming or deleting a tag changes todos
+// that carry it, so both invalidate the owner's todo cache in every workspace.
+type TagService struct {
+	repo  repo.TagRepo
+	cache *cache.TodoCache
+}
+
+// NewTagService creates a TagService. If c is nil, no cache invalidation is done.
+func NewTagService(r repo.TagRepo, c *cache.TodoCache) *TagService {
+	return &TagService{repo: r, cache: c}
+}
+
+// List returns the user's tags sorted by name.
+func (s *TagService) List(ctx context.Context, userID, workspaceID int64) ([]dom.Tag, error) {
+	return s.repo.List(ctx, userID, workspaceID)
+}
+
+// GetByID returns one tag of the user.
+func (s *TagService) GetByID(ctx context.Context, userID, workspaceID, id int64) (dom.Tag, error) {
+	t, err := s.repo.GetByID(ctx, userID, workspaceID, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return dom.Tag{}, ErrNotFound
+		}
+		return dom.Tag{}, err
+	}
+	return t, nil
+}
+
+// Create adds a tag. Names are unique per user, ignoring case.
+func (s *TagService) Create(ctx context.Context, userID int64, name string) (dom.Tag, error) {
+	name, err := normalizeTagName(name)
+	if err != nil {
+		return dom.Tag{}, err
+	}
+	t, err := s.repo.Create(ctx, userID, name)
+	if err != nil {
+		if utils.IsPGUniqueViolation(err) {
+			return dom.Tag{}, ErrTagNameTaken
+		}
+		return dom.Tag{}, err
+	}
+	return t, nil
+}
+
+// Rename changes a tag name and invalidates cached todos of the user.
+func (s *TagService) Rename(ctx context.Context, userID, workspaceID, id int64, name string) (dom.Tag, error) {
+	name, err := normalizeTagName(name)
+	if err != nil {
+		return dom.Tag{}, err
+	}
+	if _, err := s.repo.Rename(ctx, userID, id, name); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return dom.Tag{}, ErrNotFound
+		}
+		if utils.IsPGUniqueViolation(err) {
+			return dom.Tag{}, ErrTagNameTaken
+		}
+		return dom.Tag{}, err
+	}
+	s.invalidateCache(ctx, userID)
+	return s.GetByID(ctx, userID, workspaceID, id)
+}
+
+// Delete removes a tag from the user and from all their todos.
+func (s *TagService) Delete(ctx context.Context, userID, id int64) error {
+	if err := s.repo.Delete(ctx, userID, id); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNotFound
+		}
+		return err
+	}
+	s.invalidateCache(ctx, userID)
+	return nil
+}
+
+func (s *TagService) invalidateCache(ctx context.Context, userID int64) {
+	if s.cache != nil {
+		_ = s.cache.InvalidateAll(ctx, cache.AllWorkspaces, userID)
+	}
+}
+
+func normalizeTagName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > maxTagNameLen {
+		return "", ErrInvalidTagName
+	}
+	return name, nil
+}
+
+// normalizeTagNames trims names and drops case-insensitive duplicates, keeping the first spelling.
+// The result is never nil, so it can be used to replace a todo's tags with an empty set.
+func normalizeTagNames(names []string) ([]string, error) {
+	out := make([]string, 0, len(names))
+	seen := make(map[string]bool, len(names))
+	for _, n := range names {
+		n, err := normalizeTagName(n)
+		if err != nil {
+			return nil, err
+		}
+		if key := strings.ToLower(n); !seen[key] {
+			seen[key] = true
+			out = append(out, n)
+		}
+	}
+	return out, nil
+}
+
+// normalizeTagFilter normalizes filter names and sorts them case-insensitively,
+// so equal filters produce equal cache keys.
+func normalizeTagFilter(f dom.TagFilter) (dom.TagFilter, error) {
+	names, err := normalizeTagNames(f.Names)
+	if err != nil {
+		return dom.TagFilter{}, err
+	}
+	for i := range names {
+		names[i] = strings.ToLower(names[i])
+	}
+	sort.Strings(names)
+	return dom.TagFilter{Names: names, MatchAll: f.MatchAll && len(names) > 0}, nil
+}
+
+// tagFilterKey renders a normalized filter for cache keys ("" when empty).
+func tagFilterKey(f dom.TagFilter) string {
+	if len(f.Names) == 0 {
+		return ""
+	}
+	mode := "or"
+	if f.MatchAll {
+		mode = "and"
+	}
+	return "tags=" + mode + ":" + strings.Join(f.Names, ",")
+}
@@ -0,0 +1,233 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	dom "Worker/internal/domain"
+	"Worker/internal/realtime"
+	"Worker/internal/repo"
+)
+
+// MaxBatchSize caps the operations of one batch and the todos a filter selects.
+const MaxBatchSize = 200
+
+// Batch operation kinds.
+const (
+	BatchCreate   = "create"
+	BatchUpdate   = "update"
+	BatchComplete = "complete"
+	BatchDelete   = "delete"
+	BatchMove     = "move"
+)
+
+var (
+	ErrBatchSize     = errors.New("a batch must have between 1 and 200 operations")
+	ErrInvalidBatch  = errors.New("unknown batch operation")
+	ErrBatchFilter   = errors.New("a filter batch needs action complete, delete or update")
+	errBatchRollback = errors.New("batch rolled back")
+	// ErrBatchRolledBack marks an operation of an all-or-nothing batch that was
+	// undone, or never run, because another operation failed.
+	ErrBatchRolledBack = errors.New("not applied: another operation of the batch failed")
+)
+
+// batchEvents maps operations to the events they publish.
+var batchEvents = map[string]string{
+	BatchCreate:   realtime.EventCreated,
+	BatchUpdate:   realtime.EventUpdated,
+	BatchComplete: realtime.EventCompleted,
+	BatchDelete:   realtime.EventDeleted,
+	BatchMove:     realtime.EventUpdated,
+}
+
+// BatchOp is one operation of a batch. ID is the todo for everything but
+// create; the input fields used depend on Op.
+type BatchOp struct {
+	Op        string
+	ID        int64
+	Create    CreateTodoInput
+	Update    UpdateTodoInput // IfVersion here is ignored; use IfVersion below
+	IfVersion int64           // update, complete and delete: If-Match for this todo
+	Subtasks  bool            // complete: the whole subtree
+	Permanent bool            // delete: skip the trash
+	ProjectID *int64          // move: target project, nil = current, 0 = none
+	BeforeID  int64           // move
+	AfterID   int64           // move
+
+	// skipMissing turns "not found" into a no-op, for filter batches where an
+	// earlier delete may already have taken a matched subtask along.
+	skipMissing bool
+}
+
+// BatchFilter selects the user's own todos in the workspace a filter batch acts on.
+type BatchFilter struct {
+	Overdue   bool // open and due before now
+	IsDone    *bool
+	DueBefore *time.Time
+	Tags      dom.TagFilter
+	ProjectID *int64
+}
+
+// BatchResult is the outcome of one operation. Todo is set for successful
+// create, update, complete and move.
+type BatchResult struct {
+	Op   string
+	ID   int64
+	Todo *dom.Todo
+	Err  error
+}
+
+// BatchOutcome is the outcome of a batch. Committed is false when an
+// all-or-nothing batch was rolled back; More is set when a filter matched more
+// than MaxBatchSize todos and only the first ones were processed.
+type BatchOutcome struct {
+	Results   []BatchResult
+	Committed bool
+	More      bool
+}
+
+// Batch runs ops in one transaction. With atomic, the first failing operation
+// rolls the whole batch back; otherwise each operation that fails is undone on
+// its own and the rest are committed. Every operation checks access as its
+// single-todo endpoint does. The cache is invalidated once, after the commit.
+func (s *TodoService) Batch(ctx context.Context, userID, workspaceID int64, ops []BatchOp, atomic bool) (BatchOutcome, error) {
+	if len(ops) == 0 || len(ops) > MaxBatchSize {
+		return BatchOutcome{}, ErrBatchSize
+	}
+	results := make([]BatchResult, len(ops))
+	users := make([][]int64, len(ops))
+	err := s.repo.InTx(ctx, func(r repo.TodoRepo) error {
+		for i, op := range ops {
+			err := r.InTx(ctx, func(r repo.TodoRepo) error {
+				var err error
+				users[i], results[i], err = s.batchStep(ctx, r, userID, workspaceID, op)
+				return err
+			})
+			if err != nil && results[i].Err == nil {
+				results[i] = BatchResult{Op: op.Op, ID: op.ID, Err: err}
+			}
+			if err != nil && atomic {
+				for j := range results {
+					if j != i {
+						results[j] = BatchResult{Op: ops[j].Op, ID: ops[j].ID, Err: ErrBatchRolledBack}
+					}
+				}
+				return errBatchRollback
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, errBatchRollback) {
+		return BatchOutcome{Results: results}, nil
+	}
+	if err != nil {
+		return BatchOutcome{}, err
+	}
+	touched := []int64{userID}
+	for _, u := range users {
+		touched = append(touched, u...)
+	}
+	s.invalidateCache(ctx, workspaceID, touched)
+	for i, res := range results {
+		if res.Err == nil && res.ID != 0 {
+			t := dom.Todo{ID: res.ID}
+			if res.Todo != nil {
+				t = *res.Todo
+			}
+			s.publish(ctx, workspaceID, userID, append(users[i], userID), batchEvents[res.Op], t)
+		}
+	}
+	return BatchOutcome{Results: results, Committed: true}, nil
+}
+
+// BatchByFilter applies one action to the user's todos matching f, oldest
+// first, as a batch of up to MaxBatchSize operations. op carries the action and
+// its input; its ID is filled in for each todo.
+func (s *TodoService) BatchByFilter(ctx context.Context, userID, workspaceID int64, f BatchFilter, op BatchOp, atomic bool) (BatchOutcome, error) {
+	if op.Op != BatchComplete && op.Op != BatchDelete && op.Op != BatchUpdate {
+		return BatchOutcome{}, ErrBatchFilter
+	}
+	tags, err := normalizeTagFilter(f.Tags)
+	if err != nil {
+		return BatchOutcome{}, err
+	}
+	q := dom.TodoListQuery{
+		WorkspaceID: workspaceID,
+		Limit:       MaxBatchSize,
+		IsDone:      f.IsDone,
+		DueBefore:   f.DueBefore,
+		Tags:        tags,
+		ProjectID:   f.ProjectID,
+		Sort:        dom.SortCreatedAt,
+	}
+	if f.Overdue {
+		now, open := time.Now().UTC(), false
+		if q.DueBefore == nil || q.DueBefore.After(now) {
+			q.DueBefore = &now
+		}
+		q.IsDone = &open
+	}
+	page, err := s.repo.List(ctx, userID, q)
+	if err != nil {
+		return BatchOutcome{}, err
+	}
+	if len(page.Items) == 0 {
+		return BatchOutcome{Results: []BatchResult{}, Committed: true}, nil
+	}
+	ops := make([]BatchOp, len(page.Items))
+	for i, t := range page.Items {
+		ops[i] = op
+		ops[i].ID = t.ID
+		ops[i].skipMissing = op.Op == BatchDelete
+	}
+	out, err := s.Batch(ctx, userID, workspaceID, ops, atomic)
+	out.More = page.Next != nil
+	return out, err
+}
+
+// batchStep runs one operation against r, the repo of the batch transaction,
+// and returns the users whose cache it affects.
+func (s *TodoService) batchStep(ctx context.Context, r repo.TodoRepo, userID, workspaceID int64, op BatchOp) ([]int64, BatchResult, error) {
+	// A copy without cache runs the single-todo logic inside the transaction
+	// and leaves invalidation to Batch.
+	tx := &TodoService{repo: r, projects: s.projects, events: s.events}
+	res := BatchResult{Op: op.Op, ID: op.ID}
+	var users []int64
+	if (s.cache != nil || s.pub != nil) && op.ID != 0 {
+		users, _ = r.Collaborators(ctx, op.ID)
+	}
+	var t dom.Todo
+	var err error
+	switch op.Op {
+	case BatchCreate:
+		t, err = tx.Create(ctx, userID, workspaceID, op.Create)
+	case BatchUpdate:
+		in := op.Update
+		in.IfVersion = op.IfVersion
+		t, err = tx.Update(ctx, userID, workspaceID, op.ID, in)
+	case BatchComplete:
+		t, err = tx.Complete(ctx, userID, workspaceID, op.ID, op.Subtasks, op.IfVersion)
+	case BatchDelete:
+		err = tx.Delete(ctx, userID, workspaceID, op.ID, op.Permanent, op.IfVersion)
+	case BatchMove:
+		t, err = tx.Move(ctx, userID, workspaceID, op.ID, op.ProjectID, op.BeforeID, op.AfterID)
+	default:
+		err = ErrInvalidBatch
+	}
+	if errors.Is(err, ErrNotFound) && op.skipMissing {
+		return nil, res, nil
+	}
+	if err != nil {
+		res.Err = err
+		return nil, res, err
+	}
+	if op.Op != BatchDelete {
+		res.ID, res.Todo = t.ID, &t
+		if s.cache != nil || s.pub != nil {
+			more, _ := r.Collaborators(ctx, t.ID)
+			users = append(users, more...)
+		}
+	}
+	return users, res, nil
+}
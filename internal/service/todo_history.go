@@ -0,0 +1,32 @@
+package service
+
+import (
+	"context"
+
+	dom "Worker/internal/domain"
+)
+
+// History returns one page of the events of a todo the user can see, newest
+// first. Only the todo's own events are listed, not those of its subtasks.
+func (s *TodoService) History(ctx context.Context, userID, workspaceID, id int64, beforeID int64, limit int) ([]dom.TodoEvent, error) {
+	if _, err := s.access(ctx, userID, workspaceID, id, dom.PermissionViewer); err != nil {
+		return nil, err
+	}
+	return s.events.List(ctx, dom.TodoEventQuery{
+		TodoID:   id,
+		BeforeID: beforeID,
+		Limit:    clampPageSize(limit),
+	})
+}
+
+// Activity returns one page of the user's activity feed in the workspace, newest
+// first: changes the user made and changes to todos the user owns or that are
+// shared with them.
+func (s *TodoService) Activity(ctx context.Context, userID, workspaceID int64, beforeID int64, limit int) ([]dom.TodoEvent, error) {
+	return s.events.List(ctx, dom.TodoEventQuery{
+		WorkspaceID: workspaceID,
+		VisibleTo:   userID,
+		BeforeID:    beforeID,
+		Limit:       clampPageSize(limit),
+	})
+}
@@ -0,0 +1,256 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	dom "Worker/internal/domain"
+	"Worker/internal/repo"
+	"Worker/internal/todoio"
+)
+
+const (
+	// MaxImportTodos caps the todos of one import file.
+	MaxImportTodos = 10000
+	// importChunk is how many todos one statement of an import inserts.
+	importChunk = 500
+
+	maxTitleLen       = 120
+	maxDescriptionLen = 1000
+	maxTodoTags       = 20
+)
+
+var (
+	ErrImportTooLarge = fmt.Errorf("an import can hold at most %d todos", MaxImportTodos)
+	// ErrImportInvalid: some records have errors, so nothing was imported; the
+	// result lists them.
+	ErrImportInvalid = errors.New("the file has errors; nothing was imported")
+)
+
+// Export writes the user's own todos in the workspace to w: open and done ones,
+// with includeDeleted also those in the trash.
+func (s *TodoService) Export(ctx context.Context, userID, workspaceID int64, includeDeleted bool, w todoio.Writer) error {
+	projects, err := s.projects.List(ctx, userID, workspaceID, true)
+	if err != nil {
+		return err
+	}
+	names := make(map[int64]string, len(projects))
+	for _, p := range projects {
+		names[p.ID] = p.Name
+	}
+	return s.repo.Export(ctx, userID, workspaceID, includeDeleted, func(t dom.Todo) error {
+		name := ""
+		if t.ProjectID != nil {
+			name = names[*t.ProjectID]
+		}
+		return w.Write(t, name)
+	})
+}
+
+// ImportResult reports an import. Problems hold the errors and warnings of
+// the records; an import with errors imports nothing.
+type ImportResult struct {
+	DryRun   bool
+	Todos    int      // todos read from the file
+	Created  int      // todos imported; 0 on a dry run
+	Projects []string // projects created, or on a dry run to be created
+	Problems []todoio.Problem
+}
+
+// Import reads a file in format and adds its todos to the user's own todos in
+// the workspace, all or none, in one transaction. Projects are matched by name
+// among the user's projects in the workspace, case-insensitively, and created
+// when missing. Unlike Create it takes due dates in the past, done todos and
+// creation times, so that history can be brought over. With dryRun the file is
+// only checked.
+//
+// Imported todos are not pushed to event streams or webhooks, which would be
+// flooded by a large file; sync clients get them with their next changes.
+func (s *TodoService) Import(ctx context.Context, userID, workspaceID int64, format string, r io.Reader, dryRun bool) (ImportResult, error) {
+	recs, problems, err := todoio.Read(r, format)
+	if err != nil {
+		return ImportResult{}, err
+	}
+	if len(recs) > MaxImportTodos {
+		return ImportResult{}, ErrImportTooLarge
+	}
+	res := ImportResult{DryRun: dryRun, Todos: len(recs), Problems: problems}
+
+	projects, err := s.projects.List(ctx, userID, workspaceID, true)
+	if err != nil {
+		return ImportResult{}, err
+	}
+	projectIDs := make(map[string]int64, len(projects))
+	for _, p := range projects {
+		if key := strings.ToLower(p.Name); projectIDs[key] == 0 {
+			projectIDs[key] = p.ID
+		}
+	}
+	v := importValidator{recs: recs, todos: make([]dom.Todo, len(recs)), depth: make([]int, len(recs)), project: make([]string, len(recs))}
+	var newProjects []string // lowercased names, in order of first use
+	for i := range recs {
+		v.check(i, userID, workspaceID)
+		if v.project[i] == "" {
+			continue
+		}
+		key := strings.ToLower(v.project[i])
+		if _, exists := projectIDs[key]; !exists {
+			projectIDs[key] = 0
+			newProjects = append(newProjects, key)
+			res.Projects = append(res.Projects, v.project[i])
+		}
+	}
+	res.Problems = append(res.Problems, v.problems...)
+	slices.SortStableFunc(res.Problems, func(a, b todoio.Problem) int { return cmp.Compare(a.Line, b.Line) })
+	if hasErrors(res.Problems) {
+		if dryRun {
+			return res, nil
+		}
+		return res, ErrImportInvalid
+	}
+	if dryRun || len(recs) == 0 {
+		return res, nil
+	}
+
+	todos := v.todos
+	err = s.repo.InTx(ctx, func(r repo.TodoRepo) error {
+		if len(newProjects) > 0 {
+			ids, err := r.ImportProjects(ctx, userID, workspaceID, res.Projects)
+			if err != nil {
+				return err
+			}
+			for k, key := range newProjects {
+				projectIDs[key] = ids[k]
+			}
+		}
+		ids, err := r.ReserveIDs(ctx, len(todos))
+		if err != nil {
+			return err
+		}
+		for i := range todos {
+			todos[i].ID = ids[i]
+			if p := recs[i].Parent; p >= 0 {
+				todos[i].ParentID = &ids[p]
+			}
+			if name := v.project[i]; name != "" {
+				id := projectIDs[strings.ToLower(name)]
+				todos[i].ProjectID = &id
+			}
+		}
+		for chunk := range slices.Chunk(todos, importChunk) {
+			if err := r.Import(ctx, userID, chunk); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return ImportResult{}, err
+	}
+	res.Created = len(todos)
+	s.invalidateCache(ctx, workspaceID, []int64{userID})
+	return res, nil
+}
+
+// importValidator turns records into todos, collecting the problems of each.
+type importValidator struct {
+	recs     []todoio.Record
+	todos    []dom.Todo
+	depth    []int    // depth of each record in its tree
+	project  []string // project name of each record; subtasks take their parent's
+	problems []todoio.Problem
+}
+
+func (v *importValidator) errorf(line int, field string, err error) {
+	v.problems = append(v.problems, todoio.Problem{Line: line, Field: field, Message: err.Error()})
+}
+
+func (v *importValidator) warn(line int, field, msg string) {
+	v.problems = append(v.problems, todoio.Problem{Line: line, Field: field, Message: msg, Warning: true})
+}
+
+// check validates record i and stores it as a todo of the user in the
+// workspace, still without its ID, parent and project, which are known only
+// on insert.
+func (v *importValidator) check(i int, userID, workspaceID int64) {
+	rec := v.recs[i]
+	t := dom.Todo{
+		UserID:      userID,
+		WorkspaceID: workspaceID,
+		Title:       strings.TrimSpace(rec.Title),
+		Description: strings.TrimSpace(rec.Description),
+		IsDone:      rec.IsDone,
+		DueAt:       rec.DueAt,
+		DeletedAt:   rec.DeletedAt,
+	}
+	if rec.CreatedAt != nil {
+		t.CreatedAt = *rec.CreatedAt
+	}
+	switch n := utf8.RuneCountInString(t.Title); {
+	case n == 0:
+		v.errorf(rec.Line, "title", errors.New("title is required"))
+	case n > maxTitleLen:
+		v.errorf(rec.Line, "title", fmt.Errorf("title must be at most %d characters", maxTitleLen))
+	}
+	if utf8.RuneCountInString(t.Description) > maxDescriptionLen {
+		v.errorf(rec.Line, "description", fmt.Errorf("description must be at most %d characters", maxDescriptionLen))
+	}
+	var err error
+	if t.Tags, err = normalizeTagNames(rec.Tags); err != nil {
+		v.errorf(rec.Line, "tags", err)
+	} else if len(t.Tags) > maxTodoTags {
+		v.errorf(rec.Line, "tags", fmt.Errorf("a todo can have at most %d tags", maxTodoTags))
+	}
+	if t.Reminders, err = normalizeReminders(rec.Reminders); err != nil {
+		v.errorf(rec.Line, "reminders", err)
+	} else if len(t.Reminders) > 0 && t.DueAt == nil {
+		v.errorf(rec.Line, "reminders", ErrReminderNeedsDue)
+	}
+	if err := applyRecurrence(&t, rec.Recurrence, rec.Timezone); err != nil {
+		v.errorf(rec.Line, "recurrence", err)
+	}
+
+	v.project[i] = strings.TrimSpace(rec.Project)
+	if p := rec.Parent; p >= 0 {
+		v.depth[i] = v.depth[p] + 1
+		if v.depth[i] > dom.MaxTodoDepth {
+			v.errorf(rec.Line, "parent_id", ErrMaxDepth)
+		}
+		if v.project[i] != "" && !strings.EqualFold(v.project[i], v.project[p]) {
+			v.warn(rec.Line, "project", "subtasks stay in their parent's project; imported into "+projectLabel(v.project[p]))
+		}
+		v.project[i] = v.project[p]
+		// A trashed todo has its subtasks in the trash with it, as a delete
+		// would have left them.
+		if gone := v.todos[p].DeletedAt; gone != nil {
+			t.DeletedAt = gone
+		}
+	} else if utf8.RuneCountInString(v.project[i]) > 120 {
+		v.errorf(rec.Line, "project", ErrInvalidProjectName)
+	}
+	v.todos[i] = t
+}
+
+// hasErrors reports whether any of problems is more than a warning.
+func hasErrors(problems []todoio.Problem) bool {
+	for _, p := range problems {
+		if !p.Warning {
+			return true
+		}
+	}
+	return false
+}
+
+func projectLabel(name string) string {
+	if name == "" {
+		return "no project"
+	}
+	return strconv.Quote(name)
+}
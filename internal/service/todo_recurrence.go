@@ -0,0 +1,93 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	dom "Worker/internal/domain"
+	"Worker/internal/recurrence"
+)
+
+var (
+	// ErrInvalidRecurrence is returned wrapped, with the offending rule part in the message.
+	ErrInvalidRecurrence  = recurrence.ErrInvalidRule
+	ErrInvalidTimezone    = errors.New("unknown timezone; use an IANA name such as Europe/Berlin")
+	ErrRecurrenceNeedsDue = errors.New("a recurring todo needs due_at")
+)
+
+// applyRecurrence validates rule and tz and stores them on t; the series starts at
+// t.DueAt. An empty rule makes t a one-off todo again.
+func applyRecurrence(t *dom.Todo, rule, tz string) error {
+	rule = strings.TrimSpace(rule)
+	if rule == "" {
+		t.Recurrence, t.RecurrenceTZ, t.RecurrenceStart = "", "", nil
+		return nil
+	}
+	r, err := recurrence.Parse(rule)
+	if err != nil {
+		return err
+	}
+	tz = strings.TrimSpace(tz)
+	if _, err := loadZone(tz); err != nil {
+		return err
+	}
+	if t.DueAt == nil {
+		return ErrRecurrenceNeedsDue
+	}
+	start := *t.DueAt
+	t.Recurrence, t.RecurrenceTZ, t.RecurrenceStart = r.String(), tz, &start
+	return nil
+}
+
+// nextOccurrence builds the todo that follows t in its series. ok is false for a
+// one-off todo and when the series has ended (COUNT or UNTIL reached).
+func nextOccurrence(t dom.Todo) (next dom.Todo, ok bool, err error) {
+	if t.Recurrence == "" || t.DueAt == nil {
+		return dom.Todo{}, false, nil
+	}
+	rule, err := recurrence.Parse(t.Recurrence)
+	if err != nil {
+		return dom.Todo{}, false, err
+	}
+	loc, err := loadZone(t.RecurrenceTZ)
+	if err != nil {
+		return dom.Todo{}, false, err
+	}
+	start := *t.DueAt
+	if t.RecurrenceStart != nil {
+		start = *t.RecurrenceStart
+	}
+	due, ok := rule.Next(start.In(loc), *t.DueAt)
+	if !ok {
+		return dom.Todo{}, false, nil
+	}
+	due = due.UTC()
+	return dom.Todo{
+		UserID:          t.UserID,
+		WorkspaceID:     t.WorkspaceID,
+		Title:           t.Title,
+		Description:     t.Description,
+		DueAt:           &due,
+		Tags:            t.Tags,
+		Reminders:       t.Reminders,
+		ProjectID:       t.ProjectID,
+		ParentID:        t.ParentID,
+		Recurrence:      t.Recurrence,
+		RecurrenceTZ:    t.RecurrenceTZ,
+		RecurrenceStart: t.RecurrenceStart,
+	}, true, nil
+}
+
+// loadZone resolves an IANA zone name; "" means UTC. The server's local zone is
+// not accepted because it would make a series depend on where the API runs.
+func loadZone(tz string) (*time.Location, error) {
+	if tz == "Local" {
+		return nil, ErrInvalidTimezone
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return nil, ErrInvalidTimezone
+	}
+	return loc, nil
+}
@@ -0,0 +1,34 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	dom "Worker/internal/domain"
+)
+
+var (
+	ErrInvalidReminder = fmt.Errorf("reminders: at most %d, each from 0 to %s before due_at",
+		dom.MaxReminders, dom.MaxReminderOffset)
+	ErrReminderNeedsDue = errors.New("reminders need due_at")
+)
+
+// normalizeReminders validates reminder offsets and returns them sorted and
+// without duplicates. The result is never nil, so it always replaces the stored set.
+func normalizeReminders(offsets []time.Duration) ([]time.Duration, error) {
+	out := make([]time.Duration, 0, len(offsets))
+	for _, d := range offsets {
+		if d < 0 || d > dom.MaxReminderOffset {
+			return nil, ErrInvalidReminder
+		}
+		out = append(out, d.Truncate(time.Second))
+	}
+	slices.Sort(out)
+	out = slices.Compact(out)
+	if len(out) > dom.MaxReminders {
+		return nil, ErrInvalidReminder
+	}
+	return out, nil
+}
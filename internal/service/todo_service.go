@@ -3,6 +3,8 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
+	"log"
 	"strconv"
 	"strings"
 	"time"
@@ -14,169 +16,515 @@ import (
 	"golang.org/x/sync/singleflight"
 
 	"Worker/internal/cache"
+	"Worker/internal/realtime"
+	"Worker/internal/webhook"
 )
 
 var (
 	ErrNotFound       = errors.New("not found")
 	ErrInvalidDueDate = errors.New("due_at is in the past")
+	ErrInvalidSort    = errors.New("invalid sort key")
+	ErrInvalidCursor  = errors.New("invalid cursor")
+	// ErrProjectNotFound: a todo refers to a project the user does not have.
+	ErrProjectNotFound = errors.New("project not found")
+	ErrInvalidPosition = errors.New("before_id/after_id must be a todo in the target project")
+	ErrMaxDepth        = fmt.Errorf("subtasks cannot be nested deeper than %d levels", dom.MaxTodoDepth)
+	ErrSubtaskProject  = errors.New("subtasks stay in their parent's project; move the top-level todo instead")
+	ErrInvalidReorder  = errors.New("ids must list every subtask exactly once")
+	// ErrVersionConflict: the todo changed since the version the client sent in If-Match.
+	ErrVersionConflict = errors.New("todo has been modified; reload it and try again")
+)
+
+const (
+	DefaultListLimit = 50
+	MaxListLimit     = 200
 )
 
 type TodoService struct {
-	repo  repo.TodoRepo
-	cache *cache.TodoCache
-	sf    singleflight.Group
+	repo     repo.TodoRepo
+	projects repo.ProjectRepo
+	events   repo.TodoEventRepo
+	cache    *cache.TodoCache
+	pub      *realtime.Broker
+	sf       singleflight.Group
 }
 
-// NewTodoService creates a TodoService. If c is nil, caching is disabled.
-func NewTodoService(r repo.TodoRepo, c *cache.TodoCache) *TodoService {
-	return &TodoService{repo: r, cache: c}
+// NewTodoService creates a TodoService. If c is nil, caching is disabled; if
+// pub is nil, changes are not pushed to connected clients.
+func NewTodoService(r repo.TodoRepo, projects repo.ProjectRepo, events repo.TodoEventRepo, c *cache.TodoCache, pub *realtime.Broker) *TodoService {
+	return &TodoService{repo: r, projects: projects, events: events, cache: c, pub: pub}
 }
 
-func (s *TodoService) Create(ctx context.Context, userID int64, title, desc string, dueAt *time.Time) (dom.Todo, error) {
-	title = strings.TrimSpace(title)
-	desc = strings.TrimSpace(desc)
+// CreateTodoInput holds the fields of a new todo.
+type CreateTodoInput struct {
+	Title       string
+	Description string
+	DueAt       *time.Time
+	Tags        []string        // tag names; tags the user does not have yet are created
+	Reminders   []time.Duration // how long before DueAt to remind; needs DueAt
+	ProjectID   *int64          // nil = no project
+	ParentID    *int64          // makes the todo a subtask; it joins the parent's project
+	Recurrence  string          // RRULE, e.g. "FREQ=WEEKLY;BYDAY=MO"; needs DueAt
+	Timezone    string          // IANA zone the rule runs in; "" = UTC
+}
 
-	if dueAt != nil && dueAt.Before(time.Now().UTC()) {
+// UpdateTodoInput is a partial update: nil fields are left unchanged.
+type UpdateTodoInput struct {
+	Title       *string
+	Description *string
+	DueAt       *time.Time
+	IsDone      *bool
+	Tags        *[]string        // replaces the tag set; an empty slice removes all tags
+	Reminders   *[]time.Duration // replaces the reminders; an empty slice removes all
+	ProjectID   *int64           // 0 = remove from project
+	Recurrence  *string          // "" = stop repeating
+	Timezone    *string
+	IfVersion   int64 // the version the client edited (If-Match); 0 = overwrite whatever is there
+}
+
+// Create adds a todo at the end of its project.
+func (s *TodoService) Create(ctx context.Context, userID, workspaceID int64, in CreateTodoInput) (dom.Todo, error) {
+	title := strings.TrimSpace(in.Title)
+	desc := strings.TrimSpace(in.Description)
+	tags, err := normalizeTagNames(in.Tags)
+	if err != nil {
+		return dom.Todo{}, err
+	}
+
+	if in.DueAt != nil && in.DueAt.Before(time.Now().UTC()) {
 		return dom.Todo{}, ErrInvalidDueDate
 	}
+	reminders, err := normalizeReminders(in.Reminders)
+	if err != nil {
+		return dom.Todo{}, err
+	}
+	if len(reminders) > 0 && in.DueAt == nil {
+		return dom.Todo{}, ErrReminderNeedsDue
+	}
+	// A todo added to someone else's shared todo or project belongs to its owner.
+	projectID := in.ProjectID
+	var owner int64
+	var perm dom.Permission
+	if in.ParentID != nil {
+		parent, err := s.access(ctx, userID, workspaceID, *in.ParentID, dom.PermissionEditor)
+		if err != nil {
+			return dom.Todo{}, err
+		}
+		if projectID != nil && !sameID(projectID, parent.ProjectID) {
+			return dom.Todo{}, ErrSubtaskProject
+		}
+		depth, err := s.repo.Depth(ctx, parent.UserID, parent.ID)
+		if err != nil {
+			return dom.Todo{}, err
+		}
+		if depth >= dom.MaxTodoDepth {
+			return dom.Todo{}, ErrMaxDepth
+		}
+		projectID = parent.ProjectID
+		owner, perm = parent.UserID, parent.Permission
+	} else if owner, perm, err = s.projectOwner(ctx, userID, workspaceID, projectID, dom.PermissionEditor); err != nil {
+		return dom.Todo{}, err
+	}
 
-	t, err := s.repo.Create(ctx, dom.Todo{
-		UserID:      userID,
+	todo := dom.Todo{
+		UserID:      owner,
+		WorkspaceID: workspaceID,
 		Title:       title,
 		Description: desc,
-		DueAt:       dueAt,
+		DueAt:       in.DueAt,
+		Tags:        tags,
+		Reminders:   reminders,
+		ProjectID:   projectID,
+		ParentID:    in.ParentID,
+	}
+	if err := applyRecurrence(&todo, in.Recurrence, in.Timezone); err != nil {
+		return dom.Todo{}, err
+	}
+	t, err := s.write(ctx, dom.WebhookTodoCreated, userID, nil, func(r repo.TodoRepo) (dom.Todo, error) {
+		return r.Create(ctx, userID, todo)
 	})
 	if err != nil {
 		return dom.Todo{}, err
 	}
-	s.invalidateCache(ctx, userID)
+	s.changed(ctx, workspaceID, userID, s.collaborators(ctx, userID, t.ID), realtime.EventCreated, t)
+	t.Permission = perm
 	return t, nil
 }
 
-func (s *TodoService) List(ctx context.Context, userID int64) ([]dom.Todo, error) {
-	if s.cache != nil {
-		key := "list:" + strconv.FormatInt(userID, 10)
-		v, err, _ := s.sf.Do(key, func() (interface{}, error) {
-			if list, err := s.cache.GetList(ctx, userID); err == nil && list != nil {
-				return list, nil
-			}
-			list, err := s.repo.List(ctx, userID)
-			if err != nil {
-				return nil, err
-			}
-			_ = s.cache.SetList(ctx, userID, list)
-			return list, nil
-		})
+// List returns one page of the user's todos in the workspace, or of all todos of q.ProjectID when
+// that project is shared with the user. Zero Limit and empty Sort fall back to
+// DefaultListLimit and newest-first; a cursor must come from the same sort order.
+func (s *TodoService) List(ctx context.Context, userID, workspaceID int64, q dom.TodoListQuery) (dom.TodoPage, error) {
+	q, err := normalizeListQuery(q)
+	if err != nil {
+		return dom.TodoPage{}, err
+	}
+	owner, _, err := s.projectOwner(ctx, userID, workspaceID, q.ProjectID, dom.PermissionViewer)
+	if err != nil {
+		return dom.TodoPage{}, err
+	}
+	q.WorkspaceID = workspaceID
+	return s.cachedList(ctx, workspaceID, userID, listPageKey(q), func() (dom.TodoPage, error) {
+		return s.repo.List(ctx, owner, q)
+	})
+}
+
+// Shared returns one page of the todos in the workspace other users shared with
+// the user, directly or through a project; the query works as for List.
+func (s *TodoService) Shared(ctx context.Context, userID, workspaceID int64, q dom.TodoListQuery) (dom.TodoPage, error) {
+	q, err := normalizeListQuery(q)
+	if err != nil {
+		return dom.TodoPage{}, err
+	}
+	q.WorkspaceID = workspaceID
+	return s.cachedList(ctx, workspaceID, userID, "shared:"+listPageKey(q), func() (dom.TodoPage, error) {
+		return s.repo.ListShared(ctx, userID, q)
+	})
+}
+
+// normalizeListQuery applies list defaults and validates sort, cursor and tags.
+func normalizeListQuery(q dom.TodoListQuery) (dom.TodoListQuery, error) {
+	if q.Limit <= 0 {
+		q.Limit = DefaultListLimit
+	}
+	if q.Limit > MaxListLimit {
+		q.Limit = MaxListLimit
+	}
+	if q.Sort == "" {
+		q.Sort, q.Desc = dom.SortCreatedAt, true
+	}
+	if !dom.IsValidTodoSort(q.Sort) {
+		return q, ErrInvalidSort
+	}
+	if q.After != nil && (q.After.Sort != q.Sort || q.After.Desc != q.Desc) {
+		return q, ErrInvalidCursor
+	}
+	tags, err := normalizeTagFilter(q.Tags)
+	if err != nil {
+		return q, err
+	}
+	q.Tags = tags
+	return q, nil
+}
+
+// cachedList serves one list page of the user in a workspace from the cache,
+// loading it with load on a miss.
+func (s *TodoService) cachedList(ctx context.Context, workspaceID, userID int64, pageKey string, load func() (dom.TodoPage, error)) (dom.TodoPage, error) {
+	if s.cache == nil {
+		return load()
+	}
+	key := "list:" + sfKey(workspaceID, userID) + ":" + pageKey
+	v, err, _ := s.sf.Do(key, func() (interface{}, error) {
+		if page, err := s.cache.GetList(ctx, workspaceID, userID, pageKey); err == nil && page != nil {
+			return *page, nil
+		}
+		page, err := load()
 		if err != nil {
 			return nil, err
 		}
-		return v.([]dom.Todo), nil
+		_ = s.cache.SetList(ctx, workspaceID, userID, pageKey, page)
+		return page, nil
+	})
+	if err != nil {
+		return dom.TodoPage{}, err
 	}
-	return s.repo.List(ctx, userID)
+	return v.(dom.TodoPage), nil
 }
 
-func (s *TodoService) GetByID(ctx context.Context, userID, id int64) (dom.Todo, error) {
-	t, err := s.repo.GetByID(ctx, userID, id)
+// GetByID returns a todo the user owns or that is shared with them.
+func (s *TodoService) GetByID(ctx context.Context, userID, workspaceID, id int64) (dom.Todo, error) {
+	return s.access(ctx, userID, workspaceID, id, dom.PermissionViewer)
+}
+
+// Update applies a partial update. Changing the rule, the timezone or the due date
+// of a recurring todo restarts its series at the (new) due date.
+func (s *TodoService) Update(ctx context.Context, userID, workspaceID, id int64, in UpdateTodoInput) (dom.Todo, error) {
+	existing, err := s.access(ctx, userID, workspaceID, id, dom.PermissionEditor)
+	if err != nil {
+		return dom.Todo{}, err
+	}
+	if err := checkVersion(existing, in.IfVersion); err != nil {
+		return dom.Todo{}, err
+	}
+	patch := existing
+	patch.Version = in.IfVersion
+	if in.Title != nil {
+		patch.Title = strings.TrimSpace(*in.Title)
+	}
+	if in.Description != nil {
+		patch.Description = strings.TrimSpace(*in.Description)
+	}
+	if in.DueAt != nil {
+		if in.DueAt.Before(time.Now().UTC()) {
+			return dom.Todo{}, ErrInvalidDueDate
+		}
+		patch.DueAt = in.DueAt
+	}
+	if in.IsDone != nil {
+		patch.IsDone = *in.IsDone
+	}
+	patch.Tags = nil
+	if in.Tags != nil {
+		if patch.Tags, err = normalizeTagNames(*in.Tags); err != nil {
+			return dom.Todo{}, err
+		}
+	}
+	patch.Reminders = nil
+	if in.Reminders != nil {
+		if patch.Reminders, err = normalizeReminders(*in.Reminders); err != nil {
+			return dom.Todo{}, err
+		}
+		if len(patch.Reminders) > 0 && patch.DueAt == nil {
+			return dom.Todo{}, ErrReminderNeedsDue
+		}
+	}
+	if in.ProjectID != nil && !sameID(existing.ProjectID, nonZero(in.ProjectID)) {
+		if existing.ParentID != nil {
+			return dom.Todo{}, ErrSubtaskProject
+		}
+		if err := s.checkTargetProject(ctx, userID, workspaceID, existing, in.ProjectID); err != nil {
+			return dom.Todo{}, err
+		}
+		patch.ProjectID = nonZero(in.ProjectID)
+	}
+	if in.Recurrence != nil || in.Timezone != nil || (in.DueAt != nil && existing.Recurrence != "") {
+		rule, tz := existing.Recurrence, existing.RecurrenceTZ
+		if in.Recurrence != nil {
+			rule = *in.Recurrence
+		}
+		if in.Timezone != nil {
+			tz = *in.Timezone
+		}
+		if err := applyRecurrence(&patch, rule, tz); err != nil {
+			return dom.Todo{}, err
+		}
+	}
+	// Closing a todo with PATCH is a completion for webhooks too.
+	hook := dom.WebhookTodoUpdated
+	if patch.IsDone && !existing.IsDone {
+		hook = dom.WebhookTodoCompleted
+	}
+	before := s.collaborators(ctx, userID, id)
+	t, err := s.write(ctx, hook, userID, before, func(r repo.TodoRepo) (dom.Todo, error) {
+		return r.Update(ctx, userID, existing.UserID, id, patch)
+	})
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return dom.Todo{}, ErrNotFound
 		}
+		if errors.Is(err, repo.ErrVersionConflict) {
+			return dom.Todo{}, ErrVersionConflict
+		}
 		return dom.Todo{}, err
 	}
+	s.changed(ctx, workspaceID, userID, append(before, s.collaborators(ctx, userID, id)...), realtime.EventUpdated, t)
+	t.Permission = existing.Permission
 	return t, nil
 }
 
-func (s *TodoService) Update(ctx context.Context, userID, id int64, title *string, desc *string, dueAt *time.Time, isDone *bool) (dom.Todo, error) {
-	existing, err := s.repo.GetByID(ctx, userID, id)
+// Move reorders a todo: it goes into projectID (nil = keep the current project,
+// 0 = no project) right after afterID or before beforeID, or to the end if both are 0.
+func (s *TodoService) Move(ctx context.Context, userID, workspaceID, id int64, projectID *int64, beforeID, afterID int64) (dom.Todo, error) {
+	existing, err := s.access(ctx, userID, workspaceID, id, dom.PermissionEditor)
 	if err != nil {
+		return dom.Todo{}, err
+	}
+	target := existing.ProjectID
+	if projectID != nil {
+		if existing.ParentID != nil {
+			return dom.Todo{}, ErrSubtaskProject
+		}
+		target = nonZero(projectID)
+		if !sameID(existing.ProjectID, target) {
+			if err := s.checkTargetProject(ctx, userID, workspaceID, existing, projectID); err != nil {
+				return dom.Todo{}, err
+			}
+		}
+	}
+	before := s.collaborators(ctx, userID, id)
+	t, err := s.write(ctx, dom.WebhookTodoUpdated, userID, before, func(r repo.TodoRepo) (dom.Todo, error) {
+		return r.Move(ctx, userID, existing.UserID, id, target, beforeID, afterID)
+	})
+	if err != nil {
+		if errors.Is(err, repo.ErrAnchorNotFound) {
+			return dom.Todo{}, ErrInvalidPosition
+		}
 		if errors.Is(err, pgx.ErrNoRows) {
 			return dom.Todo{}, ErrNotFound
 		}
 		return dom.Todo{}, err
 	}
-	patch := existing
-	if title != nil {
-		patch.Title = strings.TrimSpace(*title)
-	}
-	if desc != nil {
-		patch.Description = strings.TrimSpace(*desc)
+	s.changed(ctx, workspaceID, userID, append(before, s.collaborators(ctx, userID, id)...), realtime.EventUpdated, t)
+	t.Permission = existing.Permission
+	return t, nil
+}
+
+// Complete marks a todo done. With withSubtasks its whole subtree is completed too.
+// Completing an open recurring todo also creates its next occurrence, due at the
+// first date of the series after the current due date. A non-zero ifVersion must
+// match the todo's version.
+func (s *TodoService) Complete(ctx context.Context, userID, workspaceID, id int64, withSubtasks bool, ifVersion int64) (dom.Todo, error) {
+	existing, err := s.access(ctx, userID, workspaceID, id, dom.PermissionEditor)
+	if err != nil {
+		return dom.Todo{}, err
 	}
-	if dueAt != nil {
-		if dueAt.Before(time.Now().UTC()) {
-			return dom.Todo{}, ErrInvalidDueDate
-		}
-		patch.DueAt = dueAt
+	if err := checkVersion(existing, ifVersion); err != nil {
+		return dom.Todo{}, err
 	}
-	if isDone != nil {
-		patch.IsDone = *isDone
+	owner := existing.UserID
+	next, repeat, err := nextOccurrence(existing)
+	if err != nil {
+		return dom.Todo{}, err
 	}
-	t, err := s.repo.Update(ctx, userID, id, patch)
+	t, err := s.write(ctx, dom.WebhookTodoCompleted, userID, nil, func(r repo.TodoRepo) (dom.Todo, error) {
+		switch {
+		case repeat && !existing.IsDone:
+			t, err := r.CompleteAndRepeat(ctx, userID, owner, id, ifVersion, next)
+			if err != nil || !withSubtasks {
+				return t, err
+			}
+			// Completing it has bumped the version checked just before.
+			return r.CompleteSubtree(ctx, userID, owner, id, 0)
+		case withSubtasks:
+			return r.CompleteSubtree(ctx, userID, owner, id, ifVersion)
+		default:
+			return r.MarkDone(ctx, userID, owner, id, true, ifVersion)
+		}
+	})
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return dom.Todo{}, ErrNotFound
 		}
+		if errors.Is(err, repo.ErrVersionConflict) {
+			return dom.Todo{}, ErrVersionConflict
+		}
 		return dom.Todo{}, err
 	}
-	s.invalidateCache(ctx, userID)
+	s.changed(ctx, workspaceID, userID, s.collaborators(ctx, userID, id), realtime.EventCompleted, t)
+	t.Permission = existing.Permission
 	return t, nil
 }
 
-func (s *TodoService) Complete(ctx context.Context, userID, id int64) (dom.Todo, error) {
-	t, err := s.repo.MarkDone(ctx, userID, id, true)
+// Subtasks returns the direct subtasks of a todo in manual order.
+func (s *TodoService) Subtasks(ctx context.Context, userID, workspaceID, id int64) ([]dom.Todo, error) {
+	parent, err := s.access(ctx, userID, workspaceID, id, dom.PermissionViewer)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.Children(ctx, parent.UserID, id)
+}
+
+// ReorderSubtasks sets the manual order of a todo's subtasks; ids must list all of them.
+func (s *TodoService) ReorderSubtasks(ctx context.Context, userID, workspaceID, id int64, ids []int64) ([]dom.Todo, error) {
+	parent, err := s.access(ctx, userID, workspaceID, id, dom.PermissionEditor)
 	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.ReorderChildren(ctx, userID, parent.UserID, id, ids); err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return dom.Todo{}, ErrNotFound
+			return nil, ErrNotFound
 		}
-		return dom.Todo{}, err
+		if errors.Is(err, repo.ErrChildrenMismatch) {
+			return nil, ErrInvalidReorder
+		}
+		return nil, err
 	}
-	s.invalidateCache(ctx, userID)
-	return t, nil
+	users := s.collaborators(ctx, userID, id)
+	s.invalidateCache(ctx, workspaceID, users)
+	children, err := s.repo.Children(ctx, parent.UserID, id)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range children {
+		s.publish(ctx, workspaceID, userID, users, realtime.EventUpdated, c)
+	}
+	return children, nil
 }
 
-func (s *TodoService) Delete(ctx context.Context, userID, id int64) error {
-	err := s.repo.SoftDelete(ctx, userID, id)
+// Delete moves a todo together with all its subtasks to the trash, or with
+// permanent deletes them for good; a permanent delete also empties a todo out of
+// the trash. Only owners may delete a shared todo. A non-zero ifVersion must
+// match the todo's version.
+func (s *TodoService) Delete(ctx context.Context, userID, workspaceID, id int64, permanent bool, ifVersion int64) error {
+	existing, err := s.access(ctx, userID, workspaceID, id, dom.PermissionOwner)
+	if err != nil {
+		if !permanent || !errors.Is(err, ErrNotFound) {
+			return err
+		}
+		if existing, err = s.trashed(ctx, userID, workspaceID, id); err != nil {
+			return err
+		}
+	}
+	if err := checkVersion(existing, ifVersion); err != nil {
+		return err
+	}
+	collaborators := s.collaborators(ctx, userID, id)
+	_, err = s.write(ctx, dom.WebhookTodoDeleted, userID, collaborators, func(r repo.TodoRepo) (dom.Todo, error) {
+		if permanent {
+			return existing, r.Purge(ctx, userID, existing.UserID, id, ifVersion)
+		}
+		return existing, r.SoftDelete(ctx, userID, existing.UserID, id, ifVersion)
+	})
 	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNotFound
+		}
+		if errors.Is(err, repo.ErrVersionConflict) {
+			return ErrVersionConflict
+		}
 		return err
 	}
-	s.invalidateCache(ctx, userID)
+	s.changed(ctx, workspaceID, userID, collaborators, realtime.EventDeleted, existing)
 	return nil
 }
 
-func (s *TodoService) Search(ctx context.Context, userID int64, q string) ([]dom.Todo, error) {
+// Search runs a full-text search over the user's todos in the workspace, most relevant first,
+// optionally narrowed to todos carrying the given tags.
+func (s *TodoService) Search(ctx context.Context, userID, workspaceID int64, q string, tags dom.TagFilter) ([]dom.TodoSearchHit, error) {
 	q = strings.TrimSpace(q)
+	tags, err := normalizeTagFilter(tags)
+	if err != nil {
+		return nil, err
+	}
 	if s.cache != nil {
-		key := "search:" + strconv.FormatInt(userID, 10) + ":" + strings.ToLower(q)
+		// The tag filter rides along in the query part of the cache key; plain
+		// searches keep the todo:<workspaceID>:search:<userID>:<q> key.
+		cacheQ := q
+		if tk := tagFilterKey(tags); tk != "" {
+			cacheQ += "|" + tk
+		}
+		key := "search:" + sfKey(workspaceID, userID) + ":" + strings.ToLower(cacheQ)
 		v, err, _ := s.sf.Do(key, func() (interface{}, error) {
-			if list, err := s.cache.GetSearch(ctx, userID, q); err == nil && list != nil {
+			if list, err := s.cache.GetSearch(ctx, workspaceID, userID, cacheQ); err == nil && list != nil {
 				return list, nil
 			}
-			list, err := s.repo.Search(ctx, userID, q)
+			list, err := s.repo.Search(ctx, userID, workspaceID, q, tags)
 			if err != nil {
 				return nil, err
 			}
-			_ = s.cache.SetSearch(ctx, userID, q, list)
+			_ = s.cache.SetSearch(ctx, workspaceID, userID, cacheQ, list)
 			return list, nil
 		})
 		if err != nil {
 			return nil, err
 		}
-		return v.([]dom.Todo), nil
+		return v.([]dom.TodoSearchHit), nil
 	}
-	return s.repo.Search(ctx, userID, q)
+	return s.repo.Search(ctx, userID, workspaceID, q, tags)
 }
 
-func (s *TodoService) Overdue(ctx context.Context, userID int64) ([]dom.Todo, error) {
+func (s *TodoService) Overdue(ctx context.Context, userID, workspaceID int64) ([]dom.Todo, error) {
 	if s.cache != nil {
-		key := "overdue:" + strconv.FormatInt(userID, 10)
+		key := "overdue:" + sfKey(workspaceID, userID)
 		v, err, _ := s.sf.Do(key, func() (interface{}, error) {
-			if list, err := s.cache.GetOverdue(ctx, userID); err == nil && list != nil {
+			if list, err := s.cache.GetOverdue(ctx, workspaceID, userID); err == nil && list != nil {
 				return list, nil
 			}
-			list, err := s.repo.Overdue(ctx, userID)
+			list, err := s.repo.Overdue(ctx, userID, workspaceID)
 			if err != nil {
 				return nil, err
 			}
-			_ = s.cache.SetOverdue(ctx, userID, list)
+			_ = s.cache.SetOverdue(ctx, workspaceID, userID, list)
 			return list, nil
 		})
 		if err != nil {
@@ -184,11 +532,183 @@ func (s *TodoService) Overdue(ctx context.Context, userID int64) ([]dom.Todo, er
 		}
 		return v.([]dom.Todo), nil
 	}
-	return s.repo.Overdue(ctx, userID)
+	return s.repo.Overdue(ctx, userID, workspaceID)
+}
+
+// access returns the todo if it lives in the workspace and the user holds at least
+// need on it. A todo the user cannot see at all is ErrNotFound, one they may see
+// but not change ErrForbidden.
+func (s *TodoService) access(ctx context.Context, userID, workspaceID, id int64, need dom.Permission) (dom.Todo, error) {
+	t, err := s.repo.Access(ctx, userID, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return dom.Todo{}, ErrNotFound
+		}
+		return dom.Todo{}, err
+	}
+	if t.WorkspaceID != workspaceID {
+		return dom.Todo{}, ErrNotFound
+	}
+	if t.Permission < need {
+		return dom.Todo{}, ErrForbidden
+	}
+	return t, nil
 }
 
-func (s *TodoService) invalidateCache(ctx context.Context, userID int64) {
+// projectOwner returns whose todos projectID holds and the user's permission on
+// it, requiring at least need. No project means the user's own todos. Projects of
+// other workspaces do not exist here.
+func (s *TodoService) projectOwner(ctx context.Context, userID, workspaceID int64, projectID *int64, need dom.Permission) (int64, dom.Permission, error) {
+	if projectID == nil {
+		return userID, dom.PermissionOwner, nil
+	}
+	p, err := s.projects.Access(ctx, userID, *projectID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, dom.PermissionNone, ErrProjectNotFound
+		}
+		return 0, dom.PermissionNone, err
+	}
+	if p.WorkspaceID != workspaceID {
+		return 0, dom.PermissionNone, ErrProjectNotFound
+	}
+	if p.Permission < need {
+		return 0, dom.PermissionNone, ErrForbidden
+	}
+	return p.UserID, p.Permission, nil
+}
+
+// checkTargetProject verifies that t may move to projectID (0 = no project): only
+// owners move todos, the user must be able to edit the target, and a todo never
+// leaves its owner's projects.
+func (s *TodoService) checkTargetProject(ctx context.Context, userID, workspaceID int64, t dom.Todo, projectID *int64) error {
+	if t.Permission < dom.PermissionOwner {
+		return ErrForbidden
+	}
+	target := nonZero(projectID)
+	if target == nil {
+		return nil
+	}
+	owner, _, err := s.projectOwner(ctx, userID, workspaceID, target, dom.PermissionEditor)
+	if err != nil {
+		return err
+	}
+	if owner != t.UserID {
+		return ErrProjectNotFound
+	}
+	return nil
+}
+
+// collaborators returns the users whose cached lists may show the todo: the
+// acting user, the owner and everyone it is shared with.
+func (s *TodoService) collaborators(ctx context.Context, userID, id int64) []int64 {
+	ids, err := s.repo.Collaborators(ctx, id)
+	if err != nil {
+		return []int64{userID}
+	}
+	return append(ids, userID)
+}
+
+// write runs fn, one change of a todo, in a transaction that also queues the
+// webhook deliveries of event for the todo fn returns, so a change is never
+// committed without its webhooks or the other way round. Besides the todo's
+// collaborators, the webhooks of users (those who lose access) get the event.
+func (s *TodoService) write(ctx context.Context, event string, actorID int64, users []int64, fn func(r repo.TodoRepo) (dom.Todo, error)) (dom.Todo, error) {
+	var t dom.Todo
+	err := s.repo.InTx(ctx, func(r repo.TodoRepo) error {
+		var err error
+		if t, err = fn(r); err != nil {
+			return err
+		}
+		e, err := webhook.NewEvent(event, actorID, t)
+		if err != nil {
+			return err
+		}
+		return r.QueueWebhooks(ctx, users, e)
+	})
+	return t, err
+}
+
+func (s *TodoService) invalidateCache(ctx context.Context, workspaceID int64, userIDs []int64) {
 	if s.cache != nil {
-		_ = s.cache.InvalidateAll(ctx, userID)
+		_ = s.cache.InvalidateUsers(ctx, workspaceID, userIDs...)
+	}
+}
+
+// changed invalidates the cache of userIDs after a write of t by actorID and
+// pushes the change to them.
+func (s *TodoService) changed(ctx context.Context, workspaceID, actorID int64, userIDs []int64, event string, t dom.Todo) {
+	s.invalidateCache(ctx, workspaceID, userIDs)
+	s.publish(ctx, workspaceID, actorID, userIDs, event, t)
+}
+
+// publish pushes one change of t to the users' event streams. Deleted events
+// carry only the ID; delivery is best effort.
+func (s *TodoService) publish(ctx context.Context, workspaceID, actorID int64, userIDs []int64, event string, t dom.Todo) {
+	if s.pub == nil {
+		return
+	}
+	e := realtime.Event{Type: event, WorkspaceID: workspaceID, TodoID: t.ID, ActorID: actorID, At: time.Now().UTC()}
+	if event != realtime.EventDeleted {
+		e.Todo = &t
+	}
+	if err := s.pub.Publish(ctx, userIDs, e); err != nil {
+		log.Printf("publish todo %d %s: %v", t.ID, event, err)
+	}
+}
+
+// sfKey identifies a user's cache entries in a workspace for singleflight.
+func sfKey(workspaceID, userID int64) string {
+	return strconv.FormatInt(workspaceID, 10) + ":" + strconv.FormatInt(userID, 10)
+}
+
+// checkVersion compares the version a client sent in If-Match with the todo's;
+// 0 means the client did not ask.
+func checkVersion(t dom.Todo, ifVersion int64) error {
+	if ifVersion != 0 && ifVersion != t.Version {
+		return ErrVersionConflict
+	}
+	return nil
+}
+
+func sameID(a, b *int64) bool {
+	return (a == nil && b == nil) || (a != nil && b != nil && *a == *b)
+}
+
+// nonZero maps the API's "0 = no project" to nil.
+func nonZero(id *int64) *int64 {
+	if id == nil || *id == 0 {
+		return nil
+	}
+	return id
+}
+
+// listPageKey builds a stable cache key for one list page from its normalized query.
+func listPageKey(q dom.TodoListQuery) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s:%t:%d", q.Sort, q.Desc, q.Limit)
+	if q.After != nil {
+		fmt.Fprintf(&b, ":a=%s,%d", q.After.Value, q.After.ID)
+	}
+	if q.IsDone != nil {
+		fmt.Fprintf(&b, ":done=%t", *q.IsDone)
+	}
+	if q.ProjectID != nil {
+		fmt.Fprintf(&b, ":p=%d", *q.ProjectID)
+	}
+	if q.TopLevelOnly {
+		b.WriteString(":top")
+	}
+	if tk := tagFilterKey(q.Tags); tk != "" {
+		b.WriteString(":" + tk)
+	}
+	for _, f := range []struct {
+		name string
+		t    *time.Time
+	}{{"db", q.DueBefore}, {"da", q.DueAfter}, {"ca", q.CreatedAfter}} {
+		if f.t != nil {
+			fmt.Fprintf(&b, ":%s=%d", f.name, f.t.UnixMicro())
+		}
 	}
+	return b.String()
 }
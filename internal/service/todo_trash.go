@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	dom "Worker/internal/domain"
+	"Worker/internal/realtime"
+	"Worker/internal/repo"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrParentInTrash: a subtask cannot be restored while its parent is deleted.
+var ErrParentInTrash = errors.New("the parent todo is in the trash; restore it first")
+
+// Trash returns one page of the user's deleted todos in the workspace, most
+// recently deleted first. Subtasks deleted together with their parent are not
+// listed on their own; they come back when the parent is restored.
+func (s *TodoService) Trash(ctx context.Context, userID, workspaceID int64, limit int, after *dom.TodoCursor) (dom.TodoPage, error) {
+	q := dom.TodoListQuery{
+		WorkspaceID: workspaceID,
+		Limit:       clampListLimit(limit),
+		After:       after,
+		Trashed:     true,
+		Sort:        dom.SortDeletedAt,
+		Desc:        true,
+	}
+	if after != nil && (after.Sort != q.Sort || !after.Desc) {
+		return dom.TodoPage{}, ErrInvalidCursor
+	}
+	return s.repo.List(ctx, userID, q)
+}
+
+// Restore takes a todo of the user out of the trash, with the subtasks deleted
+// together with it. It goes to the end of its siblings; a todo whose project was
+// deleted comes back without a project.
+func (s *TodoService) Restore(ctx context.Context, userID, workspaceID, id int64) (dom.Todo, error) {
+	if _, err := s.trashed(ctx, userID, workspaceID, id); err != nil {
+		return dom.Todo{}, err
+	}
+	// For webhooks a restored todo is a new one.
+	t, err := s.write(ctx, dom.WebhookTodoCreated, userID, nil, func(r repo.TodoRepo) (dom.Todo, error) {
+		return r.Restore(ctx, userID, userID, id)
+	})
+	if err != nil {
+		if errors.Is(err, repo.ErrParentTrashed) {
+			return dom.Todo{}, ErrParentInTrash
+		}
+		if errors.Is(err, pgx.ErrNoRows) {
+			return dom.Todo{}, ErrNotFound
+		}
+		return dom.Todo{}, err
+	}
+	s.changed(ctx, workspaceID, userID, s.collaborators(ctx, userID, id), realtime.EventCreated, t)
+	t.Permission = dom.PermissionOwner
+	return t, nil
+}
+
+// trashed returns a deleted todo the user owns in the workspace. Only owners
+// see their trash, whoever deleted the todo.
+func (s *TodoService) trashed(ctx context.Context, userID, workspaceID, id int64) (dom.Todo, error) {
+	t, err := s.repo.Trashed(ctx, userID, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return dom.Todo{}, ErrNotFound
+		}
+		return dom.Todo{}, err
+	}
+	if t.WorkspaceID != workspaceID {
+		return dom.Todo{}, ErrNotFound
+	}
+	return t, nil
+}
+
+func clampListLimit(n int) int {
+	if n <= 0 {
+		return DefaultListLimit
+	}
+	return min(n, MaxListLimit)
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	dom "Worker/internal/domain"
+	"Worker/internal/repo"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrInvalidToken     = errors.New("invalid or expired token")
+	ErrInvalidTokenName = errors.New("token name must be 1-100 characters")
+	ErrInvalidScopes    = errors.New("scopes must be read and/or write")
+)
+
+const (
+	// tokenSecretPrefix marks our tokens so they are easy to spot in logs and secret scanners.
+	tokenSecretPrefix = "tdo_"
+	tokenDisplayLen   = len(tokenSecretPrefix) + 8
+)
+
+// TokenService manages personal access tokens.
+type TokenService struct {
+	repo repo.APITokenRepo
+}
+
+// NewTokenService returns a new TokenService.
+func NewTokenService(r repo.APITokenRepo) *TokenService {
+	return &TokenService{repo: r}
+}
+
+// Create issues a token. The returned secret is shown to the user once; only its
+// hash is stored. ttl 0 means the token never expires.
+func (s *TokenService) Create(ctx context.Context, userID int64, name string, scopes []string, ttl time.Duration) (dom.APIToken, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > 100 {
+		return dom.APIToken{}, "", ErrInvalidTokenName
+	}
+	scopes = slices.Clone(scopes)
+	slices.Sort(scopes)
+	scopes = slices.Compact(scopes)
+	if len(scopes) == 0 {
+		return dom.APIToken{}, "", ErrInvalidScopes
+	}
+	for _, sc := range scopes {
+		if sc != dom.ScopeRead && sc != dom.ScopeWrite {
+			return dom.APIToken{}, "", ErrInvalidScopes
+		}
+	}
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return dom.APIToken{}, "", err
+	}
+	secret := tokenSecretPrefix + base64.RawURLEncoding.EncodeToString(b)
+	t := dom.APIToken{UserID: userID, Name: name, Prefix: secret[:tokenDisplayLen], Scopes: scopes}
+	if ttl > 0 {
+		exp := time.Now().UTC().Add(ttl)
+		t.ExpiresAt = &exp
+	}
+	t, err := s.repo.Create(ctx, t, hashToken(secret))
+	if err != nil {
+		return dom.APIToken{}, "", err
+	}
+	return t, secret, nil
+}
+
+func (s *TokenService) List(ctx context.Context, userID int64) ([]dom.APIToken, error) {
+	return s.repo.List(ctx, userID)
+}
+
+// Revoke deletes a token; it stops working immediately.
+func (s *TokenService) Revoke(ctx context.Context, userID, id int64) error {
+	if err := s.repo.Delete(ctx, userID, id); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// Authenticate resolves a bearer token to its (unexpired) record and records its use.
+func (s *TokenService) Authenticate(ctx context.Context, secret string) (dom.APIToken, error) {
+	if !strings.HasPrefix(secret, tokenSecretPrefix) {
+		return dom.APIToken{}, ErrInvalidToken
+	}
+	t, err := s.repo.GetByHash(ctx, hashToken(secret))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return dom.APIToken{}, ErrInvalidToken
+		}
+		return dom.APIToken{}, err
+	}
+	now := time.Now().UTC()
+	if t.Expired(now) {
+		return dom.APIToken{}, ErrInvalidToken
+	}
+	_ = s.repo.Touch(ctx, t.ID, now)
+	return t, nil
+}
+
+// hashToken returns the hex SHA-256 of a secret. Tokens are 256 random bits, so a
+// fast hash is enough; bcrypt would only slow down every API request.
+func hashToken(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -2,10 +2,17 @@ package service
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/base64"
 	"errors"
+	"fmt"
+	"log"
 	"strings"
+	"time"
 
+	"Worker/internal/auth"
 	dom "Worker/internal/domain"
+	"Worker/internal/mail"
 	"Worker/internal/repo"
 	"Worker/internal/utils"
 
@@ -15,15 +22,39 @@ import (
 
 var ErrInvalidCredentials = errors.New("invalid username or password")
 var ErrUsernameTaken = errors.New("username already taken")
+var ErrEmailTaken = errors.New("email already in use")
+var ErrWeakPassword = errors.New("password must be at least 8 characters")
+var ErrInvalidResetToken = errors.New("invalid or expired reset token")
+
+// ErrAccountDisabled is shared with the auth middleware, which rejects
+// requests of disabled accounts.
+var ErrAccountDisabled = auth.ErrAccountDisabled
+
+// MinPasswordLen applies to passwords set by change or reset.
+const MinPasswordLen = 8
+
+// PasswordResetOptions configures the reset flow.
+type PasswordResetOptions struct {
+	// TTL is how long a reset token stays valid.
+	TTL time.Duration
+	// URL, if set, is the page the mailed link points to; the token is appended as ?token=.
+	URL string
+}
 
 // UserService handles user auth logic.
 type UserService struct {
-	repo repo.UserRepo
+	repo   repo.UserRepo
+	resets repo.PasswordResetRepo
+	mailer mail.Mailer
+	opts   PasswordResetOptions
 }
 
 // NewUserService returns a new UserService.
-func NewUserService(repo repo.UserRepo) *UserService {
-	return &UserService{repo: repo}
+func NewUserService(repo repo.UserRepo, resets repo.PasswordResetRepo, mailer mail.Mailer, opts PasswordResetOptions) *UserService {
+	if opts.TTL <= 0 {
+		opts.TTL = time.Hour
+	}
+	return &UserService{repo: repo, resets: resets, mailer: mailer, opts: opts}
 }
 
 // ValidateCredentials checks username and password; returns user if valid.
@@ -42,12 +73,39 @@ func (s *UserService) ValidateCredentials(ctx context.Context, username, passwor
 	if err := bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password)); err != nil {
 		return dom.User{}, ErrInvalidCredentials
 	}
+	// Checked after the password so the answer does not reveal the account state.
+	if u.Disabled() {
+		return dom.User{}, ErrAccountDisabled
+	}
 	return u, nil
 }
 
-// Register creates a new user with hashed password.
-func (s *UserService) Register(ctx context.Context, username, password string) (dom.User, error) {
+// ActiveAccount returns the user unless the account is missing or disabled.
+// It implements auth.AccountChecker.
+func (s *UserService) ActiveAccount(ctx context.Context, userID int64) (dom.User, error) {
+	u, err := s.GetByID(ctx, userID)
+	if err != nil {
+		return dom.User{}, err
+	}
+	if u.Disabled() {
+		return dom.User{}, ErrAccountDisabled
+	}
+	return u, nil
+}
+
+// GetByID returns the user.
+func (s *UserService) GetByID(ctx context.Context, id int64) (dom.User, error) {
+	u, err := s.repo.GetByID(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return dom.User{}, ErrNotFound
+	}
+	return u, err
+}
+
+// Register creates a new user with hashed password. Email is optional.
+func (s *UserService) Register(ctx context.Context, username, email, password string) (dom.User, error) {
 	username = strings.TrimSpace(username)
+	email = strings.TrimSpace(email)
 	if username == "" || password == "" {
 		return dom.User{}, ErrInvalidCredentials
 	}
@@ -55,12 +113,149 @@ func (s *UserService) Register(ctx context.Context, username, password string) (
 	if err != nil {
 		return dom.User{}, err
 	}
-	u, err := s.repo.Create(ctx, username, string(hash))
+	u, err := s.repo.Create(ctx, username, email, string(hash))
 	if err != nil {
 		if utils.IsPGUniqueViolation(err) {
+			if utils.PGConstraintName(err) == "idx_users_email" {
+				return dom.User{}, ErrEmailTaken
+			}
 			return dom.User{}, ErrUsernameTaken
 		}
 		return dom.User{}, err
 	}
 	return u, nil
 }
+
+// ChangePassword sets a new password after checking the current one.
+// Returns ErrInvalidCredentials if current is wrong.
+func (s *UserService) ChangePassword(ctx context.Context, userID int64, current, newPassword string) error {
+	if len(newPassword) < MinPasswordLen {
+		return ErrWeakPassword
+	}
+	u, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrInvalidCredentials
+		}
+		return err
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(current)); err != nil {
+		return ErrInvalidCredentials
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	return s.repo.UpdatePassword(ctx, userID, string(hash))
+}
+
+// RequestPasswordReset mails a single-use reset link to the account matching
+// login (username or email). It reports success for unknown logins and
+// accounts without an email so callers cannot probe which accounts exist.
+func (s *UserService) RequestPasswordReset(ctx context.Context, login string) error {
+	login = strings.TrimSpace(login)
+	if login == "" {
+		return nil
+	}
+	u, err := s.repo.GetByLogin(ctx, login)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+	if u.Email == "" || u.Disabled() {
+		return nil
+	}
+	_, err = s.sendReset(ctx, u)
+	return err
+}
+
+// ForcePasswordReset makes the current password stop working and issues a
+// reset token. The token is mailed if the user has an email; otherwise it is
+// returned so an administrator can pass it on. emailed reports which happened.
+func (s *UserService) ForcePasswordReset(ctx context.Context, userID int64) (token string, emailed bool, err error) {
+	u, err := s.GetByID(ctx, userID)
+	if err != nil {
+		return "", false, err
+	}
+	// A random password nobody knows; it also voids older reset tokens.
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", false, err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(base64.RawStdEncoding.EncodeToString(b)), bcrypt.DefaultCost)
+	if err != nil {
+		return "", false, err
+	}
+	if err := s.repo.UpdatePassword(ctx, u.ID, string(hash)); err != nil {
+		return "", false, err
+	}
+	if u.Email == "" {
+		token, err := s.newResetToken(ctx, u.ID)
+		return token, false, err
+	}
+	if _, err := s.sendReset(ctx, u); err != nil {
+		return "", false, err
+	}
+	return "", true, nil
+}
+
+// sendReset stores a new reset token for u and mails it.
+func (s *UserService) sendReset(ctx context.Context, u dom.User) (string, error) {
+	token, err := s.newResetToken(ctx, u.ID)
+	if err != nil {
+		return "", err
+	}
+	if err := s.mailer.Send(ctx, s.resetMessage(u, token)); err != nil {
+		// The token is stored either way; the user can simply ask again.
+		log.Printf("password reset mail for user %d: %v", u.ID, err)
+	}
+	return token, nil
+}
+
+func (s *UserService) newResetToken(ctx context.Context, userID int64) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	if err := s.resets.Create(ctx, userID, hashToken(token), time.Now().UTC().Add(s.opts.TTL)); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func (s *UserService) resetMessage(u dom.User, token string) mail.Message {
+	link := tokenLink(s.opts.URL, token)
+	return mail.Message{
+		To:      u.Email,
+		Subject: "Password reset",
+		Body: fmt.Sprintf("Hello %s,\n\nUse this to reset your password (valid for %s):\n\n%s\n\nIf you did not ask for a reset, ignore this message.\n",
+			u.Username, s.opts.TTL, link),
+	}
+}
+
+// ResetPassword redeems a reset token and sets a new password. It returns the
+// user ID so the caller can revoke that user's sessions.
+func (s *UserService) ResetPassword(ctx context.Context, token, newPassword string) (int64, error) {
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return 0, ErrInvalidResetToken
+	}
+	if len(newPassword) < MinPasswordLen {
+		return 0, ErrWeakPassword
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return 0, err
+	}
+	userID, err := s.resets.Reset(ctx, hashToken(token), time.Now().UTC(), string(hash))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, ErrInvalidResetToken
+		}
+		return 0, err
+	}
+	return userID, nil
+}
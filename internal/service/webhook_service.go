@@ -0,0 +1,227 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	dom "Worker/internal/domain"
+	"Worker/internal/repo"
+	"Worker/internal/webhook"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// MaxWebhooks caps the webhooks of one user.
+const MaxWebhooks = 10
+
+var (
+	ErrInvalidWebhookURL    = errors.New("url must be an absolute http or https URL of a public host")
+	ErrInvalidWebhookEvents = errors.New("events must list at least one of todo.created, todo.updated, todo.completed, todo.deleted, todo.overdue")
+	ErrWebhookLimit         = errors.New("too many webhooks")
+	ErrWebhookDisabled      = errors.New("webhook is disabled; enable it first")
+	ErrDeliveryPending      = errors.New("delivery is still pending")
+)
+
+// webhookSecretPrefix marks webhook signing secrets, like tokenSecretPrefix for tokens.
+const webhookSecretPrefix = "whsec_"
+
+// WebhookService manages the webhooks of users and their deliveries.
+type WebhookService struct {
+	repo         repo.WebhookRepo
+	allowPrivate bool
+}
+
+// NewWebhookService returns a new WebhookService. Unless allowPrivate is set,
+// webhook URLs must name public hosts.
+func NewWebhookService(r repo.WebhookRepo, allowPrivate bool) *WebhookService {
+	return &WebhookService{repo: r, allowPrivate: allowPrivate}
+}
+
+// CreateWebhookInput holds the fields of a new webhook.
+type CreateWebhookInput struct {
+	URL         string
+	Description string
+	Events      []string
+}
+
+// UpdateWebhookInput is a partial update: nil fields are left unchanged.
+type UpdateWebhookInput struct {
+	URL         *string
+	Description *string
+	Events      *[]string
+	// Enabled turns the webhook on or off; turning it on resets its failure count.
+	Enabled *bool
+}
+
+// Create registers a webhook with a new signing secret.
+func (s *WebhookService) Create(ctx context.Context, userID int64, in CreateWebhookInput) (dom.Webhook, error) {
+	w := dom.Webhook{UserID: userID, URL: strings.TrimSpace(in.URL), Description: strings.TrimSpace(in.Description)}
+	if err := s.checkURL(w.URL); err != nil {
+		return dom.Webhook{}, err
+	}
+	var err error
+	if w.Events, err = normalizeWebhookEvents(in.Events); err != nil {
+		return dom.Webhook{}, err
+	}
+	n, err := s.repo.Count(ctx, userID)
+	if err != nil {
+		return dom.Webhook{}, err
+	}
+	if n >= MaxWebhooks {
+		return dom.Webhook{}, ErrWebhookLimit
+	}
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return dom.Webhook{}, err
+	}
+	w.Secret = webhookSecretPrefix + base64.RawURLEncoding.EncodeToString(b)
+	return s.repo.Create(ctx, w)
+}
+
+func (s *WebhookService) List(ctx context.Context, userID int64) ([]dom.Webhook, error) {
+	return s.repo.List(ctx, userID)
+}
+
+func (s *WebhookService) Get(ctx context.Context, userID, id int64) (dom.Webhook, error) {
+	w, err := s.repo.Get(ctx, userID, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return dom.Webhook{}, ErrNotFound
+	}
+	return w, err
+}
+
+// Update applies a partial update.
+func (s *WebhookService) Update(ctx context.Context, userID, id int64, in UpdateWebhookInput) (dom.Webhook, error) {
+	w, err := s.Get(ctx, userID, id)
+	if err != nil {
+		return dom.Webhook{}, err
+	}
+	if in.URL != nil {
+		w.URL = strings.TrimSpace(*in.URL)
+		if err := s.checkURL(w.URL); err != nil {
+			return dom.Webhook{}, err
+		}
+	}
+	if in.Description != nil {
+		w.Description = strings.TrimSpace(*in.Description)
+	}
+	if in.Events != nil {
+		if w.Events, err = normalizeWebhookEvents(*in.Events); err != nil {
+			return dom.Webhook{}, err
+		}
+	}
+	if in.Enabled != nil {
+		switch {
+		case *in.Enabled:
+			w.DisabledAt, w.FailureCount = nil, 0
+		case w.DisabledAt == nil:
+			now := time.Now().UTC()
+			w.DisabledAt = &now
+		}
+	}
+	w, err = s.repo.Update(ctx, w)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return dom.Webhook{}, ErrNotFound
+	}
+	return w, err
+}
+
+// Delete removes a webhook; its pending deliveries are dropped.
+func (s *WebhookService) Delete(ctx context.Context, userID, id int64) error {
+	if err := s.repo.Delete(ctx, userID, id); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// Deliveries returns one page of the deliveries of a webhook of the user,
+// newest first, optionally only those with status.
+func (s *WebhookService) Deliveries(ctx context.Context, userID, webhookID int64, status string, beforeID int64, limit int) ([]dom.WebhookDelivery, error) {
+	if _, err := s.Get(ctx, userID, webhookID); err != nil {
+		return nil, err
+	}
+	return s.repo.Deliveries(ctx, dom.WebhookDeliveryQuery{
+		WebhookID: webhookID,
+		Status:    status,
+		BeforeID:  beforeID,
+		Limit:     clampPageSize(limit),
+	})
+}
+
+// Delivery returns a delivery of a webhook of the user with its attempts.
+func (s *WebhookService) Delivery(ctx context.Context, userID, webhookID, id int64) (dom.WebhookDelivery, []dom.WebhookAttempt, error) {
+	if _, err := s.Get(ctx, userID, webhookID); err != nil {
+		return dom.WebhookDelivery{}, nil, err
+	}
+	d, err := s.repo.Delivery(ctx, webhookID, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return dom.WebhookDelivery{}, nil, ErrNotFound
+	}
+	if err != nil {
+		return dom.WebhookDelivery{}, nil, err
+	}
+	attempts, err := s.repo.Attempts(ctx, d.ID)
+	if err != nil {
+		return dom.WebhookDelivery{}, nil, err
+	}
+	return d, attempts, nil
+}
+
+// Redeliver queues a finished delivery again. The new delivery keeps the event
+// ID and body, so receivers can recognise a repeat; it is signed anew when sent.
+func (s *WebhookService) Redeliver(ctx context.Context, userID, webhookID, id int64) (dom.WebhookDelivery, error) {
+	w, err := s.Get(ctx, userID, webhookID)
+	if err != nil {
+		return dom.WebhookDelivery{}, err
+	}
+	if w.DisabledAt != nil {
+		return dom.WebhookDelivery{}, ErrWebhookDisabled
+	}
+	d, err := s.repo.Delivery(ctx, webhookID, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return dom.WebhookDelivery{}, ErrNotFound
+	}
+	if err != nil {
+		return dom.WebhookDelivery{}, err
+	}
+	if d.Status == dom.DeliveryPending {
+		return dom.WebhookDelivery{}, ErrDeliveryPending
+	}
+	d, err = s.repo.Redeliver(ctx, webhookID, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return dom.WebhookDelivery{}, ErrNotFound
+	}
+	return d, err
+}
+
+func (s *WebhookService) checkURL(raw string) error {
+	if len(raw) > 2048 || webhook.ValidateURL(raw, s.allowPrivate) != nil {
+		return ErrInvalidWebhookURL
+	}
+	return nil
+}
+
+// normalizeWebhookEvents sorts and deduplicates event types and rejects
+// unknown ones.
+func normalizeWebhookEvents(events []string) ([]string, error) {
+	out := slices.Clone(events)
+	slices.Sort(out)
+	out = slices.Compact(out)
+	if len(out) == 0 {
+		return nil, ErrInvalidWebhookEvents
+	}
+	for _, e := range out {
+		if !slices.Contains(dom.WebhookEvents, e) {
+			return nil, ErrInvalidWebhookEvents
+		}
+	}
+	return out, nil
+}
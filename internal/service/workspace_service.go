@@ -0,0 +1,359 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"Worker/internal/auth"
+	"Worker/internal/cache"
+	dom "Worker/internal/domain"
+	"Worker/internal/mail"
+	"Worker/internal/repo"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrInvalidWorkspaceName     = errors.New("workspace name must be 1-120 characters")
+	ErrInvalidWorkspaceRole     = errors.New("role must be owner, admin, member or viewer")
+	ErrPersonalWorkspace        = errors.New("a personal workspace cannot be deleted or have other members")
+	ErrLastWorkspaceOwner       = errors.New("a workspace must keep at least one owner")
+	ErrMemberNotFound           = errors.New("member not found")
+	ErrInvalidInvitation        = errors.New("invalid or expired invitation")
+	ErrInvitationEmailMismatch  = errors.New("this invitation was sent to another e-mail address")
+	ErrAlreadyWorkspaceMember   = errors.New("you are already a member of this workspace")
+	ErrInvalidInvitationAddress = errors.New("email must be a valid e-mail address")
+)
+
+// ErrNotWorkspaceMember is shared with the workspace middleware, which answers
+// requests for other workspaces with 404.
+var ErrNotWorkspaceMember = auth.ErrNotWorkspaceMember
+
+// WorkspaceOptions configures invitations.
+type WorkspaceOptions struct {
+	// InviteTTL is how long an invitation stays valid.
+	InviteTTL time.Duration
+	// InviteURL, if set, is the page the mailed link points to; the token is appended as ?token=.
+	InviteURL string
+}
+
+// WorkspaceService manages workspaces, their members and invitations. Route
+// middleware checks the acting user's role for each endpoint; the service adds
+// the rules that depend on the member being changed: only owners may appoint or
+// demote owners, and the last owner cannot leave.
+type WorkspaceService struct {
+	repo   repo.WorkspaceRepo
+	users  repo.UserRepo
+	mailer mail.Mailer
+	cache  *cache.TodoCache
+	opts   WorkspaceOptions
+}
+
+// NewWorkspaceService creates a WorkspaceService. If c is nil, no cache invalidation is done.
+func NewWorkspaceService(r repo.WorkspaceRepo, users repo.UserRepo, mailer mail.Mailer, c *cache.TodoCache, opts WorkspaceOptions) *WorkspaceService {
+	if opts.InviteTTL <= 0 {
+		opts.InviteTTL = 7 * 24 * time.Hour
+	}
+	return &WorkspaceService{repo: r, users: users, mailer: mailer, cache: c, opts: opts}
+}
+
+// ResolveWorkspace returns the workspace with the user's role in it; workspaceID 0
+// is the user's personal workspace, created on first use. It implements
+// auth.WorkspaceResolver.
+func (s *WorkspaceService) ResolveWorkspace(ctx context.Context, userID, workspaceID int64) (dom.Workspace, error) {
+	if workspaceID == 0 {
+		return s.repo.Personal(ctx, userID)
+	}
+	w, err := s.repo.Membership(ctx, workspaceID, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return dom.Workspace{}, ErrNotWorkspaceMember
+		}
+		return dom.Workspace{}, err
+	}
+	return w, nil
+}
+
+// List returns the user's workspaces, the personal one first.
+func (s *WorkspaceService) List(ctx context.Context, userID int64) ([]dom.Workspace, error) {
+	if _, err := s.repo.Personal(ctx, userID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListForUser(ctx, userID)
+}
+
+// Create adds a workspace owned by the user.
+func (s *WorkspaceService) Create(ctx context.Context, userID int64, name string) (dom.Workspace, error) {
+	name, err := normalizeWorkspaceName(name)
+	if err != nil {
+		return dom.Workspace{}, err
+	}
+	return s.repo.Create(ctx, name, userID)
+}
+
+// Rename changes the name of a workspace the user belongs to.
+func (s *WorkspaceService) Rename(ctx context.Context, userID, workspaceID int64, name string) (dom.Workspace, error) {
+	name, err := normalizeWorkspaceName(name)
+	if err != nil {
+		return dom.Workspace{}, err
+	}
+	if _, err := s.repo.Rename(ctx, workspaceID, name); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return dom.Workspace{}, ErrNotFound
+		}
+		return dom.Workspace{}, err
+	}
+	return s.ResolveWorkspace(ctx, userID, workspaceID)
+}
+
+// Delete removes a shared workspace with everything in it.
+func (s *WorkspaceService) Delete(ctx context.Context, workspaceID int64) error {
+	members, err := s.repo.Members(ctx, workspaceID)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.Delete(ctx, workspaceID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrPersonalWorkspace
+		}
+		return err
+	}
+	ids := make([]int64, len(members))
+	for i, m := range members {
+		ids[i] = m.UserID
+	}
+	s.invalidateCache(ctx, workspaceID, ids...)
+	return nil
+}
+
+// Members lists the members of a workspace.
+func (s *WorkspaceService) Members(ctx context.Context, workspaceID int64) ([]dom.WorkspaceMember, error) {
+	return s.repo.Members(ctx, workspaceID)
+}
+
+// SetMemberRole changes the role of a member. Only owners may make someone an
+// owner or change an owner's role.
+func (s *WorkspaceService) SetMemberRole(ctx context.Context, actorID, workspaceID, memberID int64, role string) (dom.WorkspaceMember, error) {
+	role = strings.ToLower(strings.TrimSpace(role))
+	if !dom.IsValidWorkspaceRole(role) {
+		return dom.WorkspaceMember{}, ErrInvalidWorkspaceRole
+	}
+	actor, target, err := s.memberPair(ctx, actorID, workspaceID, memberID)
+	if err != nil {
+		return dom.WorkspaceMember{}, err
+	}
+	if actor.Personal() {
+		return dom.WorkspaceMember{}, ErrPersonalWorkspace
+	}
+	if (role == dom.WorkspaceRoleOwner || target.Role == dom.WorkspaceRoleOwner) && actor.Role != dom.WorkspaceRoleOwner {
+		return dom.WorkspaceMember{}, ErrForbidden
+	}
+	m, err := s.repo.SetMemberRole(ctx, workspaceID, memberID, role)
+	if err != nil {
+		if errors.Is(err, repo.ErrLastOwner) {
+			return dom.WorkspaceMember{}, ErrLastWorkspaceOwner
+		}
+		if errors.Is(err, pgx.ErrNoRows) {
+			return dom.WorkspaceMember{}, ErrMemberNotFound
+		}
+		return dom.WorkspaceMember{}, err
+	}
+	return m, nil
+}
+
+// RemoveMember takes a member out of the workspace. Anyone may leave; removing
+// someone else needs the admin role, and removing an owner the owner role.
+func (s *WorkspaceService) RemoveMember(ctx context.Context, actorID, workspaceID, memberID int64) error {
+	actor, target, err := s.memberPair(ctx, actorID, workspaceID, memberID)
+	if err != nil {
+		return err
+	}
+	if actor.Personal() {
+		return ErrPersonalWorkspace
+	}
+	if memberID != actorID {
+		if !dom.WorkspaceRoleAtLeast(actor.Role, dom.WorkspaceRoleAdmin) ||
+			(target.Role == dom.WorkspaceRoleOwner && actor.Role != dom.WorkspaceRoleOwner) {
+			return ErrForbidden
+		}
+	}
+	if err := s.repo.RemoveMember(ctx, workspaceID, memberID); err != nil {
+		if errors.Is(err, repo.ErrLastOwner) {
+			return ErrLastWorkspaceOwner
+		}
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrMemberNotFound
+		}
+		return err
+	}
+	s.invalidateCache(ctx, workspaceID, memberID)
+	return nil
+}
+
+// memberPair returns the workspace as seen by the actor and by the member.
+func (s *WorkspaceService) memberPair(ctx context.Context, actorID, workspaceID, memberID int64) (actor, member dom.Workspace, err error) {
+	if actor, err = s.ResolveWorkspace(ctx, actorID, workspaceID); err != nil {
+		return actor, member, err
+	}
+	if member, err = s.repo.Membership(ctx, workspaceID, memberID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			err = ErrMemberNotFound
+		}
+	}
+	return actor, member, err
+}
+
+// InviteInput names who to invite and with which role. Email is optional: an
+// invitation without one can be accepted by any registered user holding the token.
+type InviteInput struct {
+	Email string
+	Role  string
+}
+
+// Invite creates an invitation and returns it with its token, which is shown
+// once; only its hash is stored. With an e-mail address the token is also
+// mailed there. Only owners may invite owners.
+func (s *WorkspaceService) Invite(ctx context.Context, actorID, workspaceID int64, in InviteInput) (dom.WorkspaceInvitation, string, error) {
+	role := strings.ToLower(strings.TrimSpace(in.Role))
+	if !dom.IsValidWorkspaceRole(role) {
+		return dom.WorkspaceInvitation{}, "", ErrInvalidWorkspaceRole
+	}
+	email := strings.TrimSpace(in.Email)
+	if email != "" && (!strings.Contains(email, "@") || len(email) > 255) {
+		return dom.WorkspaceInvitation{}, "", ErrInvalidInvitationAddress
+	}
+	actor, err := s.ResolveWorkspace(ctx, actorID, workspaceID)
+	if err != nil {
+		return dom.WorkspaceInvitation{}, "", err
+	}
+	if actor.Personal() {
+		return dom.WorkspaceInvitation{}, "", ErrPersonalWorkspace
+	}
+	if role == dom.WorkspaceRoleOwner && actor.Role != dom.WorkspaceRoleOwner {
+		return dom.WorkspaceInvitation{}, "", ErrForbidden
+	}
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return dom.WorkspaceInvitation{}, "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	inv, err := s.repo.CreateInvitation(ctx, dom.WorkspaceInvitation{
+		WorkspaceID: workspaceID,
+		Email:       email,
+		Role:        role,
+		InvitedBy:   &actorID,
+		ExpiresAt:   time.Now().UTC().Add(s.opts.InviteTTL),
+	}, hashToken(token))
+	if err != nil {
+		return dom.WorkspaceInvitation{}, "", err
+	}
+	if email != "" {
+		if err := s.mailer.Send(ctx, s.inviteMessage(actor, inv, token)); err != nil {
+			// The invitation is stored either way and the inviter has the token.
+			log.Printf("workspace invitation mail for workspace %d: %v", workspaceID, err)
+		}
+	}
+	return inv, token, nil
+}
+
+func (s *WorkspaceService) inviteMessage(w dom.Workspace, inv dom.WorkspaceInvitation, token string) mail.Message {
+	return mail.Message{
+		To:      inv.Email,
+		Subject: "Invitation to " + w.Name,
+		Body: fmt.Sprintf("Hello,\n\nYou have been invited to the workspace %q as %s. Sign in and use this to join (valid for %s):\n\n%s\n",
+			w.Name, inv.Role, s.opts.InviteTTL, tokenLink(s.opts.InviteURL, token)),
+	}
+}
+
+// Invitations lists the invitations of a workspace that were not accepted yet.
+func (s *WorkspaceService) Invitations(ctx context.Context, workspaceID int64) ([]dom.WorkspaceInvitation, error) {
+	return s.repo.Invitations(ctx, workspaceID)
+}
+
+// RevokeInvitation deletes an invitation so its token stops working.
+func (s *WorkspaceService) RevokeInvitation(ctx context.Context, workspaceID, id int64) error {
+	if err := s.repo.DeleteInvitation(ctx, workspaceID, id); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// AcceptInvitation adds the user to the workspace of the invitation behind token
+// and returns that workspace. An invitation sent to an e-mail address only works
+// for the account with that address.
+func (s *WorkspaceService) AcceptInvitation(ctx context.Context, userID int64, token string) (dom.Workspace, error) {
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return dom.Workspace{}, ErrInvalidInvitation
+	}
+	now := time.Now().UTC()
+	inv, err := s.repo.GetInvitation(ctx, hashToken(token))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return dom.Workspace{}, ErrInvalidInvitation
+		}
+		return dom.Workspace{}, err
+	}
+	if inv.AcceptedAt != nil || !now.Before(inv.ExpiresAt) {
+		return dom.Workspace{}, ErrInvalidInvitation
+	}
+	if inv.Email != "" {
+		u, err := s.users.GetByID(ctx, userID)
+		if err != nil {
+			return dom.Workspace{}, err
+		}
+		if !strings.EqualFold(u.Email, inv.Email) {
+			return dom.Workspace{}, ErrInvitationEmailMismatch
+		}
+	}
+	if _, err := s.repo.Membership(ctx, inv.WorkspaceID, userID); err == nil {
+		return dom.Workspace{}, ErrAlreadyWorkspaceMember
+	} else if !errors.Is(err, pgx.ErrNoRows) {
+		return dom.Workspace{}, err
+	}
+	if err := s.repo.AcceptInvitation(ctx, inv.ID, userID, now); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return dom.Workspace{}, ErrInvalidInvitation
+		}
+		return dom.Workspace{}, err
+	}
+	return s.ResolveWorkspace(ctx, userID, inv.WorkspaceID)
+}
+
+func (s *WorkspaceService) invalidateCache(ctx context.Context, workspaceID int64, userIDs ...int64) {
+	if s.cache != nil {
+		_ = s.cache.InvalidateUsers(ctx, workspaceID, userIDs...)
+	}
+}
+
+func normalizeWorkspaceName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > 120 {
+		return "", ErrInvalidWorkspaceName
+	}
+	return name, nil
+}
+
+// tokenLink appends token to base as the token query parameter, or returns the
+// bare token if base is empty.
+func tokenLink(base, token string) string {
+	if base == "" {
+		return token
+	}
+	sep := "?"
+	if strings.Contains(base, "?") {
+		sep = "&"
+	}
+	return base + sep + "token=" + url.QueryEscape(token)
+}
@@ -0,0 +1,186 @@
+package todoio
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	dom "Worker/internal/domain"
+	"Worker/internal/dto"
+)
+
+// csvColumns are the columns of an exported CSV file. On import only title is
+// required; columns may come in any order and unknown ones are ignored. Tags
+// and reminders are comma-separated lists.
+var csvColumns = []string{
+	"id", "parent_id", "project", "title", "description", "is_done", "due_at", "tags", "reminders",
+	"recurrence", "timezone", "created_at", "updated_at", "deleted_at",
+}
+
+type csvWriter struct {
+	w       *csv.Writer
+	started bool
+}
+
+func newCSVWriter(w io.Writer) *csvWriter {
+	return &csvWriter{w: csv.NewWriter(w)}
+}
+
+func (cw *csvWriter) header() error {
+	if cw.started {
+		return nil
+	}
+	cw.started = true
+	return cw.w.Write(csvColumns)
+}
+
+func (cw *csvWriter) Write(t dom.Todo, project string) error {
+	if err := cw.header(); err != nil {
+		return err
+	}
+	parent := ""
+	if t.ParentID != nil {
+		parent = strconv.FormatInt(*t.ParentID, 10)
+	}
+	return cw.w.Write([]string{
+		strconv.FormatInt(t.ID, 10), parent, project, t.Title, t.Description, strconv.FormatBool(t.IsDone),
+		formatTime(t.DueAt), strings.Join(t.Tags, ", "), strings.Join(dto.FormatOffsets(t.Reminders), ", "),
+		t.Recurrence, t.RecurrenceTZ, formatTime(&t.CreatedAt), formatTime(&t.UpdatedAt), formatTime(t.DeletedAt),
+	})
+}
+
+func (cw *csvWriter) Close() error {
+	if err := cw.header(); err != nil {
+		return err
+	}
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+func readCSV(r io.Reader) ([]Record, []Problem, error) {
+	cr := newCSVReader(r)
+	cols, err := readCSVHeader(cr)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, ok := cols["title"]; !ok {
+		return nil, nil, fmt.Errorf("%w: the header has no title column", ErrInvalidFile)
+	}
+	var rs records
+	for {
+		row, err := cr.Read()
+		if err == io.EOF {
+			return rs.list, rs.problems, nil
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+		}
+		line, _ := cr.FieldPos(0)
+		get := func(col string) string {
+			if i, ok := cols[col]; ok && i < len(row) {
+				return strings.TrimSpace(row[i])
+			}
+			return ""
+		}
+		if strings.Join(row, "") == "" {
+			continue
+		}
+		rec := Record{
+			Line:        line,
+			Title:       get("title"),
+			Description: get("description"),
+			Project:     get("project"),
+			Tags:        splitList(get("tags")),
+			Recurrence:  get("recurrence"),
+			Timezone:    get("timezone"),
+		}
+		rs.fill(&rec, fields{
+			isDone:    get("is_done"),
+			dueAt:     get("due_at"),
+			reminders: splitList(get("reminders")),
+			createdAt: get("created_at"),
+			deletedAt: get("deleted_at"),
+		})
+		rs.add(rec, get("id"), get("parent_id"))
+	}
+}
+
+// newCSVReader returns a reader that allows rows of any length.
+func newCSVReader(r io.Reader) *csv.Reader {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	return cr
+}
+
+// readCSVHeader reads the header row and returns the index of each column by
+// its lowercased name.
+func readCSVHeader(cr *csv.Reader) (map[string]int, error) {
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("%w: the file is empty", ErrInvalidFile)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+	}
+	cols := make(map[string]int, len(header))
+	for i, name := range header {
+		if i == 0 {
+			name = strings.TrimPrefix(name, "\ufeff") // byte order mark of spreadsheet exports
+		}
+		cols[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	return cols, nil
+}
+
+// fields are the values of a CSV row or JSON object that need parsing.
+type fields struct {
+	isDone    string
+	dueAt     string
+	reminders []string
+	createdAt string
+	deletedAt string
+}
+
+// fill parses f into rec, reporting the values it cannot read.
+func (rs *records) fill(rec *Record, f fields) {
+	if f.isDone != "" {
+		done, err := strconv.ParseBool(f.isDone)
+		if err != nil {
+			rs.errorf(rec.Line, "is_done", "use true or false")
+		}
+		rec.IsDone = done
+	}
+	rec.DueAt = rs.time(rec.Line, "due_at", f.dueAt)
+	rec.CreatedAt = rs.time(rec.Line, "created_at", f.createdAt)
+	rec.DeletedAt = rs.time(rec.Line, "deleted_at", f.deletedAt)
+	if len(f.reminders) > 0 {
+		offsets, err := dto.ParseOffsets(f.reminders)
+		if err != nil {
+			rs.errorf(rec.Line, "reminders", "%v", err)
+		}
+		rec.Reminders = offsets
+	}
+}
+
+// time parses a date or RFC3339 time; "" is no time.
+func (rs *records) time(line int, field, s string) *time.Time {
+	if s == "" {
+		return nil
+	}
+	t, err := dto.ParseDateOrTime(s)
+	if err != nil {
+		rs.errorf(line, field, "%v", err)
+		return nil
+	}
+	return &t
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,840 @@
+package todoio
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	dom "Worker/internal/domain"
+	"Worker/internal/recurrence"
+)
+
+// An iCalendar file (RFC 5545) holds a todo per VTODO component:
+//
+//	BEGIN:VTODO
+//	UID:todo-42@todo-api
+//	SUMMARY:Title
+//	DTSTART;TZID=Europe/Berlin:20260301T090000
+//	DUE;TZID=Europe/Berlin:20260301T090000
+//	RRULE:FREQ=WEEKLY
+//	STATUS:NEEDS-ACTION
+//	CATEGORIES:work,errands
+//	RELATED-TO:todo-41@todo-api
+//	END:VTODO
+//
+// SUMMARY, DESCRIPTION, DUE, STATUS (COMPLETED = done, CANCELLED = in the
+// trash), RRULE, CATEGORIES (tags), RELATED-TO (the parent), CREATED and the
+// VALARMs before the due date (reminders) map to the todo; the project goes
+// into X-TODO-PROJECT. Due dates at midnight UTC are written as dates, those of
+// recurring todos with a timezone on its wall clock, with a VTIMEZONE; the
+// rest in UTC. Calendar feeds write VEVENTs instead, one per todo with a due
+// date. On import other components are ignored, and so are rules this API
+// does not support, with a warning.
+
+const (
+	icsProductID = "-//Todo API//Todo API//EN"
+	icsUIDSuffix = "@todo-api"
+	// icsLineLimit is the longest a content line may be, in octets, before it
+	// has to be folded.
+	icsLineLimit = 75
+
+	icsDate     = "20060102"
+	icsDateTime = "20060102T150405"
+	icsUTC      = "20060102T150405Z"
+)
+
+// CalendarOptions configure an iCalendar writer.
+type CalendarOptions struct {
+	// Name is the calendar's display name (X-WR-CALNAME); "" = none.
+	Name string
+	// Events writes VEVENTs, for calendar apps that show no todos; todos
+	// without a due date are left out.
+	Events bool
+}
+
+type icsWriter struct {
+	w      *bufio.Writer
+	opts   CalendarOptions
+	zones  map[string]bool // VTIMEZONEs written so far
+	year   int             // the year VTIMEZONE rules are taken from
+	opened bool
+	err    error
+}
+
+// NewCalendarWriter returns a Writer of an iCalendar file to w.
+func NewCalendarWriter(w io.Writer, opts CalendarOptions) Writer {
+	return &icsWriter{w: bufio.NewWriter(w), opts: opts, zones: make(map[string]bool), year: time.Now().Year()}
+}
+
+// line writes a content line, folded into lines of at most icsLineLimit
+// octets; a fold never splits a UTF-8 sequence.
+func (iw *icsWriter) line(name, value string) {
+	if iw.err != nil {
+		return
+	}
+	s := name + ":" + value
+	limit := icsLineLimit
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		if _, iw.err = iw.w.WriteString(s[:cut] + "\r\n "); iw.err != nil {
+			return
+		}
+		s = s[cut:]
+		limit = icsLineLimit - 1 // the leading space counts
+	}
+	_, iw.err = iw.w.WriteString(s + "\r\n")
+}
+
+func (iw *icsWriter) open() {
+	if iw.opened {
+		return
+	}
+	iw.opened = true
+	iw.line("BEGIN", "VCALENDAR")
+	iw.line("VERSION", "2.0")
+	iw.line("PRODID", icsProductID)
+	iw.line("CALSCALE", "GREGORIAN")
+	if iw.opts.Name != "" {
+		iw.line("METHOD", "PUBLISH")
+		iw.line("X-WR-CALNAME", icsEscape(iw.opts.Name))
+	}
+}
+
+func (iw *icsWriter) Write(t dom.Todo, project string) error {
+	if iw.opts.Events && t.DueAt == nil {
+		return nil
+	}
+	iw.open()
+	var dueParams, due, rule string
+	if t.DueAt != nil {
+		var loc *time.Location
+		if t.Recurrence != "" && t.RecurrenceTZ != "" {
+			if l, err := time.LoadLocation(t.RecurrenceTZ); err == nil {
+				loc = l
+				iw.timezone(t.RecurrenceTZ, l)
+			}
+		}
+		dueParams, due = icsDue(*t.DueAt, t.RecurrenceTZ, loc)
+		rule = icsRule(t, loc)
+	}
+
+	kind := "VTODO"
+	if iw.opts.Events {
+		kind = "VEVENT"
+	}
+	iw.line("BEGIN", kind)
+	iw.line("UID", todoUID(t.ID))
+	iw.line("DTSTAMP", t.UpdatedAt.UTC().Format(icsUTC))
+	iw.line("CREATED", t.CreatedAt.UTC().Format(icsUTC))
+	iw.line("LAST-MODIFIED", t.UpdatedAt.UTC().Format(icsUTC))
+	iw.line("SUMMARY", icsEscape(t.Title))
+	if t.Description != "" {
+		iw.line("DESCRIPTION", icsEscape(t.Description))
+	}
+	if due != "" {
+		// A recurring VTODO needs a DTSTART for its RRULE.
+		if iw.opts.Events || rule != "" {
+			iw.line("DTSTART"+dueParams, due)
+		}
+		if !iw.opts.Events {
+			iw.line("DUE"+dueParams, due)
+		}
+	}
+	if rule != "" {
+		iw.line("RRULE", rule)
+	}
+	if iw.opts.Events {
+		iw.line("TRANSP", "TRANSPARENT")
+	} else {
+		switch {
+		case t.DeletedAt != nil:
+			iw.line("STATUS", "CANCELLED")
+		case t.IsDone:
+			iw.line("STATUS", "COMPLETED")
+			iw.line("PERCENT-COMPLETE", "100")
+		default:
+			iw.line("STATUS", "NEEDS-ACTION")
+		}
+		if t.ParentID != nil {
+			iw.line("RELATED-TO", todoUID(*t.ParentID))
+		}
+	}
+	if len(t.Tags) > 0 {
+		tags := make([]string, len(t.Tags))
+		for i, tag := range t.Tags {
+			tags[i] = icsEscape(tag)
+		}
+		iw.line("CATEGORIES", strings.Join(tags, ","))
+	}
+	if project != "" {
+		iw.line("X-TODO-PROJECT", icsEscape(project))
+	}
+	if t.DueAt != nil {
+		// Alarms of a VTODO count from DUE (its end), of a VEVENT from DTSTART.
+		trigger := "TRIGGER;RELATED=END"
+		if iw.opts.Events {
+			trigger = "TRIGGER"
+		}
+		for _, d := range t.Reminders {
+			iw.line("BEGIN", "VALARM")
+			iw.line("ACTION", "DISPLAY")
+			iw.line("DESCRIPTION", icsEscape(t.Title))
+			iw.line(trigger, "-"+formatICSDuration(d))
+			iw.line("END", "VALARM")
+		}
+	}
+	iw.line("END", kind)
+	return iw.err
+}
+
+func (iw *icsWriter) Close() error {
+	iw.open()
+	iw.line("END", "VCALENDAR")
+	if iw.err != nil {
+		return iw.err
+	}
+	return iw.w.Flush()
+}
+
+// timezone writes the VTIMEZONE of loc, once per file. Its observances repeat
+// the transitions of the current year every year; zones with other than two
+// transitions a year get them as one-off observances.
+func (iw *icsWriter) timezone(name string, loc *time.Location) {
+	if iw.zones[name] {
+		return
+	}
+	iw.zones[name] = true
+	type transition struct {
+		at       time.Time
+		from, to int // UTC offsets in seconds
+	}
+	var list []transition
+	start := time.Date(iw.year, time.January, 1, 0, 0, 0, 0, loc)
+	for t := start; len(list) < 8; {
+		_, end := t.ZoneBounds()
+		if end.IsZero() || end.Year() > iw.year {
+			break
+		}
+		_, from := t.Zone()
+		_, to := end.Zone()
+		list = append(list, transition{at: end, from: from, to: to})
+		t = end
+	}
+
+	iw.line("BEGIN", "VTIMEZONE")
+	iw.line("TZID", name)
+	// observance writes the offset that takes effect at, from dtstart on.
+	observance := func(at, dtstart time.Time, from, to int, rule string) {
+		kind := "STANDARD"
+		if at.In(loc).IsDST() {
+			kind = "DAYLIGHT"
+		}
+		abbr, _ := at.In(loc).Zone()
+		iw.line("BEGIN", kind)
+		// DTSTART is the local time of the transition before it takes effect.
+		iw.line("DTSTART", dtstart.In(time.FixedZone("", from)).Format(icsDateTime))
+		iw.line("TZOFFSETFROM", formatOffset(from))
+		iw.line("TZOFFSETTO", formatOffset(to))
+		if abbr != "" && abbr[0] != '+' && abbr[0] != '-' {
+			iw.line("TZNAME", abbr)
+		}
+		if rule != "" {
+			iw.line("RRULE", rule)
+		}
+		iw.line("END", kind)
+	}
+	switch len(list) {
+	case 0:
+		_, offset := start.Zone()
+		observance(start, time.Date(1970, time.January, 1, 0, 0, 0, 0, time.FixedZone("", offset)), offset, offset, "")
+	case 2:
+		for _, tr := range list {
+			// The same weekday of the month since 1970, e.g. the last Sunday of March.
+			local := tr.at.In(time.FixedZone("", tr.from))
+			n := (local.Day()-1)/7 + 1
+			if local.Day()+7 > daysIn(local.Year(), local.Month()) {
+				n = -1
+			}
+			first := nthWeekday(1970, local.Month(), n, local.Weekday())
+			at := time.Date(1970, local.Month(), first, local.Hour(), local.Minute(), local.Second(), 0, time.FixedZone("", tr.from))
+			observance(tr.at, at, tr.from, tr.to, fmt.Sprintf("FREQ=YEARLY;BYMONTH=%d;BYDAY=%d%s",
+				int(local.Month()), n, strings.ToUpper(local.Weekday().String()[:2])))
+		}
+	default:
+		_, offset := start.Zone()
+		observance(start, time.Date(1970, time.January, 1, 0, 0, 0, 0, time.FixedZone("", offset)), offset, offset, "")
+		for _, tr := range list {
+			observance(tr.at, tr.at, tr.from, tr.to, "")
+		}
+	}
+	iw.line("END", "VTIMEZONE")
+}
+
+// icsDue formats a due date as the parameters and the value of a DUE or
+// DTSTART property: a date for midnight UTC, wall-clock time in loc when
+// given, UTC otherwise.
+func icsDue(due time.Time, tz string, loc *time.Location) (params, value string) {
+	due = due.UTC()
+	switch {
+	case loc != nil:
+		return ";TZID=" + tz, due.In(loc).Format(icsDateTime)
+	case due.Hour() == 0 && due.Minute() == 0 && due.Second() == 0 && due.Nanosecond() == 0:
+		return ";VALUE=DATE", due.Format(icsDate)
+	}
+	return "", due.Format(icsUTC)
+}
+
+// icsRule returns the RRULE of a recurring todo as of its current due date.
+// Calendars start the series there, so a COUNT is reduced by the occurrences
+// already past, and a date-only UNTIL becomes the end of that day in loc, as
+// RFC 5545 wants UNTIL in UTC with a zoned DTSTART.
+func icsRule(t dom.Todo, loc *time.Location) string {
+	if t.Recurrence == "" {
+		return ""
+	}
+	r, err := recurrence.Parse(t.Recurrence)
+	if err != nil {
+		return ""
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+	if r.Count > 0 && t.RecurrenceStart != nil {
+		past := 0
+		for at := t.RecurrenceStart.In(loc); at.Before(*t.DueAt) && past < r.Count; past++ {
+			next, ok := r.Next(t.RecurrenceStart.In(loc), at)
+			if !ok {
+				break
+			}
+			at = next
+		}
+		r.Count = max(r.Count-past, 1)
+	}
+	if r.Until != nil && r.UntilDate {
+		y, m, d := r.Until.Date()
+		end := time.Date(y, m, d, 23, 59, 59, 0, loc).UTC()
+		r.Until, r.UntilDate = &end, false
+	}
+	return r.String()
+}
+
+func todoUID(id int64) string {
+	return "todo-" + strconv.FormatInt(id, 10) + icsUIDSuffix
+}
+
+// icsEscape escapes a TEXT value.
+func icsEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", "").Replace(s)
+}
+
+// icsUnescape reads a TEXT value.
+func icsUnescape(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n', 'N':
+			b.WriteByte('\n')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+// icsSplit splits a list of TEXT values at the unescaped commas.
+func icsSplit(s string) []string {
+	var out []string
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case ',':
+			out = append(out, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(out, s[start:])
+}
+
+// formatICSDuration writes a positive duration as a DURATION value.
+func formatICSDuration(d time.Duration) string {
+	const day = 24 * time.Hour
+	if d <= 0 {
+		return "PT0S"
+	}
+	if d%(7*day) == 0 {
+		return fmt.Sprintf("P%dW", d/(7*day))
+	}
+	s := "P"
+	if d >= day {
+		s += fmt.Sprintf("%dD", d/day)
+		d %= day
+	}
+	if d == 0 {
+		return s
+	}
+	s += "T"
+	if h := d / time.Hour; h > 0 {
+		s += fmt.Sprintf("%dH", h)
+	}
+	if m := d % time.Hour / time.Minute; m > 0 {
+		s += fmt.Sprintf("%dM", m)
+	}
+	if sec := d % time.Minute / time.Second; sec > 0 || d < time.Minute {
+		s += fmt.Sprintf("%dS", sec)
+	}
+	return s
+}
+
+// parseICSDuration reads a DURATION value such as -PT15M or P1DT12H.
+func parseICSDuration(s string) (time.Duration, bool) {
+	sign := time.Duration(1)
+	switch {
+	case strings.HasPrefix(s, "-"):
+		sign, s = -1, s[1:]
+	case strings.HasPrefix(s, "+"):
+		s = s[1:]
+	}
+	s, ok := strings.CutPrefix(strings.ToUpper(s), "P")
+	if !ok || s == "" {
+		return 0, false
+	}
+	var d time.Duration
+	inTime := false
+	for s != "" {
+		if s[0] == 'T' {
+			inTime, s = true, s[1:]
+			continue
+		}
+		i := 0
+		for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+			i++
+		}
+		if i == 0 || i == len(s) {
+			return 0, false
+		}
+		n, err := strconv.Atoi(s[:i])
+		if err != nil {
+			return 0, false
+		}
+		unit := map[byte]time.Duration{'W': 7 * 24 * time.Hour, 'D': 24 * time.Hour}
+		if inTime {
+			unit = map[byte]time.Duration{'H': time.Hour, 'M': time.Minute, 'S': time.Second}
+		}
+		u, ok := unit[s[i]]
+		if !ok {
+			return 0, false
+		}
+		d += time.Duration(n) * u
+		s = s[i+1:]
+	}
+	return sign * d, true
+}
+
+// formatOffset writes a UTC offset in seconds as +HHMM.
+func formatOffset(sec int) string {
+	sign := "+"
+	if sec < 0 {
+		sign, sec = "-", -sec
+	}
+	s := fmt.Sprintf("%s%02d%02d", sign, sec/3600, sec%3600/60)
+	if sec%60 != 0 {
+		s += fmt.Sprintf("%02d", sec%60)
+	}
+	return s
+}
+
+func daysIn(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// nthWeekday returns the day of the n-th weekday wd of a month; n = -1 is the last.
+func nthWeekday(year int, month time.Month, n int, wd time.Weekday) int {
+	if n < 0 {
+		last := daysIn(year, month)
+		return last - (int(time.Date(year, month, last, 0, 0, 0, 0, time.UTC).Weekday())-int(wd)+7)%7
+	}
+	first := 1 + (int(wd)-int(time.Date(year, month, 1, 0, 0, 0, 0, time.UTC).Weekday())+7)%7
+	return first + 7*(n-1)
+}
+
+// icsProperty is a content line: NAME;PARAM=value:value.
+type icsProperty struct {
+	line   int
+	name   string
+	params map[string]string
+	value  string
+}
+
+// parseICSLine splits an unfolded content line. Of a parameter with several
+// values only the first is kept.
+func parseICSLine(s string) (icsProperty, bool) {
+	i := strings.IndexAny(s, ";:")
+	if i <= 0 {
+		return icsProperty{}, false
+	}
+	p := icsProperty{name: strings.ToUpper(s[:i])}
+	for s[i] == ';' {
+		s = s[i+1:]
+		eq := strings.IndexByte(s, '=')
+		if eq <= 0 {
+			return icsProperty{}, false
+		}
+		name, rest := strings.ToUpper(s[:eq]), s[eq+1:]
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.IndexByte(rest[1:], '"')
+			if end < 0 {
+				return icsProperty{}, false
+			}
+			value, rest = rest[1:end+1], rest[end+2:]
+		} else if j := strings.IndexAny(rest, ",;:"); j >= 0 {
+			value, rest = rest[:j], rest[j:]
+		} else {
+			return icsProperty{}, false
+		}
+		// Skip further values of the parameter.
+		for quoted, k := false, 0; k < len(rest); k++ {
+			if rest[k] == '"' {
+				quoted = !quoted
+			} else if !quoted && (rest[k] == ';' || rest[k] == ':') {
+				rest = rest[k:]
+				break
+			}
+		}
+		if rest == "" || (rest[0] != ';' && rest[0] != ':') {
+			return icsProperty{}, false
+		}
+		if p.params == nil {
+			p.params = make(map[string]string)
+		}
+		p.params[name] = value
+		s, i = rest, 0
+	}
+	p.value = s[i+1:]
+	return p, true
+}
+
+// icsTodo is a VTODO as read, before its parent is resolved.
+type icsTodo struct {
+	rec       Record
+	uid       string
+	parentUID string
+	props     []icsProperty
+	alarms    [][]icsProperty
+}
+
+func readICS(r io.Reader) ([]Record, []Problem, error) {
+	var rs records
+	var (
+		todos   []*icsTodo
+		cur     *icsTodo
+		alarm   []icsProperty
+		stack   []string // open components, outermost first
+		ignored = map[string]int{}
+		first   = map[string]int{} // line of the first ignored component of each kind
+		seen    bool
+	)
+	handle := func(p icsProperty) error {
+		switch p.name {
+		case "BEGIN":
+			kind := strings.ToUpper(p.value)
+			if len(stack) == 0 && kind != "VCALENDAR" {
+				return fmt.Errorf("%w: line %d: expected BEGIN:VCALENDAR", ErrInvalidFile, p.line)
+			}
+			seen = true
+			switch {
+			case len(stack) == 1 && kind == "VTODO":
+				cur = &icsTodo{rec: Record{Line: p.line}}
+				todos = append(todos, cur)
+			case cur != nil && len(stack) == 2 && kind == "VALARM":
+				alarm = []icsProperty{}
+			case len(stack) == 1 && kind != "VTIMEZONE":
+				if ignored[kind] == 0 {
+					first[kind] = p.line
+				}
+				ignored[kind]++
+			}
+			stack = append(stack, kind)
+		case "END":
+			kind := strings.ToUpper(p.value)
+			if len(stack) == 0 || stack[len(stack)-1] != kind {
+				return fmt.Errorf("%w: line %d: END:%s does not close the open component", ErrInvalidFile, p.line, p.value)
+			}
+			stack = stack[:len(stack)-1]
+			switch {
+			case kind == "VALARM" && alarm != nil:
+				cur.alarms = append(cur.alarms, alarm)
+				alarm = nil
+			case kind == "VTODO" && len(stack) == 1:
+				cur = nil
+			}
+		default:
+			switch {
+			case alarm != nil:
+				alarm = append(alarm, p)
+			case cur != nil && len(stack) == 2:
+				cur.props = append(cur.props, p)
+			}
+		}
+		return nil
+	}
+
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	var (
+		logical string
+		start   int
+	)
+	flush := func() error {
+		if logical == "" {
+			return nil
+		}
+		p, ok := parseICSLine(logical)
+		logical = ""
+		if !ok {
+			if len(stack) == 0 && !seen {
+				return fmt.Errorf("%w: not an iCalendar file", ErrInvalidFile)
+			}
+			rs.warnf(start, "", "not an iCalendar content line; ignored")
+			return nil
+		}
+		p.line = start
+		return handle(p)
+	}
+	for line := 1; sc.Scan(); line++ {
+		text := strings.TrimSuffix(sc.Text(), "\r")
+		if line == 1 {
+			text = strings.TrimPrefix(text, "\ufeff")
+		}
+		if strings.HasPrefix(text, " ") || strings.HasPrefix(text, "\t") {
+			logical += text[1:]
+			continue
+		}
+		if err := flush(); err != nil {
+			return nil, nil, err
+		}
+		logical, start = text, line
+	}
+	if err := sc.Err(); err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+	}
+	if err := flush(); err != nil {
+		return nil, nil, err
+	}
+	if !seen {
+		return nil, nil, fmt.Errorf("%w: not an iCalendar file", ErrInvalidFile)
+	}
+	if len(stack) > 0 {
+		return nil, nil, fmt.Errorf("%w: the file ends inside %s", ErrInvalidFile, stack[len(stack)-1])
+	}
+	for kind, n := range ignored {
+		rs.warnf(first[kind], "", "%d %s component(s) ignored; only VTODOs are imported", n, kind)
+	}
+
+	for _, t := range todos {
+		rs.readVTODO(t)
+	}
+	rs.addICS(todos)
+	return rs.list, rs.problems, nil
+}
+
+// readVTODO fills in the record of a VTODO from its properties.
+func (rs *records) readVTODO(t *icsTodo) {
+	rec := &t.rec
+	var (
+		rule      string
+		ruleLine  int
+		tz        string
+		cancelled bool
+		modified  *time.Time
+	)
+	for _, p := range t.props {
+		switch p.name {
+		case "UID":
+			t.uid = p.value
+		case "SUMMARY":
+			rec.Title = icsUnescape(p.value)
+		case "DESCRIPTION":
+			rec.Description = icsUnescape(p.value)
+		case "DUE":
+			due, zone, ok := rs.icsTime(p, "due_at")
+			if ok {
+				rec.DueAt, tz = &due, zone
+			}
+		case "STATUS":
+			switch strings.ToUpper(p.value) {
+			case "COMPLETED":
+				rec.IsDone = true
+			case "CANCELLED":
+				cancelled = true
+			}
+		case "COMPLETED":
+			rec.IsDone = true
+		case "RRULE":
+			rule, ruleLine = p.value, p.line
+		case "CATEGORIES":
+			for _, tag := range icsSplit(p.value) {
+				if tag = strings.TrimSpace(icsUnescape(tag)); tag != "" {
+					rec.Tags = append(rec.Tags, tag)
+				}
+			}
+		case "RELATED-TO":
+			if rel := strings.ToUpper(p.params["RELTYPE"]); rel == "" || rel == "PARENT" {
+				t.parentUID = p.value
+			}
+		case "X-TODO-PROJECT":
+			rec.Project = icsUnescape(p.value)
+		case "CREATED":
+			if at, _, ok := rs.icsTime(p, "created_at"); ok {
+				rec.CreatedAt = &at
+			}
+		case "LAST-MODIFIED":
+			if at, _, ok := rs.icsTime(p, ""); ok {
+				modified = &at
+			}
+		}
+	}
+	if cancelled {
+		gone := time.Now().UTC()
+		if modified != nil {
+			gone = *modified
+		}
+		rec.DeletedAt = &gone
+	}
+	if rule != "" {
+		r, err := recurrence.Parse(rule)
+		switch {
+		case err != nil:
+			rs.warnf(ruleLine, "recurrence", "%v; imported as a one-off todo", err)
+		case rec.DueAt == nil:
+			rs.warnf(ruleLine, "recurrence", "a rule without DUE; imported as a one-off todo")
+		default:
+			rec.Recurrence, rec.Timezone = r.String(), tz
+		}
+	}
+	for _, props := range t.alarms {
+		for _, p := range props {
+			if p.name != "TRIGGER" {
+				continue
+			}
+			if rec.DueAt == nil {
+				rs.warnf(p.line, "reminders", "an alarm of a todo without DUE; ignored")
+				continue
+			}
+			var before time.Duration
+			if strings.EqualFold(p.params["VALUE"], "DATE-TIME") {
+				at, _, ok := rs.icsTime(p, "reminders")
+				if !ok {
+					continue
+				}
+				before = rec.DueAt.Sub(at)
+			} else {
+				d, ok := parseICSDuration(p.value)
+				if !ok {
+					rs.warnf(p.line, "reminders", "%q is not a duration; alarm ignored", p.value)
+					continue
+				}
+				before = -d
+			}
+			if before < 0 {
+				rs.warnf(p.line, "reminders", "an alarm after the due date; ignored")
+				continue
+			}
+			rec.Reminders = append(rec.Reminders, before)
+		}
+	}
+}
+
+// icsTime reads a DATE or DATE-TIME property. A date is midnight UTC; a
+// time in a TZID that is not an IANA name, or a floating one, is read as UTC.
+// zone is the IANA name of a valid TZID. A field of "" reports no problems.
+func (rs *records) icsTime(p icsProperty, field string) (t time.Time, zone string, ok bool) {
+	v := strings.TrimSpace(p.value)
+	var err error
+	switch {
+	case strings.EqualFold(p.params["VALUE"], "DATE") || len(v) == len(icsDate):
+		t, err = time.Parse(icsDate, v)
+	case strings.HasSuffix(v, "Z"):
+		t, err = time.Parse(icsUTC, v)
+	default:
+		loc := time.UTC
+		if tzid := strings.TrimPrefix(p.params["TZID"], "/"); tzid != "" {
+			if l, lerr := time.LoadLocation(tzid); lerr == nil && tzid != "Local" {
+				loc, zone = l, tzid
+			} else if field != "" {
+				rs.warnf(p.line, field, "unknown TZID %q; read as UTC", tzid)
+			}
+		}
+		t, err = time.ParseInLocation(icsDateTime, v, loc)
+	}
+	if err != nil {
+		if field != "" {
+			rs.errorf(p.line, field, "%q is not an iCalendar date or date-time", v)
+		}
+		return time.Time{}, "", false
+	}
+	return t.UTC(), zone, true
+}
+
+// addICS adds the VTODOs as records, parents before their subtasks whatever
+// the order of the file. A parent that is not in the file, or a cycle of
+// RELATED-TO, leaves the todo at the top level.
+func (rs *records) addICS(todos []*icsTodo) {
+	byUID := make(map[string]int, len(todos))
+	for i, t := range todos {
+		if _, dup := byUID[t.uid]; t.uid != "" && !dup {
+			byUID[t.uid] = i
+		}
+	}
+	const (
+		unvisited = iota
+		visiting
+		added
+	)
+	state := make([]int, len(todos))
+	var visit func(i int)
+	visit = func(i int) {
+		if state[i] != unvisited {
+			return
+		}
+		state[i] = visiting
+		t := todos[i]
+		parentRef := ""
+		if t.parentUID != "" {
+			p, ok := byUID[t.parentUID]
+			if ok && state[p] == unvisited {
+				visit(p)
+			}
+			switch {
+			case !ok:
+				rs.warnf(t.rec.Line, "parent_id", "parent %s is not in the file; imported at the top level", t.parentUID)
+			case state[p] != added:
+				rs.warnf(t.rec.Line, "parent_id", "RELATED-TO forms a cycle; imported at the top level")
+			default:
+				parentRef = t.parentUID
+			}
+		}
+		rs.add(t.rec, t.uid, parentRef)
+		state[i] = added
+	}
+	for i := range todos {
+		visit(i)
+	}
+}
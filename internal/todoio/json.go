@@ -0,0 +1,145 @@
+package todoio
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	dom "Worker/internal/domain"
+	"Worker/internal/dto"
+)
+
+// jsonTodo is one element of the array of an exported JSON file.
+type jsonTodo struct {
+	ID          int64      `json:"id"`
+	ParentID    *int64     `json:"parent_id"`
+	Project     string     `json:"project,omitempty"`
+	Title       string     `json:"title"`
+	Description string     `json:"description,omitempty"`
+	IsDone      bool       `json:"is_done"`
+	DueAt       *time.Time `json:"due_at"`
+	Tags        []string   `json:"tags"`
+	Reminders   []string   `json:"reminders"`
+	Recurrence  string     `json:"recurrence,omitempty"`
+	Timezone    string     `json:"timezone,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	DeletedAt   *time.Time `json:"deleted_at,omitempty"`
+}
+
+// jsonImport is an element read on import: dates stay strings, so that a bad
+// one is reported for its todo and does not fail the file.
+type jsonImport struct {
+	ID          json.Number `json:"id"`
+	ParentID    json.Number `json:"parent_id"`
+	Project     string      `json:"project"`
+	Title       string      `json:"title"`
+	Description string      `json:"description"`
+	IsDone      bool        `json:"is_done"`
+	DueAt       string      `json:"due_at"`
+	Tags        []string    `json:"tags"`
+	Reminders   []string    `json:"reminders"`
+	Recurrence  string      `json:"recurrence"`
+	Timezone    string      `json:"timezone"`
+	CreatedAt   string      `json:"created_at"`
+	DeletedAt   string      `json:"deleted_at"`
+}
+
+// jsonWriter writes one array, an element per line.
+type jsonWriter struct {
+	w *bufio.Writer
+	n int
+}
+
+func newJSONWriter(w io.Writer) *jsonWriter {
+	return &jsonWriter{w: bufio.NewWriter(w)}
+}
+
+func (jw *jsonWriter) Write(t dom.Todo, project string) error {
+	tags := t.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	b, err := json.Marshal(jsonTodo{
+		ID:          t.ID,
+		ParentID:    t.ParentID,
+		Project:     project,
+		Title:       t.Title,
+		Description: t.Description,
+		IsDone:      t.IsDone,
+		DueAt:       t.DueAt,
+		Tags:        tags,
+		Reminders:   dto.FormatOffsets(t.Reminders),
+		Recurrence:  t.Recurrence,
+		Timezone:    t.RecurrenceTZ,
+		CreatedAt:   t.CreatedAt,
+		UpdatedAt:   t.UpdatedAt,
+		DeletedAt:   t.DeletedAt,
+	})
+	if err != nil {
+		return err
+	}
+	sep := ",\n"
+	if jw.n == 0 {
+		sep = "[\n"
+	}
+	jw.n++
+	if _, err := jw.w.WriteString(sep); err != nil {
+		return err
+	}
+	_, err = jw.w.Write(b)
+	return err
+}
+
+func (jw *jsonWriter) Close() error {
+	end := "\n]\n"
+	if jw.n == 0 {
+		end = "[]\n"
+	}
+	if _, err := jw.w.WriteString(end); err != nil {
+		return err
+	}
+	return jw.w.Flush()
+}
+
+func readJSON(r io.Reader) ([]Record, []Problem, error) {
+	dec := json.NewDecoder(r)
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('[') {
+		return nil, nil, fmt.Errorf("%w: expected an array of todos", ErrInvalidFile)
+	}
+	var rs records
+	for n := 1; dec.More(); n++ {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return nil, nil, fmt.Errorf("%w: todo %d: %v", ErrInvalidFile, n, err)
+		}
+		var item jsonImport
+		if err := json.Unmarshal(raw, &item); err != nil {
+			rs.errorf(n, "", "%v", err)
+			continue
+		}
+		rec := Record{
+			Line:        n,
+			Title:       item.Title,
+			Description: item.Description,
+			IsDone:      item.IsDone,
+			Project:     item.Project,
+			Tags:        item.Tags,
+			Recurrence:  item.Recurrence,
+			Timezone:    item.Timezone,
+		}
+		rs.fill(&rec, fields{
+			dueAt:     item.DueAt,
+			reminders: item.Reminders,
+			createdAt: item.CreatedAt,
+			deletedAt: item.DeletedAt,
+		})
+		rs.add(rec, item.ID.String(), item.ParentID.String())
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+	}
+	return rs.list, rs.problems, nil
+}
@@ -0,0 +1,229 @@
+package todoio
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	dom "Worker/internal/domain"
+)
+
+// A Markdown file is a task list:
+//
+//	## Project
+//
+//	- [ ] Title (due: 2026-03-01) (repeat: FREQ=WEEKLY) #tag
+//	  Description
+//	  - [x] Subtask
+//
+// A heading names the project of the todos under it (a level-1 heading is the
+// title of the document and is ignored); subtasks are indented under their
+// parent, description lines under their todo. Metadata in parentheses (due,
+// repeat, deleted) and #tags close the item line. Reminders, the timezone of a
+// rule and creation times are not kept.
+
+var (
+	mdItemRe    = regexp.MustCompile(`^(\s*)[-*+]\s+\[([ xX])\]\s+(.*)$`)
+	mdHeadingRe = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	mdMetaRe    = regexp.MustCompile(`\s*\((due|repeat|deleted):\s*([^()]*)\)$`)
+	mdTagRe     = regexp.MustCompile(`\s+#([^\s#]+)$`)
+)
+
+type markdownWriter struct {
+	w       *bufio.Writer
+	depth   map[int64]int
+	project *int64
+	n       int
+}
+
+func newMarkdownWriter(w io.Writer) *markdownWriter {
+	return &markdownWriter{w: bufio.NewWriter(w), depth: make(map[int64]int)}
+}
+
+func (mw *markdownWriter) Write(t dom.Todo, project string) error {
+	var b strings.Builder
+	if mw.n == 0 || !sameProject(mw.project, t.ProjectID) {
+		if mw.n > 0 {
+			b.WriteString("\n")
+		}
+		if t.ProjectID != nil {
+			b.WriteString("## " + oneLine(project) + "\n\n")
+		}
+		mw.project = t.ProjectID
+	}
+	mw.n++
+	depth := 0
+	if t.ParentID != nil {
+		if d, ok := mw.depth[*t.ParentID]; ok {
+			depth = d + 1
+		}
+	}
+	mw.depth[t.ID] = depth
+	indent := strings.Repeat("  ", depth)
+	box := " "
+	if t.IsDone {
+		box = "x"
+	}
+	b.WriteString(indent + "- [" + box + "] " + oneLine(t.Title))
+	if t.DueAt != nil {
+		b.WriteString(" (due: " + formatDay(*t.DueAt) + ")")
+	}
+	if t.Recurrence != "" {
+		b.WriteString(" (repeat: " + t.Recurrence + ")")
+	}
+	if t.DeletedAt != nil {
+		b.WriteString(" (deleted: " + formatTime(t.DeletedAt) + ")")
+	}
+	for _, tag := range t.Tags {
+		b.WriteString(" #" + strings.Join(strings.Fields(tag), "_"))
+	}
+	b.WriteString("\n")
+	if t.Description != "" {
+		for _, l := range strings.Split(t.Description, "\n") {
+			l = strings.TrimSpace(l)
+			if mdItemRe.MatchString(l) {
+				l = `\` + l
+			}
+			b.WriteString(strings.TrimRight(indent+"  "+l, " ") + "\n")
+		}
+	}
+	_, err := mw.w.WriteString(b.String())
+	return err
+}
+
+func (mw *markdownWriter) Close() error {
+	return mw.w.Flush()
+}
+
+func readMarkdown(r io.Reader) ([]Record, []Problem, error) {
+	type open struct{ indent, index int }
+	var (
+		rs      records
+		project string
+		stack   []open     // the todos later lines may belong to, outermost first
+		desc    [][]string // description lines of each record
+		last    = -1       // the record the previous line added to
+	)
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; sc.Scan(); line++ {
+		text := sc.Text()
+		if strings.TrimSpace(text) == "" {
+			if last >= 0 {
+				desc[last] = append(desc[last], "")
+			}
+			continue
+		}
+		if m := mdHeadingRe.FindStringSubmatch(text); m != nil {
+			if len(m[1]) > 1 {
+				project = m[2]
+			}
+			stack, last = nil, -1
+			continue
+		}
+		indent := indentWidth(text)
+		if m := mdItemRe.FindStringSubmatch(text); m != nil {
+			for len(stack) > 0 && stack[len(stack)-1].indent >= indent {
+				stack = stack[:len(stack)-1]
+			}
+			rec := Record{Line: line, Project: project, IsDone: m[2] != " "}
+			rs.parseItem(&rec, m[3])
+			rs.add(rec, "", "")
+			i := len(rs.list) - 1
+			if len(stack) > 0 {
+				rs.list[i].Parent = stack[len(stack)-1].index
+			}
+			stack = append(stack, open{indent: indent, index: i})
+			desc = append(desc, nil)
+			last = -1
+			continue
+		}
+		// Any other line describes the innermost todo it is indented under.
+		owner := -1
+		for k := len(stack) - 1; k >= 0; k-- {
+			if stack[k].indent < indent {
+				owner = stack[k].index
+				break
+			}
+		}
+		if owner < 0 {
+			rs.warnf(line, "", "not a todo (- [ ] title) or a heading; ignored")
+			last = -1
+			continue
+		}
+		l := strings.TrimPrefix(strings.TrimSpace(text), `\`)
+		desc[owner] = append(desc[owner], l)
+		last = owner
+	}
+	if err := sc.Err(); err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+	}
+	for i := range rs.list {
+		rs.list[i].Description = strings.TrimSpace(strings.Join(desc[i], "\n"))
+	}
+	return rs.list, rs.problems, nil
+}
+
+// parseItem reads the text of an item line after the checkbox: the title,
+// then metadata and tags from the end.
+func (rs *records) parseItem(rec *Record, text string) {
+	text = strings.TrimSpace(text)
+	for {
+		if m := mdTagRe.FindStringSubmatchIndex(text); m != nil {
+			rec.Tags = append([]string{text[m[2]:m[3]]}, rec.Tags...)
+			text = text[:m[0]]
+			continue
+		}
+		m := mdMetaRe.FindStringSubmatchIndex(text)
+		if m == nil {
+			break
+		}
+		key, value := text[m[2]:m[3]], strings.TrimSpace(text[m[4]:m[5]])
+		text = text[:m[0]]
+		switch key {
+		case "due":
+			rec.DueAt = rs.time(rec.Line, "due_at", value)
+		case "repeat":
+			rec.Recurrence = value
+		case "deleted":
+			rec.DeletedAt = rs.time(rec.Line, "deleted_at", value)
+		}
+	}
+	rec.Title = strings.TrimSpace(text)
+}
+
+// indentWidth is the width of the leading whitespace, a tab counting as four.
+func indentWidth(s string) int {
+	w := 0
+	for _, c := range s {
+		switch c {
+		case ' ':
+			w++
+		case '\t':
+			w += 4
+		default:
+			return w
+		}
+	}
+	return w
+}
+
+// formatDay writes a time at midnight UTC as a date only.
+func formatDay(t time.Time) string {
+	t = t.UTC()
+	if t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0 && t.Nanosecond() == 0 {
+		return t.Format(time.DateOnly)
+	}
+	return t.Format(time.RFC3339)
+}
+
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func sameProject(a, b *int64) bool {
+	return (a == nil && b == nil) || (a != nil && b != nil && *a == *b)
+}
@@ -0,0 +1,167 @@
+// Package todoio reads and writes the files todos are exported to and
+// imported from: CSV, JSON, Markdown and iCalendar, and for import also the
+// CSV export of Todoist. The iCalendar writer also renders calendar feeds.
+package todoio
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	dom "Worker/internal/domain"
+)
+
+// File formats.
+const (
+	FormatCSV      = "csv"
+	FormatJSON     = "json"
+	FormatMarkdown = "md"
+	FormatICS      = "ics"
+	FormatTodoist  = "todoist" // import only
+)
+
+var (
+	ErrUnknownFormat = errors.New("unknown format")
+	// ErrInvalidFile is returned wrapped, with the place and the reason, for a
+	// file that cannot be read at all.
+	ErrInvalidFile = errors.New("invalid file")
+)
+
+// Record is one todo read from a file.
+type Record struct {
+	// Line is where the todo starts: the line in CSV, Markdown and iCalendar
+	// files, the position in the array (from 1) in JSON files.
+	Line int
+	// Parent is the index in the records of the todo's parent, -1 for a
+	// top-level todo. Parents always come before their subtasks, even where
+	// the file has them after.
+	Parent      int
+	Title       string
+	Description string
+	IsDone      bool
+	DueAt       *time.Time
+	Tags        []string
+	Reminders   []time.Duration
+	Project     string // project name; "" = none
+	Recurrence  string
+	Timezone    string
+	CreatedAt   *time.Time
+	DeletedAt   *time.Time // the todo is in the trash
+}
+
+// Problem is something wrong with one record. Errors keep a file from being
+// imported; a record with a warning is imported without what the warning is
+// about.
+type Problem struct {
+	Line    int
+	Field   string // "" = the record as a whole
+	Message string
+	Warning bool
+}
+
+// Read reads all records of a file in format. Records that can be read only in
+// part come with problems; the error is for files that cannot be read at all.
+func Read(r io.Reader, format string) ([]Record, []Problem, error) {
+	switch format {
+	case FormatCSV:
+		return readCSV(r)
+	case FormatJSON:
+		return readJSON(r)
+	case FormatMarkdown:
+		return readMarkdown(r)
+	case FormatICS:
+		return readICS(r)
+	case FormatTodoist:
+		return readTodoist(r)
+	}
+	return nil, nil, ErrUnknownFormat
+}
+
+// Writer writes todos to a file, each with the name of its project ("" =
+// none). Todos are expected in the order of TodoRepo.Export: by project, each
+// followed by its subtasks.
+type Writer interface {
+	Write(t dom.Todo, project string) error
+	// Close finishes the file; it does not close the underlying writer.
+	Close() error
+}
+
+// NewWriter returns a Writer of format to w.
+func NewWriter(w io.Writer, format string) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w), nil
+	case FormatJSON:
+		return newJSONWriter(w), nil
+	case FormatMarkdown:
+		return newMarkdownWriter(w), nil
+	case FormatICS:
+		return NewCalendarWriter(w, CalendarOptions{}), nil
+	}
+	return nil, ErrUnknownFormat
+}
+
+// ContentType returns the media type of an exported file in format.
+func ContentType(format string) string {
+	switch format {
+	case FormatJSON:
+		return "application/json; charset=utf-8"
+	case FormatMarkdown:
+		return "text/markdown; charset=utf-8"
+	case FormatICS:
+		return "text/calendar; charset=utf-8"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// records collects records and their problems while a file is read.
+type records struct {
+	list     []Record
+	problems []Problem
+	refs     map[string]int // the file's IDs of the records read so far
+}
+
+func (rs *records) errorf(line int, field, format string, args ...any) {
+	rs.problems = append(rs.problems, Problem{Line: line, Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+func (rs *records) warnf(line int, field, format string, args ...any) {
+	rs.problems = append(rs.problems, Problem{Line: line, Field: field, Message: fmt.Sprintf(format, args...), Warning: true})
+}
+
+// add appends rec under the file's ID ref ("" = none), with the parent named
+// by parentRef ("" = none), which must be an earlier record.
+func (rs *records) add(rec Record, ref, parentRef string) {
+	rec.Parent = -1
+	if parentRef != "" {
+		if i, ok := rs.refs[parentRef]; ok {
+			rec.Parent = i
+		} else {
+			rs.errorf(rec.Line, "parent_id", "parent %s is not a todo further up in the file", parentRef)
+		}
+	}
+	if ref != "" {
+		if rs.refs == nil {
+			rs.refs = make(map[string]int)
+		}
+		if _, dup := rs.refs[ref]; dup {
+			rs.errorf(rec.Line, "id", "id %s appears more than once", ref)
+		} else {
+			rs.refs[ref] = len(rs.list)
+		}
+	}
+	rs.list = append(rs.list, rec)
+}
+
+// splitList splits a comma-separated list, dropping empty items.
+func splitList(s string) []string {
+	var out []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
@@ -0,0 +1,141 @@
+package todoio
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A Todoist CSV export holds one project. Rows have a TYPE: tasks become
+// todos, nested by INDENT (1 = top level); a section names the project of the
+// tasks after it; notes (comments) are appended to the description of the
+// task before them. @labels in CONTENT become tags. DATE is free text in
+// Todoist: the common absolute forms are read, in TIMEZONE if given; other
+// dates, recurring ones included, are dropped with a warning.
+
+var todoistLabelRe = regexp.MustCompile(`(^|\s)@([^\s@]+)`)
+
+// todoistLayouts are the date forms read from DATE, tried in order.
+var todoistLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04",
+	time.DateOnly,
+	"Jan 2 2006 15:04",
+	"Jan 2 2006",
+	"January 2 2006 15:04",
+	"January 2 2006",
+	"2 Jan 2006 15:04",
+	"2 Jan 2006",
+	"2 January 2006 15:04",
+	"2 January 2006",
+}
+
+func readTodoist(r io.Reader) ([]Record, []Problem, error) {
+	cr := newCSVReader(r)
+	cols, err := readCSVHeader(cr)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, col := range []string{"type", "content"} {
+		if _, ok := cols[col]; !ok {
+			return nil, nil, fmt.Errorf("%w: not a Todoist export: the header has no %s column", ErrInvalidFile, strings.ToUpper(col))
+		}
+	}
+	type open struct{ indent, index int }
+	var (
+		rs      records
+		project string
+		stack   []open // the tasks later tasks may be nested in, outermost first
+	)
+	for {
+		row, err := cr.Read()
+		if err == io.EOF {
+			return rs.list, rs.problems, nil
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+		}
+		line, _ := cr.FieldPos(0)
+		get := func(col string) string {
+			if i, ok := cols[col]; ok && i < len(row) {
+				return strings.TrimSpace(row[i])
+			}
+			return ""
+		}
+		content := get("content")
+		switch strings.ToLower(get("type")) {
+		case "section":
+			project, stack = content, nil
+			continue
+		case "note":
+			if len(stack) == 0 {
+				rs.warnf(line, "", "a note without a task before it; ignored")
+				continue
+			}
+			rec := &rs.list[stack[len(stack)-1].index]
+			rec.Description = strings.TrimSpace(rec.Description + "\n\n" + content)
+			continue
+		case "task":
+		default:
+			continue // blank separator rows and view settings
+		}
+		indent := 1
+		if s := get("indent"); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil || n < 1 {
+				rs.errorf(line, "INDENT", "must be a number from 1")
+			} else {
+				indent = n
+			}
+		}
+		for len(stack) > 0 && stack[len(stack)-1].indent >= indent {
+			stack = stack[:len(stack)-1]
+		}
+		rec := Record{Line: line, Project: project, Description: get("description")}
+		rec.Title, rec.Tags = todoistLabels(content)
+		if date := get("date"); date != "" {
+			if due, ok := parseTodoistDate(date, get("timezone")); ok {
+				rec.DueAt = &due
+			} else {
+				rs.warnf(line, "DATE", "%q is not a date this import understands; imported without a due date", date)
+			}
+		}
+		rs.add(rec, "", "")
+		i := len(rs.list) - 1
+		if len(stack) > 0 {
+			rs.list[i].Parent = stack[len(stack)-1].index
+		}
+		stack = append(stack, open{indent: indent, index: i})
+	}
+}
+
+// todoistLabels takes the @labels out of the content of a task.
+func todoistLabels(content string) (string, []string) {
+	var tags []string
+	for _, m := range todoistLabelRe.FindAllStringSubmatch(content, -1) {
+		tags = append(tags, m[2])
+	}
+	title := todoistLabelRe.ReplaceAllString(content, "$1")
+	return strings.Join(strings.Fields(title), " "), tags
+}
+
+// parseTodoistDate reads an absolute date in the IANA zone tz ("" = UTC).
+func parseTodoistDate(s, tz string) (time.Time, bool) {
+	loc := time.UTC
+	if tz != "" {
+		if l, err := time.LoadLocation(tz); err == nil {
+			loc = l
+		}
+	}
+	s = strings.Join(strings.Fields(strings.ReplaceAll(s, ",", " ")), " ")
+	for _, layout := range todoistLayouts {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
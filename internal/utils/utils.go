@@ -66,3 +66,11 @@ func IsPGUniqueViolation(err error) bool {
 	return false
 }
 
+// PGConstraintName returns the constraint a PostgreSQL error refers to, or "".
+func PGConstraintName(err error) string {
+	var pge *pgconn.PgError
+	if errors.As(err, &pge) {
+		return pge.ConstraintName
+	}
+	return ""
+}
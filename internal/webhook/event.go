@@ -0,0 +1,113 @@
+// Package webhook builds, signs and sends the todo events delivered to the
+// webhooks users register.
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	dom "Worker/internal/domain"
+	"Worker/internal/dto"
+)
+
+// Request headers of a delivery.
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderID        = "X-Webhook-ID" // event ID; receivers use it to drop duplicates
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// Payload is the JSON body of a delivery.
+type Payload struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      Data      `json:"data"`
+}
+
+// Data describes the todo an event is about.
+type Data struct {
+	WorkspaceID int64             `json:"workspace_id"`
+	TodoID      int64             `json:"todo_id"`
+	ActorID     *int64            `json:"actor_id"`       // who made the change; null for todo.overdue
+	Todo        *dto.TodoResponse `json:"todo,omitempty"` // the todo after the change; not set for todo.deleted
+}
+
+// NewEvent builds an event of type typ about t, made by actorID (0 = the
+// system), with a fresh event ID.
+func NewEvent(typ string, actorID int64, t dom.Todo) (dom.WebhookEvent, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return dom.WebhookEvent{}, err
+	}
+	p := Payload{
+		ID:        "evt_" + hex.EncodeToString(b),
+		Type:      typ,
+		CreatedAt: time.Now().UTC(),
+		Data:      Data{WorkspaceID: t.WorkspaceID, TodoID: t.ID},
+	}
+	if actorID != 0 {
+		p.Data.ActorID = &actorID
+	}
+	if typ != dom.WebhookTodoDeleted {
+		todo := todoToResponse(t)
+		p.Data.Todo = &todo
+	}
+	body, err := json.Marshal(p)
+	if err != nil {
+		return dom.WebhookEvent{}, err
+	}
+	return dom.WebhookEvent{ID: p.ID, Type: typ, TodoID: t.ID, Payload: body}, nil
+}
+
+// Sign returns the X-Webhook-Signature value for body sent at timestamp (Unix
+// seconds): "sha256=" and the hex HMAC-SHA256 of "<timestamp>.<body>".
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// todoToResponse renders a todo as the API does. Permission is left out: it
+// depends on the receiver.
+func todoToResponse(t dom.Todo) dto.TodoResponse {
+	out := dto.TodoResponse{
+		ID:          t.ID,
+		WorkspaceID: t.WorkspaceID,
+		Title:       t.Title,
+		Description: t.Description,
+		IsDone:      t.IsDone,
+		DueAt:       t.DueAt,
+		Tags:        t.Tags,
+		Reminders:   dto.FormatOffsets(t.Reminders),
+		ProjectID:   t.ProjectID,
+		Position:    t.Position,
+		ParentID:    t.ParentID,
+		CreatedAt:   t.CreatedAt,
+		UpdatedAt:   t.UpdatedAt,
+		DeletedAt:   t.DeletedAt,
+		Version:     t.Version,
+	}
+	if out.Tags == nil {
+		out.Tags = []string{}
+	}
+	if t.ChildrenTotal > 0 {
+		out.Progress = &dto.Progress{Done: t.ChildrenDone, Total: t.ChildrenTotal, Percent: t.ChildrenDone * 100 / t.ChildrenTotal}
+	}
+	if t.Recurrence != "" && t.RecurrenceStart != nil {
+		tz := t.RecurrenceTZ
+		if tz == "" {
+			tz = "UTC"
+		}
+		out.Recurrence = &dto.Recurrence{Rule: t.Recurrence, Timezone: tz, Start: *t.RecurrenceStart}
+	}
+	return out
+}
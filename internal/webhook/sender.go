@@ -0,0 +1,124 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	dom "Worker/internal/domain"
+)
+
+// maxResponse is how much of a response body is kept with an attempt.
+const maxResponse = 1024
+
+// ErrPrivateAddress is returned for webhook URLs that point into the server's
+// own network.
+var ErrPrivateAddress = errors.New("webhook address is not public")
+
+// Sender posts deliveries to webhook endpoints.
+type Sender struct {
+	client *http.Client
+}
+
+// NewSender returns a Sender whose requests time out after timeout. Unless
+// allowPrivate is set it refuses to connect to loopback, private and
+// link-local addresses, whatever a host name resolves to.
+func NewSender(timeout time.Duration, allowPrivate bool) *Sender {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowPrivate {
+		dialer.Control = func(_, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !publicIP(ip) {
+				return ErrPrivateAddress
+			}
+			return nil
+		}
+	}
+	// No proxy: the address check has to see the receiver itself.
+	transport := &http.Transport{
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   timeout,
+		ResponseHeaderTimeout: timeout,
+		MaxIdleConnsPerHost:   2,
+		IdleConnTimeout:       90 * time.Second,
+	}
+	return &Sender{client: &http.Client{
+		Transport: transport,
+		Timeout:   timeout,
+		// A redirect counts as a failure: the receiver should be configured with its final URL.
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}}
+}
+
+// Send posts d to its webhook and returns the attempt. Only a 2xx answer
+// counts as delivered.
+func (s *Sender) Send(ctx context.Context, d dom.WebhookDelivery) dom.WebhookAttempt {
+	start := time.Now()
+	a := dom.WebhookAttempt{DeliveryID: d.ID, Attempt: d.Attempts + 1, CreatedAt: start.UTC()}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		a.Error = err.Error()
+		return a
+	}
+	ts := start.Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "TodoAPI-Webhooks/1.0")
+	req.Header.Set(HeaderEvent, d.Event)
+	req.Header.Set(HeaderID, d.EventID)
+	req.Header.Set(HeaderDelivery, strconv.FormatInt(d.ID, 10))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(ts, 10))
+	req.Header.Set(HeaderSignature, Sign(d.Secret, ts, d.Payload))
+
+	resp, err := s.client.Do(req)
+	a.Duration = time.Since(start)
+	if err != nil {
+		a.Error = err.Error()
+		return a
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponse))
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10)) // lets the connection be reused
+	a.StatusCode = resp.StatusCode
+	a.Response = strings.ToValidUTF8(string(body), "")
+	if !a.OK() {
+		a.Error = "unexpected status " + resp.Status
+	}
+	return a
+}
+
+// ValidateURL checks that raw is an absolute http(s) URL. Unless allowPrivate
+// is set, hosts that are obviously internal (localhost, private IP literals)
+// are rejected up front; names resolving to such addresses fail on delivery.
+func ValidateURL(raw string, allowPrivate bool) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.User != nil {
+		return errors.New("url must be an absolute http or https URL without credentials")
+	}
+	if allowPrivate {
+		return nil
+	}
+	host := strings.ToLower(u.Hostname())
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return ErrPrivateAddress
+	}
+	if ip := net.ParseIP(host); ip != nil && !publicIP(ip) {
+		return ErrPrivateAddress
+	}
+	return nil
+}
+
+func publicIP(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast())
+}
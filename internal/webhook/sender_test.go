@@ -0,0 +1,183 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	dom "Worker/internal/domain"
+)
+
+func testDelivery(url string) dom.WebhookDelivery {
+	return dom.WebhookDelivery{
+		ID:        42,
+		WebhookID: 7,
+		EventID:   "evt_0123",
+		Event:     dom.WebhookTodoCompleted,
+		Payload:   []byte(`{"id":"evt_0123","type":"todo.completed"}`),
+		Attempts:  2,
+		URL:       url,
+		Secret:    "whsec_test",
+	}
+}
+
+func TestSenderSendSignsRequest(t *testing.T) {
+	var got *http.Request
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	d := testDelivery(srv.URL + "/hook")
+	a := NewSender(time.Second, true).Send(context.Background(), d)
+	if !a.OK() {
+		t.Fatalf("Send: status %d, error %q; want delivered", a.StatusCode, a.Error)
+	}
+	if a.StatusCode != http.StatusNoContent || a.DeliveryID != d.ID || a.Attempt != d.Attempts+1 {
+		t.Errorf("attempt = status %d, delivery %d, attempt %d; want 204, %d, %d",
+			a.StatusCode, a.DeliveryID, a.Attempt, d.ID, d.Attempts+1)
+	}
+	if got == nil {
+		t.Fatal("the receiver got no request")
+	}
+	if got.Method != http.MethodPost || got.URL.Path != "/hook" {
+		t.Errorf("request = %s %s, want POST /hook", got.Method, got.URL.Path)
+	}
+	if string(body) != string(d.Payload) {
+		t.Errorf("body = %s, want the payload %s", body, d.Payload)
+	}
+	for header, want := range map[string]string{
+		"Content-Type": "application/json",
+		HeaderEvent:    d.Event,
+		HeaderID:       d.EventID,
+		HeaderDelivery: "42",
+	} {
+		if v := got.Header.Get(header); v != want {
+			t.Errorf("%s = %q, want %q", header, v, want)
+		}
+	}
+	ts, err := strconv.ParseInt(got.Header.Get(HeaderTimestamp), 10, 64)
+	if err != nil {
+		t.Fatalf("%s = %q: %v", HeaderTimestamp, got.Header.Get(HeaderTimestamp), err)
+	}
+	if age := time.Since(time.Unix(ts, 0)); age < -time.Second || age > time.Minute {
+		t.Errorf("%s is %v off the current time", HeaderTimestamp, age)
+	}
+	if sig := got.Header.Get(HeaderSignature); sig != Sign(d.Secret, ts, d.Payload) {
+		t.Errorf("%s = %q, want %q", HeaderSignature, sig, Sign(d.Secret, ts, d.Payload))
+	}
+}
+
+func TestSign(t *testing.T) {
+	// printf '1700000000.{}' | openssl dgst -sha256 -hmac secret
+	want := "sha256=b8569b78799ff9e3cbff0fc2d63a33a2b57f3282abd07c37ae5e8e7d79a5f163"
+	if got := Sign("secret", 1700000000, []byte("{}")); got != want {
+		t.Errorf("Sign = %q, want %q", got, want)
+	}
+}
+
+func TestSenderSendStatus(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		body      string
+		wantOK    bool
+		wantError string
+	}{
+		{name: "ok", status: http.StatusOK, body: "thanks", wantOK: true},
+		{name: "accepted", status: http.StatusAccepted, wantOK: true},
+		{name: "client error", status: http.StatusBadRequest, body: "bad signature", wantError: "unexpected status 400 Bad Request"},
+		{name: "gone", status: http.StatusGone, wantError: "unexpected status 410 Gone"},
+		{name: "server error", status: http.StatusBadGateway, body: "upstream down", wantError: "unexpected status 502 Bad Gateway"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				_, _ = io.WriteString(w, tt.body)
+			}))
+			defer srv.Close()
+
+			a := NewSender(time.Second, true).Send(context.Background(), testDelivery(srv.URL))
+			if a.OK() != tt.wantOK {
+				t.Errorf("OK = %v, want %v", a.OK(), tt.wantOK)
+			}
+			if a.StatusCode != tt.status {
+				t.Errorf("StatusCode = %d, want %d", a.StatusCode, tt.status)
+			}
+			if a.Error != tt.wantError {
+				t.Errorf("Error = %q, want %q", a.Error, tt.wantError)
+			}
+			if a.Response != tt.body {
+				t.Errorf("Response = %q, want %q", a.Response, tt.body)
+			}
+		})
+	}
+}
+
+func TestSenderSendKeepsStartOfResponse(t *testing.T) {
+	long := strings.Repeat("x", 3*maxResponse)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = io.WriteString(w, long)
+	}))
+	defer srv.Close()
+
+	a := NewSender(time.Second, true).Send(context.Background(), testDelivery(srv.URL))
+	if len(a.Response) != maxResponse {
+		t.Errorf("kept %d bytes of the response, want %d", len(a.Response), maxResponse)
+	}
+}
+
+func TestSenderSendRedirectFails(t *testing.T) {
+	var followed atomic.Bool
+	mux := http.NewServeMux()
+	mux.HandleFunc("/old", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/new", http.StatusPermanentRedirect)
+	})
+	mux.HandleFunc("/new", func(w http.ResponseWriter, r *http.Request) {
+		followed.Store(true)
+		w.WriteHeader(http.StatusOK)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	a := NewSender(time.Second, true).Send(context.Background(), testDelivery(srv.URL+"/old"))
+	if a.OK() {
+		t.Error("a redirect counted as delivered")
+	}
+	if a.StatusCode != http.StatusPermanentRedirect || a.Error != "unexpected status 308 Permanent Redirect" {
+		t.Errorf("attempt = status %d, error %q; want 308 as a failure", a.StatusCode, a.Error)
+	}
+	if followed.Load() {
+		t.Error("the redirect was followed")
+	}
+}
+
+func TestSenderSendRefusesPrivateAddress(t *testing.T) {
+	var hit atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hit.Store(true)
+	}))
+	defer srv.Close()
+
+	a := NewSender(time.Second, false).Send(context.Background(), testDelivery(srv.URL))
+	if a.OK() || a.StatusCode != 0 {
+		t.Errorf("attempt = status %d, OK %v; want no response", a.StatusCode, a.OK())
+	}
+	if !strings.Contains(a.Error, ErrPrivateAddress.Error()) {
+		t.Errorf("Error = %q, want it to mention %q", a.Error, ErrPrivateAddress)
+	}
+	if hit.Load() {
+		t.Error("the sender connected to a loopback address")
+	}
+}
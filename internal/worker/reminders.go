@@ -0,0 +1,91 @@
+// Package worker holds the background jobs run by cmd/worker.
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	dom "Worker/internal/domain"
+	"Worker/internal/notify"
+	"Worker/internal/repo"
+)
+
+// reminderLease is how long claimed reminders stay reserved for the scheduler
+// sending them; reminders of a scheduler that dies mid-batch are claimed again
+// after it.
+const reminderLease = 5 * time.Minute
+
+// ReminderScheduler polls for due reminders and hands them to a Notifier.
+// Any number of schedulers may run against the same database.
+type ReminderScheduler struct {
+	repo     repo.ReminderRepo
+	notifier notify.Notifier
+	interval time.Duration
+	batch    int
+}
+
+// NewReminderScheduler creates a scheduler that polls every interval and claims
+// at most batch reminders at a time.
+func NewReminderScheduler(r repo.ReminderRepo, n notify.Notifier, interval time.Duration, batch int) *ReminderScheduler {
+	return &ReminderScheduler{repo: r, notifier: n, interval: interval, batch: batch}
+}
+
+// Run polls until ctx is cancelled.
+func (s *ReminderScheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		if n, err := s.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("reminders: %v", err)
+		} else if n > 0 {
+			log.Printf("reminders: processed %d", n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce processes every reminder that is due now, batch by batch, and returns
+// how many were claimed (delivered or failed). After a failed delivery it stops,
+// so failed reminders are retried on the next poll rather than right away.
+func (s *ReminderScheduler) RunOnce(ctx context.Context) (int, error) {
+	total := 0
+	for {
+		now := time.Now().UTC()
+		until := now.Add(reminderLease)
+		list, err := s.repo.ClaimDue(ctx, now, until, s.batch)
+		if err != nil || len(list) == 0 {
+			return total, err
+		}
+		total += len(list)
+		errs, failed := s.send(ctx, list, until)
+		// Delivered reminders are recorded even when ctx is cancelled meanwhile,
+		// or they would be sent again.
+		if err := s.repo.Record(context.WithoutCancel(ctx), time.Now().UTC(), until, list, errs); err != nil {
+			return total, err
+		}
+		if len(list) < s.batch || failed {
+			return total, nil
+		}
+	}
+}
+
+// send delivers a claimed batch within its lease and returns the error of each
+// reminder and whether any failed.
+func (s *ReminderScheduler) send(ctx context.Context, list []dom.Reminder, until time.Time) ([]error, bool) {
+	ctx, cancel := context.WithDeadline(ctx, until)
+	defer cancel()
+	errs := make([]error, len(list))
+	failed := false
+	for i, r := range list {
+		if errs[i] = s.notifier.Notify(ctx, r); errs[i] != nil {
+			failed = true
+			log.Printf("reminders: reminder %d (todo %d): %v", r.ID, r.TodoID, errs[i])
+		}
+	}
+	return errs, failed
+}
@@ -0,0 +1,113 @@
+package worker
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	dom "Worker/internal/domain"
+	"Worker/internal/notify"
+)
+
+// memReminderRepo is a ReminderRepo over a slice that leases due, unsent
+// reminders in remind_at order. It models no locking and no attempt cap; those
+// are in the SQL of PGReminderRepo.
+type memReminderRepo struct {
+	reminders []*memReminder
+}
+
+type memReminder struct {
+	dom.Reminder
+	sent        bool
+	lastErr     string
+	leasedUntil time.Time
+}
+
+func (r *memReminderRepo) ClaimDue(ctx context.Context, now, until time.Time, limit int) ([]dom.Reminder, error) {
+	var due []*memReminder
+	for _, rm := range r.reminders {
+		if !rm.sent && !rm.RemindAt.After(now) && !rm.leasedUntil.After(now) {
+			due = append(due, rm)
+		}
+	}
+	sort.SliceStable(due, func(i, j int) bool { return due[i].RemindAt.Before(due[j].RemindAt) })
+	if len(due) > limit {
+		due = due[:limit]
+	}
+	list := make([]dom.Reminder, len(due))
+	for i, rm := range due {
+		rm.leasedUntil = until
+		list[i] = rm.Reminder
+	}
+	return list, nil
+}
+
+func (r *memReminderRepo) Record(ctx context.Context, now, until time.Time, list []dom.Reminder, errs []error) error {
+	for i, sent := range list {
+		for _, rm := range r.reminders {
+			if rm.ID != sent.ID || !rm.leasedUntil.Equal(until) {
+				continue
+			}
+			rm.leasedUntil = time.Time{}
+			if errs[i] != nil {
+				rm.Attempts++
+				rm.lastErr = errs[i].Error()
+			} else {
+				rm.sent, rm.lastErr = true, ""
+			}
+		}
+	}
+	return nil
+}
+
+func newReminders(n int, remindAt time.Time) *memReminderRepo {
+	r := &memReminderRepo{}
+	for i := 1; i <= n; i++ {
+		r.reminders = append(r.reminders, &memReminder{Reminder: dom.Reminder{
+			ID:       int64(i),
+			TodoID:   int64(100 + i),
+			UserID:   1,
+			Title:    "todo",
+			DueAt:    remindAt.Add(time.Hour),
+			RemindAt: remindAt.Add(time.Duration(i) * time.Second),
+		}})
+	}
+	return r
+}
+
+func TestReminderSchedulerRunOnceDelivers(t *testing.T) {
+	past := time.Now().UTC().Add(-time.Hour)
+	reminders := newReminders(5, past)
+	// Not yet due.
+	reminders.reminders = append(reminders.reminders, &memReminder{Reminder: dom.Reminder{
+		ID: 99, TodoID: 199, RemindAt: time.Now().UTC().Add(time.Hour),
+	}})
+	n := notify.NewMemoryNotifier()
+	s := NewReminderScheduler(reminders, n, time.Minute, 2)
+
+	got, err := s.RunOnce(context.Background())
+	if err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+	if got != 5 {
+		t.Errorf("RunOnce processed %d, want 5 (all due reminders, over several batches)", got)
+	}
+	sent := n.Sent()
+	if len(sent) != 5 {
+		t.Fatalf("delivered %d reminders, want 5", len(sent))
+	}
+	for i, rm := range sent {
+		if rm.ID != int64(i+1) {
+			t.Errorf("delivery %d is reminder %d, want %d (in remind_at order)", i, rm.ID, i+1)
+		}
+	}
+
+	// Delivered reminders are not sent again.
+	if got, err := s.RunOnce(context.Background()); err != nil || got != 0 {
+		t.Errorf("second RunOnce = %d, %v; want 0, nil", got, err)
+	}
+	if len(n.Sent()) != 5 {
+		t.Errorf("delivered %d reminders after a second run, want still 5", len(n.Sent()))
+	}
+}
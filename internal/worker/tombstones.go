@@ -0,0 +1,55 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"Worker/internal/repo"
+)
+
+// TombstonePurger deletes the tombstones of purged todos once no sync token
+// that could still need them is accepted.
+type TombstonePurger struct {
+	repo      repo.TombstoneRepo
+	retention time.Duration
+	interval  time.Duration
+}
+
+// NewTombstonePurger creates a purger that runs every interval and deletes
+// tombstones older than retention, the lifetime of sync tokens.
+func NewTombstonePurger(r repo.TombstoneRepo, retention, interval time.Duration) *TombstonePurger {
+	return &TombstonePurger{repo: r, retention: retention, interval: interval}
+}
+
+// Run purges until ctx is cancelled.
+func (p *TombstonePurger) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		if n, err := p.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("tombstones: %v", err)
+		} else if n > 0 {
+			log.Printf("tombstones: purged %d", n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce deletes every tombstone past the retention period, batch by batch,
+// and returns how many it deleted.
+func (p *TombstonePurger) RunOnce(ctx context.Context) (int, error) {
+	cutoff := time.Now().UTC().Add(-p.retention)
+	total := 0
+	for {
+		n, err := p.repo.PurgeTombstones(ctx, cutoff, trashBatch)
+		total += n
+		if err != nil || n < trashBatch {
+			return total, err
+		}
+	}
+}
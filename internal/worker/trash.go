@@ -0,0 +1,58 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"Worker/internal/repo"
+)
+
+// trashBatch is how many todos one purge statement deletes at most.
+const trashBatch = 500
+
+// TrashPurger hard-deletes todos that have been in the trash longer than the
+// retention period. Any number of purgers may run against the same database.
+type TrashPurger struct {
+	repo      repo.TrashRepo
+	retention time.Duration
+	interval  time.Duration
+}
+
+// NewTrashPurger creates a purger that runs every interval and deletes todos
+// deleted more than retention ago.
+func NewTrashPurger(r repo.TrashRepo, retention, interval time.Duration) *TrashPurger {
+	return &TrashPurger{repo: r, retention: retention, interval: interval}
+}
+
+// Run purges until ctx is cancelled.
+func (p *TrashPurger) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		if n, err := p.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("trash: %v", err)
+		} else if n > 0 {
+			log.Printf("trash: purged %d todos", n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce deletes every todo past the retention period, batch by batch, and
+// returns how many it deleted (subtasks included).
+func (p *TrashPurger) RunOnce(ctx context.Context) (int, error) {
+	cutoff := time.Now().UTC().Add(-p.retention)
+	total := 0
+	for {
+		n, err := p.repo.PurgeTrashed(ctx, cutoff, trashBatch)
+		total += n
+		if err != nil || n < trashBatch {
+			return total, err
+		}
+	}
+}
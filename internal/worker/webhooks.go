@@ -0,0 +1,216 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	dom "Worker/internal/domain"
+	"Worker/internal/repo"
+	"Worker/internal/webhook"
+)
+
+// webhookLease is how long claimed deliveries stay reserved for the dispatcher
+// sending them; deliveries of a dispatcher that dies mid-batch are due again
+// after it. Sends are cancelled when the lease runs out.
+const webhookLease = 5 * time.Minute
+
+// WebhookDispatcher sends queued webhook deliveries and schedules retries.
+// Any number of dispatchers may run against the same database.
+type WebhookDispatcher struct {
+	repo         repo.WebhookRepo
+	sender       *webhook.Sender
+	interval     time.Duration
+	batch        int
+	maxAttempts  int
+	retryBase    time.Duration
+	disableAfter int
+}
+
+// WebhookDispatcherOptions configures a WebhookDispatcher.
+type WebhookDispatcherOptions struct {
+	Interval time.Duration
+	Batch    int
+	// MaxAttempts is how often a delivery is tried before it is marked failed;
+	// the n-th retry waits RetryBase·2^(n-1).
+	MaxAttempts int
+	RetryBase   time.Duration
+	// DisableAfter is how many failed attempts in a row disable a webhook.
+	DisableAfter int
+}
+
+// NewWebhookDispatcher creates a dispatcher that polls every opts.Interval and
+// claims at most opts.Batch deliveries at a time.
+func NewWebhookDispatcher(r repo.WebhookRepo, s *webhook.Sender, opts WebhookDispatcherOptions) *WebhookDispatcher {
+	return &WebhookDispatcher{
+		repo:         r,
+		sender:       s,
+		interval:     opts.Interval,
+		batch:        opts.Batch,
+		maxAttempts:  opts.MaxAttempts,
+		retryBase:    opts.RetryBase,
+		disableAfter: opts.DisableAfter,
+	}
+}
+
+// Run polls until ctx is cancelled.
+func (d *WebhookDispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+	for {
+		if n, err := d.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("webhooks: %v", err)
+		} else if n > 0 {
+			log.Printf("webhooks: processed %d deliveries", n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce sends every delivery that is due now, batch by batch, and returns
+// how many were claimed. Like the reminder scheduler it stops after a batch
+// with failures, so those are not hammered before their retry time anyway.
+func (d *WebhookDispatcher) RunOnce(ctx context.Context) (int, error) {
+	total := 0
+	for {
+		now := time.Now().UTC()
+		until := now.Add(webhookLease)
+		list, err := d.repo.ClaimDue(ctx, now, until, d.batch)
+		if err != nil || len(list) == 0 {
+			return total, err
+		}
+		total += len(list)
+		attempts, failed := d.send(ctx, list, until)
+		// Attempts are recorded even when ctx is cancelled meanwhile, or
+		// delivered events would be sent again.
+		err = d.repo.RecordAttempts(context.WithoutCancel(ctx), until, list, attempts, webhookFailures(list, attempts), d.disable)
+		if err != nil {
+			return total, err
+		}
+		if len(list) < d.batch || failed {
+			return total, nil
+		}
+	}
+}
+
+// send posts a claimed batch in parallel within its lease and returns one
+// attempt per delivery, with the retry time of failed ones set, and whether
+// any failed.
+func (d *WebhookDispatcher) send(ctx context.Context, list []dom.WebhookDelivery, until time.Time) ([]dom.WebhookAttempt, bool) {
+	ctx, cancel := context.WithDeadline(ctx, until)
+	defer cancel()
+	attempts := make([]dom.WebhookAttempt, len(list))
+	var wg sync.WaitGroup
+	for i := range list {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			attempts[i] = d.sender.Send(ctx, list[i])
+		}()
+	}
+	wg.Wait()
+	failed := false
+	for i := range attempts {
+		a := &attempts[i]
+		if a.OK() {
+			continue
+		}
+		failed = true
+		if a.Attempt < d.maxAttempts {
+			retry := time.Now().UTC().Add(d.backoff(a.Attempt))
+			a.RetryAt = &retry
+		}
+		log.Printf("webhooks: delivery %d (webhook %d), attempt %d: %s", list[i].ID, list[i].WebhookID, a.Attempt, a.Error)
+	}
+	return attempts, failed
+}
+
+// webhookFailures folds a batch of attempts, in order, into the change of each
+// webhook's count of failed attempts in a row: a delivered attempt starts the
+// count over, a failed one adds to it.
+func webhookFailures(list []dom.WebhookDelivery, attempts []dom.WebhookAttempt) []repo.WebhookFailures {
+	var out []repo.WebhookFailures
+	index := make(map[int64]int)
+	for i, a := range attempts {
+		j, ok := index[list[i].WebhookID]
+		if !ok {
+			j = len(out)
+			index[list[i].WebhookID] = j
+			out = append(out, repo.WebhookFailures{WebhookID: list[i].WebhookID})
+		}
+		if a.OK() {
+			out[j].Reset, out[j].Add = true, 0
+		} else {
+			out[j].Add++
+		}
+	}
+	return out
+}
+
+// disable reports whether a webhook that has failed failures times in a row is
+// to be disabled.
+func (d *WebhookDispatcher) disable(failures int) bool {
+	return d.disableAfter > 0 && failures >= d.disableAfter
+}
+
+// backoff is the wait after the given failed attempt, capped at a day.
+func (d *WebhookDispatcher) backoff(attempt int) time.Duration {
+	wait := d.retryBase
+	for i := 1; i < attempt && wait < 24*time.Hour; i++ {
+		wait *= 2
+	}
+	return min(wait, 24*time.Hour)
+}
+
+// OverdueScanner queues todo.overdue webhook events for open todos whose due
+// date has passed. Each todo is reported once per due date.
+type OverdueScanner struct {
+	repo     repo.WebhookRepo
+	interval time.Duration
+	batch    int
+}
+
+// NewOverdueScanner creates a scanner that runs every interval and reports at
+// most batch todos per transaction.
+func NewOverdueScanner(r repo.WebhookRepo, interval time.Duration, batch int) *OverdueScanner {
+	return &OverdueScanner{repo: r, interval: interval, batch: batch}
+}
+
+// Run scans until ctx is cancelled.
+func (s *OverdueScanner) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		if n, err := s.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("overdue: %v", err)
+		} else if n > 0 {
+			log.Printf("overdue: reported %d todos", n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce reports every todo that is overdue now and returns how many it
+// reported.
+func (s *OverdueScanner) RunOnce(ctx context.Context) (int, error) {
+	build := func(t dom.Todo) (dom.WebhookEvent, error) {
+		return webhook.NewEvent(dom.WebhookTodoOverdue, 0, t)
+	}
+	total := 0
+	for {
+		n, err := s.repo.QueueOverdue(ctx, time.Now().UTC(), s.batch, build)
+		total += n
+		if err != nil || n < s.batch {
+			return total, err
+		}
+	}
+}
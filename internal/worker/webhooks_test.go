@@ -0,0 +1,288 @@
+package worker
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sort"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	dom "Worker/internal/domain"
+	"Worker/internal/repo"
+	"Worker/internal/webhook"
+)
+
+// memWebhookRepo is a WebhookRepo over slices. It models no locking and does
+// not skip disabled webhooks; those are in the SQL of PGWebhookRepo. Only
+// ClaimDue and RecordAttempts are implemented.
+type memWebhookRepo struct {
+	repo.WebhookRepo
+	webhooks   map[int64]*memWebhook
+	deliveries []*dom.WebhookDelivery
+	attempts   []dom.WebhookAttempt
+}
+
+type memWebhook struct {
+	url          string
+	failureCount int
+	disabled     bool
+}
+
+func newMemWebhookRepo(urls map[int64]string) *memWebhookRepo {
+	r := &memWebhookRepo{webhooks: make(map[int64]*memWebhook)}
+	for id, url := range urls {
+		r.webhooks[id] = &memWebhook{url: url}
+	}
+	return r
+}
+
+// queue adds a pending delivery to webhookID that is due now.
+func (r *memWebhookRepo) queue(webhookID int64) *dom.WebhookDelivery {
+	d := &dom.WebhookDelivery{
+		ID:            int64(len(r.deliveries) + 1),
+		WebhookID:     webhookID,
+		EventID:       "evt_test",
+		Event:         dom.WebhookTodoUpdated,
+		Payload:       []byte(`{}`),
+		Status:        "pending",
+		NextAttemptAt: time.Now().UTC().Add(-time.Second),
+	}
+	r.deliveries = append(r.deliveries, d)
+	return d
+}
+
+// rewind makes every pending delivery due now, as if its retry time had come.
+func (r *memWebhookRepo) rewind() {
+	for _, d := range r.deliveries {
+		if d.Status == "pending" {
+			d.NextAttemptAt = time.Now().UTC().Add(-time.Second)
+		}
+	}
+}
+
+func (r *memWebhookRepo) ClaimDue(ctx context.Context, now, until time.Time, limit int) ([]dom.WebhookDelivery, error) {
+	var due []*dom.WebhookDelivery
+	for _, d := range r.deliveries {
+		if d.Status == "pending" && !d.NextAttemptAt.After(now) {
+			due = append(due, d)
+		}
+	}
+	sort.SliceStable(due, func(i, j int) bool { return due[i].NextAttemptAt.Before(due[j].NextAttemptAt) })
+	if len(due) > limit {
+		due = due[:limit]
+	}
+	list := make([]dom.WebhookDelivery, len(due))
+	for i, d := range due {
+		d.NextAttemptAt = until
+		list[i] = *d
+		list[i].URL = r.webhooks[d.WebhookID].url
+	}
+	return list, nil
+}
+
+func (r *memWebhookRepo) RecordAttempts(ctx context.Context, until time.Time, list []dom.WebhookDelivery, attempts []dom.WebhookAttempt,
+	failures []repo.WebhookFailures, disable func(failures int) bool) error {
+	for i, claimed := range list {
+		d := r.deliveries[claimed.ID-1]
+		if d.Status != "pending" || !d.NextAttemptAt.Equal(until) {
+			continue
+		}
+		a := attempts[i]
+		r.attempts = append(r.attempts, a)
+		d.Attempts++
+		switch {
+		case a.OK():
+			d.Status = "succeeded"
+		case a.RetryAt == nil:
+			d.Status = "failed"
+		default:
+			d.NextAttemptAt = *a.RetryAt
+		}
+	}
+	for _, f := range failures {
+		w := r.webhooks[f.WebhookID]
+		if f.Reset {
+			w.failureCount = 0
+		}
+		w.failureCount += f.Add
+		if !w.disabled && f.Add > 0 && disable(w.failureCount) {
+			w.disabled = true
+		}
+	}
+	return nil
+}
+
+// statusServer answers every request with the status it holds.
+func statusServer(t *testing.T, status *atomic.Int32) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(int(status.Load()))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestWebhookDispatcherBackoff(t *testing.T) {
+	d := NewWebhookDispatcher(nil, nil, WebhookDispatcherOptions{RetryBase: time.Minute})
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{1, time.Minute},
+		{2, 2 * time.Minute},
+		{3, 4 * time.Minute},
+		{8, 128 * time.Minute},
+		{11, 1024 * time.Minute},
+		{12, 24 * time.Hour}, // 2048m, capped
+		{100, 24 * time.Hour},
+	}
+	for _, tt := range tests {
+		if got := d.backoff(tt.attempt); got != tt.want {
+			t.Errorf("backoff(%d) = %v, want %v", tt.attempt, got, tt.want)
+		}
+	}
+}
+
+func TestWebhookDispatcherRetriesWithBackoff(t *testing.T) {
+	var status atomic.Int32
+	status.Store(http.StatusInternalServerError)
+	srv := statusServer(t, &status)
+	webhooks := newMemWebhookRepo(map[int64]string{1: srv.URL})
+	delivery := webhooks.queue(1)
+	d := NewWebhookDispatcher(webhooks, webhook.NewSender(time.Second, true), WebhookDispatcherOptions{
+		Batch: 10, MaxAttempts: 3, RetryBase: time.Minute,
+	})
+
+	for attempt := 1; attempt <= 3; attempt++ {
+		start := time.Now().UTC()
+		if n, err := d.RunOnce(context.Background()); err != nil || n != 1 {
+			t.Fatalf("RunOnce %d = %d, %v; want 1, nil", attempt, n, err)
+		}
+		a := webhooks.attempts[len(webhooks.attempts)-1]
+		if a.Attempt != attempt || a.StatusCode != http.StatusInternalServerError || a.OK() {
+			t.Fatalf("attempt %d recorded as attempt %d, status %d", attempt, a.Attempt, a.StatusCode)
+		}
+		if attempt == 3 {
+			if a.RetryAt != nil {
+				t.Errorf("the last attempt was rescheduled for %v, want it given up", a.RetryAt)
+			}
+			break
+		}
+		if a.RetryAt == nil {
+			t.Fatalf("attempt %d was given up, want a retry", attempt)
+		}
+		wait := d.backoff(attempt)
+		if a.RetryAt.Before(start.Add(wait)) || a.RetryAt.After(time.Now().UTC().Add(wait)) {
+			t.Errorf("attempt %d: retry at %v, want %v after the attempt", attempt, a.RetryAt, wait)
+		}
+		// Not due before its retry time.
+		if n, err := d.RunOnce(context.Background()); err != nil || n != 0 {
+			t.Errorf("RunOnce before the retry time = %d, %v; want 0, nil", n, err)
+		}
+		webhooks.rewind()
+	}
+	if delivery.Status != "failed" || delivery.Attempts != 3 {
+		t.Errorf("delivery is %s after %d attempts, want failed after 3", delivery.Status, delivery.Attempts)
+	}
+}
+
+func TestWebhookFailures(t *testing.T) {
+	ok := dom.WebhookAttempt{StatusCode: http.StatusOK}
+	failed := dom.WebhookAttempt{StatusCode: http.StatusServiceUnavailable, Error: "unexpected status 503 Service Unavailable"}
+	tests := []struct {
+		name     string
+		webhooks []int64 // the webhook of each delivery
+		attempts []dom.WebhookAttempt
+		want     []repo.WebhookFailures
+	}{
+		{name: "none"},
+		{name: "failures add up", webhooks: []int64{1, 1}, attempts: []dom.WebhookAttempt{failed, failed},
+			want: []repo.WebhookFailures{{WebhookID: 1, Add: 2}}},
+		{name: "success resets", webhooks: []int64{1}, attempts: []dom.WebhookAttempt{ok},
+			want: []repo.WebhookFailures{{WebhookID: 1, Reset: true}}},
+		{name: "failures after a success count", webhooks: []int64{1, 1, 1}, attempts: []dom.WebhookAttempt{failed, ok, failed},
+			want: []repo.WebhookFailures{{WebhookID: 1, Reset: true, Add: 1}}},
+		{name: "a success forgets earlier failures", webhooks: []int64{1, 1}, attempts: []dom.WebhookAttempt{failed, ok},
+			want: []repo.WebhookFailures{{WebhookID: 1, Reset: true}}},
+		{name: "per webhook", webhooks: []int64{2, 1, 2}, attempts: []dom.WebhookAttempt{failed, ok, failed},
+			want: []repo.WebhookFailures{{WebhookID: 2, Add: 2}, {WebhookID: 1, Reset: true}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list := make([]dom.WebhookDelivery, len(tt.webhooks))
+			for i, id := range tt.webhooks {
+				list[i] = dom.WebhookDelivery{ID: int64(i + 1), WebhookID: id}
+			}
+			if got := webhookFailures(list, tt.attempts); !slices.Equal(got, tt.want) {
+				t.Errorf("webhookFailures = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWebhookDispatcherDisable(t *testing.T) {
+	tests := []struct {
+		disableAfter int
+		failures     int
+		want         bool
+	}{
+		{0, 1, false},
+		{0, 1000, false},
+		{3, 1, false},
+		{3, 2, false},
+		{3, 3, true},
+		{3, 4, true},
+	}
+	for _, tt := range tests {
+		d := NewWebhookDispatcher(nil, nil, WebhookDispatcherOptions{DisableAfter: tt.disableAfter})
+		if got := d.disable(tt.failures); got != tt.want {
+			t.Errorf("DisableAfter %d: disable(%d) = %v, want %v", tt.disableAfter, tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestWebhookDispatcherDisablesAfterFailures(t *testing.T) {
+	var status atomic.Int32
+	status.Store(http.StatusServiceUnavailable)
+	srv := statusServer(t, &status)
+	webhooks := newMemWebhookRepo(map[int64]string{1: srv.URL, 2: srv.URL})
+	d := NewWebhookDispatcher(webhooks, webhook.NewSender(time.Second, true), WebhookDispatcherOptions{
+		Batch: 10, MaxAttempts: 10, RetryBase: time.Minute, DisableAfter: 3,
+	})
+	run := func(want int) {
+		t.Helper()
+		if n, err := d.RunOnce(context.Background()); err != nil || n != want {
+			t.Fatalf("RunOnce = %d, %v; want %d, nil", n, err, want)
+		}
+	}
+
+	// Two failures in a row, then a success: the count starts over.
+	webhooks.queue(1)
+	webhooks.queue(1)
+	run(2)
+	if w := webhooks.webhooks[1]; w.failureCount != 2 || w.disabled {
+		t.Fatalf("webhook after 2 failures: count %d, disabled %v; want 2, enabled", w.failureCount, w.disabled)
+	}
+	status.Store(http.StatusOK)
+	webhooks.rewind()
+	run(2)
+	if w := webhooks.webhooks[1]; w.failureCount != 0 || w.disabled {
+		t.Fatalf("webhook after a success: count %d, disabled %v; want 0, enabled", w.failureCount, w.disabled)
+	}
+
+	// Three failures in a row disable it, but not another webhook that failed once.
+	status.Store(http.StatusServiceUnavailable)
+	for range 3 {
+		webhooks.queue(1)
+	}
+	webhooks.queue(2)
+	run(4)
+	if w := webhooks.webhooks[1]; w.failureCount != 3 || !w.disabled {
+		t.Fatalf("webhook after 3 failures: count %d, disabled %v; want 3, disabled", w.failureCount, w.disabled)
+	}
+	if w := webhooks.webhooks[2]; w.failureCount != 1 || w.disabled {
+		t.Fatalf("other webhook after 1 failure: count %d, disabled %v; want 1, enabled", w.failureCount, w.disabled)
+	}
+}
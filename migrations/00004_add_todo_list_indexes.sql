@@ -0,0 +1,10 @@
+-- +goose Up
+-- Keyset pagination on GET /todos: (sort key, id) per user, live rows only.
+CREATE INDEX IF NOT EXISTS idx_todos_user_created ON todos (user_id, created_at, id) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_todos_user_updated ON todos (user_id, updated_at, id) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_todos_user_due ON todos (user_id, (COALESCE(due_at, 'infinity'::timestamptz)), id) WHERE deleted_at IS NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_todos_user_due;
+DROP INDEX IF EXISTS idx_todos_user_updated;
+DROP INDEX IF EXISTS idx_todos_user_created;
@@ -0,0 +1,12 @@
+-- +goose Up
+-- Full-text search: title weighs more than description. 'simple' config: no stemming,
+-- so mixed-language todos are matched as typed (prefix search covers word forms).
+ALTER TABLE todos ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('simple', COALESCE(title, '')), 'A') ||
+    setweight(to_tsvector('simple', COALESCE(description, '')), 'B')
+) STORED;
+CREATE INDEX IF NOT EXISTS idx_todos_search_vector ON todos USING GIN (search_vector);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_todos_search_vector;
+ALTER TABLE todos DROP COLUMN search_vector;
@@ -0,0 +1,22 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS tags (
+    id         BIGSERIAL PRIMARY KEY,
+    user_id    BIGINT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    name       VARCHAR(64) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Tag names are unique per user, case-insensitively.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tags_user_name ON tags (user_id, lower(name));
+
+CREATE TABLE IF NOT EXISTS todo_tags (
+    todo_id BIGINT NOT NULL REFERENCES todos (id) ON DELETE CASCADE,
+    tag_id  BIGINT NOT NULL REFERENCES tags (id) ON DELETE CASCADE,
+    PRIMARY KEY (todo_id, tag_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_todo_tags_tag_id ON todo_tags (tag_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS todo_tags;
+DROP TABLE IF EXISTS tags;
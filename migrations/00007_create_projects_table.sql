@@ -0,0 +1,32 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS projects (
+    id         BIGSERIAL PRIMARY KEY,
+    user_id    BIGINT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    name       VARCHAR(120) NOT NULL,
+    color      VARCHAR(16),
+    archived   BOOLEAN NOT NULL DEFAULT FALSE,
+    is_inbox   BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_projects_user_id ON projects (user_id);
+-- At most one inbox per user; it is created on first use.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_projects_user_inbox ON projects (user_id) WHERE is_inbox;
+
+ALTER TABLE todos ADD COLUMN project_id BIGINT REFERENCES projects (id) ON DELETE SET NULL;
+-- Manual order inside a project (or among todos without one). Positions are sparse
+-- (step 1024), so a move usually updates one row; only a crowded list is renumbered.
+ALTER TABLE todos ADD COLUMN position BIGINT NOT NULL DEFAULT 0;
+
+UPDATE todos SET position = s.rn * 1024
+FROM (SELECT id, row_number() OVER (PARTITION BY user_id ORDER BY created_at, id) AS rn FROM todos) s
+WHERE todos.id = s.id;
+
+CREATE INDEX IF NOT EXISTS idx_todos_project_position ON todos (project_id, position, id) WHERE deleted_at IS NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_todos_project_position;
+ALTER TABLE todos DROP COLUMN position;
+ALTER TABLE todos DROP COLUMN project_id;
+DROP TABLE IF EXISTS projects;
@@ -0,0 +1,8 @@
+-- +goose Up
+-- Subtasks: a todo may belong to a parent todo. Nesting depth is bounded by the service.
+ALTER TABLE todos ADD COLUMN parent_id BIGINT REFERENCES todos (id) ON DELETE CASCADE;
+CREATE INDEX IF NOT EXISTS idx_todos_parent_id ON todos (parent_id, position, id) WHERE parent_id IS NOT NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_todos_parent_id;
+ALTER TABLE todos DROP COLUMN parent_id;
@@ -0,0 +1,13 @@
+-- +goose Up
+-- Recurring todos: an RFC 5545 RRULE evaluated in recurrence_tz, starting at recurrence_start
+-- (the due date of the first todo of the series). Completing one creates the next.
+ALTER TABLE todos
+    ADD COLUMN recurrence TEXT,
+    ADD COLUMN recurrence_tz VARCHAR(64),
+    ADD COLUMN recurrence_start TIMESTAMPTZ;
+
+-- +goose Down
+ALTER TABLE todos
+    DROP COLUMN recurrence_start,
+    DROP COLUMN recurrence_tz,
+    DROP COLUMN recurrence;
@@ -0,0 +1,20 @@
+-- +goose Up
+-- Reminders: "remind before_due before the todo is due". remind_at is kept equal to
+-- todos.due_at - before_due; moving the due date re-arms a reminder that was already sent.
+CREATE TABLE IF NOT EXISTS todo_reminders (
+    id         BIGSERIAL PRIMARY KEY,
+    todo_id    BIGINT      NOT NULL REFERENCES todos (id) ON DELETE CASCADE,
+    before_due INTERVAL    NOT NULL CHECK (before_due >= INTERVAL '0'),
+    remind_at  TIMESTAMPTZ,
+    sent_at    TIMESTAMPTZ,
+    attempts   INT         NOT NULL DEFAULT 0,
+    last_error TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (todo_id, before_due)
+);
+
+-- The worker polls pending reminders by time.
+CREATE INDEX IF NOT EXISTS idx_todo_reminders_pending ON todo_reminders (remind_at) WHERE sent_at IS NULL;
+
+-- +goose Down
+DROP TABLE IF EXISTS todo_reminders;
@@ -0,0 +1,19 @@
+-- +goose Up
+-- Personal access tokens. Only the SHA-256 of a token is stored; prefix is its first
+-- characters, shown in listings so the user can tell tokens apart.
+CREATE TABLE IF NOT EXISTS api_tokens (
+    id           BIGSERIAL PRIMARY KEY,
+    user_id      BIGINT       NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    name         VARCHAR(100) NOT NULL,
+    token_hash   CHAR(64)     NOT NULL UNIQUE,
+    prefix       VARCHAR(16)  NOT NULL,
+    scopes       TEXT[]       NOT NULL,
+    expires_at   TIMESTAMPTZ,
+    last_used_at TIMESTAMPTZ,
+    created_at   TIMESTAMPTZ  NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_tokens_user_id ON api_tokens (user_id, created_at DESC);
+
+-- +goose Down
+DROP TABLE IF EXISTS api_tokens;
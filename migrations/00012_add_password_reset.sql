@@ -0,0 +1,21 @@
+-- +goose Up
+-- Optional e-mail for password reset, unique without regard to case.
+ALTER TABLE users ADD COLUMN email VARCHAR(255);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users (lower(email)) WHERE email IS NOT NULL;
+
+-- Single-use reset tokens; only the SHA-256 of a token is stored.
+CREATE TABLE IF NOT EXISTS password_reset_tokens (
+    id         BIGSERIAL PRIMARY KEY,
+    user_id    BIGINT      NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    token_hash CHAR(64)    NOT NULL UNIQUE,
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at    TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_password_reset_tokens_user_id ON password_reset_tokens (user_id) WHERE used_at IS NULL;
+
+-- +goose Down
+DROP TABLE IF EXISTS password_reset_tokens;
+DROP INDEX IF EXISTS idx_users_email;
+ALTER TABLE users DROP COLUMN email;
@@ -0,0 +1,22 @@
+-- +goose Up
+-- TOTP secret per user, AES-GCM encrypted. enabled_at is NULL while enrollment awaits confirmation.
+CREATE TABLE IF NOT EXISTS user_mfa (
+    user_id        BIGINT PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+    secret_enc     BYTEA       NOT NULL,
+    enabled_at     TIMESTAMPTZ,
+    last_used_step BIGINT      NOT NULL DEFAULT 0,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- One-time recovery codes; only the SHA-256 of a code is stored.
+CREATE TABLE IF NOT EXISTS mfa_recovery_codes (
+    id         BIGSERIAL PRIMARY KEY,
+    user_id    BIGINT   NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    code_hash  CHAR(64) NOT NULL,
+    used_at    TIMESTAMPTZ,
+    UNIQUE (user_id, code_hash)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS mfa_recovery_codes;
+DROP TABLE IF EXISTS user_mfa;
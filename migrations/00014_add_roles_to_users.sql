@@ -0,0 +1,25 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN role VARCHAR(16) NOT NULL DEFAULT 'user' CHECK (role IN ('user', 'admin'));
+ALTER TABLE users ADD COLUMN disabled_at TIMESTAMPTZ;
+
+-- The seeded account from 00002 was only special by name.
+UPDATE users SET role = 'admin' WHERE username = 'admin';
+
+-- Administrative actions on accounts (disable, impersonate, ...).
+CREATE TABLE IF NOT EXISTS admin_audit_log (
+    id             BIGSERIAL PRIMARY KEY,
+    admin_id       BIGINT      REFERENCES users (id) ON DELETE SET NULL,
+    action         VARCHAR(64) NOT NULL,
+    target_user_id BIGINT      REFERENCES users (id) ON DELETE SET NULL,
+    details        JSONB       NOT NULL DEFAULT '{}',
+    ip             VARCHAR(64) NOT NULL DEFAULT '',
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_admin_audit_log_created_at ON admin_audit_log (created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_admin_audit_log_target ON admin_audit_log (target_user_id, created_at DESC);
+
+-- +goose Down
+DROP TABLE IF EXISTS admin_audit_log;
+ALTER TABLE users DROP COLUMN disabled_at;
+ALTER TABLE users DROP COLUMN role;
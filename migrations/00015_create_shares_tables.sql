@@ -0,0 +1,60 @@
+-- +goose Up
+-- A todo share covers the todo and its whole subtree; a project share covers every
+-- todo of the project. The owner of a todo (todos.user_id) never has a share row.
+CREATE TABLE IF NOT EXISTS todo_shares (
+    todo_id    BIGINT      NOT NULL REFERENCES todos (id) ON DELETE CASCADE,
+    user_id    BIGINT      NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    permission VARCHAR(16) NOT NULL CHECK (permission IN ('viewer', 'editor', 'owner')),
+    granted_by BIGINT      REFERENCES users (id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (todo_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_todo_shares_user_id ON todo_shares (user_id);
+
+CREATE TABLE IF NOT EXISTS project_shares (
+    project_id BIGINT      NOT NULL REFERENCES projects (id) ON DELETE CASCADE,
+    user_id    BIGINT      NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    permission VARCHAR(16) NOT NULL CHECK (permission IN ('viewer', 'editor', 'owner')),
+    granted_by BIGINT      REFERENCES users (id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (project_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_project_shares_user_id ON project_shares (user_id);
+
+-- share_level orders permissions so the strongest grant wins: 0 = no access.
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION share_level(permission TEXT) RETURNS SMALLINT
+IMMUTABLE LANGUAGE sql AS $$
+    SELECT (CASE permission WHEN 'viewer' THEN 1 WHEN 'editor' THEN 2 WHEN 'owner' THEN 3 ELSE 0 END)::smallint
+$$;
+-- +goose StatementEnd
+
+-- todo_permission is the level a user has on a todo: 3 for its owner, otherwise the
+-- strongest share on the todo, any of its ancestors or the project they are in.
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION todo_permission(p_todo BIGINT, p_user BIGINT) RETURNS SMALLINT
+STABLE LANGUAGE sql AS $$
+    WITH RECURSIVE chain AS (
+        SELECT id, parent_id, user_id, project_id FROM todos WHERE id = p_todo
+        UNION ALL
+        SELECT t.id, t.parent_id, t.user_id, t.project_id FROM todos t JOIN chain c ON t.id = c.parent_id
+    )
+    SELECT COALESCE(max(level), 0)::smallint FROM (
+        SELECT 3 AS level FROM chain WHERE user_id = p_user
+        UNION ALL
+        SELECT share_level(s.permission) FROM todo_shares s JOIN chain c ON c.id = s.todo_id
+        WHERE s.user_id = p_user
+        UNION ALL
+        SELECT share_level(s.permission) FROM project_shares s JOIN chain c ON c.project_id = s.project_id
+        WHERE s.user_id = p_user
+    ) AS grants
+$$;
+-- +goose StatementEnd
+
+-- +goose Down
+DROP FUNCTION IF EXISTS todo_permission(BIGINT, BIGINT);
+DROP FUNCTION IF EXISTS share_level(TEXT);
+DROP TABLE IF EXISTS project_shares;
+DROP TABLE IF EXISTS todo_shares;
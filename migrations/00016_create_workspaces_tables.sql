@@ -0,0 +1,70 @@
+-- +goose Up
+-- Workspaces isolate teams: every todo and project lives in exactly one. Each user
+-- has a personal workspace (personal_user_id), created here for existing users and
+-- on first use for new ones.
+CREATE TABLE IF NOT EXISTS workspaces (
+    id               BIGSERIAL PRIMARY KEY,
+    name             VARCHAR(120) NOT NULL,
+    personal_user_id BIGINT       UNIQUE REFERENCES users (id) ON DELETE CASCADE,
+    created_by       BIGINT       REFERENCES users (id) ON DELETE SET NULL,
+    created_at       TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    updated_at       TIMESTAMPTZ  NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS workspace_members (
+    workspace_id BIGINT      NOT NULL REFERENCES workspaces (id) ON DELETE CASCADE,
+    user_id      BIGINT      NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    role         VARCHAR(16) NOT NULL CHECK (role IN ('owner', 'admin', 'member', 'viewer')),
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (workspace_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_workspace_members_user_id ON workspace_members (user_id);
+
+-- Only the SHA-256 of an invitation token is stored. email, when set, must match
+-- the account that accepts the invitation.
+CREATE TABLE IF NOT EXISTS workspace_invitations (
+    id           BIGSERIAL PRIMARY KEY,
+    workspace_id BIGINT       NOT NULL REFERENCES workspaces (id) ON DELETE CASCADE,
+    email        VARCHAR(255),
+    role         VARCHAR(16)  NOT NULL CHECK (role IN ('owner', 'admin', 'member', 'viewer')),
+    token_hash   CHAR(64)     NOT NULL UNIQUE,
+    invited_by   BIGINT       REFERENCES users (id) ON DELETE SET NULL,
+    expires_at   TIMESTAMPTZ  NOT NULL,
+    accepted_at  TIMESTAMPTZ,
+    accepted_by  BIGINT       REFERENCES users (id) ON DELETE SET NULL,
+    created_at   TIMESTAMPTZ  NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_workspace_invitations_workspace ON workspace_invitations (workspace_id, created_at DESC);
+
+INSERT INTO workspaces (name, personal_user_id, created_by) SELECT 'Personal', id, id FROM users;
+INSERT INTO workspace_members (workspace_id, user_id, role)
+SELECT id, personal_user_id, 'owner' FROM workspaces WHERE personal_user_id IS NOT NULL;
+
+ALTER TABLE projects ADD COLUMN workspace_id BIGINT REFERENCES workspaces (id) ON DELETE CASCADE;
+UPDATE projects p SET workspace_id = w.id FROM workspaces w WHERE w.personal_user_id = p.user_id;
+ALTER TABLE projects ALTER COLUMN workspace_id SET NOT NULL;
+-- One inbox per user and workspace.
+DROP INDEX IF EXISTS idx_projects_user_inbox;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_projects_user_inbox ON projects (user_id, workspace_id) WHERE is_inbox;
+CREATE INDEX IF NOT EXISTS idx_projects_workspace_user ON projects (workspace_id, user_id);
+
+ALTER TABLE todos ADD COLUMN workspace_id BIGINT REFERENCES workspaces (id) ON DELETE CASCADE;
+UPDATE todos t SET workspace_id = w.id FROM workspaces w WHERE w.personal_user_id = t.user_id;
+ALTER TABLE todos ALTER COLUMN workspace_id SET NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_todos_workspace_user_created ON todos (workspace_id, user_id, created_at, id) WHERE deleted_at IS NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_todos_workspace_user_created;
+ALTER TABLE todos DROP COLUMN workspace_id;
+DROP INDEX IF EXISTS idx_projects_workspace_user;
+DROP INDEX IF EXISTS idx_projects_user_inbox;
+-- Keep one inbox per user again; the others become regular projects.
+UPDATE projects SET is_inbox = FALSE
+WHERE is_inbox AND id NOT IN (SELECT min(id) FROM projects WHERE is_inbox GROUP BY user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_projects_user_inbox ON projects (user_id) WHERE is_inbox;
+ALTER TABLE projects DROP COLUMN workspace_id;
+DROP TABLE IF EXISTS workspace_invitations;
+DROP TABLE IF EXISTS workspace_members;
+DROP TABLE IF EXISTS workspaces;
@@ -0,0 +1,23 @@
+-- +goose Up
+-- Append-only history of todo mutations. todo_id has no foreign key so the
+-- history outlives the todo; deleting a workspace drops its history.
+CREATE TABLE IF NOT EXISTS todo_events (
+    id           BIGSERIAL PRIMARY KEY,
+    todo_id      BIGINT      NOT NULL,
+    workspace_id BIGINT      NOT NULL REFERENCES workspaces (id) ON DELETE CASCADE,
+    owner_id     BIGINT      REFERENCES users (id) ON DELETE SET NULL,
+    actor_id     BIGINT      REFERENCES users (id) ON DELETE SET NULL,
+    action       VARCHAR(32) NOT NULL,
+    -- field -> {"from": ..., "to": ...}
+    changes      JSONB       NOT NULL DEFAULT '{}',
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_todo_events_todo ON todo_events (todo_id, id DESC);
+CREATE INDEX IF NOT EXISTS idx_todo_events_owner ON todo_events (owner_id, workspace_id, id DESC);
+CREATE INDEX IF NOT EXISTS idx_todo_events_actor ON todo_events (actor_id, id DESC);
+CREATE INDEX IF NOT EXISTS idx_todo_events_workspace ON todo_events (workspace_id, id DESC);
+CREATE INDEX IF NOT EXISTS idx_todo_events_created_at ON todo_events (created_at DESC, id DESC);
+
+-- +goose Down
+DROP TABLE IF EXISTS todo_events;
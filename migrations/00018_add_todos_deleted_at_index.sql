@@ -0,0 +1,8 @@
+-- +goose Up
+-- The trash list and the retention job only look at deleted rows.
+CREATE INDEX IF NOT EXISTS idx_todos_trash ON todos (user_id, workspace_id, deleted_at DESC, id DESC) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_todos_deleted_at ON todos (deleted_at, id) WHERE deleted_at IS NOT NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_todos_deleted_at;
+DROP INDEX IF EXISTS idx_todos_trash;
@@ -0,0 +1,22 @@
+-- +goose Up
+-- version is the optimistic-concurrency counter behind the todo ETag. The trigger
+-- bumps it on every UPDATE, so no write path can forget to.
+ALTER TABLE todos ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION bump_todo_version() RETURNS TRIGGER
+LANGUAGE plpgsql AS $$
+BEGIN
+    NEW.version := OLD.version + 1;
+    RETURN NEW;
+END
+$$;
+-- +goose StatementEnd
+
+CREATE TRIGGER todos_bump_version BEFORE UPDATE ON todos
+FOR EACH ROW EXECUTE FUNCTION bump_todo_version();
+
+-- +goose Down
+DROP TRIGGER IF EXISTS todos_bump_version ON todos;
+DROP FUNCTION IF EXISTS bump_todo_version();
+ALTER TABLE todos DROP COLUMN IF EXISTS version;
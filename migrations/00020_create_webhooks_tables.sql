@@ -0,0 +1,76 @@
+-- +goose Up
+-- Webhook endpoints of a user. secret is the HMAC-SHA256 key deliveries are signed
+-- with; failure_count counts failed attempts in a row and disabled_at is set once
+-- it reaches the limit (or when the user turns the webhook off).
+CREATE TABLE IF NOT EXISTS webhooks (
+    id            BIGSERIAL PRIMARY KEY,
+    user_id       BIGINT        NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    url           VARCHAR(2048) NOT NULL,
+    description   VARCHAR(255)  NOT NULL DEFAULT '',
+    events        TEXT[]        NOT NULL,
+    secret        VARCHAR(64)   NOT NULL,
+    failure_count INT           NOT NULL DEFAULT 0,
+    disabled_at   TIMESTAMPTZ,
+    created_at    TIMESTAMPTZ   NOT NULL DEFAULT NOW(),
+    updated_at    TIMESTAMPTZ   NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhooks_user_id ON webhooks (user_id);
+
+-- Outbox: one row per event and webhook, inserted in the transaction of the todo
+-- change. The worker sends pending rows and retries failures at next_attempt_at.
+-- todo_id has no foreign key: deliveries outlive purged todos.
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id               BIGSERIAL PRIMARY KEY,
+    webhook_id       BIGINT      NOT NULL REFERENCES webhooks (id) ON DELETE CASCADE,
+    event_id         VARCHAR(64) NOT NULL,
+    event            VARCHAR(32) NOT NULL,
+    todo_id          BIGINT      NOT NULL,
+    payload          JSONB       NOT NULL,
+    status           VARCHAR(16) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'succeeded', 'failed')),
+    attempts         INT         NOT NULL DEFAULT 0,
+    next_attempt_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_status_code INT,
+    last_error       TEXT,
+    delivered_at     TIMESTAMPTZ,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- The worker polls pending deliveries by time; the API lists them per webhook.
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_pending ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook ON webhook_deliveries (webhook_id, id DESC);
+
+-- Every delivery attempt with the receiver's answer; status_code is NULL when
+-- there was no response.
+CREATE TABLE IF NOT EXISTS webhook_delivery_attempts (
+    id          BIGSERIAL PRIMARY KEY,
+    delivery_id BIGINT      NOT NULL REFERENCES webhook_deliveries (id) ON DELETE CASCADE,
+    attempt     INT         NOT NULL,
+    status_code INT,
+    error       TEXT,
+    response    TEXT        NOT NULL DEFAULT '',
+    duration_ms INT         NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_delivery_attempts_delivery ON webhook_delivery_attempts (delivery_id, id);
+
+-- The due date a todo.overdue event was last queued for, so each due date is
+-- reported once. Todos that are overdue already are not reported.
+CREATE TABLE IF NOT EXISTS todo_overdue_notices (
+    todo_id BIGINT PRIMARY KEY REFERENCES todos (id) ON DELETE CASCADE,
+    due_at  TIMESTAMPTZ NOT NULL
+);
+
+INSERT INTO todo_overdue_notices (todo_id, due_at)
+SELECT id, due_at FROM todos WHERE due_at <= NOW() AND is_done = FALSE AND deleted_at IS NULL;
+
+-- The worker looks for open todos by due date across all users.
+CREATE INDEX IF NOT EXISTS idx_todos_open_due ON todos (due_at) WHERE is_done = FALSE AND deleted_at IS NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_todos_open_due;
+DROP TABLE IF EXISTS todo_overdue_notices;
+DROP TABLE IF EXISTS webhook_delivery_attempts;
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhooks;
@@ -0,0 +1,97 @@
+-- +goose Up
+-- Delta sync. Every write of a todo stamps it with the next value of a global
+-- sequence and the ID of the writing transaction. Sequence values are drawn
+-- before commit, so a row may become visible after rows with higher values; the
+-- transaction ID lets the sync feed pick such late rows up (see PGTodoRepo.Changes).
+CREATE SEQUENCE IF NOT EXISTS todo_change_seq;
+
+-- The volatile default numbers the existing rows; change_xid stays NULL for them.
+ALTER TABLE todos
+    ADD COLUMN IF NOT EXISTS change_seq BIGINT NOT NULL DEFAULT nextval('todo_change_seq'),
+    ADD COLUMN IF NOT EXISTS change_xid xid8;
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION stamp_todo_change() RETURNS TRIGGER
+LANGUAGE plpgsql AS $$
+BEGIN
+    -- Transaction ID first: it is then never newer than the sequence value.
+    NEW.change_xid := pg_current_xact_id();
+    NEW.change_seq := nextval('todo_change_seq');
+    RETURN NEW;
+END
+$$;
+-- +goose StatementEnd
+
+CREATE TRIGGER todos_stamp_change BEFORE INSERT OR UPDATE ON todos
+FOR EACH ROW EXECUTE FUNCTION stamp_todo_change();
+
+CREATE INDEX IF NOT EXISTS idx_todos_change_seq ON todos (user_id, workspace_id, change_seq);
+CREATE INDEX IF NOT EXISTS idx_todos_change_xid ON todos (user_id, workspace_id, change_xid);
+
+-- Hard-deleted todos (purged from the trash or deleted permanently) leave a
+-- tombstone, so sync clients learn about them. The worker drops tombstones once
+-- no sync token can be old enough to need them.
+CREATE TABLE IF NOT EXISTS todo_tombstones (
+    todo_id      BIGINT      PRIMARY KEY,
+    user_id      BIGINT      NOT NULL,
+    workspace_id BIGINT      NOT NULL,
+    change_seq   BIGINT      NOT NULL DEFAULT nextval('todo_change_seq'),
+    change_xid   xid8        NOT NULL DEFAULT pg_current_xact_id(),
+    purged_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_todo_tombstones_change_seq ON todo_tombstones (user_id, workspace_id, change_seq);
+CREATE INDEX IF NOT EXISTS idx_todo_tombstones_change_xid ON todo_tombstones (user_id, workspace_id, change_xid);
+CREATE INDEX IF NOT EXISTS idx_todo_tombstones_purged_at ON todo_tombstones (purged_at);
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION record_todo_tombstones() RETURNS TRIGGER
+LANGUAGE plpgsql AS $$
+BEGIN
+    INSERT INTO todo_tombstones (todo_id, user_id, workspace_id)
+    SELECT id, user_id, workspace_id FROM gone
+    ON CONFLICT (todo_id) DO NOTHING;
+    RETURN NULL;
+END
+$$;
+-- +goose StatementEnd
+
+CREATE TRIGGER todos_record_tombstones AFTER DELETE ON todos
+REFERENCING OLD TABLE AS gone
+FOR EACH STATEMENT EXECUTE FUNCTION record_todo_tombstones();
+
+-- Renaming or deleting a tag changes the todos carrying it without writing their
+-- rows; touch them so they show up in the feed (and get a new version).
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION touch_tagged_todos() RETURNS TRIGGER
+LANGUAGE plpgsql AS $$
+BEGIN
+    UPDATE todos SET change_seq = change_seq
+    WHERE id IN (SELECT todo_id FROM todo_tags WHERE tag_id = OLD.id);
+    IF TG_OP = 'DELETE' THEN
+        RETURN OLD;
+    END IF;
+    RETURN NEW;
+END
+$$;
+-- +goose StatementEnd
+
+CREATE TRIGGER tags_touch_todos_on_rename BEFORE UPDATE OF name ON tags
+FOR EACH ROW WHEN (OLD.name IS DISTINCT FROM NEW.name) EXECUTE FUNCTION touch_tagged_todos();
+
+CREATE TRIGGER tags_touch_todos_on_delete BEFORE DELETE ON tags
+FOR EACH ROW EXECUTE FUNCTION touch_tagged_todos();
+
+-- +goose Down
+DROP TRIGGER IF EXISTS tags_touch_todos_on_delete ON tags;
+DROP TRIGGER IF EXISTS tags_touch_todos_on_rename ON tags;
+DROP FUNCTION IF EXISTS touch_tagged_todos();
+DROP TRIGGER IF EXISTS todos_record_tombstones ON todos;
+DROP FUNCTION IF EXISTS record_todo_tombstones();
+DROP TABLE IF EXISTS todo_tombstones;
+DROP INDEX IF EXISTS idx_todos_change_xid;
+DROP INDEX IF EXISTS idx_todos_change_seq;
+DROP TRIGGER IF EXISTS todos_stamp_change ON todos;
+DROP FUNCTION IF EXISTS stamp_todo_change();
+ALTER TABLE todos DROP COLUMN IF EXISTS change_xid, DROP COLUMN IF EXISTS change_seq;
+DROP SEQUENCE IF EXISTS todo_change_seq;
@@ -0,0 +1,17 @@
+-- +goose Up
+-- iCalendar feeds: a secret URL per user and workspace that calendar apps poll
+-- without a session. Only the SHA-256 of the secret is stored, as for api_tokens;
+-- creating the feed again replaces the secret.
+CREATE TABLE IF NOT EXISTS calendar_feeds (
+    id           BIGSERIAL PRIMARY KEY,
+    user_id      BIGINT      NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    workspace_id BIGINT      NOT NULL REFERENCES workspaces (id) ON DELETE CASCADE,
+    token_hash   CHAR(64)    NOT NULL UNIQUE,
+    prefix       VARCHAR(16) NOT NULL,
+    last_used_at TIMESTAMPTZ,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (user_id, workspace_id)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS calendar_feeds;
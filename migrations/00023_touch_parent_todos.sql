@@ -0,0 +1,50 @@
+-- +goose Up
+-- A todo's progress (children_done / children_total) is computed from its live
+-- subtasks but is part of its representation and ETag. Whenever a subtask is
+-- added, completed or reopened, moved, trashed, restored or purged, touch its old
+-- and new parent so their version (and change_seq) move on too. Touching a
+-- parent changes none of the columns looked at here, so the recursion stops there.
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION touch_parent_todos() RETURNS TRIGGER
+LANGUAGE plpgsql AS $$
+DECLARE
+    parents BIGINT[];
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        SELECT array_agg(DISTINCT parent_id) INTO parents
+        FROM new_rows WHERE parent_id IS NOT NULL AND deleted_at IS NULL;
+    ELSIF TG_OP = 'DELETE' THEN
+        SELECT array_agg(DISTINCT parent_id) INTO parents
+        FROM old_rows WHERE parent_id IS NOT NULL AND deleted_at IS NULL;
+    ELSE
+        SELECT array_agg(DISTINCT p.id) INTO parents
+        FROM old_rows o JOIN new_rows n ON n.id = o.id,
+            LATERAL (VALUES (o.parent_id), (n.parent_id)) AS p (id)
+        WHERE p.id IS NOT NULL
+            AND (o.is_done, o.parent_id, o.deleted_at IS NULL) IS DISTINCT FROM (n.is_done, n.parent_id, n.deleted_at IS NULL);
+    END IF;
+    IF parents IS NOT NULL THEN
+        UPDATE todos SET version = version WHERE id = ANY (parents);
+    END IF;
+    RETURN NULL;
+END
+$$;
+-- +goose StatementEnd
+
+CREATE TRIGGER todos_touch_parent_on_insert AFTER INSERT ON todos
+REFERENCING NEW TABLE AS new_rows
+FOR EACH STATEMENT EXECUTE FUNCTION touch_parent_todos();
+
+CREATE TRIGGER todos_touch_parent_on_update AFTER UPDATE ON todos
+REFERENCING OLD TABLE AS old_rows NEW TABLE AS new_rows
+FOR EACH STATEMENT EXECUTE FUNCTION touch_parent_todos();
+
+CREATE TRIGGER todos_touch_parent_on_delete AFTER DELETE ON todos
+REFERENCING OLD TABLE AS old_rows
+FOR EACH STATEMENT EXECUTE FUNCTION touch_parent_todos();
+
+-- +goose Down
+DROP TRIGGER IF EXISTS todos_touch_parent_on_delete ON todos;
+DROP TRIGGER IF EXISTS todos_touch_parent_on_update ON todos;
+DROP TRIGGER IF EXISTS todos_touch_parent_on_insert ON todos;
+DROP FUNCTION IF EXISTS touch_parent_todos();
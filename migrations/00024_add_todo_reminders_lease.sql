@@ -0,0 +1,9 @@
+-- +goose Up
+-- The worker claims due reminders by leasing them until leased_until and sends
+-- them after the claim has committed, so no transaction or row lock is held
+-- while a channel is slow. Reminders of a worker that dies mid-batch are
+-- claimed again once their lease has run out.
+ALTER TABLE todo_reminders ADD COLUMN IF NOT EXISTS leased_until TIMESTAMPTZ;
+
+-- +goose Down
+ALTER TABLE todo_reminders DROP COLUMN IF EXISTS leased_until;
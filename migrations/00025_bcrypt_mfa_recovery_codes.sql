@@ -0,0 +1,9 @@
+-- +goose Up
+-- Recovery codes are short enough to brute-force from a bare SHA-256, so new
+-- ones are stored as bcrypt hashes, which do not fit CHAR(64). Codes issued
+-- earlier keep their SHA-256 until used or regenerated.
+ALTER TABLE mfa_recovery_codes ALTER COLUMN code_hash TYPE TEXT;
+
+-- +goose Down
+DELETE FROM mfa_recovery_codes WHERE code_hash LIKE '$2%';
+ALTER TABLE mfa_recovery_codes ALTER COLUMN code_hash TYPE CHAR(64);